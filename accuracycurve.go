@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// The accuracy curve shows how accuracy holds up as tests get longer: the
+// average accuracy of runs in each band of text length, and the longest
+// band up to which it still meets target_accuracy, as the length to
+// practise at. Press L on the welcome screen to see it.
+
+// lengthBands are the lower bounds of the text length bands, in characters
+var lengthBands = []int{0, 100, 200, 400, 800, 1600}
+
+// accuracyCurveMinRuns is how many runs a band needs for its accuracy to
+// be trusted
+const accuracyCurveMinRuns = 3
+
+// defaultTargetAccuracy is the accuracy to hold when target_accuracy isn't
+// set
+const defaultTargetAccuracy = 95.0
+
+// lengthBand is the runs of one band of text lengths
+type lengthBand struct {
+	min, max int // characters, max exclusive; 0 for no upper bound
+	runs     int
+	accuracy float64 // average
+}
+
+func (b lengthBand) String() string {
+	switch {
+	case b.min == 0:
+		return fmt.Sprintf("under %d", b.max)
+	case b.max == 0:
+		return fmt.Sprintf("%d+", b.min)
+	}
+	return fmt.Sprintf("%d-%d", b.min, b.max-1)
+}
+
+// characters is about how many characters were typed in a run, worked
+// back from its speed
+func (r result) characters() int {
+	return int(math.Round(r.WPM * 5 * r.Duration.Minutes()))
+}
+
+// accuracyByLength averages the accuracy of results in each band of
+// lengthBands
+func accuracyByLength(results []result) []lengthBand {
+	bands := make([]lengthBand, len(lengthBands))
+	for i, lo := range lengthBands {
+		bands[i].min = lo
+		if i+1 < len(lengthBands) {
+			bands[i].max = lengthBands[i+1]
+		}
+	}
+	for _, r := range results {
+		n := r.characters()
+		i := len(bands) - 1
+		for bands[i].min > n {
+			i--
+		}
+		b := &bands[i]
+		b.accuracy = (b.accuracy*float64(b.runs) + r.Accuracy) / float64(b.runs+1)
+		b.runs++
+	}
+	return bands
+}
+
+// recommendedLength is the longest band up to which every band with
+// enough runs holds target. ok is false if the shortest one doesn't.
+func recommendedLength(bands []lengthBand, target float64) (best lengthBand, ok bool) {
+	for _, b := range bands {
+		if b.runs < accuracyCurveMinRuns {
+			continue
+		}
+		if b.accuracy < target {
+			break
+		}
+		best, ok = b, true
+	}
+	return best, ok
+}
+
+// accuracyAdvice says what the curve means for the length to practise at
+func accuracyAdvice(bands []lengthBand, target float64) string {
+	var charted []lengthBand
+	for _, b := range bands {
+		if b.runs >= accuracyCurveMinRuns {
+			charted = append(charted, b)
+		}
+	}
+	if len(charted) == 0 {
+		return fmt.Sprintf("Complete %d tests of a length to chart it", accuracyCurveMinRuns)
+	}
+	best, ok := recommendedLength(bands, target)
+	if !ok {
+		return fmt.Sprintf("Your accuracy is under %.0f%% even on texts of %s characters: slow down until it holds", target, charted[0])
+	}
+	advice := fmt.Sprintf("Your accuracy holds %.0f%% on texts up to %s characters; practise at about that length", target, best)
+	for _, b := range charted {
+		if b.min > best.min {
+			return advice + fmt.Sprintf(" (beyond it, %.1f%%)", b.accuracy)
+		}
+	}
+	return advice
+}
+
+// accuracyBar draws accuracy as a bar width cells long, from floor to
+// 100%, with a mark where target falls
+func accuracyBar(accuracy, target, floor float64, width int) string {
+	cell := func(v float64) int {
+		return max(0, min(width, int(math.Round((v-floor)/(100-floor)*float64(width)))))
+	}
+	bar := []rune(strings.Repeat("█", cell(accuracy)) + strings.Repeat("·", width-cell(accuracy)))
+	if mark := cell(target); mark < width {
+		bar[mark] = '│'
+	}
+	return string(bar)
+}
+
+// showAccuracyCurve charts accuracy by text length until a key is pressed
+func showAccuracyCurve(screen tcell.Screen, bands []lengthBand, target float64) {
+	floor := 80.0
+	for _, b := range bands {
+		if b.runs > 0 {
+			floor = min(floor, math.Floor(b.accuracy/10)*10)
+		}
+	}
+	for {
+		screen.Clear()
+		width, height := screen.Size()
+		drawCenteredText(screen, width/2, 1, tcell.StyleDefault, "ACCURACY BY TEXT LENGTH")
+		drawCenteredText(screen, width/2, 2, tcell.StyleDefault, fmt.Sprintf("Characters typed, bars from %.0f%% to 100%%, │ at your target of %.0f%%", floor, target))
+		barWidth := min(40, max(10, width-40))
+		x := max(0, (width-barWidth-36)/2)
+		for i, b := range bands {
+			row := fmt.Sprintf("%-10s %s", b, accuracyBar(b.accuracy, target, floor, barWidth))
+			switch {
+			case b.runs == 0:
+				row = fmt.Sprintf("%-10s %s", b, strings.Repeat(" ", barWidth))
+			case b.runs < accuracyCurveMinRuns:
+				row += fmt.Sprintf(" %5.1f%%  %d runs, too few", b.accuracy, b.runs)
+			default:
+				row += fmt.Sprintf(" %5.1f%%  %d runs", b.accuracy, b.runs)
+			}
+			style := tcell.StyleDefault
+			if b.runs >= accuracyCurveMinRuns && b.accuracy < target {
+				style = styleMissed
+			}
+			drawText(screen, x, 4+i, style, row)
+		}
+		drawCenteredText(screen, width/2, 5+len(bands), tcell.StyleDefault, accuracyAdvice(bands, target))
+		drawCenteredText(screen, width/2, height-1, tcell.StyleDefault, "Press any key to go back")
+		screen.Show()
+
+		switch screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			return
+		}
+	}
+}
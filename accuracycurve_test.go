@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// runOf is a result of about chars characters at 60 WPM
+func runOf(chars int, accuracy float64) result {
+	return result{WPM: 60, Duration: time.Duration(chars) * time.Minute / 300, Accuracy: accuracy}
+}
+
+func TestAccuracyByLength(t *testing.T) {
+	var results []result
+	for i := 0; i < 3; i++ {
+		results = append(results, runOf(60, 99), runOf(150, 97), runOf(300, 94), runOf(2000, 90))
+	}
+	results = append(results, runOf(500, 96))
+	bands := accuracyByLength(results)
+
+	for _, want := range []struct {
+		band     string
+		runs     int
+		accuracy float64
+	}{
+		{"under 100", 3, 99}, {"100-199", 3, 97}, {"200-399", 3, 94}, {"400-799", 1, 96}, {"800-1599", 0, 0}, {"1600+", 3, 90},
+	} {
+		i := -1
+		for j, b := range bands {
+			if b.String() == want.band {
+				i = j
+			}
+		}
+		if i < 0 {
+			t.Fatalf("no band %s in %v", want.band, bands)
+		}
+		if b := bands[i]; b.runs != want.runs || b.accuracy != want.accuracy {
+			t.Errorf("%s: %d runs at %.1f%%, want %d at %.1f%%", want.band, b.runs, b.accuracy, want.runs, want.accuracy)
+		}
+	}
+
+	if best, ok := recommendedLength(bands, 95); !ok || best.String() != "100-199" {
+		t.Errorf("recommended %v (ok %v), want 100-199", best, ok)
+	}
+	if advice := accuracyAdvice(bands, 95); !strings.Contains(advice, "up to 100-199 characters") || !strings.Contains(advice, "beyond it, 94.0%") {
+		t.Errorf("advice = %q", advice)
+	}
+	if advice := accuracyAdvice(bands, 99.5); !strings.Contains(advice, "even on texts of under 100") {
+		t.Errorf("advice for an unmet target = %q", advice)
+	}
+	if advice := accuracyAdvice(accuracyByLength(nil), 95); !strings.HasPrefix(advice, "Complete 3 tests") {
+		t.Errorf("advice with no runs = %q", advice)
+	}
+}
+
+func TestAccuracyBar(t *testing.T) {
+	if got := accuracyBar(95, 90, 80, 10); got != "█████│██··" {
+		t.Errorf("bar = %q", got)
+	}
+	if got := accuracyBar(85, 90, 80, 10); got != "███··│····" {
+		t.Errorf("bar under target = %q", got)
+	}
+}
+
+func TestShowAccuracyCurve(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(100, 20)
+	go screen.PostEventWait(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+
+	bands := accuracyByLength([]result{runOf(60, 99), runOf(60, 98), runOf(60, 97)})
+	showAccuracyCurve(screen, bands, 95)
+	if row := cellText(screen, 0, 4, 100); !strings.Contains(row, "under 100") || !strings.Contains(row, "98.0%  3 runs") {
+		t.Errorf("first band row = %q", row)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+// A test's final error count comes from aligning what was typed with the
+// reference rather than comparing them position by position. Skipping a
+// character puts everything typed after it one place out, so until it's
+// noticed every keystroke is scored wrong; aligned, the stretch counts as
+// the one omission it was. Each wrong stretch is aligned when the player
+// starts backspacing over it, and whatever is left wrong when the test
+// ends, and only the errors not already counted for the part of it kept
+// from last time are added.
+
+// slipCost is the number of errors in the wrong stretch at the end of the
+// input: from the first character that doesn't match the reference, the
+// fewest insertions, omissions and substitutions that make it a stretch
+// of the reference from there
+func (s *TestState) slipCost() int {
+	first := s.firstWrong()
+	typed := make([]string, 0, s.typed()-first)
+	for i := first; i < s.typed(); i++ {
+		end := len(s.userInput)
+		if i+1 < len(s.unitStarts) {
+			end = s.unitStarts[i+1]
+		}
+		typed = append(typed, s.userInput[s.unitStarts[i]:end])
+	}
+	return alignCost(typed, s.reference[min(first, len(s.reference)):])
+}
+
+// alignCost is the edit distance from typed to the prefix of reference it
+// comes closest to, counting clusters. A typed stretch can't be more than
+// len(typed) edits from anything, so no prefix longer than twice that need
+// be tried.
+func alignCost(typed, reference []string) int {
+	reference = reference[:min(len(reference), 2*len(typed))]
+	// row[j] is the cost of typed so far against reference[:j]
+	row := make([]int, len(reference)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i, t := range typed {
+		diagonal := row[0]
+		row[0] = i + 1
+		for j, r := range reference {
+			substitute := diagonal
+			if t != r {
+				substitute++
+			}
+			diagonal = row[j+1]
+			row[j+1] = min(substitute, row[j+1]+1, row[j]+1)
+		}
+	}
+	best := row[0]
+	for _, cost := range row {
+		best = min(best, cost)
+	}
+	return best
+}
+
+// afterBackspace reports whether the last key pressed was a backspace
+func (s *TestState) afterBackspace() bool {
+	return len(s.keys) > 0 && s.keys[len(s.keys)-1].r == backspaceKey
+}
+
+// countSlip adds the errors of the wrong stretch at the end of the input
+// to slipErrors, less those counted for it before it was last cut back
+func (s *TestState) countSlip() {
+	s.slipErrors += max(0, s.slipCost()-s.slipCredit)
+	s.slipCredit = 0
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlignCost(t *testing.T) {
+	for _, tt := range []struct {
+		typed, reference string
+		want             int
+	}{
+		{"", "own fox", 0},
+		{"wn fox", "own fox", 1}, // o left out
+		{"xown", "own fox", 1},   // x put in
+		{"ywn", "own fox", 1},    // o typed as y
+		{"wq", "own fox", 2},
+		{"abc", "own", 3},
+		{"own fox jumps", "own", 10}, // past the end of the text
+	} {
+		if got := alignCost(strings.Split(tt.typed, ""), strings.Split(tt.reference, "")); got != tt.want {
+			t.Errorf("alignCost(%q, %q) = %d, want %d", tt.typed, tt.reference, got, tt.want)
+		}
+	}
+}
+
+// typeKeys types keys into state, with '<' for a backspace
+func typeKeys(state *TestState, keys string) {
+	for _, r := range keys {
+		if r == '<' {
+			state.backspace()
+		} else {
+			state.typeRune(r)
+		}
+	}
+}
+
+func TestAlignedErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name, keys string
+		want       int
+	}{
+		{"clean", "the brown fox", 0},
+		{"a character left out", "the brwn f<<<<<rown fox", 1},
+		{"one put in", "the bxrown<<<<<rown fox", 1},
+		{"two substitutions", "the bzoxn<<<<rown fox", 2},
+		// Cutting back part of a stretch and carrying on counts only the
+		// new errors
+		{"partly corrected", "the bxy<z<<rown fox", 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			state := newTestState("the brown fox", "test.txt", &fakeClock{})
+			typeKeys(&state, tt.keys)
+			if !state.testComplete {
+				t.Fatalf("test didn't complete: typed %q", state.userInput)
+			}
+			if state.errors != tt.want {
+				t.Errorf("errors = %d, want %d", state.errors, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlignedErrorsWhenTimeRunsOut(t *testing.T) {
+	clock := &fakeClock{}
+	state := newTestState("the brown fox", "test.txt", clock)
+	state.timeLimit = time.Minute
+	typeKeys(&state, "the brwn fo")
+	clock.advance(time.Minute)
+	if !state.expire() {
+		t.Fatal("test didn't expire")
+	}
+	if state.errors != 1 {
+		t.Errorf("errors = %d, want 1 for the o left out", state.errors)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// auditEntry is one change to the results store, as kept in the audit
+// log. The log is only ever appended to, so it can answer questions like
+// where a run went long after the store itself has moved on.
+type auditEntry struct {
+	Time   time.Time  `json:"time"`
+	Action string     `json:"action"`           // add, import, sync, delete, tag, exclude, include, comfort or hash
+	Source string     `json:"source,omitempty"` // the file imported or synced with
+	Detail string     `json:"detail,omitempty"` // the tag added or comfort recorded
+	Runs   []auditRun `json:"runs"`
+}
+
+// auditRun identifies a run changed by an audited action
+type auditRun struct {
+	Key       string    `json:"key"`
+	TestFile  string    `json:"test_file"`
+	Completed time.Time `json:"completed"`
+	WPM       float64   `json:"wpm"`
+}
+
+func auditRuns(results []result) []auditRun {
+	runs := make([]auditRun, len(results))
+	for i, r := range results {
+		runs[i] = auditRun{Key: r.key(), TestFile: r.TestFile, Completed: r.Completed, WPM: r.WPM}
+	}
+	return runs
+}
+
+func auditPath() (string, error) {
+	return dataFile("audit.jsonl")
+}
+
+// audit notes a change to the store, to be appended to its audit log when
+// the store is next saved. Stores loaded from elsewhere than the data
+// directory, such as files being imported, aren't audited.
+func (s *resultStore) audit(action, source, detail string, runs []result) {
+	if s.auditPath == "" || len(runs) == 0 {
+		return
+	}
+	s.pendingAudit = append(s.pendingAudit, auditEntry{
+		Time:   time.Now(),
+		Action: action,
+		Source: source,
+		Detail: detail,
+		Runs:   auditRuns(runs),
+	})
+}
+
+// flushAudit appends the changes noted since the last save to the audit
+// log, one JSON object per line
+func (s *resultStore) flushAudit() error {
+	if len(s.pendingAudit) == 0 {
+		return nil
+	}
+	if readOnly {
+		s.pendingAudit = nil
+		return nil
+	}
+	f, err := os.OpenFile(s.auditPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+	for _, entry := range s.pendingAudit {
+		if err := encoder.Encode(entry); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	s.pendingAudit = nil
+	return f.Close()
+}
+
+// readAudit reads the audit log at path, oldest first. A missing log is
+// empty.
+func readAudit(path string) ([]auditEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []auditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16<<20) // a big import is one long line
+	for line := 1; scanner.Scan(); line++ {
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// runAudit prints the audit log, or with a test file only the changes
+// touching its runs, and returns the process exit code
+func runAudit(w io.Writer, args []string) int {
+	if len(args) > 1 {
+		fmt.Fprintln(w, "Usage: keysmash audit [TEST_FILE]")
+		return 2
+	}
+	path, err := auditPath()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	entries, err := readAudit(path)
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+
+	for _, entry := range entries {
+		runs := entry.Runs
+		if len(args) == 1 {
+			runs = nil
+			for _, run := range entry.Runs {
+				if run.TestFile == args[0] {
+					runs = append(runs, run)
+				}
+			}
+			if len(runs) == 0 {
+				continue
+			}
+		}
+		line := fmt.Sprintf("%s  %s", entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Action)
+		if entry.Detail != "" {
+			line += " " + entry.Detail
+		}
+		if entry.Source != "" {
+			line += " from " + entry.Source
+		}
+		fmt.Fprintf(w, "%s: %d run(s)\n", line, len(runs))
+		for _, run := range runs {
+			fmt.Fprintf(w, "    %s  %s  %.1f WPM  [%s]\n", run.Completed.Local().Format("2006-01-02 15:04"), run.TestFile, run.WPM, run.Key[:min(12, len(run.Key))])
+		}
+	}
+	return 0
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	store, err := loadResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.add(result{TestFile: "a.txt", Started: start, Completed: start.Add(time.Minute), WPM: 90})
+	store.add(result{TestFile: "b.txt", Started: start.Add(time.Hour), Completed: start.Add(time.Hour + time.Minute), WPM: 60})
+	store.tag([]int{0, 1}, "travel")
+	store.tag([]int{0}, "travel") // already tagged, so not a change
+	if err := store.save(); err != nil {
+		t.Fatal(err)
+	}
+	store.remove([]int{0})
+	if err := store.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An import logs the runs it added
+	other := &resultStore{path: filepath.Join(t.TempDir(), "other.json")}
+	other.add(result{TestFile: "c.txt", Started: start.Add(2 * time.Hour), Completed: start.Add(2*time.Hour + time.Minute), WPM: 70})
+	if err := other.save(); err != nil {
+		t.Fatal(err)
+	}
+	if code := runImport(io.Discard, []string{other.path}); code != 0 {
+		t.Fatalf("import exit code %d", code)
+	}
+
+	path, _ := auditPath()
+	entries, err := readAudit(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+	}
+	if got := strings.Join(actions, " "); got != "add add tag delete import" {
+		t.Errorf("audited %q", got)
+	}
+
+	// Where did the 90 WPM run on a.txt go?
+	var out bytes.Buffer
+	if code := runAudit(&out, []string{"a.txt"}); code != 0 {
+		t.Fatalf("audit exit code %d", code)
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 6 || !strings.Contains(out.String(), "delete: 1 run(s)") || strings.Contains(out.String(), "import") {
+		t.Errorf("audit of a.txt:\n%s", out.String())
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import "fmt"
+
+// The backspacing advisor looks at how mistakes get fixed. Fixing one
+// takes as many backspaces as it takes to reach it; any more erase text
+// that was right and has to be typed again, such as deleting a whole word
+// for a one-letter slip.
+
+// overBackspaceSlack is how many more characters than needed a correction
+// may erase before it's counted as overdone, so a habit of rubbing out
+// the odd extra character isn't flagged
+const overBackspaceSlack = 2
+
+// backspacingExamples is how many overdone corrections are kept to show
+const backspacingExamples = 3
+
+// correction is a run of backspaces in one test
+type correction struct {
+	file    string
+	erased  string // in reading order
+	deleted int
+	needed  int // backspaces that still had a mistake to reach
+}
+
+func (c correction) excess() int { return c.deleted - c.needed }
+
+// backspacing tallies the backspaces in the keystroke log
+type backspacing struct {
+	Backspaces int
+	Unneeded   int          // backspaces with no mistake left to reach
+	Overdone   int          // corrections erasing more than overBackspaceSlack too many
+	Examples   []correction // the most overdone, worst first
+
+	run correction // the run going on, if any
+}
+
+// backspace adds a logged backspace to the run going on
+func (b *backspacing) backspace(e keyLogEntry) {
+	if b.run.deleted > 0 && b.run.file != e.TestFile {
+		b.end()
+	}
+	b.run.file = e.TestFile
+	b.run.erased = e.Deleted + b.run.erased
+	b.run.deleted++
+	b.Backspaces++
+	if e.Correct {
+		b.run.needed++
+	} else {
+		b.Unneeded++
+	}
+}
+
+// end judges the run of backspaces going on, if any, now that it's over
+func (b *backspacing) end() {
+	run := b.run
+	b.run = correction{}
+	if run.excess() <= overBackspaceSlack {
+		return
+	}
+	b.Overdone++
+	// Later runs go before earlier ones as bad, to show recent habits
+	i := 0
+	for i < len(b.Examples) && b.Examples[i].excess() > run.excess() {
+		i++
+	}
+	b.Examples = append(b.Examples[:i], append([]correction{run}, b.Examples[i:]...)...)
+	b.Examples = b.Examples[:min(len(b.Examples), backspacingExamples)]
+}
+
+// report returns the tally with the run going on judged as if it were
+// over, leaving b to carry on
+func (b *backspacing) report() backspacing {
+	r := *b
+	r.Examples = append([]correction(nil), b.Examples...)
+	r.end()
+	return r
+}
+
+// advice is the analytics screen's lines on backspacing: the share of
+// backspaces that weren't needed, and the worst examples; nil before any
+// backspaces
+func (b backspacing) advice() []string {
+	if b.Backspaces == 0 {
+		return nil
+	}
+	lines := []string{fmt.Sprintf("Backspacing: %.0f%% of %d backspaces weren't needed", float64(b.Unneeded)/float64(b.Backspaces)*100, b.Backspaces)}
+	if b.Overdone == 0 {
+		return lines
+	}
+	lines = append(lines, fmt.Sprintf("%d correction(s) erased %d or more past the mistake; delete back to it, not the whole word", b.Overdone, overBackspaceSlack+1))
+	for _, c := range b.Examples {
+		lines = append(lines, fmt.Sprintf("  erased %q (%d) where %d would do", c.erased, c.deleted, c.needed))
+	}
+	return lines
+}
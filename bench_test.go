@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// benchSizes are the reference text lengths (in bytes) every benchmark runs
+// at: a short quote, a long passage, and a book-sized chunk.
+var benchSizes = []int{100, 1000, 10000}
+
+const benchSentence = "The quick brown fox jumps over the lazy dog, then naps in the afternoon sun.\n"
+
+// benchText returns deterministic prose of exactly n bytes
+func benchText(n int) string {
+	text := strings.Repeat(benchSentence, n/len(benchSentence)+1)
+	return strings.TrimSpace(text[:n])
+}
+
+func BenchmarkWrapText(b *testing.B) {
+	for _, size := range benchSizes {
+		text := benchText(size)
+		b.Run(fmt.Sprintf("chars=%d", size), func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				wrapText(text, 72)
+			}
+		})
+	}
+}
+
+// BenchmarkTypeRune scores a complete run, one keystroke at a time, with a
+// typo every 20 characters so the error path is exercised too.
+func BenchmarkTypeRune(b *testing.B) {
+	for _, size := range benchSizes {
+		text := benchText(size)
+		b.Run(fmt.Sprintf("chars=%d", size), func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				state := newTestState(text, "bench.txt", &fakeClock{})
+				for j, r := range text {
+					if j%20 == 19 {
+						state.typeRune('#')
+						state.backspace()
+					}
+					state.typeRune(r)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRenderScreen draws a full frame of a half-finished test onto a
+// simulated 100x40 terminal.
+func BenchmarkRenderScreen(b *testing.B) {
+	for _, size := range benchSizes {
+		text := benchText(size)
+		b.Run(fmt.Sprintf("chars=%d", size), func(b *testing.B) {
+			screen := tcell.NewSimulationScreen("UTF-8")
+			if err := screen.Init(); err != nil {
+				b.Fatal(err)
+			}
+			defer screen.Fini()
+			screen.SetSize(100, 40)
+
+			clock := &fakeClock{}
+			state := newTestState(text, "bench.txt", clock)
+			state.typeRune(rune(text[0]))
+			setInput(&state, text[:len(text)/2])
+			clock.advance(time.Minute)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				renderScreen(screen, &state, 100, renderOptions{})
+			}
+		})
+	}
+}
+
+// BenchmarkKeystrokeFrame types one character into a half-finished test
+// and draws the frame the way runTypingTest does, through a real terminfo
+// screen, reporting the bytes each keystroke sends to the terminal.
+func BenchmarkKeystrokeFrame(b *testing.B) {
+	for _, size := range benchSizes {
+		text := benchText(size)
+		b.Run(fmt.Sprintf("chars=%d", size), func(b *testing.B) {
+			screen, tty := newCountingScreen(b, 100, 40)
+			clock := &fakeClock{}
+			opts := renderOptions{reference: &wrapCache{}}
+			half := len(text) / 2
+
+			var state TestState
+			written := 0
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Start over at the halfway mark whenever the second half
+				// has been typed
+				pos := i % (len(text) - half - 1)
+				if pos == 0 {
+					b.StopTimer()
+					state = newTestState(text, "bench.txt", clock)
+					state.typeRune(rune(text[0]))
+					setInput(&state, text[:half])
+					renderScreen(screen, &state, 100, opts)
+					tty.take()
+					b.StartTimer()
+				}
+				clock.advance(200 * time.Millisecond)
+				state.typeRune(rune(text[half+pos]))
+				renderScreen(screen, &state, 100, opts)
+				written += tty.take()
+			}
+			b.ReportMetric(float64(written)/float64(b.N), "bytes/keystroke")
+		})
+	}
+}
+
+// BenchmarkReplaySession is the harness for profiling the test screen as a
+// whole. It replays a session keystroke by keystroke, scoring each and
+// drawing its frame through a real terminfo screen, as fast as it can:
+//
+//	go test -run '^$' -bench ReplaySession -cpuprofile cpu.out -memprofile mem.out
+//	go tool pprof -top cpu.out
+//
+// The session is a synthetic run through a long passage with a typo and correction
+// every 20 characters, or the .ksm recording named by
+// KEYSMASH_BENCH_RECORDING, so a session that felt slow can be profiled
+// exactly. A CPU profile from a realistic session also serves as a
+// default.pgo for profile-guided builds.
+func BenchmarkReplaySession(b *testing.B) {
+	text := benchText(2000)
+	var keys []rune
+	for i, r := range text {
+		if i%20 == 19 {
+			keys = append(keys, '#', backspaceKey)
+		}
+		keys = append(keys, r)
+	}
+	if path := os.Getenv("KEYSMASH_BENCH_RECORDING"); path != "" {
+		rec, err := loadRecording(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if text, err = rec.reference(); err != nil {
+			b.Fatal(err)
+		}
+		keys = []rune(rec.Keys)
+	}
+
+	screen, _ := newCountingScreen(b, 100, 40)
+	clock := &fakeClock{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		state := newTestState(text, "bench.txt", clock)
+		opts := renderOptions{reference: &wrapCache{}}
+		for _, r := range keys {
+			clock.advance(150 * time.Millisecond)
+			if r == backspaceKey {
+				state.backspace()
+			} else {
+				state.typeRune(r)
+			}
+			renderScreen(screen, &state, 100, opts)
+		}
+	}
+	b.ReportMetric(float64(len(keys)), "keystrokes/op")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// In blind mode the reference is shown but not what's typed: the input
+// area stays empty, the reference only dims what's left rather than
+// marking mistakes, and the error count is hidden, so the player has to
+// trust their fingers. With no way to see a mistake to fix it, the test
+// ends once the whole text has been typed, right or not. The results
+// screen then shows what was typed, marked against the reference, from
+// the line of the first mistake.
+//
+// F1 peeks: the test is shown as it would be without blind mode until F1
+// is pressed again. Terminals don't report key releases, so it can't be
+// held. Each peek is counted on the result.
+
+// blindDone reports whether a blind test has been typed to the end of the
+// text
+func (s *TestState) blindDone() bool {
+	return s.blind && s.typed() == len(s.reference) && s.lastUnit != unitPending
+}
+
+// hidden reports whether what's typed is hidden: a blind test that's
+// running and not being peeked at
+func (s *TestState) hidden() bool {
+	return s.blind && !s.testComplete && !s.peeking
+}
+
+// peek turns peeking at a running blind test on or off, counting each
+// time it's turned on
+func (s *TestState) peek() {
+	if !s.blind || s.testComplete {
+		return
+	}
+	s.peeking = !s.peeking
+	if s.peeking {
+		s.peeks++
+	}
+}
+
+// blindStyle styles the ith character of the reference while a blind test
+// is running, with no sign of whether it was typed right. ok is false
+// otherwise.
+func (s *TestState) blindStyle(i int) (style tcell.Style, ok bool) {
+	if !s.hidden() {
+		return tcell.StyleDefault, false
+	}
+	if i >= s.typed() {
+		return styleUntyped, true
+	}
+	return tcell.StyleDefault, true
+}
+
+// inputLabel labels the input area
+func (s *TestState) inputLabel() string {
+	switch {
+	case s.hidden():
+		return "Your typing: hidden until the end (blind mode, F1 to peek)"
+	case s.blind && !s.testComplete:
+		return "Your typing (peeking, F1 to hide):"
+	}
+	return "Your typing:"
+}
+
+// shownErrors is the live error count as the stats line shows it, hidden
+// in blind mode
+func (s *TestState) shownErrors(errors int) string {
+	if s.hidden() {
+		return "?"
+	}
+	return strconv.Itoa(errors)
+}
+
+// drawBlindReveal shows a finished blind test's input on the results
+// screen in the rows from y down to height, styled right or wrong, from
+// the line holding the first mistake so it's on screen
+func drawBlindReveal(screen tcell.Screen, state *TestState, y, width, height int) {
+	if y+1 >= height || state.userInput == "" {
+		return
+	}
+	heading := "What you typed:"
+	if state.peeks > 0 {
+		heading = fmt.Sprintf("What you typed (peeked %d times):", state.peeks)
+	}
+	drawCenteredText(screen, width/2, y, tcell.StyleDefault, heading)
+	lineWidth := min(80, width-4)
+	lines := wrapText(state.userInput, lineWidth)
+	offsets := lineOffsets(state.userInput, lines)
+	first := 0
+	if wrong := state.firstWrong(); wrong < len(state.unitStarts) {
+		for first+1 < len(offsets) && offsets[first+1] <= state.unitStarts[wrong] {
+			first++
+		}
+	}
+	x := (width - lineWidth) / 2
+	for i := first; i < len(lines) && y+1+i-first < height; i++ {
+		drawWrappedLine(screen, x, y+1+i-first, lines[i], state.userInput, offsets[i], func(offset int) tcell.Style {
+			return state.inputStyle(clusterIndex(state.unitStarts, offset))
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestBlindStyle(t *testing.T) {
+	state := newTestState("the cat", "test.txt", &fakeClock{})
+	state.blind = true
+	typeKeys(&state, "thx")
+
+	// The wrong key looks like the right ones until the end
+	for i, want := range []tcell.Style{tcell.StyleDefault, tcell.StyleDefault, tcell.StyleDefault, styleUntyped} {
+		if got := state.referenceStyle(i); got != want {
+			t.Errorf("character %d styled %v while blind, want %v", i, got, want)
+		}
+	}
+	if got := state.shownErrors(state.errors); got != "?" {
+		t.Errorf("errors shown as %q while blind, want them hidden", got)
+	}
+
+	typeKeys(&state, " cat")
+	if !state.testComplete {
+		t.Fatal("test not complete")
+	}
+	if got := state.referenceStyle(2); got != styleMissed {
+		t.Errorf("wrong character styled %v once complete, want it marked", got)
+	}
+	if got := state.shownErrors(state.errors); got != "1" {
+		t.Errorf("errors shown as %q once complete, want 1", got)
+	}
+}
+
+func TestBlindPeek(t *testing.T) {
+	state := newTestState("the cat", "test.txt", &fakeClock{})
+	state.blind = true
+	state.events = newEventBus()
+	var ended []Event
+	state.events.Subscribe(func(ev Event) { ended = append(ended, ev) }, EventTestCompleted)
+	typeKeys(&state, "thx")
+
+	// Peeking shows the test as it is; F1 again hides it
+	state.peek()
+	if got := state.referenceStyle(2); got != styleMissed || state.shownErrors(state.errors) != "1" {
+		t.Errorf("peeking styled the mistake %v and showed %q errors, want it marked and counted", got, state.shownErrors(state.errors))
+	}
+	state.peek()
+	if !state.hidden() {
+		t.Error("still peeking after F1 a second time")
+	}
+	state.peek()
+	state.peek()
+	typeKeys(&state, " cat")
+	if len(ended) != 1 || ended[0].Peeks != 2 {
+		t.Fatalf("events = %+v, want one completion with 2 peeks", ended)
+	}
+	if r := eventResult(ended[0]); r.Peeks != 2 {
+		t.Errorf("result has %d peeks, want 2", r.Peeks)
+	}
+
+	// Once it's over there's nothing to peek at
+	state.peek()
+	if state.peeks != 2 {
+		t.Errorf("%d peeks counted after the end, want 2", state.peeks)
+	}
+
+	// A retry starts hidden, with no peeks, even if the last run ended
+	// while peeking
+	state.reset()
+	typeKeys(&state, "t")
+	state.peek()
+	state.reset()
+	if !state.hidden() || state.peeks != 0 {
+		t.Errorf("retry hidden %v with %d peeks, want hidden with none", state.hidden(), state.peeks)
+	}
+}
+
+func TestBlindReveal(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(40, 24)
+
+	// Two lines of input at this width, the mistake on the second
+	text := "one two three four five six seven eight nine ten"
+	state := newTestState(text, "test.txt", &fakeClock{})
+	state.blind = true
+	typeKeys(&state, strings.Replace(text, "nine", "nime", 1))
+	if !state.testComplete {
+		t.Fatal("test not complete")
+	}
+
+	drawBlindReveal(screen, &state, 10, 40, 24)
+	if got := cellText(screen, 0, 10, 40); !strings.Contains(got, "What you typed:") {
+		t.Errorf("row 10 = %q, want the heading", got)
+	}
+	if got := cellText(screen, 2, 11, 36); !strings.HasPrefix(got, "eight nime ten") {
+		t.Errorf("row 11 = %q, want the line with the mistake first", got)
+	}
+	m := 2 + strings.Index("eight nime ten", "m")
+	if _, _, style, _ := screen.GetContent(m, 11); style != styleIncorrect {
+		t.Errorf("mistake styled %v, want it marked", style)
+	}
+}
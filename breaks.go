@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// breakMinimum is how long a pause between tests must last to count as a
+// break, resetting the continuous typing time
+const breakMinimum = 5 * time.Minute
+
+// breakReminder is one reminder shown and what came of it
+type breakReminder struct {
+	Shown   time.Time     `json:"shown"`
+	Typing  time.Duration `json:"typing"` // continuous typing time at the reminder
+	Snoozed bool          `json:"snoozed"`
+	Taken   bool          `json:"taken"` // a break followed before the next test
+}
+
+// breakLog keeps every reminder so compliance can be tracked across
+// sessions
+type breakLog struct {
+	Reminders []breakReminder `json:"reminders"`
+	path      string
+}
+
+func loadBreakLog() (*breakLog, error) {
+	path, err := dataFile("breaks.json")
+	if err != nil {
+		return nil, err
+	}
+	log := &breakLog{path: path}
+	if err := readJSONFile(path, log); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+func (l *breakLog) save() error {
+	if l.path == "" {
+		return nil
+	}
+	return saveDataFile(l.path, l)
+}
+
+// compliance counts how many of the last n reminders were followed by a
+// break
+func (l *breakLog) compliance(n int) (taken, reminders int) {
+	for i := len(l.Reminders) - 1; i >= 0 && reminders < n; i-- {
+		reminders++
+		if l.Reminders[i].Taken {
+			taken++
+		}
+	}
+	return taken, reminders
+}
+
+// breakTracker adds up typing time across tests and decides when to remind
+// the player to rest. Time only counts while a test is running, and any
+// pause of breakMinimum between tests starts the count again.
+type breakTracker struct {
+	after  time.Duration // continuous typing before a reminder; 0 disables
+	snooze time.Duration
+
+	typing     time.Duration
+	remindAt   time.Duration // typing time of the next reminder
+	lastActive time.Time
+	pending    bool // the last reminder awaits a verdict on whether a break followed
+	log        *breakLog
+}
+
+func newBreakTracker(after, snooze time.Duration, log *breakLog) *breakTracker {
+	return &breakTracker{after: after, snooze: snooze, remindAt: after, log: log}
+}
+
+// subscribe keeps the tracker up to date with the tests being typed
+func (t *breakTracker) subscribe(bus *EventBus) {
+	bus.Subscribe(func(ev Event) {
+		switch ev.Kind {
+		case EventTestStarted:
+			rested := !t.lastActive.IsZero() && ev.Time.Sub(t.lastActive) >= breakMinimum
+			if rested {
+				t.typing = 0
+				t.remindAt = t.after
+			}
+			if t.pending {
+				t.pending = false
+				t.log.Reminders[len(t.log.Reminders)-1].Taken = rested
+				logger.Info("break reminder outcome", "taken", rested)
+				if err := t.log.save(); err != nil {
+					logger.Error("saving break log failed", "err", err)
+				}
+			}
+		case EventTestCompleted, EventTestFailed:
+			t.typing += ev.Duration
+			t.lastActive = ev.Time
+		}
+	}, EventTestStarted, EventTestCompleted, EventTestFailed)
+}
+
+// due reports whether a reminder should be shown before the next test
+func (t *breakTracker) due() bool {
+	return t.after > 0 && t.typing >= t.remindAt
+}
+
+// remind records that a reminder was shown and answered. A snoozed
+// reminder comes back after the snooze time; a dismissed one after another
+// full stretch of typing.
+func (t *breakTracker) remind(now time.Time, snoozed bool) {
+	t.log.Reminders = append(t.log.Reminders, breakReminder{Shown: now, Typing: t.typing, Snoozed: snoozed})
+	t.pending = true
+	if snoozed {
+		t.remindAt = t.typing + t.snooze
+	} else {
+		t.remindAt = t.typing + t.after
+	}
+	logger.Info("break reminder", "typing", t.typing, "snoozed", snoozed)
+	if err := t.log.save(); err != nil {
+		logger.Error("saving break log failed", "err", err)
+	}
+}
+
+// showBreakReminder draws the reminder over the current screen and waits
+// for an answer. It reports whether the player snoozed it.
+func showBreakReminder(screen tcell.Screen, t *breakTracker) bool {
+	lines := []string{
+		"Time for a break",
+		"",
+		fmt.Sprintf("You've been typing for %.0f minutes.", t.typing.Minutes()),
+		fmt.Sprintf("Rest your hands and eyes for %.0f minutes.", breakMinimum.Minutes()),
+	}
+	if taken, reminders := t.log.compliance(10); reminders > 0 {
+		lines = append(lines, fmt.Sprintf("You took a break after %d of your last %d reminders.", taken, reminders))
+	}
+	lines = append(lines, "", fmt.Sprintf("S: snooze %.0f min   Enter: dismiss", t.snooze.Minutes()))
+	drawBox(screen, lines)
+	screen.Show()
+
+	for {
+		if ev, ok := screen.PollEvent().(*tcell.EventKey); ok {
+			switch {
+			case ev.Key() == tcell.KeyRune && (ev.Rune() == 's' || ev.Rune() == 'S'):
+				return true
+			case ev.Key() == tcell.KeyEnter || ev.Key() == tcell.KeyEscape:
+				return false
+			}
+		}
+	}
+}
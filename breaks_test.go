@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakTracker(t *testing.T) {
+	bus := newEventBus()
+	tracker := newBreakTracker(20*time.Minute, 5*time.Minute, &breakLog{})
+	tracker.subscribe(bus)
+
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	// typeTest runs a test of length d starting after a pause
+	typeTest := func(pause, d time.Duration) {
+		now = now.Add(pause)
+		bus.Publish(Event{Kind: EventTestStarted, Time: now})
+		now = now.Add(d)
+		bus.Publish(Event{Kind: EventTestCompleted, Time: now, Duration: d})
+	}
+
+	// Short pauses don't reset the count
+	for i := 0; i < 3; i++ {
+		typeTest(time.Minute, 6*time.Minute)
+	}
+	if tracker.due() {
+		t.Fatal("reminder due after 18 minutes of typing")
+	}
+	typeTest(time.Minute, 6*time.Minute)
+	if !tracker.due() {
+		t.Fatal("no reminder after 24 minutes of typing")
+	}
+
+	// Snoozing brings it back after five more minutes of typing
+	tracker.remind(now, true)
+	typeTest(time.Minute, 3*time.Minute)
+	if tracker.due() {
+		t.Error("snoozed reminder came back after 3 minutes")
+	}
+	typeTest(time.Minute, 3*time.Minute)
+	if !tracker.due() {
+		t.Error("snoozed reminder didn't come back after 6 minutes")
+	}
+
+	// Dismissing and then resting counts as a break taken and resets
+	tracker.remind(now, false)
+	typeTest(10*time.Minute, time.Minute)
+	if tracker.due() || tracker.typing != time.Minute {
+		t.Errorf("after a break: due = %v, typing = %v", tracker.due(), tracker.typing)
+	}
+	if taken, reminders := tracker.log.compliance(10); taken != 1 || reminders != 2 {
+		t.Errorf("compliance = %d of %d, want 1 of 2", taken, reminders)
+	}
+}
+
+func TestBreakTrackerDisabled(t *testing.T) {
+	bus := newEventBus()
+	tracker := newBreakTracker(0, 5*time.Minute, &breakLog{})
+	tracker.subscribe(bus)
+	bus.Publish(Event{Kind: EventTestCompleted, Time: time.Now(), Duration: 10 * time.Hour})
+	if tracker.due() {
+		t.Error("reminder due with reminders turned off")
+	}
+}
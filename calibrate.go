@@ -0,0 +1,254 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// calibrationTrials is how many keystrokes a calibration measures
+const calibrationTrials = 20
+
+// cursorQuery asks the terminal where its cursor is (DSR). It answers
+// with a cursor position report, ESC [ row ; column R, so the time to
+// the answer is a round trip to the terminal and back, as a keystroke
+// and its echo make over SSH or mosh.
+const cursorQuery = "\x1b[6n"
+
+// cursorQueryTimeout is how long to wait for the terminal to answer
+// cursorQuery
+const cursorQueryTimeout = 2 * time.Second
+
+// latencyProfile summarises a terminal's echo latency: the round trip
+// from keysmash to the terminal and back, timed with cursorQuery after
+// each keystroke. Remote sessions show up here as slow.
+type latencyProfile struct {
+	Term     string        `json:"term"`
+	Remote   bool          `json:"remote"`
+	Trials   int           `json:"trials"`
+	Median   time.Duration `json:"median"`
+	P95      time.Duration `json:"p95"`
+	Measured time.Time     `json:"measured"`
+}
+
+// latencyProfiles keeps one profile per terminal, keyed by terminalKey
+type latencyProfiles struct {
+	Profiles map[string]latencyProfile `json:"profiles"`
+	path     string
+}
+
+// isRemoteSession reports whether keysmash is running over SSH or mosh
+func isRemoteSession() bool {
+	return remoteSession() != ""
+}
+
+// terminalKey identifies the current terminal for latency profiles. The
+// same terminal type behaves very differently locally and remotely.
+func terminalKey() string {
+	where := "local"
+	if isRemoteSession() {
+		where = "remote"
+	}
+	return os.Getenv("TERM") + "/" + where
+}
+
+func loadLatencyProfiles() (*latencyProfiles, error) {
+	path, err := dataFile("latency.json")
+	if err != nil {
+		return nil, err
+	}
+	profiles := &latencyProfiles{Profiles: make(map[string]latencyProfile), path: path}
+	if err := readJSONFile(path, profiles); err != nil {
+		return nil, err
+	}
+	if profiles.Profiles == nil {
+		profiles.Profiles = make(map[string]latencyProfile)
+	}
+	return profiles, nil
+}
+
+func (p *latencyProfiles) save() error {
+	return saveDataFile(p.path, p)
+}
+
+// current returns the profile for the terminal keysmash is running in. A
+// nil set of profiles has none.
+func (p *latencyProfiles) current() (latencyProfile, bool) {
+	if p == nil {
+		return latencyProfile{}, false
+	}
+	profile, ok := p.Profiles[terminalKey()]
+	return profile, ok
+}
+
+// summariseLatency builds a profile from measured samples
+func summariseLatency(samples []time.Duration) latencyProfile {
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return latencyProfile{
+		Term:   os.Getenv("TERM"),
+		Remote: isRemoteSession(),
+		Trials: len(sorted),
+		Median: sorted[len(sorted)/2],
+		P95:    sorted[min(len(sorted)-1, len(sorted)*95/100)],
+	}
+}
+
+// cursorReport picks a cursor position report out of key events. tcell
+// has no key for it, so the escape arrives as Alt on the [ and the rest
+// as plain keys.
+type cursorReport struct {
+	text []rune
+}
+
+// feed adds a key event, reporting whether it ended a report
+func (c *cursorReport) feed(ev *tcell.EventKey) bool {
+	r := ev.Rune()
+	switch {
+	case ev.Key() != tcell.KeyRune:
+		c.text = nil
+	case r == '[' && ev.Modifiers()&tcell.ModAlt != 0:
+		c.text = []rune{r}
+	case len(c.text) > 0 && (r >= '0' && r <= '9' || r == ';'):
+		c.text = append(c.text, r)
+	case len(c.text) > 1 && r == 'R':
+		c.text = nil
+		return true
+	default:
+		c.text = nil
+	}
+	return false
+}
+
+// roundTripTimeout is posted to the screen when a cursorQuery sent at
+// sent goes unanswered
+type roundTripTimeout struct {
+	sent time.Time
+}
+
+// measureRoundTrip writes cursorQuery to tty, the screen's terminal, and
+// times the answer. Keys pressed while it waits are dropped.
+func measureRoundTrip(screen tcell.Screen, tty io.Writer) (time.Duration, error) {
+	sent := time.Now()
+	if _, err := io.WriteString(tty, cursorQuery); err != nil {
+		return 0, fmt.Errorf("asking the terminal for the cursor: %w", err)
+	}
+	timeout := time.AfterFunc(cursorQueryTimeout, func() {
+		_ = screen.PostEvent(tcell.NewEventInterrupt(roundTripTimeout{sent}))
+	})
+	defer timeout.Stop()
+	var report cursorReport
+	for {
+		switch ev := screen.PollEvent().(type) {
+		case *tcell.EventKey:
+			if report.feed(ev) {
+				return ev.When().Sub(sent), nil
+			}
+		case *tcell.EventInterrupt:
+			if ev.Data() == (roundTripTimeout{sent}) {
+				return 0, fmt.Errorf("the terminal didn't answer a cursor position query in %v", cursorQueryTimeout)
+			}
+		case nil:
+			return 0, errors.New("the screen closed")
+		}
+	}
+}
+
+// calibrateLatency asks for calibrationTrials keystrokes, echoing each one
+// and timing a round trip to the terminal, tty, straight after. ok is
+// false if the player pressed Escape.
+func calibrateLatency(screen tcell.Screen, rng *rand.Rand, tty io.Writer) (samples []time.Duration, ok bool, err error) {
+	target := rune('a' + rng.Intn(26))
+	status := "Type each letter as it appears"
+	for len(samples) < calibrationTrials {
+		screen.Clear()
+		width, height := screen.Size()
+		drawCenteredText(screen, width/2, height/2-4, tcell.StyleDefault, "LATENCY CALIBRATION")
+		drawCenteredText(screen, width/2, height/2-2, tcell.StyleDefault, fmt.Sprintf("Trial %d of %d", len(samples)+1, calibrationTrials))
+		drawCenteredText(screen, width/2, height/2, tcell.StyleDefault.Bold(true), string(target))
+		drawCenteredText(screen, width/2, height/2+2, tcell.StyleDefault, status)
+		drawCenteredText(screen, width/2, height/2+4, tcell.StyleDefault, "ESC to cancel")
+		screen.Show()
+
+		ev, isKey := screen.PollEvent().(*tcell.EventKey)
+		if !isKey {
+			continue
+		}
+		if ev.Key() == tcell.KeyEscape {
+			return nil, false, nil
+		}
+		if ev.Rune() != target {
+			continue
+		}
+
+		// Echo the key the way a test would, then time a round trip to
+		// the terminal. The cursor is put below the first row, since a
+		// report from there can look like Shift+F3.
+		drawCenteredText(screen, width/2, height/2+1, tcell.StyleDefault, string(ev.Rune()))
+		screen.ShowCursor(width/2, height/2+1)
+		screen.Show()
+		latency, err := measureRoundTrip(screen, tty)
+		screen.HideCursor()
+		if err != nil {
+			return nil, false, err
+		}
+		samples = append(samples, latency)
+		status = fmt.Sprintf("Last echo: %.1fms", float64(latency.Microseconds())/1000)
+		target = rune('a' + rng.Intn(26))
+	}
+	return samples, true, nil
+}
+
+// runCalibrate runs the calibration screen, saves the resulting profile
+// for this terminal and prints it to w. It returns the process exit code.
+func runCalibrate(w io.Writer) int {
+	profiles, err := loadLatencyProfiles()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		fmt.Fprintf(w, "Error creating screen: %v\n", err)
+		return 1
+	}
+	if err := screen.Init(); err != nil {
+		fmt.Fprintf(w, "Error initializing screen: %v\n", err)
+		return 1
+	}
+	tty, ok := screen.Tty()
+	if !ok {
+		screen.Fini()
+		fmt.Fprintln(w, "Error: calibration needs a terminal")
+		return 1
+	}
+	samples, ok, err := calibrateLatency(screen, rand.New(rand.NewSource(time.Now().UnixNano())), tty)
+	screen.Fini()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	if !ok {
+		fmt.Fprintln(w, "Calibration cancelled")
+		return 1
+	}
+
+	profile := summariseLatency(samples)
+	profile.Measured = time.Now()
+	profiles.Profiles[terminalKey()] = profile
+	if err := profiles.save(); err != nil {
+		fmt.Fprintf(w, "Error saving latency profile: %v\n", err)
+		return 1
+	}
+	logger.Info("latency calibrated", "terminal", terminalKey(), "median", profile.Median, "p95", profile.P95)
+	fmt.Fprintf(w, "%s: median echo latency %v, 95th percentile %v over %d keystrokes\n",
+		terminalKey(), profile.Median.Round(time.Microsecond), profile.P95.Round(time.Microsecond), profile.Trials)
+	return 0
+}
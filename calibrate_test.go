@@ -0,0 +1,114 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestSummariseLatency(t *testing.T) {
+	var samples []time.Duration
+	for i := 20; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+	profile := summariseLatency(samples)
+	if profile.Trials != 20 || profile.Median != 11*time.Millisecond || profile.P95 != 20*time.Millisecond {
+		t.Errorf("profile = %+v, want 20 trials, median 11ms, p95 20ms", profile)
+	}
+}
+
+func TestCalibrateLatency(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+
+	// Replay the same seed to know which letters will be asked for, with a
+	// wrong key before each that must be ignored. Each trial's keys wait
+	// for the terminal to have answered the last one's query.
+	targets := rand.New(rand.NewSource(1))
+	tty := &fakeTerminal{screen: screen, delay: 2 * time.Millisecond, answered: make(chan bool)}
+	go func() {
+		for i := 0; i < calibrationTrials; i++ {
+			target := rune('a' + targets.Intn(26))
+			screen.PostEventWait(tcell.NewEventKey(tcell.KeyRune, '0', tcell.ModNone))
+			screen.PostEventWait(tcell.NewEventKey(tcell.KeyRune, target, tcell.ModNone))
+			<-tty.answered
+		}
+	}()
+
+	samples, ok, err := calibrateLatency(screen, rand.New(rand.NewSource(1)), tty)
+	if err != nil || !ok || len(samples) != calibrationTrials {
+		t.Fatalf("calibration returned %d samples, ok %v (%v)", len(samples), ok, err)
+	}
+	for _, sample := range samples {
+		if sample < tty.delay {
+			t.Errorf("latency sample %v, shorter than the terminal's round trip of %v", sample, tty.delay)
+		}
+	}
+}
+
+func TestMeasureRoundTrip(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+
+	// Keys typed while waiting aren't a report, and an earlier query's
+	// timeout isn't this one's
+	screen.PostEventWait(tcell.NewEventKey(tcell.KeyRune, 'R', tcell.ModNone))
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		screen.PostEventWait(tcell.NewEventInterrupt(roundTripTimeout{time.Time{}}))
+	}()
+	var tty strings.Builder
+	start := time.Now()
+	done := make(chan error)
+	go func() {
+		_, err := measureRoundTrip(screen, &tty)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("round trip ended after %v with no answer (%v)", time.Since(start), err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// Then the terminal answers
+	screen.PostEventWait(tcell.NewEventKey(tcell.KeyRune, '[', tcell.ModAlt))
+	for _, r := range "5;1R" {
+		screen.PostEventWait(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if tty.String() != cursorQuery {
+		t.Errorf("wrote %q to the terminal, want the cursor query", tty.String())
+	}
+}
+
+// fakeTerminal answers cursor queries written to it with a cursor
+// position report, as a terminal delay away would
+type fakeTerminal struct {
+	screen   tcell.Screen
+	delay    time.Duration
+	answered chan bool
+}
+
+func (f *fakeTerminal) Write(p []byte) (int, error) {
+	if string(p) == cursorQuery {
+		go func() {
+			time.Sleep(f.delay)
+			f.screen.PostEventWait(tcell.NewEventKey(tcell.KeyRune, '[', tcell.ModAlt))
+			for _, r := range "12;40R" {
+				f.screen.PostEventWait(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+			}
+			f.answered <- true
+		}()
+	}
+	return len(p), nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// animation plays a few frames over a screen that's waiting for input.
+// Frames advance on a ticker's interrupts, which the screen's own event
+// loop hands to step, so a key is handled the moment it's pressed. Each
+// frame is an overlay, so the screen underneath is left as it was, and a
+// screen ends the animation before drawing anything of its own.
+type animation struct {
+	screen tcell.Screen
+	frames int
+	frame  int
+	draw   func(screen tcell.Screen, frame int, layer *overlay)
+	layer  overlay
+	stop   func() // stops the ticker; nil once the animation is over
+}
+
+// startAnimation shows the first of frames frames drawn by draw, then a
+// frame every interval as step is called
+func startAnimation(screen tcell.Screen, frames int, interval time.Duration, draw func(tcell.Screen, int, *overlay)) *animation {
+	a := &animation{screen: screen, frames: frames, draw: draw, stop: startTicker(screen, interval)}
+	a.show()
+	return a
+}
+
+func (a *animation) show() {
+	a.layer.clear(a.screen)
+	a.draw(a.screen, a.frame, &a.layer)
+	a.screen.Show()
+}
+
+// step shows the next frame, or ends the animation after the last. A nil
+// or finished animation ignores it.
+func (a *animation) step() {
+	if a == nil || a.stop == nil {
+		return
+	}
+	a.frame++
+	if a.frame >= a.frames {
+		a.end()
+		return
+	}
+	a.show()
+}
+
+// end takes the animation off the screen and stops its ticker. A nil or
+// finished animation ignores it.
+func (a *animation) end() {
+	if a == nil || a.stop == nil {
+		return
+	}
+	a.stop()
+	a.stop = nil
+	a.layer.clear(a.screen)
+	a.screen.Show()
+}
+
+// A celebration is two seconds of confetti at 20 frames a second
+const (
+	celebrationFrames   = 40
+	celebrationInterval = 50 * time.Millisecond
+)
+
+var (
+	confettiGlyphs = []rune("*+o~'.")
+	confettiColors = []tcell.Color{tcell.ColorRed, tcell.ColorYellow, tcell.ColorGreen, tcell.ColorAqua, tcell.ColorBlue, tcell.ColorFuchsia}
+)
+
+// confettiPiece is one piece of confetti: where it starts, above the
+// screen, and how far it moves each frame
+type confettiPiece struct {
+	x, y, dx, dy float64
+	glyph        rune
+	color        tcell.Color
+}
+
+// confetti draws pieces of confetti falling through a width by height
+// screen over celebrationFrames frames. Pieces only show on blank cells,
+// so the results underneath stay readable throughout.
+func confetti(rng *rand.Rand, width, height int) func(tcell.Screen, int, *overlay) {
+	pieces := make([]confettiPiece, width*height/20)
+	for i := range pieces {
+		pieces[i] = confettiPiece{
+			x:     rng.Float64() * float64(width),
+			y:     -rng.Float64() * float64(height),
+			dx:    (rng.Float64() - 0.5) / 2,
+			dy:    (0.5 + rng.Float64()) * 2 * float64(height) / celebrationFrames,
+			glyph: confettiGlyphs[rng.Intn(len(confettiGlyphs))],
+			color: confettiColors[rng.Intn(len(confettiColors))],
+		}
+	}
+	return func(screen tcell.Screen, frame int, layer *overlay) {
+		for _, p := range pieces {
+			x, y := int(p.x+p.dx*float64(frame)), int(p.y+p.dy*float64(frame))
+			if x < 0 || x >= width || y < 0 || y >= height {
+				continue
+			}
+			if main, _, _, _ := screen.GetContent(x, y); main != ' ' {
+				continue
+			}
+			layer.set(screen, x, y, p.glyph, tcell.StyleDefault.Foreground(p.color))
+		}
+	}
+}
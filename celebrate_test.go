@@ -0,0 +1,77 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestAnimation(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(10, 2)
+	drawText(screen, 0, 0, tcell.StyleDefault, "results")
+
+	// Each frame writes its number over the first cell
+	count := func(screen tcell.Screen, frame int, layer *overlay) {
+		layer.set(screen, 0, 0, rune('0'+frame), tcell.StyleDefault.Bold(true))
+	}
+	a := startAnimation(screen, 3, time.Hour, count)
+	for _, want := range []string{"0esults", "1esults", "2esults", "results"} {
+		if got := cellText(screen, 0, 0, 7); got != want {
+			t.Errorf("screen shows %q, want %q", got, want)
+		}
+		a.step()
+	}
+
+	// Ending early takes it off too, and ending twice is harmless
+	a = startAnimation(screen, 3, time.Hour, count)
+	a.end()
+	a.end()
+	if got := cellText(screen, 0, 0, 7); got != "results" {
+		t.Errorf("after ending, screen shows %q", got)
+	}
+	var none *animation
+	none.step()
+	none.end()
+}
+
+func TestConfetti(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(40, 12)
+	text := strings.Repeat("#", 40)
+	for y := 4; y < 8; y++ {
+		drawText(screen, 0, y, tcell.StyleDefault, text)
+	}
+
+	draw := confetti(rand.New(rand.NewSource(1)), 40, 12)
+	var layer overlay
+	drawn := 0
+	for frame := 0; frame < celebrationFrames; frame++ {
+		layer.clear(screen)
+		draw(screen, frame, &layer)
+		drawn += len(layer.cells)
+		for y := 4; y < 8; y++ {
+			if got := cellText(screen, 0, y, 40); got != text {
+				t.Fatalf("frame %d covered the text: row %d = %q", frame, y, got)
+			}
+		}
+	}
+	if drawn == 0 {
+		t.Error("no confetti was drawn")
+	}
+	layer.clear(screen)
+	if got := cellText(screen, 0, 0, 40); got != strings.Repeat(" ", 40) {
+		t.Errorf("confetti left behind: %q", got)
+	}
+}
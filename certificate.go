@@ -0,0 +1,203 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// certificate is a printable record of one run
+type certificate struct {
+	Name        string
+	WPM         float64
+	Accuracy    float64
+	Date        time.Time
+	TestFile    string
+	TextHash    string
+	Duration    time.Duration
+	Exam        bool   // sat under keysmash exam's rules
+	Fingerprint string // the signer's key, empty if the run wasn't signed
+	Recording   string // the signed recording backing a practice run
+}
+
+// headline is the certificate's one-line claim
+func (c certificate) headline() string {
+	return fmt.Sprintf("%s typed %.0f WPM at %.1f%% accuracy on %s", c.Name, c.WPM, c.Accuracy, c.Date.Format("2 January 2006"))
+}
+
+// details are the smaller lines under the headline
+func (c certificate) details() []string {
+	kind := "Practice run"
+	if c.Exam {
+		kind = "Proctored exam"
+	}
+	lines := []string{
+		fmt.Sprintf("%s: %s, %.1f seconds", kind, c.TestFile, c.Duration.Seconds()),
+		"Text " + c.TextHash,
+	}
+	if c.Fingerprint != "" {
+		lines = append(lines, "Signed by "+c.Fingerprint)
+		if c.Recording != "" {
+			lines = append(lines, "Check with: keysmash verify "+c.Recording)
+		}
+	} else {
+		lines = append(lines, "Unsigned")
+	}
+	return lines
+}
+
+// text renders the certificate as a box of plain text
+func (c certificate) text() string {
+	lines := append([]string{"CERTIFICATE OF TYPING", "", c.headline(), ""}, c.details()...)
+	inner := 0
+	for _, line := range lines {
+		inner = max(inner, runewidth.StringWidth(line))
+	}
+	inner += 4
+
+	var out strings.Builder
+	out.WriteString("+" + strings.Repeat("=", inner) + "+\n")
+	for _, line := range lines {
+		pad := inner - runewidth.StringWidth(line)
+		out.WriteString("|" + strings.Repeat(" ", pad/2) + line + strings.Repeat(" ", pad-pad/2) + "|\n")
+	}
+	out.WriteString("+" + strings.Repeat("=", inner) + "+\n")
+	return out.String()
+}
+
+// svg renders the certificate as a landscape page
+func (c certificate) svg() string {
+	var out strings.Builder
+	out.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" width="1100" height="780" viewBox="0 0 1100 780">` + "\n")
+	out.WriteString(`  <rect width="1100" height="780" fill="#fffdf6"/>` + "\n")
+	out.WriteString(`  <rect x="30" y="30" width="1040" height="720" fill="none" stroke="#2b2b2b" stroke-width="6"/>` + "\n")
+	out.WriteString(`  <rect x="46" y="46" width="1008" height="688" fill="none" stroke="#2b2b2b" stroke-width="1.5"/>` + "\n")
+	text := func(y, size int, weight, s string) {
+		fmt.Fprintf(&out, `  <text x="550" y="%d" font-family="Georgia, serif" font-size="%d" font-weight="%s" text-anchor="middle" fill="#2b2b2b">%s</text>`+"\n", y, size, weight, html.EscapeString(s))
+	}
+	text(170, 48, "bold", "Certificate of Typing")
+	text(260, 24, "normal", "This certifies that")
+	text(340, 56, "bold", c.Name)
+	text(420, 28, "normal", fmt.Sprintf("typed %.0f words per minute at %.1f%% accuracy", c.WPM, c.Accuracy))
+	text(470, 24, "normal", "on "+c.Date.Format("2 January 2006"))
+	for i, line := range c.details() {
+		fmt.Fprintf(&out, `  <text x="550" y="%d" font-family="monospace" font-size="16" text-anchor="middle" fill="#555">%s</text>`+"\n", 600+i*28, html.EscapeString(line))
+	}
+	out.WriteString("</svg>\n")
+	return out.String()
+}
+
+// saveCertificate writes the certificate as .txt and .svg files to the
+// certificates directory and returns their paths
+func saveCertificate(c certificate) (textPath, svgPath string, err error) {
+	dir, err := dataFile("certificates")
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", err
+	}
+	base := filepath.Join(dir, fmt.Sprintf("%s-%s", c.Date.Format("20060102-150405"), fileSafe(c.Name)))
+	if err := os.WriteFile(base+".txt", []byte(c.text()), 0o644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(base+".svg", []byte(c.svg()), 0o644); err != nil {
+		return "", "", err
+	}
+	return base + ".txt", base + ".svg", nil
+}
+
+// saveRunCertificate renders a certificate for the run on the results
+// screen and returns the SVG's path. With a signing key, a signed
+// recording is saved alongside for the certificate to point to.
+func saveRunCertificate(state *TestState, name string, key ed25519.PrivateKey, now time.Time) (string, error) {
+	cert := certificate{
+		Name:     name,
+		WPM:      state.wpm(state.elapsed()),
+		Accuracy: calculateAccuracy(state.errors, state.typed()),
+		Date:     now,
+		TestFile: state.testFile,
+		TextHash: hashText(state.referenceText),
+		Duration: state.elapsed(),
+	}
+	if key != nil {
+		path, err := saveRun(state, key, now)
+		if err != nil {
+			return "", err
+		}
+		cert.Fingerprint = keyFingerprint(key.Public().(ed25519.PublicKey))
+		cert.Recording = filepath.Base(path)
+	}
+	_, svgPath, err := saveCertificate(cert)
+	return svgPath, err
+}
+
+// certificateName is the name printed on the player's certificates
+func certificateName(cfg Config) string {
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "keysmash player"
+}
+
+// examCertificate is the certificate for a completed exam
+func examCertificate(report examReport) (certificate, error) {
+	if report.Outcome != examCompleted {
+		return certificate{}, fmt.Errorf("exam was %s, not completed", report.Outcome)
+	}
+	return certificate{
+		Name:        report.Candidate,
+		WPM:         report.WPM,
+		Accuracy:    report.Accuracy,
+		Date:        report.Finished,
+		TestFile:    report.TestFile,
+		TextHash:    report.TextHash,
+		Duration:    report.Duration,
+		Exam:        true,
+		Fingerprint: keyFingerprint(report.PublicKey),
+	}, nil
+}
+
+// runExamCertificate verifies exam reports and renders their certificates
+// for the certificate subcommand. It returns the process exit code.
+func runExamCertificate(w io.Writer, paths []string) int {
+	status := 0
+	for _, path := range paths {
+		if !strings.HasSuffix(path, examExt) {
+			fmt.Fprintf(w, "%s: FAILED: certificates are issued for exam reports (%s)\n", path, examExt)
+			status = 1
+			continue
+		}
+		report, err := loadExamReport(path)
+		if err == nil {
+			err = report.verify()
+		}
+		var cert certificate
+		if err == nil {
+			cert, err = examCertificate(report)
+		}
+		var textPath, svgPath string
+		if err == nil {
+			textPath, svgPath, err = saveCertificate(cert)
+		}
+		if err != nil {
+			fmt.Fprintf(w, "%s: FAILED: %v\n", path, err)
+			status = 1
+			continue
+		}
+		fmt.Fprint(w, cert.text())
+		fmt.Fprintf(w, "Saved %s and %s\n", textPath, svgPath)
+	}
+	return status
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+func TestCertificateRendering(t *testing.T) {
+	cert := certificate{
+		Name:     "Zoë <Admin>",
+		WPM:      84.4,
+		Accuracy: 98.23,
+		Date:     time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC),
+		TestFile: "pangrams.txt",
+		TextHash: "abc123",
+		Duration: 42 * time.Second,
+	}
+	if want := "Zoë <Admin> typed 84 WPM at 98.2% accuracy on 16 October 2026"; cert.headline() != want {
+		t.Errorf("headline = %q, want %q", cert.headline(), want)
+	}
+
+	text := cert.text()
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for _, line := range lines {
+		if runewidth.StringWidth(line) != runewidth.StringWidth(lines[0]) {
+			t.Errorf("ragged box line %q", line)
+		}
+	}
+	if !strings.Contains(text, cert.headline()) || !strings.Contains(text, "Unsigned") {
+		t.Errorf("text certificate:\n%s", text)
+	}
+
+	svg := cert.svg()
+	if !strings.Contains(svg, "Zoë &lt;Admin&gt;") || strings.Contains(svg, "<Admin>") {
+		t.Errorf("name not escaped in SVG:\n%s", svg)
+	}
+}
+
+func TestExamCertificate(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	key, err := loadSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sit produces a signed report of an exam typed as far as input
+	sit := func(input string) string {
+		t.Helper()
+		clock := &fakeClock{now: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}
+		state := newTestState("hi", "exam.txt", clock)
+		p := &proctor{state: &state, idleLimit: 30 * time.Second}
+		for _, r := range input {
+			clock.advance(200 * time.Millisecond)
+			p.key(r, false)
+		}
+		if p.outcome == "" {
+			p.outcome = examAbandoned
+		}
+		report := newExamReport("Ada", p, clock.Now())
+		if err := report.sign(key); err != nil {
+			t.Fatal(err)
+		}
+		path, err := saveExamReport(report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	var out bytes.Buffer
+	if code := runExamCertificate(&out, []string{sit("hi")}); code != 0 {
+		t.Fatalf("exit %d: %s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "Ada typed") || !strings.Contains(out.String(), "Proctored exam") {
+		t.Errorf("output:\n%s", out.String())
+	}
+	dir, _ := dataFile("certificates")
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 2 {
+		t.Errorf("certificates dir has %d files (%v), want .txt and .svg", len(entries), err)
+	}
+
+	out.Reset()
+	if code := runExamCertificate(&out, []string{sit("h")}); code != 1 || !strings.Contains(out.String(), "abandoned, not completed") {
+		t.Errorf("abandoned exam: exit %d: %s", code, out.String())
+	}
+}
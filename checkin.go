@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// comfortScale is the top of the check-in scale: 1 is comfortable,
+// comfortScale is painful
+const comfortScale = 5
+
+// askComfort asks how the player's wrists feel and waits for a one-key
+// answer. ok is false if they skipped the question.
+func askComfort(screen tcell.Screen) (comfort int, ok bool) {
+	drawBox(screen, []string{
+		"How do your wrists feel?",
+		"",
+		fmt.Sprintf("1: fine ... %d: painful", comfortScale),
+		"",
+		"Esc: skip",
+	})
+	screen.Show()
+
+	for {
+		ev, isKey := screen.PollEvent().(*tcell.EventKey)
+		if !isKey {
+			continue
+		}
+		if ev.Key() == tcell.KeyEscape {
+			return 0, false
+		}
+		if r := ev.Rune(); r >= '1' && r <= '0'+comfortScale {
+			return int(r - '0'), true
+		}
+	}
+}
+
+// recordComfort attaches a check-in answer to the tests of the session it
+// was asked at the end of. They're found by run key rather than position,
+// since deletes shift the store and a daemon's store holds other
+// terminals' runs too.
+func (s *resultStore) recordComfort(comfort int) error {
+	session := make(map[string]bool, len(s.session))
+	for _, key := range s.session {
+		session[key] = true
+	}
+	var tagged []result
+	for i := range s.Results {
+		if session[s.Results[i].key()] {
+			s.Results[i].Comfort = comfort
+			tagged = append(tagged, s.Results[i])
+		}
+	}
+	s.audit("comfort", "", strconv.Itoa(comfort), tagged)
+	return s.save()
+}
+
+// checkInComfort runs the end-of-session check-in if any test was
+// completed this session
+func checkInComfort(screen tcell.Screen, results *resultStore) {
+	if len(results.session) == 0 {
+		return
+	}
+	comfort, ok := askComfort(screen)
+	if !ok {
+		logger.Info("comfort check-in skipped")
+		return
+	}
+	logger.Info("comfort check-in", "comfort", comfort, "tests", len(results.session))
+	if err := results.recordComfort(comfort); err != nil {
+		logger.Error("saving comfort check-in failed", "err", err)
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gdamore/tcell/v2"
+)
+
+// classRoster is an instructor's classroom.toml: who's in the class and
+// which texts from the tests directory they're assigned, in order
+//
+//	students = ["Ada", "Grace", "Linus"]
+//	tests = ["home-row.txt", "pangrams.txt"]
+type classRoster struct {
+	Students []string `toml:"students"`
+	Tests    []string `toml:"tests"`
+}
+
+func classRosterPath() (string, error) {
+	return dataFile("classroom.toml")
+}
+
+// loadClassRoster reads a roster, rejecting unknown keys like loadConfig
+func loadClassRoster(path string) (classRoster, error) {
+	var roster classRoster
+	meta, err := toml.DecodeFile(path, &roster)
+	if errors.Is(err, fs.ErrNotExist) {
+		return roster, fmt.Errorf("no class roster; create %s listing students and tests", path)
+	}
+	if err != nil {
+		return roster, fmt.Errorf("reading roster %s: %w", path, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+		return roster, fmt.Errorf("roster %s: unknown setting(s): %s", path, strings.Join(keys, ", "))
+	}
+	if len(roster.Students) == 0 || len(roster.Tests) == 0 {
+		return roster, fmt.Errorf("roster %s: needs at least one student and one test", path)
+	}
+	seen := make(map[string]bool)
+	for _, student := range roster.Students {
+		if strings.TrimSpace(student) == "" {
+			return roster, fmt.Errorf("roster %s: blank student name", path)
+		}
+		if seen[student] {
+			return roster, fmt.Errorf("roster %s: %q is listed twice", path, student)
+		}
+		seen[student] = true
+	}
+	return roster, nil
+}
+
+// classRun is one completed assigned test
+type classRun struct {
+	Student   string        `json:"student"`
+	TestFile  string        `json:"test_file"`
+	Completed time.Time     `json:"completed"`
+	WPM       float64       `json:"wpm"`
+	Accuracy  float64       `json:"accuracy"`
+	Errors    int           `json:"errors"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// classLog holds every student's runs. Students may be typing in several
+// SSH sessions on the same machine at once, so runs are appended to what's
+// on disk rather than to a copy loaded at startup.
+type classLog struct {
+	Runs []classRun `json:"runs"`
+	path string
+}
+
+func loadClassLog() (*classLog, error) {
+	path, err := dataFile("classroom.json")
+	if err != nil {
+		return nil, err
+	}
+	log := &classLog{path: path}
+	if err := readJSONFile(path, log); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// append reloads the log, adds run and saves it
+func (l *classLog) append(run classRun) error {
+	fresh := &classLog{path: l.path}
+	if err := readJSONFile(l.path, fresh); err != nil {
+		return err
+	}
+	fresh.Runs = append(fresh.Runs, run)
+	l.Runs = fresh.Runs
+	return saveDataFile(l.path, fresh)
+}
+
+// classSession is class mode's roster, log and the student at the keyboard
+type classSession struct {
+	roster  classRoster
+	log     *classLog
+	student string
+}
+
+// loadClassSession reads the roster and log for class mode
+func loadClassSession() (*classSession, error) {
+	path, err := classRosterPath()
+	if err != nil {
+		return nil, err
+	}
+	roster, err := loadClassRoster(path)
+	if err != nil {
+		return nil, err
+	}
+	log, err := loadClassLog()
+	if err != nil {
+		return nil, err
+	}
+	return &classSession{roster: roster, log: log}, nil
+}
+
+// completed returns which assigned tests student has finished, and how
+// many runs they've done in all
+func (c *classSession) completed(student string) (done map[string]bool, runs int) {
+	done = make(map[string]bool)
+	for _, run := range c.log.Runs {
+		if run.Student == student {
+			done[run.TestFile] = true
+			runs++
+		}
+	}
+	return done, runs
+}
+
+// next returns the first assigned test the student hasn't completed. Once
+// they've done them all the assignment starts over.
+func (c *classSession) next() string {
+	done, runs := c.completed(c.student)
+	for _, test := range c.roster.Tests {
+		if !done[test] {
+			return test
+		}
+	}
+	return c.roster.Tests[runs%len(c.roster.Tests)]
+}
+
+// classTest loads the current student's next assigned test
+func (e *Engine) classTest() (TestState, error) {
+	name := e.class.next()
+	logger.Info("selected assigned test", "student", e.class.student, "file", name)
+	return e.loadTest(name)
+}
+
+// recordClassResults adds every test completed in class mode to the log
+// under the student who typed it
+func recordClassResults(bus *EventBus, c *classSession) {
+	bus.Subscribe(func(ev Event) {
+		err := c.log.append(classRun{
+			Student:   c.student,
+			TestFile:  ev.TestFile,
+			Completed: ev.Time,
+			WPM:       ev.WPM,
+			Accuracy:  ev.Accuracy,
+			Errors:    ev.Errors,
+			Duration:  ev.Duration,
+		})
+		if err != nil {
+			logger.Error("saving class log failed", "err", err)
+		}
+	}, EventTestCompleted)
+}
+
+// chooseStudent replaces the welcome screen in class mode, asking who's
+// typing. The last student chosen starts selected, so someone taking
+// several tests in a row only has to press Enter.
+func chooseStudent(screen tcell.Screen, c *classSession) bool {
+	list := menu{centered: true}
+	for i, student := range c.roster.Students {
+		if student == c.student {
+			list.selected = i
+		}
+	}
+	for {
+		screen.Clear()
+		width, height := screen.Size()
+		drawCenteredText(screen, width/2, 1, tcell.StyleDefault, "KEYSMASH CLASSROOM")
+		drawCenteredText(screen, width/2, 3, tcell.StyleDefault, "Who's typing? Up/Down to choose, Enter to start, ESC to quit")
+
+		// Scroll so the selection stays on screen
+		rows := max(1, height-6)
+		items := make([]string, len(c.roster.Students))
+		for i, student := range c.roster.Students {
+			done, _ := c.completed(student)
+			finished := 0
+			for _, test := range c.roster.Tests {
+				if done[test] {
+					finished++
+				}
+			}
+			items[i] = fmt.Sprintf(" %s  %d/%d ", student, finished, len(c.roster.Tests))
+		}
+		list.draw(screen, width/2, 5, rows, items)
+		screen.Show()
+
+		switch ev := screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			if list.handle(ev, len(c.roster.Students), rows) {
+				break
+			}
+			switch ev.Key() {
+			case tcell.KeyEscape:
+				return false
+			case tcell.KeyEnter:
+				c.student = c.roster.Students[list.selected]
+				return true
+			}
+		}
+	}
+}
+
+// classSummary is one student's line of the instructor's report
+type classSummary struct {
+	student         string
+	completed, runs int
+	bestWPM         float64
+	totalWPM        float64
+	totalAccuracy   float64
+	errors          int
+	last            time.Time
+}
+
+// summarizeClass totals the log per student, roster order first and then
+// anyone who has runs but has since left the roster
+func summarizeClass(roster classRoster, runs []classRun) []classSummary {
+	assigned := make(map[string]bool)
+	for _, test := range roster.Tests {
+		assigned[test] = true
+	}
+	index := make(map[string]int)
+	var summaries []classSummary
+	add := func(student string) {
+		if _, ok := index[student]; !ok {
+			index[student] = len(summaries)
+			summaries = append(summaries, classSummary{student: student})
+		}
+	}
+	for _, student := range roster.Students {
+		add(student)
+	}
+
+	done := make(map[[2]string]bool)
+	for _, run := range runs {
+		add(run.Student)
+		s := &summaries[index[run.Student]]
+		s.runs++
+		s.bestWPM = max(s.bestWPM, run.WPM)
+		s.totalWPM += run.WPM
+		s.totalAccuracy += run.Accuracy
+		s.errors += run.Errors
+		if run.Completed.After(s.last) {
+			s.last = run.Completed
+		}
+		key := [2]string{run.Student, run.TestFile}
+		if assigned[run.TestFile] && !done[key] {
+			done[key] = true
+			s.completed++
+		}
+	}
+	return summaries
+}
+
+// runClassReport writes the per-student summary as CSV for the instructor.
+// It returns the process exit code.
+func runClassReport(w, stderr io.Writer) int {
+	path, err := classRosterPath()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	roster, err := loadClassRoster(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	log, err := loadClassLog()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"student", "assigned", "completed", "runs", "best_wpm", "average_wpm", "average_accuracy", "errors", "last_run"})
+	for _, s := range summarizeClass(roster, log.Runs) {
+		average, accuracy, last := "", "", ""
+		if s.runs > 0 {
+			average = strconv.FormatFloat(s.totalWPM/float64(s.runs), 'f', 1, 64)
+			accuracy = strconv.FormatFloat(s.totalAccuracy/float64(s.runs), 'f', 1, 64)
+			last = s.last.Format(time.RFC3339)
+		}
+		out.Write([]string{
+			s.student,
+			strconv.Itoa(len(roster.Tests)),
+			strconv.Itoa(s.completed),
+			strconv.Itoa(s.runs),
+			strconv.FormatFloat(s.bestWPM, 'f', 1, 64),
+			average,
+			accuracy,
+			strconv.Itoa(s.errors),
+			last,
+		})
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
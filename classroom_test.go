@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadClassRoster(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	path, err := classRosterPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadClassRoster(path); err == nil || !strings.Contains(err.Error(), "no class roster") {
+		t.Errorf("missing roster: err = %v", err)
+	}
+
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"students = [\"a\"]\ntests = [\"t.txt\"]\nteacher = \"x\"", "unknown setting(s): teacher"},
+		{"students = [\"a\"]", "at least one student and one test"},
+		{"students = [\"a\", \" \"]\ntests = [\"t.txt\"]", "blank student name"},
+		{"students = [\"a\", \"a\"]\ntests = [\"t.txt\"]", `"a" is listed twice`},
+	}
+	for _, tt := range tests {
+		if err := os.WriteFile(path, []byte(tt.body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadClassRoster(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: err = %v, want it to contain %q", tt.body, err, tt.want)
+		}
+	}
+}
+
+func TestClassNextTest(t *testing.T) {
+	c := &classSession{
+		roster:  classRoster{Students: []string{"ada", "grace"}, Tests: []string{"a.txt", "b.txt"}},
+		log:     &classLog{},
+		student: "ada",
+	}
+	c.log.Runs = []classRun{{Student: "grace", TestFile: "a.txt"}}
+	if got := c.next(); got != "a.txt" {
+		t.Errorf("ada's first test = %q, want a.txt", got)
+	}
+
+	// An unassigned or repeated run doesn't count, and once everything is
+	// done the assignment starts over
+	c.log.Runs = append(c.log.Runs, classRun{Student: "ada", TestFile: "a.txt"})
+	if got := c.next(); got != "b.txt" {
+		t.Errorf("after a.txt, next = %q, want b.txt", got)
+	}
+	c.log.Runs = append(c.log.Runs, classRun{Student: "ada", TestFile: "b.txt"})
+	if got := c.next(); got != "a.txt" {
+		t.Errorf("after everything, next = %q, want a.txt", got)
+	}
+}
+
+func TestClassReport(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	path, err := classRosterPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("students = [\"ada\", \"grace\"]\ntests = [\"a.txt\", \"b.txt\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := loadClassLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	for _, run := range []classRun{
+		{Student: "ada", TestFile: "a.txt", Completed: day, WPM: 40, Accuracy: 90, Errors: 3},
+		{Student: "ada", TestFile: "a.txt", Completed: day.Add(time.Hour), WPM: 50, Accuracy: 100},
+		{Student: "linus", TestFile: "b.txt", Completed: day, WPM: 70, Accuracy: 95, Errors: 1},
+	} {
+		if err := log.append(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out, stderr bytes.Buffer
+	if code := runClassReport(&out, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	want := `student,assigned,completed,runs,best_wpm,average_wpm,average_accuracy,errors,last_run
+ada,2,1,2,50.0,45.0,95.0,3,2026-10-16T10:00:00Z
+grace,2,0,0,0.0,,,0,
+linus,2,1,1,70.0,70.0,95.0,1,2026-10-16T09:00:00Z
+`
+	if out.String() != want {
+		t.Errorf("report:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
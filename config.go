@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config holds the settings read from config.toml. Every field has a
+// default, so a missing file is the same as an empty one.
+type Config struct {
+	// ScoreFormula ranks completed runs for personal bests and daily
+	// challenge bests; see formula.go for the language. The default ranks
+	// by speed alone.
+	ScoreFormula string `toml:"score_formula"`
+
+	// BreakAfter is how much continuous typing earns a break reminder; 0
+	// turns reminders off. BreakSnooze is how long a snoozed one waits.
+	BreakAfter  time.Duration `toml:"break_after"`
+	BreakSnooze time.Duration `toml:"break_snooze"`
+
+	// ComfortCheckin asks how the player's wrists feel when they quit,
+	// storing the answer with that session's results
+	ComfortCheckin bool `toml:"comfort_checkin"`
+
+	// SessionMetrics names numbers to ask for when keysmash starts, such
+	// as resting_hr or sleep_hours, stored with the session's results.
+	// MetricsHook is a shell command whose output can supply them, and
+	// others, instead; see parseMetrics.
+	SessionMetrics []string `toml:"session_metrics"`
+	MetricsHook    string   `toml:"metrics_hook"`
+
+	// StripEmoji removes emoji from texts before they're typed, for
+	// keyboards and terminals that can't enter them
+	StripEmoji bool `toml:"strip_emoji"`
+
+	// InputMethod turns on IME cursor handling for every text; texts in
+	// Chinese, Japanese or Korean get it regardless
+	InputMethod bool `toml:"input_method"`
+
+	// Steno scores for stenotype output such as Plover's, which commits
+	// whole words at once: speed counts real words and rhythm is measured
+	// between strokes rather than between characters
+	Steno bool `toml:"steno"`
+
+	// WordByWord has space submit each word, right or not, and move on
+	// to the next, as on monkeytype; see wordbyword.go
+	WordByWord bool `toml:"word_by_word"`
+
+	// StopOnError keeps the cursor on a wrong key until the right one is
+	// typed; see strict.go
+	StopOnError bool `toml:"stop_on_error"`
+
+	// SuddenDeath ends a test on its first error; see suddendeath.go
+	SuddenDeath bool `toml:"sudden_death"`
+
+	// NoBackspace starts in typewriter mode, where backspace does nothing;
+	// see typewriter.go
+	NoBackspace bool `toml:"no_backspace"`
+
+	// Blind hides what's typed until the test ends; see blind.go
+	Blind bool `toml:"blind"`
+
+	// Remap rewrites typed characters before scoring, for keyboards whose
+	// layout or firmware sends the wrong one; see remap.go
+	Remap map[string]string `toml:"remap"`
+
+	// SignRecordings signs saved .ksm recordings with a key generated on
+	// this machine, so a leaderboard can tell they haven't been altered
+	SignRecordings bool `toml:"sign_recordings"`
+
+	// Name is printed on certificates; the login name if unset
+	Name string `toml:"name"`
+
+	// LowPowerBattery is the battery charge, in percent, at or below which
+	// running on battery turns on low-power mode; 0 never does
+	LowPowerBattery int `toml:"low_power_battery"`
+
+	// MaxFPS caps how often the test screen redraws, coalescing keystrokes
+	// that arrive faster; 0 redraws for every event
+	MaxFPS int `toml:"max_fps"`
+
+	// ToastDuration is how long each notice, such as a new personal best,
+	// stays in the corner of the screen; 0 turns them off
+	ToastDuration time.Duration `toml:"toast_duration"`
+
+	// SpeedGauge shows live speed during tests on a dial banded by the
+	// player's average and best, in place of the WPM figure
+	SpeedGauge bool `toml:"speed_gauge"`
+
+	// SplitScreen puts the text on the left and the typing on the right,
+	// line beside line, on screens wide enough for two columns
+	SplitScreen bool `toml:"split_screen"`
+
+	// LargeText shows the characters around the typing position spaced
+	// out and in bold above the text, for low vision, at the cost of some
+	// lines of the text
+	LargeText bool `toml:"large_text"`
+
+	// LineNumbers numbers the lines of the text in a gutter and shows
+	// which line and column is being typed, for long texts and code
+	LineNumbers bool `toml:"line_numbers"`
+
+	// TestsDir is the directory of texts to use instead of looking for a
+	// tests directory; --dir overrides it. A leading ~/ is the home
+	// directory.
+	TestsDir string `toml:"tests_dir"`
+
+	// Mode is the test mode to start in unless --mode says otherwise;
+	// pack mode needs --pack, so it can only be chosen there
+	Mode string `toml:"mode"`
+
+	// Theme names the colours used for feedback while typing; see themes
+	Theme string `toml:"theme"`
+
+	// Keys gives commands other keys, by action name; see keyActions
+	Keys map[string]string `toml:"keys"`
+
+	// TargetWPM is the speed the results screen measures each run
+	// against; 0 sets none. --target overrides it.
+	TargetWPM float64 `toml:"target_wpm"`
+
+	// TargetAccuracy is the accuracy the accuracy curve recommends a text
+	// length for; see accuracycurve.go
+	TargetAccuracy float64 `toml:"target_accuracy"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		ScoreFormula:    "wpm",
+		BreakSnooze:     5 * time.Minute,
+		LowPowerBattery: 20,
+		MaxFPS:          60,
+		ToastDuration:   3 * time.Second,
+		Theme:           "default",
+		TargetAccuracy:  defaultTargetAccuracy,
+	}
+}
+
+// loadConfig reads the config file at path over the defaults. A missing
+// file is not an error, but unknown keys are, since they're almost always
+// typos that would otherwise be silently ignored.
+func loadConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	meta, err := toml.DecodeFile(path, &cfg)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("reading config %s: %w", path, err)
+	}
+
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+		return cfg, fmt.Errorf("config %s: unknown setting(s): %s", path, strings.Join(keys, ", "))
+	}
+
+	if _, err := compileFormula(cfg.ScoreFormula); err != nil {
+		return cfg, fmt.Errorf("config %s: %w", path, err)
+	}
+	if _, err := parseRemap(cfg.Remap); err != nil {
+		return cfg, fmt.Errorf("config %s: %w", path, err)
+	}
+	if cfg.BreakAfter < 0 || cfg.BreakSnooze <= 0 {
+		return cfg, fmt.Errorf("config %s: break_after must not be negative and break_snooze must be positive", path)
+	}
+	if cfg.LowPowerBattery < 0 || cfg.LowPowerBattery > 100 {
+		return cfg, fmt.Errorf("config %s: low_power_battery must be a percentage from 0 to 100", path)
+	}
+	if cfg.MaxFPS < 0 {
+		return cfg, fmt.Errorf("config %s: max_fps must not be negative", path)
+	}
+	if cfg.ToastDuration < 0 {
+		return cfg, fmt.Errorf("config %s: toast_duration must not be negative", path)
+	}
+	if cfg.TestsDir, err = expandHome(cfg.TestsDir); err != nil {
+		return cfg, fmt.Errorf("config %s: tests_dir: %w", path, err)
+	}
+	if info, err := os.Stat(cfg.TestsDir); cfg.TestsDir != "" && (err != nil || !info.IsDir()) {
+		return cfg, fmt.Errorf("config %s: tests_dir %s is not a directory", path, cfg.TestsDir)
+	}
+	switch cfg.Mode {
+	case "", modeRandom, modeDaily, modeLearn, modeClass, modeWords:
+	default:
+		return cfg, fmt.Errorf("config %s: mode must be random, daily, learn, class or words, not %q", path, cfg.Mode)
+	}
+	if _, ok := themes[cfg.Theme]; !ok {
+		return cfg, fmt.Errorf("config %s: theme must be one of %s, not %q", path, themeNames(), cfg.Theme)
+	}
+	if _, err := parseKeyBindings(cfg.Keys); err != nil {
+		return cfg, fmt.Errorf("config %s: %w", path, err)
+	}
+	if err := checkMetricNames(cfg.SessionMetrics); err != nil {
+		return cfg, fmt.Errorf("config %s: session_metrics: %w", path, err)
+	}
+	if cfg.TargetWPM < 0 {
+		return cfg, fmt.Errorf("config %s: target_wpm must not be negative", path)
+	}
+	if cfg.TargetAccuracy <= 0 || cfg.TargetAccuracy > 100 {
+		return cfg, fmt.Errorf("config %s: target_accuracy must be over 0 and at most 100", path)
+	}
+	return cfg, nil
+}
+
+// expandHome replaces a leading ~/ in path with the home directory
+func expandHome(path string) (string, error) {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, rest), nil
+}
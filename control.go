@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// The control API lets other programs, such as editor plugins and stream
+// overlays, work with a running session over controlSocket in the data
+// directory: read the live state of the test, fetch stats, or start a
+// test. It speaks JSON lines, a controlResponse for each controlRequest.
+// It reads only snapshots, so it never waits on the input loop; a test it
+// starts is handed to the welcome screen, which starts it straight away
+// or as soon as the player is back there. An editor plugin sends its
+// selection as a start with wait set, and the answer comes when the test
+// is done, with its result.
+
+// controlSocket is the control API's socket in the data directory
+const controlSocket = "control.sock"
+
+// controlFile names tests started from the control API with their text
+// in TestState.testFile
+const controlFile = "control"
+
+// controlKey is what waitForStart returns when the control API has a test
+// to start. It's in the private use area, so no key sends it.
+const controlKey = '\uf8ff'
+
+// controlRequest is a request to the control API. Op is state, stats or
+// start; start takes File, a text in the tests directory, or Text to
+// type, and optionally TimeLimit in seconds. A start with Wait isn't
+// answered until the test is completed, with its result, or until another
+// start replaces it.
+type controlRequest struct {
+	Op        string `json:"op"`
+	File      string `json:"file,omitempty"`
+	Text      string `json:"text,omitempty"`
+	TimeLimit int    `json:"time_limit,omitempty"`
+	Wait      bool   `json:"wait,omitempty"`
+}
+
+// controlResponse answers a controlRequest
+type controlResponse struct {
+	State  *controlState `json:"state,omitempty"`
+	Stats  *controlStats `json:"stats,omitempty"`
+	Result *result       `json:"result,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// controlState is the test on screen, or the last one
+type controlState struct {
+	File     string        `json:"file"`
+	Started  bool          `json:"started"`
+	Complete bool          `json:"complete"`
+	Typed    int           `json:"typed"`
+	Length   int           `json:"length"`
+	Errors   int           `json:"errors"`
+	WPM      float64       `json:"wpm"`
+	Accuracy float64       `json:"accuracy"`
+	Elapsed  time.Duration `json:"elapsed"`
+}
+
+// controlStats sums up the player's results
+type controlStats struct {
+	Tests      int     `json:"tests"`
+	BestWPM    float64 `json:"best_wpm"`
+	AverageWPM float64 `json:"average_wpm"` // of the last averageWindow
+	Last       *result `json:"last,omitempty"`
+}
+
+// newControlState describes a snapshot of a test
+func newControlState(state TestState) *controlState {
+	wpm := 0.0
+	if state.testStarted {
+		wpm = state.wpm(state.elapsed())
+	}
+	return &controlState{
+		File:     state.testFile,
+		Started:  state.testStarted,
+		Complete: state.testComplete,
+		Typed:    state.typed(),
+		Length:   len(state.reference),
+		Errors:   state.errors,
+		WPM:      wpm,
+		Accuracy: calculateAccuracy(state.errors, state.typed()),
+		Elapsed:  state.elapsed(),
+	}
+}
+
+// newControlStats sums up results as of now
+func newControlStats(results *resultStore, now time.Time) *controlStats {
+	counted := results.counted()
+	stats := &controlStats{Tests: len(counted), BestWPM: results.bestWPM()}
+	stats.AverageWPM, _ = results.averageBefore(now.Add(time.Second), averageWindow)
+	if len(counted) > 0 {
+		last := counted[len(counted)-1]
+		stats.Last = &last
+	}
+	return stats
+}
+
+// controlServer serves the control API for a session
+type controlServer struct {
+	engine *Engine
+	screen tcell.Screen
+
+	// stats is refreshed on the input loop as tests complete, so
+	// requests never read the results store while it's being changed
+	stats atomic.Pointer[controlStats]
+
+	mu      sync.Mutex
+	pending *controlRequest // a test to start, waiting for the welcome screen
+
+	// waiter answers the start request with wait set that's pending or
+	// running, if any
+	waiter *controlWaiter
+}
+
+// controlWaiter is a client waiting on the result of the test it started
+type controlWaiter struct {
+	req      *controlRequest
+	textHash string // the test's, once it's loaded
+	answer   chan controlResponse
+}
+
+// serveControl serves the control API on the socket until the listener
+// is closed. A socket left by a session that didn't exit cleanly is
+// replaced; one another session is serving is left alone.
+func serveControl(engine *Engine, screen tcell.Screen, results *resultStore) (*controlServer, net.Listener, error) {
+	path, err := dataFile(controlSocket)
+	if err != nil {
+		return nil, nil, err
+	}
+	if conn, err := net.DialTimeout("unix", path, daemonDialTimeout); err == nil {
+		conn.Close()
+		return nil, nil, errors.New("another session is serving the control API")
+	}
+	os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("serving the control API: %w", err)
+	}
+
+	c := &controlServer{engine: engine, screen: screen}
+	c.stats.Store(newControlStats(results, engine.clock.Now()))
+	engine.events.Subscribe(func(ev Event) {
+		c.stats.Store(newControlStats(results, ev.Time))
+		c.completed(ev)
+	}, EventTestCompleted, EventTestFailed)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					logger.Error("accepting control client failed", "err", err)
+				}
+				return
+			}
+			go c.serveClient(conn)
+		}
+	}()
+	logger.Info("serving the control API", "path", path)
+	return c, listener, nil
+}
+
+func (c *controlServer) serveClient(conn net.Conn) {
+	defer conn.Close()
+	decoder := json.NewDecoder(bufio.NewReader(conn))
+	encoder := json.NewEncoder(conn)
+	for {
+		var req controlRequest
+		if err := decoder.Decode(&req); err != nil {
+			if !errors.Is(err, io.EOF) {
+				logger.Warn("reading control request failed", "err", err)
+			}
+			return
+		}
+		if err := encoder.Encode(c.handle(req)); err != nil {
+			logger.Warn("answering control client failed", "err", err)
+			return
+		}
+	}
+}
+
+// handle answers one request
+func (c *controlServer) handle(req controlRequest) controlResponse {
+	switch req.Op {
+	case "state":
+		state, ok := c.engine.Snapshot()
+		if !ok {
+			return controlResponse{}
+		}
+		return controlResponse{State: newControlState(state)}
+	case "stats":
+		return controlResponse{Stats: c.stats.Load()}
+	case "start":
+		if (req.File == "") == (req.Text == "") {
+			return controlResponse{Error: "start takes a file or a text"}
+		}
+		switch req.TimeLimit {
+		case 0, 30, 60, 120:
+		default:
+			return controlResponse{Error: fmt.Sprintf("time_limit %d is not a test length (want 30, 60 or 120)", req.TimeLimit)}
+		}
+		var answer chan controlResponse
+		c.mu.Lock()
+		c.pending = &req
+		c.answer(controlResponse{Error: "another test was started instead"})
+		if req.Wait {
+			answer = make(chan controlResponse, 1)
+			c.waiter = &controlWaiter{req: &req, answer: answer}
+		}
+		c.mu.Unlock()
+		logger.Info("control API asked for a test", "file", req.File, "wait", req.Wait)
+		_ = c.screen.PostEvent(tcell.NewEventInterrupt(controlWake{}))
+		if answer == nil {
+			return controlResponse{}
+		}
+		return <-answer
+	}
+	return controlResponse{Error: fmt.Sprintf("unknown op %q", req.Op)}
+}
+
+// controlWake tells waitForStart there's a test to start
+type controlWake struct{}
+
+// takePending returns the test waiting to be started, if any, and clears
+// it
+func (c *controlServer) takePending() *controlRequest {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	req := c.pending
+	c.pending = nil
+	return req
+}
+
+// answer answers the waiting client, if there is one, with resp. c.mu must
+// be held.
+func (c *controlServer) answer(resp controlResponse) {
+	if c.waiter != nil {
+		c.waiter.answer <- resp
+		c.waiter = nil
+	}
+}
+
+// startTest loads the test req asks for, pointing a client waiting on it
+// at the test, or answering it if the test can't be loaded
+func (c *controlServer) startTest(req *controlRequest) (TestState, error) {
+	state, err := c.engine.controlTest(req)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.waiter != nil && c.waiter.req == req {
+		if err != nil {
+			c.answer(controlResponse{Error: err.Error()})
+		} else {
+			c.waiter.textHash = hashText(state.referenceText)
+		}
+	}
+	return state, err
+}
+
+// completed answers the client waiting on a test, if ev completes it.
+// Retrying the test keeps the client waiting, and so does playing another
+// in between.
+func (c *controlServer) completed(ev Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.waiter != nil && c.waiter.textHash != "" && c.waiter.textHash == ev.TextHash {
+		r := eventResult(ev)
+		c.answer(controlResponse{Result: &r})
+	}
+}
+
+// controlTest loads the test req asks for
+func (e *Engine) controlTest(req *controlRequest) (TestState, error) {
+	var state TestState
+	if req.File == "" {
+		text, err := e.prepareText(req.Text, controlFile)
+		if err != nil {
+			return TestState{}, err
+		}
+		state = e.newTest(text, controlFile)
+	} else {
+		var err error
+		if state, err = e.loadTest(req.File); err != nil {
+			return TestState{}, err
+		}
+	}
+	if req.TimeLimit > 0 {
+		state.timeLimit = time.Duration(req.TimeLimit) * time.Second
+		state.publishSnapshot()
+	}
+	return state, nil
+}
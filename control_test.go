@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestControlAPI(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "fox.txt"), []byte("The quick brown fox."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}
+	engine := newEngine(clock, fixedRand(0), dir)
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	results := &resultStore{Results: []result{{TestFile: "fox.txt", Completed: clock.now.Add(-time.Hour), WPM: 60}}}
+
+	server, listener, err := serveControl(engine, screen, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	conn, err := net.Dial("unix", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	encoder, decoder := json.NewEncoder(conn), json.NewDecoder(conn)
+	call := func(req controlRequest) controlResponse {
+		t.Helper()
+		var resp controlResponse
+		if err := encoder.Encode(req); err != nil {
+			t.Fatal(err)
+		}
+		if err := decoder.Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := call(controlRequest{Op: "stats"}); resp.Stats == nil || resp.Stats.Tests != 1 || resp.Stats.BestWPM != 60 {
+		t.Errorf("stats = %+v", resp.Stats)
+	}
+	if resp := call(controlRequest{Op: "start", File: "fox.txt", TimeLimit: 45}); resp.Error == "" {
+		t.Error("a test of 45 seconds was accepted")
+	}
+
+	// A start request wakes the welcome screen, which loads the test
+	if resp := call(controlRequest{Op: "start", File: "fox.txt", TimeLimit: 30}); resp.Error != "" {
+		t.Fatal(resp.Error)
+	}
+	if ev, ok := screen.PollEvent().(*tcell.EventInterrupt); !ok || ev.Data() != (controlWake{}) {
+		t.Fatalf("no wake-up for the welcome screen: %v", ev)
+	}
+	req := server.takePending()
+	if req == nil || server.takePending() != nil {
+		t.Fatal("the request wasn't pending exactly once")
+	}
+	state, err := engine.controlTest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.timeLimit != 30*time.Second {
+		t.Errorf("time limit = %v", state.timeLimit)
+	}
+	state.typeRune('T')
+	clock.advance(time.Second)
+	state.typeRune('x')
+
+	resp := call(controlRequest{Op: "state"})
+	if s := resp.State; s == nil || s.File != "fox.txt" || !s.Started || s.Typed != 2 || s.Errors != 1 || s.Length != 20 {
+		t.Errorf("state = %+v", resp.State)
+	}
+	if resp := call(controlRequest{Op: "pause"}); resp.Error == "" {
+		t.Error("an unknown op didn't fail")
+	}
+}
+
+func TestControlStartWaitsForResult(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}
+	engine := newEngine(clock, fixedRand(0), t.TempDir())
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+
+	server, listener, err := serveControl(engine, screen, &resultStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	// start sends a selection as an editor plugin would, and returns the
+	// answer when it comes
+	start := func(text string) <-chan controlResponse {
+		answer := make(chan controlResponse, 1)
+		conn, err := net.Dial("unix", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		if err := json.NewEncoder(conn).Encode(controlRequest{Op: "start", Text: text, Wait: true}); err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			var resp controlResponse
+			if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+				resp.Error = err.Error()
+			}
+			answer <- resp
+		}()
+		if _, ok := screen.PollEvent().(*tcell.EventInterrupt); !ok {
+			t.Fatal("no wake-up for the welcome screen")
+		}
+		return answer
+	}
+
+	first := start("func main() {}")
+	second := start("return nil")
+	if resp := <-first; resp.Result != nil || resp.Error == "" {
+		t.Errorf("replaced start answered %+v, want an error", resp)
+	}
+
+	state, err := server.startTest(server.takePending())
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case resp := <-second:
+		t.Fatalf("answered before the test was typed: %+v", resp)
+	case <-time.After(50 * time.Millisecond):
+	}
+	for _, r := range "return nil" {
+		clock.advance(200 * time.Millisecond)
+		state.typeRune(r)
+	}
+	resp := <-second
+	if resp.Error != "" || resp.Result == nil {
+		t.Fatalf("answer = %+v, want the result", resp)
+	}
+	if r := resp.Result; r.TestFile != controlFile || r.TextHash != hashText("return nil") || r.Errors != 0 || r.Duration != 1800*time.Millisecond {
+		t.Errorf("result = %+v", r)
+	}
+}
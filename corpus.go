@@ -0,0 +1,23 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// corpusFiles are the texts built into the binary, all in the public
+// domain, so keysmash has something to play with no tests directory at
+// all. keysmash init copies them into a new one.
+//
+//go:embed corpus/*.txt
+var corpusFiles embed.FS
+
+// builtinTexts is the built-in corpus as a directory of texts, like a
+// tests directory
+func builtinTexts() fs.FS {
+	texts, err := fs.Sub(corpusFiles, "corpus")
+	if err != nil {
+		panic(err) // corpus is a valid path, so this can't happen
+	}
+	return texts
+}
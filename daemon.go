@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// keysmash daemon runs in the background holding the data lock and owns
+// the results store and keystroke log, serving them on a Unix socket in
+// the data directory. A session started while it runs is its client: it
+// loads results from it and saves them and keystrokes through it, so
+// several terminals can play at once and all their runs are kept. Other
+// data files stay read-only in a client, as in any second session.
+
+// daemonSocket is the daemon's socket in the data directory
+const daemonSocket = "keysmash.sock"
+
+// daemonDialTimeout is how long a session waits to connect to the daemon
+const daemonDialTimeout = time.Second
+
+// daemonRequest is a request to the daemon, one JSON object per line. Op
+// is results (send the store), merge (fold Results into the store, then
+// send it), delete (take the runs keyed Runs out of the store, then send
+// it), update (replace the runs in Results, as Action with Detail, as the
+// audit log has them, then send the store) or keys (append Keys to the
+// keystroke log).
+type daemonRequest struct {
+	Op      string        `json:"op"`
+	Results []result      `json:"results,omitempty"`
+	Runs    []string      `json:"runs,omitempty"` // run keys; see result.key
+	Action  string        `json:"action,omitempty"`
+	Detail  string        `json:"detail,omitempty"`
+	Keys    []keyLogEntry `json:"keys,omitempty"`
+}
+
+// daemonUpdates are the audit actions a client's update can carry: the
+// changes made to runs already in the store
+var daemonUpdates = []string{"tag", "exclude", "include", "comfort", "hash"}
+
+// daemonResponse answers a daemonRequest, one JSON object per line
+type daemonResponse struct {
+	Results []result `json:"results,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// daemon serves the data it owns to its clients, a request at a time
+type daemon struct {
+	mu      sync.Mutex
+	results *resultStore
+	keys    *keyLog
+}
+
+// handle carries out one request
+func (d *daemon) handle(req daemonRequest) daemonResponse {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch req.Op {
+	case "results":
+	case "merge":
+		added, _ := d.results.mergeRuns(req.Results)
+		if len(added) == 0 {
+			break
+		}
+		d.results.audit("add", "", "", added)
+		if err := d.results.save(); err != nil {
+			logger.Error("saving results failed", "err", err)
+			return daemonResponse{Error: err.Error()}
+		}
+		logger.Info("daemon saved results", "added", len(added))
+		return daemonResponse{Results: d.results.Results}
+	case "delete":
+		removed := d.results.removeRuns(req.Runs)
+		if len(removed) == 0 {
+			break
+		}
+		d.results.audit("delete", "", "", removed)
+		if err := d.results.save(); err != nil {
+			logger.Error("saving results failed", "err", err)
+			return daemonResponse{Error: err.Error()}
+		}
+		logger.Info("daemon deleted results", "deleted", len(removed))
+		return daemonResponse{Results: d.results.Results}
+	case "update":
+		if !slices.Contains(daemonUpdates, req.Action) {
+			return daemonResponse{Error: fmt.Sprintf("unknown update %q", req.Action)}
+		}
+		updated := d.results.updateRuns(req.Results)
+		if len(updated) == 0 {
+			break
+		}
+		d.results.audit(req.Action, "", req.Detail, updated)
+		if err := d.results.save(); err != nil {
+			logger.Error("saving results failed", "err", err)
+			return daemonResponse{Error: err.Error()}
+		}
+		logger.Info("daemon updated results", "action", req.Action, "updated", len(updated))
+		return daemonResponse{Results: d.results.Results}
+	case "keys":
+		d.keys.pending = append(d.keys.pending, req.Keys...)
+		if err := d.keys.flush(); err != nil {
+			logger.Error("saving keystroke log failed", "err", err)
+			return daemonResponse{Error: err.Error()}
+		}
+		return daemonResponse{}
+	default:
+		return daemonResponse{Error: fmt.Sprintf("unknown op %q", req.Op)}
+	}
+	return daemonResponse{Results: d.results.Results}
+}
+
+// saveToDaemon sends the changes noted for the audit log since the last
+// save to the daemon, in order, and takes back the daemon's store. New
+// runs are merged, and runs deleted or changed go as deletes and updates
+// by key, so the daemon's copies don't undo them. Changes the daemon
+// hasn't taken are kept to send at the next save.
+func (s *resultStore) saveToDaemon() error {
+	current := make(map[string]result, len(s.Results))
+	for _, r := range s.Results {
+		current[r.key()] = r
+	}
+	if len(s.pendingAudit) == 0 {
+		store, err := s.daemon.results()
+		if err != nil {
+			return err
+		}
+		s.Results = store
+		return nil
+	}
+	for len(s.pendingAudit) > 0 {
+		entry := s.pendingAudit[0]
+		keys := make([]string, len(entry.Runs))
+		var runs []result
+		for i, run := range entry.Runs {
+			keys[i] = run.Key
+			if r, ok := current[run.Key]; ok {
+				runs = append(runs, r)
+			}
+		}
+		var store []result
+		var err error
+		switch entry.Action {
+		case "delete":
+			store, err = s.daemon.delete(keys)
+		case "add", "import", "sync":
+			store, err = s.daemon.merge(runs)
+		default:
+			store, err = s.daemon.update(entry.Action, entry.Detail, runs)
+		}
+		if err != nil {
+			return err
+		}
+		s.Results, s.pendingAudit = store, s.pendingAudit[1:]
+	}
+	return nil
+}
+
+// removeRuns deletes the results with the given keys, returning them
+func (s *resultStore) removeRuns(keys []string) []result {
+	var removed []result
+	kept := s.Results[:0]
+	for _, r := range s.Results {
+		if slices.Contains(keys, r.key()) {
+			removed = append(removed, r)
+		} else {
+			kept = append(kept, r)
+		}
+	}
+	s.Results = kept
+	return removed
+}
+
+// updateRuns replaces the results runs are new versions of, returning
+// those it found. A run whose text hash was just filled in is found by
+// its key from before.
+func (s *resultStore) updateRuns(runs []result) []result {
+	index := make(map[string]int, len(s.Results))
+	for i, r := range s.Results {
+		index[r.key()] = i
+	}
+	var updated []result
+	for _, r := range runs {
+		i, ok := index[r.key()]
+		if !ok {
+			unhashed := r
+			unhashed.TextHash = ""
+			i, ok = index[unhashed.key()]
+		}
+		if ok {
+			s.Results[i] = r
+			updated = append(updated, r)
+		}
+	}
+	return updated
+}
+
+// serve answers the requests of clients connecting to listener until it's
+// closed
+func (d *daemon) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Error("accepting daemon client failed", "err", err)
+			}
+			return
+		}
+		go d.serveClient(conn)
+	}
+}
+
+func (d *daemon) serveClient(conn net.Conn) {
+	defer conn.Close()
+	logger.Info("daemon client connected")
+	decoder := json.NewDecoder(bufio.NewReader(conn))
+	encoder := json.NewEncoder(conn)
+	for {
+		var req daemonRequest
+		if err := decoder.Decode(&req); err != nil {
+			if !errors.Is(err, io.EOF) {
+				logger.Warn("reading daemon request failed", "err", err)
+			}
+			logger.Info("daemon client disconnected")
+			return
+		}
+		if err := encoder.Encode(d.handle(req)); err != nil {
+			logger.Warn("answering daemon client failed", "err", err)
+			return
+		}
+	}
+}
+
+// runDaemon runs keysmash daemon until it's interrupted and returns the
+// process exit code
+func runDaemon(w io.Writer) int {
+	lock, err := lockData()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	if !lock.held() {
+		fmt.Fprintf(w, "Error: %s\n", lock.busyText())
+		return 1
+	}
+	defer lock.release()
+
+	results, err := loadResults()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	keysPath, err := keyLogPath()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	path, err := dataFile(daemonSocket)
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	// Holding the lock, any socket there is left from a daemon that died
+	os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	defer os.Remove(path)
+	defer listener.Close()
+
+	d := &daemon{results: results, keys: &keyLog{path: keysPath, tally: newKeyTally()}}
+	go d.serve(listener)
+	fmt.Fprintf(w, "Serving %s; Ctrl+C stops\n", path)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	return 0
+}
+
+// daemonClient is a session's connection to the daemon
+type daemonClient struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	decoder *json.Decoder
+	encoder *json.Encoder
+}
+
+// dialDaemon connects to the daemon, if one is running
+func dialDaemon() (*daemonClient, error) {
+	path, err := dataFile(daemonSocket)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("unix", path, daemonDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return newDaemonClient(conn), nil
+}
+
+func newDaemonClient(conn net.Conn) *daemonClient {
+	return &daemonClient{conn: conn, decoder: json.NewDecoder(bufio.NewReader(conn)), encoder: json.NewEncoder(conn)}
+}
+
+// call sends req and waits for the answer
+func (c *daemonClient) call(req daemonRequest) (daemonResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var resp daemonResponse
+	if err := c.encoder.Encode(req); err != nil {
+		return resp, fmt.Errorf("sending to the daemon: %w", err)
+	}
+	if err := c.decoder.Decode(&resp); err != nil {
+		return resp, fmt.Errorf("reading from the daemon: %w", err)
+	}
+	if resp.Error != "" {
+		return resp, fmt.Errorf("daemon: %s", resp.Error)
+	}
+	return resp, nil
+}
+
+// results fetches the daemon's results store
+func (c *daemonClient) results() ([]result, error) {
+	resp, err := c.call(daemonRequest{Op: "results"})
+	return resp.Results, err
+}
+
+// merge sends results to be folded into the daemon's store and returns
+// the store as it then is, with other sessions' runs
+func (c *daemonClient) merge(results []result) ([]result, error) {
+	resp, err := c.call(daemonRequest{Op: "merge", Results: results})
+	return resp.Results, err
+}
+
+// delete takes the runs with the given keys out of the daemon's store and
+// returns the store as it then is
+func (c *daemonClient) delete(keys []string) ([]result, error) {
+	resp, err := c.call(daemonRequest{Op: "delete", Runs: keys})
+	return resp.Results, err
+}
+
+// update sends new versions of runs in the daemon's store, changed as
+// action with detail, and returns the store as it then is
+func (c *daemonClient) update(action, detail string, runs []result) ([]result, error) {
+	resp, err := c.call(daemonRequest{Op: "update", Action: action, Detail: detail, Results: runs})
+	return resp.Results, err
+}
+
+// appendKeys sends keystrokes for the daemon's keystroke log
+func (c *daemonClient) appendKeys(entries []keyLogEntry) error {
+	_, err := c.call(daemonRequest{Op: "keys", Keys: entries})
+	return err
+}
+
+func (c *daemonClient) Close() error {
+	return c.conn.Close()
+}
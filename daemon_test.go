@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDaemon(t *testing.T) {
+	dir := t.TempDir()
+	store := &resultStore{path: filepath.Join(dir, "results.json")}
+	keys := &keyLog{path: filepath.Join(dir, "keystrokes.jsonl"), tally: newKeyTally()}
+	listener, err := net.Listen("unix", filepath.Join(dir, daemonSocket))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go (&daemon{results: store, keys: keys}).serve(listener)
+
+	dial := func() *daemonClient {
+		conn, err := net.Dial("unix", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return newDaemonClient(conn)
+	}
+	first, second := dial(), dial()
+
+	// Two sessions each save a run; both are kept, and each sees the other's
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	a := result{TestFile: "a.txt", Started: start, Completed: start.Add(time.Minute), WPM: 80}
+	b := result{TestFile: "b.txt", Started: start.Add(time.Hour), Completed: start.Add(time.Hour + time.Minute), WPM: 70}
+	if _, err := first.merge([]result{a}); err != nil {
+		t.Fatal(err)
+	}
+	merged, err := second.merge([]result{b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 2 {
+		t.Errorf("second session got %d results back, want 2", len(merged))
+	}
+	if got, err := first.results(); err != nil || len(got) != 2 {
+		t.Errorf("first session sees %d results (%v), want 2", len(got), err)
+	}
+	saved, err := loadResultStore(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Results) != 2 {
+		t.Errorf("daemon saved %d results, want 2", len(saved.Results))
+	}
+
+	if err := first.appendKeys([]keyLogEntry{{TestFile: "a.txt", Typed: "a", Expected: "a", Correct: true}}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := readKeyLog(keys.path); err != nil || len(entries) != 1 {
+		t.Errorf("daemon logged %d keystrokes (%v), want 1", len(entries), err)
+	}
+
+	if _, err := first.call(daemonRequest{Op: "shutdown"}); err == nil {
+		t.Error("an unknown op didn't fail")
+	}
+}
+
+func TestDaemonClientChanges(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	a := result{TestFile: "a.txt", Started: start, Completed: start.Add(time.Minute), WPM: 80}
+	b := result{TestFile: "b.txt", Started: start.Add(time.Hour), Completed: start.Add(time.Hour + time.Minute), WPM: 70, Tags: []string{"warmup"}, Excluded: true}
+	store := &resultStore{Results: []result{a, b}, path: filepath.Join(dir, "results.json"), auditPath: filepath.Join(dir, "audit.jsonl")}
+	listener, err := net.Listen("unix", filepath.Join(dir, daemonSocket))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go (&daemon{results: store}).serve(listener)
+
+	conn, err := net.Dial("unix", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := &resultStore{auditPath: filepath.Join(dir, "client-audit.jsonl"), daemon: newDaemonClient(conn)}
+	if client.Results, err = client.daemon.results(); err != nil {
+		t.Fatal(err)
+	}
+
+	// From the history screen: delete a, tag b again and count it again
+	client.remove([]int{0})
+	client.tag([]int{0}, "keyboard")
+	client.setExcluded([]int{0}, false)
+	if err := client.save(); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := loadResultStore(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, results := range [][]result{client.Results, saved.Results} {
+		if len(results) != 1 || results[0].TestFile != "b.txt" {
+			t.Fatalf("results %+v after deleting a, want only b", results)
+		}
+		if r := results[0]; len(r.Tags) != 2 || r.Excluded {
+			t.Errorf("b has tags %v, excluded %v; want both tags and counted", r.Tags, r.Excluded)
+		}
+	}
+	entries, err := readAudit(store.auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	if got := strings.Join(actions, " "); got != "delete tag include" {
+		t.Errorf("daemon audited %q, want the client's changes", got)
+	}
+
+	// Filling in a hash updates the run rather than adding a copy
+	client.Results[0].TextHash = "abc"
+	client.audit("hash", "", "", client.Results)
+	if err := client.save(); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.Results) != 1 || client.Results[0].TextHash != "abc" {
+		t.Errorf("results %+v after filling in the hash, want b with it", client.Results)
+	}
+}
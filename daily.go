@@ -0,0 +1,343 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// dailyGeneratorVersion is mixed into the daily seed. Bump it whenever a
+// change to the generator would alter its output, so everyone running the
+// same version still gets the same challenge on the same date.
+const dailyGeneratorVersion = 1
+
+// dailyWordCount is the length of a daily challenge in words
+const dailyWordCount = 40
+
+// dailyVocabulary is how many of commonWords the generator draws from.
+// It's fixed rather than len(commonWords) so that appending words to the
+// list doesn't silently change every challenge.
+const dailyVocabulary = 200
+
+// dailyFilePrefix marks daily challenges in TestState.testFile; the rest
+// of the name is the challenge date
+const dailyFilePrefix = "daily-"
+
+const dateLayout = "2006-01-02"
+
+// dailyChallengeText generates the challenge for date's calendar day
+func dailyChallengeText(date time.Time) string {
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "keysmash-daily-v%d-%s", dailyGeneratorVersion, date.Format(dateLayout))
+	rng := rand.New(rand.NewSource(int64(hash.Sum64())))
+
+	words := make([]string, dailyWordCount)
+	for i := range words {
+		words[i] = commonWords[rng.Intn(dailyVocabulary)]
+	}
+	return strings.Join(words, " ")
+}
+
+// dailyChallenge returns today's challenge as a test
+func (e *Engine) dailyChallenge() TestState {
+	today := e.clock.Now()
+	logger.Info("selected daily challenge", "date", today.Format(dateLayout), "version", dailyGeneratorVersion)
+	return e.newTest(dailyChallengeText(today), dailyFilePrefix+today.Format(dateLayout))
+}
+
+// dailyResult is the best-scoring completed run of one day's challenge
+type dailyResult struct {
+	Score          float64       `json:"score"`
+	WPM            float64       `json:"wpm"`
+	Accuracy       float64       `json:"accuracy"`
+	Errors         int           `json:"errors"`
+	Duration       time.Duration `json:"duration"`
+	Attempts       int           `json:"attempts"`
+	FirstCompleted time.Time     `json:"first_completed"`
+}
+
+// Streak freezes are earned one per freezeEvery challenges completed, and
+// at most maxFreezes can be held
+const (
+	freezeEvery = 5
+	maxFreezes  = 2
+)
+
+// maxVacation is the longest vacation that can be booked, in days
+const maxVacation = 90
+
+// vacation is a range of days, inclusive, that don't break a streak
+type vacation struct {
+	From string `json:"from"` // YYYY-MM-DD
+	To   string `json:"to"`
+}
+
+// dailyHistory is kept in its own file, separate from regular results, so
+// challenge stats aren't mixed with free practice
+type dailyHistory struct {
+	Days map[string]dailyResult `json:"days"` // keyed by YYYY-MM-DD
+
+	// Freezes are unspent streak freezes; Frozen the missed days they've
+	// covered. Frozen and vacation days neither break nor extend a streak.
+	Freezes   int             `json:"freezes,omitempty"`
+	Frozen    map[string]bool `json:"frozen,omitempty"`
+	Vacations []vacation      `json:"vacations,omitempty"`
+
+	path string
+}
+
+func loadDailyHistory() (*dailyHistory, error) {
+	path, err := dataFile("daily.json")
+	if err != nil {
+		return nil, err
+	}
+	history := &dailyHistory{Days: make(map[string]dailyResult), path: path}
+	if err := readJSONFile(path, history); err != nil {
+		return nil, err
+	}
+	if history.Days == nil {
+		history.Days = make(map[string]dailyResult)
+	}
+	return history, nil
+}
+
+// record notes a completed challenge run, keeping the day's best score
+func (h *dailyHistory) record(date string, ev Event) {
+	result, seen := h.Days[date]
+	result.Attempts++
+	if !seen {
+		result.FirstCompleted = ev.Time
+		if (len(h.Days)+1)%freezeEvery == 0 && h.Freezes < maxFreezes {
+			h.Freezes++
+		}
+	}
+	if !seen || ev.Score > result.Score {
+		result.Score = ev.Score
+		result.WPM = ev.WPM
+		result.Accuracy = ev.Accuracy
+		result.Errors = ev.Errors
+		result.Duration = ev.Duration
+	}
+	h.Days[date] = result
+}
+
+func (h *dailyHistory) save() error {
+	return saveDataFile(h.path, h)
+}
+
+// streak counts consecutive completed days up to today. An unfinished
+// today doesn't break a streak that ran through yesterday, and nor do
+// frozen or vacation days.
+func (h *dailyHistory) streak(today time.Time) int {
+	day := today
+	if _, done := h.Days[day.Format(dateLayout)]; !done {
+		day = day.AddDate(0, 0, -1)
+	}
+	count := 0
+	for {
+		date := day.Format(dateLayout)
+		if _, done := h.Days[date]; done {
+			count++
+		} else if !h.excused(date) {
+			return count
+		}
+		day = day.AddDate(0, 0, -1)
+	}
+}
+
+// excused reports whether a missed day is covered by a freeze or vacation
+func (h *dailyHistory) excused(date string) bool {
+	return h.Frozen[date] || h.vacationOn(date) != nil
+}
+
+// vacationOn returns the vacation covering date, if there is one
+func (h *dailyHistory) vacationOn(date string) *vacation {
+	for i, v := range h.Vacations {
+		if v.From <= date && date <= v.To {
+			return &h.Vacations[i]
+		}
+	}
+	return nil
+}
+
+// nextVacation returns the current or next upcoming vacation, if any
+func (h *dailyHistory) nextVacation(today time.Time) *vacation {
+	date := today.Format(dateLayout)
+	var next *vacation
+	for i, v := range h.Vacations {
+		if v.To >= date && (next == nil || v.From < next.From) {
+			next = &h.Vacations[i]
+		}
+	}
+	return next
+}
+
+// applyFreezes spends freezes on the days missed since the last completed
+// one, so the streak survives them, and returns how many were spent. A
+// gap longer than the freezes held is left alone: the streak is lost
+// either way, and the freezes are better kept for the next one.
+func (h *dailyHistory) applyFreezes(today time.Time) int {
+	if len(h.Days) == 0 {
+		return 0
+	}
+	var missed []string
+	for day := today.AddDate(0, 0, -1); ; day = day.AddDate(0, 0, -1) {
+		date := day.Format(dateLayout)
+		if _, done := h.Days[date]; done {
+			break
+		}
+		if !h.excused(date) {
+			missed = append(missed, date)
+		}
+		if len(missed) > h.Freezes {
+			return 0
+		}
+	}
+	if h.Frozen == nil && len(missed) > 0 {
+		h.Frozen = make(map[string]bool)
+	}
+	for _, date := range missed {
+		h.Frozen[date] = true
+	}
+	h.Freezes -= len(missed)
+	return len(missed)
+}
+
+// bookVacation adds a vacation from one date to another, inclusive
+func (h *dailyHistory) bookVacation(from, to string) error {
+	start, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return fmt.Errorf("bad start date %q (want YYYY-MM-DD)", from)
+	}
+	end, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return fmt.Errorf("bad end date %q (want YYYY-MM-DD)", to)
+	}
+	if end.Before(start) {
+		return errors.New("vacation ends before it starts")
+	}
+	if days := int(end.Sub(start).Hours()/24) + 1; days > maxVacation {
+		return fmt.Errorf("vacation is %d days; the most is %d", days, maxVacation)
+	}
+	h.Vacations = append(h.Vacations, vacation{From: from, To: to})
+	return nil
+}
+
+// runVacation lists, books or clears vacations for the vacation
+// subcommand. It returns the process exit code.
+func runVacation(w io.Writer, args []string) int {
+	history, err := loadDailyHistory()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	switch {
+	case len(args) == 0:
+		if len(history.Vacations) == 0 {
+			fmt.Fprintln(w, "No vacations booked")
+		}
+		for _, v := range history.Vacations {
+			fmt.Fprintf(w, "%s to %s\n", v.From, v.To)
+		}
+		return 0
+	case len(args) == 1 && args[0] == "clear":
+		history.Vacations = nil
+	case len(args) == 2:
+		if err := history.bookVacation(args[0], args[1]); err != nil {
+			fmt.Fprintf(w, "Error: %v\n", err)
+			return 2
+		}
+		fmt.Fprintf(w, "Booked %s to %s; missed challenges then won't break your streak\n", args[0], args[1])
+	default:
+		fmt.Fprintln(w, "Usage: keysmash vacation [FROM TO | clear]")
+		return 2
+	}
+	if err := history.save(); err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// recordDailyResults saves every completed daily challenge to history
+func recordDailyResults(bus *EventBus, history *dailyHistory) {
+	bus.Subscribe(func(ev Event) {
+		date, ok := strings.CutPrefix(ev.TestFile, dailyFilePrefix)
+		if !ok {
+			return
+		}
+		history.record(date, ev)
+		if err := history.save(); err != nil {
+			logger.Error("saving daily history failed", "err", err)
+		}
+	}, EventTestCompleted)
+}
+
+// calendarWidth is the width of the grid drawn by drawCalendar
+const calendarWidth = 20
+
+// drawCalendar draws the month containing today as a Monday-first grid,
+// highlighting completed days. It takes eight rows.
+func drawCalendar(screen tcell.Screen, x, y int, today time.Time, history *dailyHistory) {
+	first := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+	title := first.Format("January 2006")
+	drawText(screen, x+(calendarWidth-len(title))/2, y, tcell.StyleDefault, title)
+	drawText(screen, x, y+1, tcell.StyleDefault, "Mo Tu We Th Fr Sa Su")
+
+	// time.Weekday starts on Sunday; shift so Monday is column 0
+	column := (int(first.Weekday()) + 6) % 7
+	row := 0
+	for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+		style := tcell.StyleDefault
+		if _, done := history.Days[day.Format(dateLayout)]; done {
+			style = style.Reverse(true)
+		} else if history.excused(day.Format(dateLayout)) {
+			style = style.Dim(true)
+		}
+		if day.Day() == today.Day() {
+			style = style.Underline(true)
+		}
+		drawText(screen, x+column*3, y+2+row, style, fmt.Sprintf("%2d", day.Day()))
+
+		column++
+		if column == 7 {
+			column = 0
+			row++
+		}
+	}
+}
+
+// showDailyWelcomeScreen replaces the welcome screen in daily mode with
+// today's status and the month's calendar
+func showDailyWelcomeScreen(screen tcell.Screen, today time.Time, history *dailyHistory) {
+	screen.Clear()
+	width, height := screen.Size()
+
+	drawCenteredText(screen, width/2, height/2-9, tcell.StyleDefault, "KEYSMASH")
+	drawCenteredText(screen, width/2, height/2-7, tcell.StyleDefault, "DAILY CHALLENGE "+today.Format(dateLayout))
+
+	status := "Not completed yet today"
+	if result, done := history.Days[today.Format(dateLayout)]; done {
+		status = fmt.Sprintf("Completed: %.1f WPM, %.1f%% accuracy (%d attempts)", result.WPM, result.Accuracy, result.Attempts)
+	}
+	drawCenteredText(screen, width/2, height/2-5, tcell.StyleDefault, status)
+
+	drawCalendar(screen, width/2-calendarWidth/2, height/2-3, today, history)
+
+	streak := history.streak(today)
+	drawCenteredText(screen, width/2, height/2+6, tcell.StyleDefault, fmt.Sprintf("Streak: %d day(s)  Freezes: %d of %d", streak, history.Freezes, maxFreezes))
+	rules := fmt.Sprintf("Every %d challenges earns a freeze, which covers a missed day", freezeEvery)
+	if v := history.nextVacation(today); v != nil {
+		rules = fmt.Sprintf("Vacation %s to %s won't break your streak", v.From, v.To)
+	}
+	drawCenteredText(screen, width/2, height/2+7, tcell.StyleDefault, rules)
+	drawCenteredText(screen, width/2, height/2+8, tcell.StyleDefault, "Press any key to start, Ctrl+P for commands, ESC to quit")
+
+	screen.Show()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDailyChallengeIsStable(t *testing.T) {
+	date := time.Date(2026, 10, 16, 23, 59, 0, 0, time.UTC)
+
+	// Pinned output: if this fails the generator changed, and
+	// dailyGeneratorVersion must be bumped along with this expectation
+	text := dailyChallengeText(date)
+	if want := "any should order mean would change"; !strings.HasPrefix(text, want) {
+		t.Errorf("challenge for %s starts %q, want %q", date.Format(dateLayout), text[:len(want)], want)
+	}
+	if words := strings.Fields(text); len(words) != dailyWordCount {
+		t.Errorf("challenge has %d words, want %d", len(words), dailyWordCount)
+	}
+
+	// Time of day doesn't matter, the date does
+	if dailyChallengeText(date.Add(-23*time.Hour)) != text {
+		t.Error("challenge changed within the same day")
+	}
+	if dailyChallengeText(date.AddDate(0, 0, 1)) == text {
+		t.Error("consecutive days produced the same challenge")
+	}
+}
+
+func TestDailyHistoryRecordAndStreak(t *testing.T) {
+	history := &dailyHistory{Days: make(map[string]dailyResult)}
+	history.record("2026-10-14", Event{WPM: 50, Score: 50})
+	history.record("2026-10-15", Event{WPM: 60, Score: 60})
+	history.record("2026-10-15", Event{WPM: 55, Score: 55})
+	history.record("2026-10-12", Event{WPM: 40, Score: 40})
+
+	if got := history.Days["2026-10-15"]; got.WPM != 60 || got.Attempts != 2 {
+		t.Errorf("2026-10-15 = %+v, want best 60 WPM over 2 attempts", got)
+	}
+
+	// Today (the 16th) isn't done yet, so the streak runs through yesterday
+	today := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	if got := history.streak(today); got != 2 {
+		t.Errorf("streak = %d, want 2", got)
+	}
+	if got := history.streak(today.AddDate(0, 0, 1)); got != 0 {
+		t.Errorf("streak after a missed day = %d, want 0", got)
+	}
+}
+
+func TestStreakFreezes(t *testing.T) {
+	history := &dailyHistory{Days: make(map[string]dailyResult)}
+	day := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < freezeEvery*3; i++ {
+		history.record(day.AddDate(0, 0, i).Format(dateLayout), Event{WPM: 50})
+	}
+	if history.Freezes != maxFreezes {
+		t.Fatalf("freezes = %d after %d challenges, want the maximum %d", history.Freezes, freezeEvery*3, maxFreezes)
+	}
+
+	// Two missed days are covered; today is still open so isn't a miss
+	last := day.AddDate(0, 0, freezeEvery*3-1)
+	today := last.AddDate(0, 0, 3)
+	if spent := history.applyFreezes(today); spent != 2 {
+		t.Errorf("spent %d freezes, want 2", spent)
+	}
+	if got := history.streak(today); got != freezeEvery*3 {
+		t.Errorf("streak = %d, want %d", got, freezeEvery*3)
+	}
+	if spent := history.applyFreezes(today); spent != 0 {
+		t.Errorf("spent %d more freezes on the same gap", spent)
+	}
+
+	// A gap longer than the freezes held keeps them
+	history.Freezes = 1
+	if spent := history.applyFreezes(today.AddDate(0, 0, 2)); spent != 0 || history.Freezes != 1 {
+		t.Errorf("spent %d on a gap too long to save, %d left", spent, history.Freezes)
+	}
+}
+
+func TestVacation(t *testing.T) {
+	history := &dailyHistory{Days: make(map[string]dailyResult)}
+	history.record("2026-10-01", Event{})
+	history.record("2026-10-02", Event{})
+	if err := history.bookVacation("2026-10-03", "2026-10-09"); err != nil {
+		t.Fatal(err)
+	}
+	history.record("2026-10-10", Event{})
+
+	today := time.Date(2026, 10, 11, 12, 0, 0, 0, time.UTC)
+	if got := history.streak(today); got != 3 {
+		t.Errorf("streak across a vacation = %d, want 3", got)
+	}
+	if v := history.nextVacation(time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)); v == nil || v.From != "2026-10-03" {
+		t.Errorf("upcoming vacation = %v", v)
+	}
+	if v := history.nextVacation(today); v != nil {
+		t.Errorf("vacation %v still shown after it ended", v)
+	}
+
+	for _, dates := range [][2]string{{"2026-10-09", "2026-10-03"}, {"2026-01-01", "2026-12-31"}, {"soon", "2026-10-03"}} {
+		if err := history.bookVacation(dates[0], dates[1]); err == nil {
+			t.Errorf("booked %s to %s", dates[0], dates[1])
+		}
+	}
+}
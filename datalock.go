@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Two keysmash sessions on one data directory would each save the data
+// they loaded at startup over the other's, losing results. The first to
+// start takes an exclusive lock on dataLockFile for as long as it runs;
+// one started while it's held plays as usual but is read-only, keeping
+// this session's results in memory and saying so on the welcome screen.
+// Subcommands that change the data refuse to run while it's held.
+
+// dataLockFile is the lock file in the data directory. It holds the
+// process ID of the session that has it, for telling the player which.
+const dataLockFile = "keysmash.lock"
+
+// readOnly is set when another keysmash holds the data lock. Writes to the
+// data stores are skipped while it's set; see saveDataFile.
+var readOnly bool
+
+// dataLock is the lock on the data directory, or the failure to get it
+type dataLock struct {
+	file   *os.File // open while the lock is held, nil otherwise
+	holder int      // if another keysmash holds it, its process ID (0 if unknown)
+}
+
+// lockData tries to take the data lock without waiting. If another
+// keysmash has it, the lock returned isn't held and says who does.
+func lockData() (*dataLock, error) {
+	path, err := dataFile(dataLockFile)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := tryLockFile(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("locking %s: %w", path, err)
+	}
+	if !locked {
+		data, _ := os.ReadFile(path)
+		f.Close()
+		holder, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+		return &dataLock{holder: holder}, nil
+	}
+	if err := f.Truncate(0); err != nil {
+		logger.Warn("writing the data lock failed", "err", err)
+	} else if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		logger.Warn("writing the data lock failed", "err", err)
+	}
+	return &dataLock{file: f}, nil
+}
+
+// held reports whether this keysmash has the lock
+func (l *dataLock) held() bool {
+	return l.file != nil
+}
+
+// release gives the lock up, if it's held
+func (l *dataLock) release() {
+	if l == nil || l.file == nil {
+		return
+	}
+	// Closing the file drops the lock; the stale PID is harmless
+	l.file.Close()
+	l.file = nil
+}
+
+// busyText describes who holds the lock, for when it isn't held
+func (l *dataLock) busyText() string {
+	if l.holder == 0 {
+		return "keysmash is already running"
+	}
+	return fmt.Sprintf("keysmash is already running (process %d)", l.holder)
+}
+
+// requireDataLock takes the data lock for a subcommand that changes the
+// data, exiting if another keysmash has it. The lock must be kept
+// reachable until the command is done, or closing the file with it
+// could give it up early; exiting releases it. It's nil if it couldn't
+// be taken for any other reason.
+func requireDataLock() *dataLock {
+	lock, err := lockData()
+	if err != nil {
+		// A file system without locks shouldn't stop the command
+		logger.Warn("locking the data directory failed", "err", err)
+		return nil
+	}
+	if !lock.held() {
+		fmt.Fprintf(os.Stderr, "Error: %s; quit it first, as this would change its data\n", lock.busyText())
+		os.Exit(1)
+	}
+	return lock
+}
+
+// drawReadOnlyNotice says at the top of the welcome screen that this
+// session's results won't be kept, or that they're kept by the daemon
+func drawReadOnlyNotice(screen tcell.Screen, lock *dataLock, daemon *daemonClient) {
+	if lock == nil || lock.held() {
+		return
+	}
+	width, _ := screen.Size()
+	if daemon != nil {
+		drawCenteredText(screen, width/2, 1, tcell.StyleDefault, "Connected to keysmash daemon: results and keystrokes are saved through it")
+	} else {
+		drawCenteredText(screen, width/2, 1, styleMissed, "READ-ONLY: "+lock.busyText()+"; nothing from this session will be saved")
+	}
+	screen.Show()
+}
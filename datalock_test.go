@@ -0,0 +1,82 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDataLock(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	first, err := lockData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.held() {
+		t.Fatal("the first session didn't get the lock")
+	}
+
+	second, err := lockData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.held() || second.holder != os.Getpid() {
+		t.Fatalf("second session: held %v, holder %d", second.held(), second.holder)
+	}
+
+	first.release()
+	third, err := lockData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer third.release()
+	if !third.held() {
+		t.Error("the lock wasn't free once released")
+	}
+}
+
+func TestReadOnlySkipsWrites(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	readOnly = true
+	defer func() { readOnly = false }()
+
+	store, err := loadResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.add(result{TestFile: "a.txt", Completed: time.Now(), WPM: 90})
+	if err := store.save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(store.path); !os.IsNotExist(err) {
+		t.Errorf("results were written read-only: %v", err)
+	}
+	if _, err := os.Stat(store.auditPath); !os.IsNotExist(err) {
+		t.Errorf("the audit log was written read-only: %v", err)
+	}
+	if len(store.Results) != 1 {
+		t.Error("the session's result wasn't kept in memory")
+	}
+
+	// Exports and the signing key aren't data stores, so they're written
+	path, err := exportResults(store.Results, "json", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := loadResultStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exported.Results) != 1 {
+		t.Errorf("export read-only has %d results, want 1", len(exported.Results))
+	}
+	key, err := loadSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := loadSigningKey(); err != nil || !key.Equal(again) {
+		t.Errorf("signing key not kept read-only (%v)", err)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// doctorReport accumulates the output of `keysmash doctor`. Each check is
+// printed as one line tagged ok/warn/fail so reports pasted into issues are
+// easy to scan.
+type doctorReport struct {
+	w        io.Writer
+	failures int
+}
+
+func (r *doctorReport) section(title string) {
+	fmt.Fprintf(r.w, "\n%s\n", title)
+}
+
+func (r *doctorReport) line(tag, format string, args ...interface{}) {
+	fmt.Fprintf(r.w, "  [%-4s] %s\n", tag, fmt.Sprintf(format, args...))
+}
+
+func (r *doctorReport) ok(format string, args ...interface{}) {
+	r.line("ok", format, args...)
+}
+
+func (r *doctorReport) warn(format string, args ...interface{}) {
+	r.line("warn", format, args...)
+}
+
+func (r *doctorReport) fail(format string, args ...interface{}) {
+	r.failures++
+	r.line("fail", format, args...)
+}
+
+// runDoctor prints a plain-text diagnostic report about the environment
+// keysmash is running in and returns the process exit code (1 if any check
+// failed). It never touches the terminal UI, so its output can be
+// redirected into a file and attached to a bug report.
+func runDoctor(w io.Writer) int {
+	r := &doctorReport{w: w}
+
+	fmt.Fprintln(w, "KEYSMASH DOCTOR")
+	fmt.Fprintf(w, "keysmash %s, %s %s/%s\n", appVersion(), runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+	r.section("Tests")
+	checkTestsDir(r)
+
+	r.section("Config")
+	checkConfig(r)
+
+	r.section("Data store")
+	checkDataStore(r)
+
+	r.section("Last run")
+	checkLastRun(r)
+
+	r.section("Terminal")
+	checkTerminal(r)
+	checkLatency(r)
+
+	r.section("Locale")
+	checkLocale(r)
+
+	fmt.Fprintln(w)
+	if r.failures > 0 {
+		fmt.Fprintf(w, "%d check(s) failed\n", r.failures)
+		return 1
+	}
+	fmt.Fprintln(w, "No problems found")
+	return 0
+}
+
+// checkLastRun prints the environment the most recent test was played in,
+// for bug reports about a run that has already happened
+func checkLastRun(r *doctorReport) {
+	dir := dataDir()
+	if dir == "" {
+		r.warn("no data directory")
+		return
+	}
+	// Not loadResults, which would create the data directory
+	store, err := loadResultStore(filepath.Join(dir, "results.json"))
+	if err != nil {
+		r.warn("results unreadable: %v", err)
+		return
+	}
+	last, ok := store.latest()
+	if !ok {
+		r.ok("no completed tests yet")
+		return
+	}
+	env := last.Environment
+	r.ok("%s at %s: %.1f WPM, %.1f%% accuracy", last.TestFile, last.Completed.Format(time.RFC3339), last.WPM, last.Accuracy)
+	r.ok("version %s, %s/%s, TERM=%s, %dx%d", env.Version, env.OS, env.Arch, env.Term, env.TerminalWidth, env.TerminalHeight)
+	r.ok("mode %s, bots %q, handicap %t, score formula %q", env.Mode, env.Settings.Bots, env.Settings.Handicap, env.Settings.ScoreFormula)
+}
+
+func checkTestsDir(r *doctorReport) {
+	dir := findTestsDir()
+	if cfg, err := loadConfig(configPath()); err == nil && cfg.TestsDir != "" {
+		dir = cfg.TestsDir
+	}
+	if dir == "" {
+		files, _ := listTexts(builtinTexts())
+		r.warn("tests directory not found (looked in $%s, %s, next to the executable and ./tests); playing the %d built-in texts, or run keysmash init to make a directory for your own", testsDirEnv, defaultTestsDir(), len(files))
+		return
+	}
+
+	files, err := listTextFiles(dir)
+	if err != nil {
+		r.fail("tests directory %s is unreadable: %v", dir, err)
+		return
+	}
+	if len(files) == 0 {
+		r.fail("tests directory %s contains no .txt files", dir)
+		return
+	}
+	r.ok("tests directory: %s (%d texts)", dir, len(files))
+}
+
+func checkConfig(r *doctorReport) {
+	path := configPath()
+	if path == "" {
+		r.warn("config path unknown: no home directory")
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		r.ok("config file: %s (not present, using defaults)", path)
+		return
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		r.fail("%v", err)
+		return
+	}
+	r.ok("config file: %s", path)
+	r.ok("score formula: %s", cfg.ScoreFormula)
+}
+
+func checkDataStore(r *doctorReport) {
+	dir := dataDir()
+	if dir == "" {
+		r.warn("data directory unknown: no home directory")
+		return
+	}
+
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		r.ok("data directory: %s (not created yet)", dir)
+		return
+	}
+	if err != nil {
+		r.fail("data directory %s: %v", dir, err)
+		return
+	}
+	if !info.IsDir() {
+		r.fail("data directory %s is not a directory", dir)
+		return
+	}
+
+	// The only reliable writability check is to actually write something
+	probe, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		r.fail("data directory %s is not writable: %v", dir, err)
+		return
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	r.ok("data directory: %s (writable)", dir)
+}
+
+func checkLatency(r *doctorReport) {
+	dir := dataDir()
+	if dir == "" {
+		return
+	}
+	profiles := &latencyProfiles{path: filepath.Join(dir, "latency.json")}
+	if err := readJSONFile(profiles.path, profiles); err != nil {
+		r.warn("latency profiles unreadable: %v", err)
+		return
+	}
+	profile, ok := profiles.current()
+	if !ok {
+		r.ok("echo latency not calibrated for %s (run keysmash calibrate)", terminalKey())
+		return
+	}
+	r.ok("echo latency for %s: median %v, p95 %v (measured %s)", terminalKey(),
+		profile.Median.Round(time.Microsecond), profile.P95.Round(time.Microsecond), profile.Measured.Format(dateLayout))
+}
+
+func checkTerminal(r *doctorReport) {
+	term := os.Getenv("TERM")
+	if term == "" {
+		r.warn("TERM is not set")
+	} else if ti, err := tcell.LookupTerminfo(term); err != nil {
+		r.fail("TERM=%s has no terminfo entry: %v", term, err)
+	} else {
+		r.ok("TERM=%s (%d colors)", term, ti.Colors)
+	}
+
+	if colorterm := os.Getenv("COLORTERM"); colorterm != "" {
+		r.ok("COLORTERM=%s", colorterm)
+	}
+
+	// Ambiguous-width mode makes box-drawing characters and arrows two
+	// cells wide, which misaligns the scroll indicators and frames
+	if runewidth.DefaultCondition.EastAsianWidth {
+		r.warn("East Asian ambiguous-width mode is on; UI symbols may misalign")
+	} else {
+		r.ok("East Asian ambiguous-width mode is off")
+	}
+
+	var widths []string
+	for _, s := range []string{"e", "é", "—", "↑", "中", "😀"} {
+		widths = append(widths, fmt.Sprintf("%s=%d", s, runewidth.StringWidth(s)))
+	}
+	r.ok("display widths: %s", strings.Join(widths, " "))
+}
+
+func checkLocale(r *doctorReport) {
+	// LC_ALL overrides LC_CTYPE, which overrides LANG
+	name, value := "", ""
+	for _, key := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(key); v != "" {
+			name, value = key, v
+			break
+		}
+	}
+
+	if value == "" {
+		r.warn("no locale set (LC_ALL, LC_CTYPE and LANG are empty); non-ASCII texts may not render")
+		return
+	}
+
+	normalized := strings.ToLower(strings.ReplaceAll(value, "-", ""))
+	if !strings.Contains(normalized, "utf8") {
+		r.warn("%s=%s is not a UTF-8 locale; non-ASCII texts may not render or type correctly", name, value)
+		return
+	}
+	r.ok("%s=%s", name, value)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckLocale(t *testing.T) {
+	for _, tt := range []struct {
+		name                 string
+		lcAll, lcCtype, lang string
+		want                 string
+	}{
+		{"UTF-8", "", "", "en_US.UTF-8", "[ok  ] LANG=en_US.UTF-8"},
+		{"utf8 spelling", "", "", "de_DE.utf8", "[ok  ] LANG=de_DE.utf8"},
+		{"not UTF-8", "", "", "C", "[warn] LANG=C is not a UTF-8 locale"},
+		{"LC_ALL overrides LANG", "POSIX", "", "en_US.UTF-8", "[warn] LC_ALL=POSIX is not a UTF-8 locale"},
+		{"LC_CTYPE overrides LANG", "", "en_GB.UTF-8", "C", "[ok  ] LC_CTYPE=en_GB.UTF-8"},
+		{"none", "", "", "", "[warn] no locale set"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LC_ALL", tt.lcAll)
+			t.Setenv("LC_CTYPE", tt.lcCtype)
+			t.Setenv("LANG", tt.lang)
+			var out strings.Builder
+			r := &doctorReport{w: &out}
+			checkLocale(r)
+			if !strings.Contains(out.String(), tt.want) || r.failures != 0 {
+				t.Errorf("report %q with %d failures, want %q and none", out.String(), r.failures, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTerminal(t *testing.T) {
+	for _, tt := range []struct {
+		term     string
+		want     string
+		failures int
+	}{
+		{"xterm", "[ok  ] TERM=xterm", 0},
+		{"", "[warn] TERM is not set", 0},
+		{"no-such-terminal", "[fail] TERM=no-such-terminal has no terminfo entry", 1},
+	} {
+		t.Setenv("TERM", tt.term)
+		var out strings.Builder
+		r := &doctorReport{w: &out}
+		checkTerminal(r)
+		if !strings.Contains(out.String(), tt.want) || r.failures != tt.failures {
+			t.Errorf("TERM=%q: report %q with %d failures, want %q and %d", tt.term, out.String(), r.failures, tt.want, tt.failures)
+		}
+	}
+}
+
+func TestCheckDataStore(t *testing.T) {
+	check := func() (string, int) {
+		var out strings.Builder
+		r := &doctorReport{w: &out}
+		checkDataStore(r)
+		return out.String(), r.failures
+	}
+	home := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", home)
+	dir := filepath.Join(home, "keysmash")
+
+	if out, failures := check(); !strings.Contains(out, "not created yet") || failures != 0 {
+		t.Errorf("missing directory: report %q with %d failures", out, failures)
+	}
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if out, failures := check(); !strings.Contains(out, "(writable)") || failures != 0 {
+		t.Errorf("writable directory: report %q with %d failures", out, failures)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("the writability probe was left behind: %v", entries)
+	}
+
+	if err := os.Chmod(dir, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0o755) })
+	if probe, err := os.CreateTemp(dir, "probe"); err == nil {
+		probe.Close()
+		os.Remove(probe.Name())
+		t.Skip("permissions aren't enforced for this user (running as root?)")
+	}
+	if out, failures := check(); !strings.Contains(out, "is not writable") || failures != 1 {
+		t.Errorf("read-only directory: report %q with %d failures, want it failed", out, failures)
+	}
+}
+
+func TestRunDoctorExitCode(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	home := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", home)
+	t.Setenv("TERM", "xterm")
+	t.Setenv("LANG", "en_US.UTF-8")
+
+	var out strings.Builder
+	if code := runDoctor(&out); code != 0 {
+		t.Fatalf("exit %d with every check passing:\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "No problems found") {
+		t.Errorf("report doesn't say it's clean:\n%s", out.String())
+	}
+
+	// A data directory that's a file fails the data store check
+	if err := os.WriteFile(filepath.Join(home, "keysmash"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if code := runDoctor(&out); code != 1 {
+		t.Errorf("exit %d with a failed check, want 1:\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "is not a directory") || !strings.Contains(out.String(), "1 check(s) failed") {
+		t.Errorf("report doesn't name the failure:\n%s", out.String())
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Typing economy is what a run cost in keystrokes against what it needed.
+// Accuracy counts mistakes, but not what fixing them took: a slip caught
+// five characters late costs those five again, plus five backspaces.
+
+// typingEconomy counts a run's keystrokes
+type typingEconomy struct {
+	Keystrokes int // every key pressed, backspaces included
+	Backspaces int
+	Retyped    int // characters typed and then erased
+	Kept       int // characters still there at the end
+}
+
+// economy counts the keystrokes of the test so far
+func (s *TestState) economy() typingEconomy {
+	e := typingEconomy{Keystrokes: len(s.keys), Kept: utf8.RuneCountInString(s.userInput)}
+	for _, k := range s.keys {
+		if k.r == backspaceKey {
+			e.Backspaces++
+		}
+	}
+	e.Retyped = e.Keystrokes - e.Backspaces - e.Kept
+	return e
+}
+
+// efficiency is the percentage of keystrokes that went into the text that
+// was kept, 100 for a run with nothing erased
+func (e typingEconomy) efficiency() float64 {
+	if e.Keystrokes == 0 {
+		return 100
+	}
+	return float64(e.Kept) / float64(e.Keystrokes) * 100
+}
+
+// economyText is the results screen's line on typing economy
+func economyText(e typingEconomy) string {
+	return fmt.Sprintf("Efficiency: %.1f%% (%d keystrokes, %d backspaces, %d retyped)", e.efficiency(), e.Keystrokes, e.Backspaces, e.Retyped)
+}
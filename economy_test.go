@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestEconomy(t *testing.T) {
+	state := newTestState("the cat", "test.txt", &fakeClock{})
+	if got := state.economy().efficiency(); got != 100 {
+		t.Errorf("efficiency before typing = %v", got)
+	}
+
+	// A slip noticed two characters late: "he" retyped, three backspaces
+	for _, r := range "tje" {
+		state.typeRune(r)
+	}
+	state.backspace()
+	state.backspace()
+	state.backspace()
+	for _, r := range "the cat" {
+		state.typeRune(r)
+	}
+	if !state.testComplete {
+		t.Fatal("test didn't complete")
+	}
+	want := typingEconomy{Keystrokes: 13, Backspaces: 3, Retyped: 3, Kept: 7}
+	if got := state.economy(); got != want {
+		t.Errorf("economy = %+v, want %+v", got, want)
+	}
+	if got := economyText(want); got != "Efficiency: 53.8% (13 keystrokes, 3 backspaces, 3 retyped)" {
+		t.Errorf("economyText = %q", got)
+	}
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// Clock is the engine's only source of time. The terminal UI uses the
+// system clock; tests and replays substitute a fake so WPM math and
+// timing-dependent behaviour are exact and reproducible.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the wall-clock Clock used in normal runs
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Rand is the subset of *rand.Rand the engine needs to pick texts.
+// Injecting a seeded source makes text selection reproducible.
+type Rand interface {
+	Intn(n int) int
+}
+
+// Test modes, selected with --mode
+const (
+	modeRandom = "random" // a random file from the tests directory
+	modeDaily  = "daily"  // the generated challenge of the day
+	modeLearn  = "learn"  // the next drill of a lesson curriculum
+	modeClass  = "class"  // the chosen student's next assigned test
+	modePack   = "pack"   // the next challenge of a challenge pack
+	modeWords  = "words"  // random common words; see wordTest
+)
+
+// Engine picks reference texts and hands out TestStates wired to its clock.
+// It knows nothing about the screen, so it can be driven by the terminal UI,
+// by tests, or by a replay.
+//
+// Concurrency: the TestState of a running test belongs to the goroutine
+// running the input loop, and only that goroutine may read or modify it.
+// Every change publishes an immutable copy that other goroutines (autosave,
+// metrics, integrations) read through Snapshot; they never see a
+// half-applied keystroke and never contend with the input loop for a lock.
+type Engine struct {
+	clock    Clock
+	rng      Rand
+	testsDir string
+	mode     string
+	events   *EventBus
+	live     atomic.Pointer[TestState]
+
+	// scorer ranks completed runs; nil ranks by WPM
+	scorer *formula
+
+	// library marks archived texts, which random selection skips unless
+	// includeArchived is set
+	library         *library
+	includeArchived bool
+
+	// favoritesOnly limits random selection to starred texts
+	favoritesOnly bool
+
+	// index is the content hash of each text, taken at startup; see
+	// textIndex
+	index textIndex
+
+	// wordByWord has space submit each word; see wordbyword.go
+	wordByWord bool
+
+	// stopOnError refuses wrong keys; see strict.go
+	stopOnError bool
+
+	// suddenDeath ends tests on their first error; see suddendeath.go
+	suddenDeath bool
+
+	// noBackspace makes every keystroke final; see typewriter.go. It's
+	// toggled from the welcome screen.
+	noBackspace bool
+
+	// blind hides what's typed until tests end; see blind.go
+	blind bool
+
+	// steno scores tests for stenotype output, where whole words arrive
+	// in one burst
+	steno bool
+
+	// course is learn mode's curriculum, nil in other modes
+	course *course
+
+	// class is class mode's roster and current student, nil in other modes
+	class *classSession
+
+	// pack is pack mode's challenge pack, nil in other modes
+	pack *packSession
+
+	// stripEmoji removes emoji from texts for players who can't type them
+	stripEmoji bool
+
+	// transliterate, if set, turns each text into the romanized keystrokes
+	// that are scored, while the original is shown to copy from
+	transliterate transliteration
+
+	// bots race against the player in every test, handicapped to the
+	// player's average speed if handicap is set
+	bots     []botProfile
+	handicap bool
+
+	// words, if set, cuts texts from files after that many words
+	words int
+
+	// wordCount is how many words a words mode test has
+	wordCount int
+
+	// targetWPM is the speed the player is aiming for; 0 for none
+	targetWPM float64
+
+	// targetAccuracy is the accuracy they want to hold; see
+	// accuracycurve.go
+	targetAccuracy float64
+
+	// timeLimit, if set, makes every test a timed one, ending this long
+	// after the first keystroke however much of the text is typed
+	timeLimit time.Duration
+
+	// recentWPM holds the speeds of the last averageWindow completed tests,
+	// oldest first, and bestWPM the fastest ever, both seeded from stored
+	// results at startup
+	recentWPM []float64
+	bestWPM   float64
+}
+
+// averageWindow is how many recent tests the player's average speed is
+// taken over
+const averageWindow = 10
+
+func newEngine(clock Clock, rng Rand, testsDir string) *Engine {
+	e := &Engine{clock: clock, rng: rng, testsDir: testsDir, mode: modeRandom, wordCount: defaultWordCount, events: newEventBus()}
+	e.events.Subscribe(func(ev Event) {
+		e.recentWPM = append(e.recentWPM, ev.WPM)
+		e.bestWPM = max(e.bestWPM, ev.WPM)
+		if len(e.recentWPM) > averageWindow {
+			e.recentWPM = e.recentWPM[len(e.recentWPM)-averageWindow:]
+		}
+	}, EventTestCompleted)
+	return e
+}
+
+// averageWPM is the player's mean WPM over their recent tests, or
+// defaultAverageWPM before the first one
+func (e *Engine) averageWPM() float64 {
+	if len(e.recentWPM) == 0 {
+		return defaultAverageWPM
+	}
+	total := 0.0
+	for _, wpm := range e.recentWPM {
+		total += wpm
+	}
+	return total / float64(len(e.recentWPM))
+}
+
+// unitState is how the last typed grapheme cluster scored
+type unitState int
+
+const (
+	unitCorrect unitState = iota
+	unitWrong
+	unitPending // a prefix of the reference's cluster, scored once finished
+)
+
+type TestState struct {
+	referenceText string
+	reference     []string // referenceText split into grapheme clusters, the unit of scoring
+	refStarts     []int    // byte offset in referenceText of each cluster in reference
+	userInput     string
+	unitStarts    []int // byte offset in userInput of each typed cluster
+	lastUnit      unitState
+	keys          []keystroke // every keystroke, for recordings
+	errors        int
+	mistakes      []mistake  // each error, in the order made; see reviewMistakes
+	slipErrors    int        // errors counted by alignment so far; see slipCost
+	slipCredit    int        // errors already counted in the wrong stretch left by backspacing
+	uncorrected   int        // of errors, those left in the input when the test ended
+	wordByWord    bool       // space submits each word; see wordbyword.go
+	words         []wordSpan // the reference's words, word by word
+	skipped       []int      // clusters skipped by submitting their word early, in order
+	wordErrors    int        // errors from skipped clusters and refused letters
+	stopOnError   bool       // wrong keys are refused; see strict.go
+	refusedErrors int        // keys refused for stopOnError
+	suddenDeath   bool       // the first error ends the test; see suddendeath.go
+	failed        bool       // a sudden death test ended by an error
+	failedAt      int        // the cluster it was made at
+	noBackspace   bool       // typewriter mode; see typewriter.go
+	blind         bool       // what's typed is hidden until the end; see blind.go
+	peeking       bool       // a blind test shown as it would be otherwise
+	peeks         int        // times peeking was turned on
+	startTime     time.Time
+	endTime       time.Time
+	testStarted   bool
+	testComplete  bool
+	testFile      string
+	mode          string  // the engine mode the test was chosen in
+	display       string  // shown in place of referenceText when typing a transliteration
+	attribution   string  // credit line for the text, from the library
+	averageWPM    float64 // the player's average when the test began, for the time estimate
+	bestWPM       float64 // and their best, for the speed gauge
+	targetWPM     float64 // and the speed they're aiming for, if any
+	clock         Clock
+	events        *EventBus
+	live          *atomic.Pointer[TestState]
+	opponents     []opponent
+	scorer        *formula
+	steno         bool          // score for a stenotype; see wpm and stenoBurstGap
+	timeLimit     time.Duration // if set, the test ends this long after it starts; see expire
+	correctionOf  time.Time     // for a correction drill, when the run it corrects ended
+	flow          flowTracker
+
+	// Running statistics of the gaps between keystrokes, updated with
+	// Welford's method so consistency needs no per-keystroke storage
+	lastKeyTime   time.Time
+	intervalCount int
+	intervalMean  float64
+	intervalM2    float64
+}
+
+// newTestState returns a fresh, unstarted test for referenceText
+func newTestState(referenceText, testFile string, clock Clock) TestState {
+	reference := splitGraphemes(referenceText)
+	refStarts := make([]int, len(reference))
+	for i := 1; i < len(reference); i++ {
+		refStarts[i] = refStarts[i-1] + len(reference[i-1])
+	}
+	return TestState{
+		referenceText: referenceText,
+		reference:     reference,
+		refStarts:     refStarts,
+		testFile:      testFile,
+		clock:         clock,
+	}
+}
+
+// Snapshot returns a copy of the current test as of its last change. It is
+// safe to call from any goroutine. ok is false until a test is selected.
+func (e *Engine) Snapshot() (state TestState, ok bool) {
+	p := e.live.Load()
+	if p == nil {
+		return TestState{}, false
+	}
+	return *p, true
+}
+
+// publishSnapshot makes the current state visible to Engine.Snapshot. The
+// copy is detached from the bus so readers can't publish events.
+func (s *TestState) publishSnapshot() {
+	if s.live == nil {
+		return
+	}
+	snapshot := *s
+	snapshot.events = nil
+	snapshot.live = nil
+	s.live.Store(&snapshot)
+}
+
+// shownText is the text the player copies from: the reference itself, or
+// the original script when the reference is its transliteration
+func (s *TestState) shownText() string {
+	if s.display != "" {
+		return s.display
+	}
+	return s.referenceText
+}
+
+// elapsed returns how long the test has been running, or its final
+// duration once complete
+func (s *TestState) elapsed() time.Duration {
+	if !s.testStarted {
+		return 0
+	}
+	if s.testComplete {
+		return s.endTime.Sub(s.startTime)
+	}
+	return s.clock.Now().Sub(s.startTime)
+}
+
+// listTextFiles returns the .txt files directly inside dir
+func listTextFiles(dir string) ([]os.DirEntry, error) {
+	return listTexts(os.DirFS(dir))
+}
+
+// listTexts returns the .txt files at the top of texts
+func listTexts(texts fs.FS) ([]fs.DirEntry, error) {
+	files, err := fs.ReadDir(texts, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var textFiles []fs.DirEntry
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(strings.ToLower(file.Name()), ".txt") {
+			textFiles = append(textFiles, file)
+		}
+	}
+	return textFiles, nil
+}
+
+func (e *Engine) selectRandomTest() (TestState, error) {
+	// Read test files from the identified tests directory
+	textFiles, err := listTexts(e.texts())
+	if err != nil {
+		return TestState{}, err
+	}
+
+	if len(textFiles) == 0 {
+		return TestState{}, &emptyLibraryError{reason: fmt.Sprintf("no .txt files found in %s directory", e.testsDir)}
+	}
+
+	if !e.includeArchived {
+		active := textFiles[:0]
+		for _, file := range textFiles {
+			if !e.library.isArchived(file.Name()) {
+				active = append(active, file)
+			}
+		}
+		if len(active) == 0 {
+			return TestState{}, &emptyLibraryError{reason: fmt.Sprintf("every text in %s is archived (run with --include-archived, or unarchive some)", e.textsName()), archived: true}
+		}
+		textFiles = active
+	}
+	if e.favoritesOnly {
+		starred := textFiles[:0]
+		for _, file := range textFiles {
+			if e.library.isStarred(file.Name()) {
+				starred = append(starred, file)
+			}
+		}
+		if len(starred) == 0 {
+			return TestState{}, &emptyLibraryError{reason: "no starred texts to choose from (star some with F on the results screen or keysmash star)"}
+		}
+		textFiles = starred
+	}
+
+	// Select random file
+	randomFile := textFiles[e.rng.Intn(len(textFiles))]
+	logger.Info("selected test", "file", randomFile.Name(), "candidates", len(textFiles))
+	return e.loadTest(randomFile.Name())
+}
+
+// texts are the texts to play: the tests directory's, or the built-in
+// ones if there isn't one
+func (e *Engine) texts() fs.FS {
+	if e.testsDir == "" {
+		return builtinTexts()
+	}
+	return os.DirFS(e.testsDir)
+}
+
+// textsName says where texts comes from, for messages
+func (e *Engine) textsName() string {
+	if e.testsDir == "" {
+		return "the built-in texts"
+	}
+	return e.testsDir
+}
+
+// loadTest reads the named file from the tests directory as a test,
+// stripping emoji and transliterating it as configured
+func (e *Engine) loadTest(name string) (TestState, error) {
+	reference, display, err := e.readText(name)
+	if err != nil {
+		return TestState{}, err
+	}
+	state := e.newTest(reference, name)
+	if display != "" {
+		state.display = display
+		state.publishSnapshot()
+	}
+	return state, nil
+}
+
+// readText reads the named file from the tests directory and prepares it
+// as loadTest does, returning the text to type and, if it's
+// transliterated, the text to show
+func (e *Engine) readText(name string) (reference, display string, err error) {
+	content, err := fs.ReadFile(e.texts(), name)
+	if err != nil {
+		return "", "", err
+	}
+	logger.Debug("loaded test", "file", name, "bytes", len(content))
+
+	text, err := e.prepareText(firstWords(strings.TrimSpace(string(content)), e.words), name)
+	if err != nil {
+		return "", "", err
+	}
+	if e.transliterate != nil {
+		romanized, err := e.transliterate(text)
+		if err != nil {
+			return "", "", fmt.Errorf("%s: %w", name, err)
+		}
+		return romanized, text, nil
+	}
+	return text, "", nil
+}
+
+// prepareText strips emoji from a text the player supplied, if
+// configured, failing if that leaves nothing to type. newTest strips
+// them too; this is for texts that may be nothing but emoji, and for
+// stripping before a text is transliterated.
+func (e *Engine) prepareText(text, name string) (string, error) {
+	if !e.stripEmoji {
+		return text, nil
+	}
+	text = stripEmoji(text)
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("%s: nothing left to type once emoji are stripped (strip_emoji)", name)
+	}
+	return text, nil
+}
+
+// firstWords cuts text after its nth word, keeping the spacing between
+// them. Text no longer than that, or any text for n <= 0, comes back whole.
+func firstWords(text string, n int) string {
+	if n <= 0 {
+		return text
+	}
+	inWord := false
+	for i, r := range text {
+		space := unicode.IsSpace(r)
+		if inWord && space {
+			if n--; n == 0 {
+				return text[:i]
+			}
+		}
+		inWord = !space
+	}
+	return text
+}
+
+// nextTest picks the reference text for the next test according to the
+// engine's mode
+func (e *Engine) nextTest() (TestState, error) {
+	switch e.mode {
+	case modeDaily:
+		return e.dailyChallenge(), nil
+	case modeLearn:
+		return e.lessonTest()
+	case modeClass:
+		return e.classTest()
+	case modePack:
+		return e.packTest()
+	case modeWords:
+		return e.wordTest(), nil
+	default:
+		return e.selectRandomTest()
+	}
+}
+
+// newTest wraps referenceText in a TestState wired to the engine's clock,
+// event bus, snapshot, opponents and library metadata
+func (e *Engine) newTest(referenceText, testFile string) TestState {
+	if e.stripEmoji {
+		referenceText = stripEmoji(referenceText)
+	}
+	state := newTestState(referenceText, testFile, e.clock)
+	state.events = e.events
+	state.live = &e.live
+	state.averageWPM = e.averageWPM()
+	state.bestWPM = e.bestWPM
+	state.targetWPM = e.targetWPM
+	state.opponents = newOpponents(e.bots, state.averageWPM, e.rng)
+	state.scorer = e.scorer
+	state.steno = e.steno
+	if e.wordByWord {
+		state.wordByWord, state.words = true, splitWords(state.reference)
+	}
+	state.stopOnError = e.stopOnError
+	state.suddenDeath = e.suddenDeath
+	state.noBackspace = e.noBackspace
+	state.blind = e.blind
+	state.mode = e.mode
+	state.timeLimit = e.timeLimit
+	state.attribution = e.library.entry(testFile).attribution()
+	if e.handicap {
+		applyHandicap(state.opponents, state.averageWPM, len(state.reference))
+	}
+	state.publishSnapshot()
+	return state
+}
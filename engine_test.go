@@ -0,0 +1,458 @@
+package main
+
+import (
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// fakeClock is a Clock that only moves when told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// fixedRand always picks the same index
+type fixedRand int
+
+func (r fixedRand) Intn(n int) int { return int(r) % n }
+
+func TestTypeRuneTiming(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	state := newTestState("hello world", "test.txt", clock)
+
+	// Time before the first keystroke doesn't count
+	clock.advance(time.Hour)
+	for _, r := range "hello world" {
+		if state.typeRune(r) {
+			break
+		}
+		clock.advance(time.Second)
+	}
+
+	if !state.testComplete {
+		t.Fatal("test not complete after typing the full text")
+	}
+	if got, want := state.elapsed(), 10*time.Second; got != want {
+		t.Errorf("elapsed = %v, want %v", got, want)
+	}
+
+	// 11 chars = 2 words in 10s
+	if got, want := calculateWPM(len(state.referenceText), state.elapsed()), 12.0; math.Abs(got-want) > 1e-9 {
+		t.Errorf("WPM = %v, want %v", got, want)
+	}
+}
+
+func TestTypeRuneErrors(t *testing.T) {
+	state := newTestState("abc", "test.txt", &fakeClock{})
+
+	state.typeRune('a')
+	state.typeRune('x')
+	state.backspace()
+	state.typeRune('b')
+	if state.errors != 1 {
+		t.Errorf("errors = %d, want 1 (backspace keeps the error)", state.errors)
+	}
+
+	if !state.typeRune('c') {
+		t.Error("typing the last character did not complete the test")
+	}
+	if state.uncorrected != 0 {
+		t.Errorf("uncorrected = %d, want 0 once fixed", state.uncorrected)
+	}
+	if got := calculateAccuracy(state.errors, len(state.userInput)); math.Abs(got-100.0*2/3) > 1e-9 {
+		t.Errorf("accuracy = %v, want %v", got, 100.0*2/3)
+	}
+}
+
+func TestConsistency(t *testing.T) {
+	typeWithGaps := func(gaps ...time.Duration) float64 {
+		clock := &fakeClock{}
+		state := newTestState("abcde", "test.txt", clock)
+		state.typeRune('a')
+		for i, gap := range gaps {
+			clock.advance(gap)
+			state.typeRune(rune('b' + i))
+		}
+		return state.consistency()
+	}
+
+	ms := time.Millisecond
+	if got := typeWithGaps(200*ms, 200*ms, 200*ms, 200*ms); got != 100 {
+		t.Errorf("even rhythm: consistency = %v, want 100", got)
+	}
+	uneven := typeWithGaps(50*ms, 400*ms, 50*ms, 400*ms)
+	if uneven <= 0 || uneven >= 100 {
+		t.Errorf("uneven rhythm: consistency = %v, want strictly between 0 and 100", uneven)
+	}
+	if slight := typeWithGaps(180*ms, 220*ms, 180*ms, 220*ms); slight <= uneven {
+		t.Errorf("slightly uneven rhythm scored %v, not above very uneven %v", slight, uneven)
+	}
+}
+
+func TestTimedTest(t *testing.T) {
+	clock := &fakeClock{}
+	state := newTestState(strings.Repeat("ab ", 100), "test.txt", clock)
+	state.timeLimit = 30 * time.Second
+	state.events = newEventBus()
+	var completed []Event
+	state.events.Subscribe(func(ev Event) { completed = append(completed, ev) }, EventTestCompleted)
+
+	if state.expire() {
+		t.Fatal("expired before the first keystroke")
+	}
+	// 50 characters, one a mistake, in the first 25 seconds
+	for i, r := range strings.Repeat("ab ", 17)[:50] {
+		if i == 10 {
+			r = 'x'
+		}
+		state.typeRune(r)
+		clock.advance(500 * time.Millisecond)
+	}
+	if state.expire() {
+		t.Fatal("expired with time left")
+	}
+	if got := state.timeLeft(); got != 5*time.Second {
+		t.Errorf("timeLeft = %v, want 5s", got)
+	}
+
+	// The ticker is late; the test still ends on the dot
+	clock.advance(10 * time.Second)
+	if !state.expire() {
+		t.Fatal("not expired after the time limit")
+	}
+	if state.expire() {
+		t.Error("expired twice")
+	}
+	if got := state.elapsed(); got != 30*time.Second {
+		t.Errorf("elapsed = %v, want the 30s limit", got)
+	}
+	if len(completed) != 1 {
+		t.Fatalf("got %d completion events, want 1", len(completed))
+	}
+	// 50 characters = 10 words in half a minute
+	if ev := completed[0]; ev.WPM != 20 || ev.Accuracy != 98 || ev.Duration != 30*time.Second || ev.Estimate != 0 {
+		t.Errorf("completed with %+v, want 20 WPM, 98%% accuracy over 30s and no estimate", ev)
+	}
+	// The mistake was left in: two words a minute off over half a minute
+	if ev := completed[0]; ev.Errors != 1 || ev.Uncorrected != 1 || ev.NetWPM != 18 {
+		t.Errorf("completed with %d errors, %d uncorrected, %v net WPM; want 1, 1 and 18", ev.Errors, ev.Uncorrected, ev.NetWPM)
+	}
+
+	untimed := newTestState("abc", "test.txt", clock)
+	untimed.typeRune('a')
+	clock.advance(time.Hour)
+	if untimed.expire() {
+		t.Error("an untimed test expired")
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	// 100 characters = 20 words, a minute at 20 WPM
+	state := newTestState(benchText(100), "test.txt", &fakeClock{})
+	state.averageWPM = 20
+	if got := state.estimate(); got != time.Minute {
+		t.Errorf("estimate at 20 WPM = %v, want 1m0s", got)
+	}
+	state.averageWPM = 0
+	if got := state.estimate(); got != 0 {
+		t.Errorf("estimate with no average = %v, want none", got)
+	}
+}
+
+func TestWPMSamples(t *testing.T) {
+	clock := &fakeClock{}
+	state := newTestState("aaaaaaaaaaaa", "test.txt", clock)
+	// Ten characters in the first two seconds, then two in the next two
+	for i := 0; i < 10; i++ {
+		state.typeRune('a')
+		clock.advance(200 * time.Millisecond)
+	}
+	state.typeRune('x')
+	clock.advance(time.Second)
+	state.backspace() // not a character typed
+	state.typeRune('a')
+	clock.advance(time.Second)
+	state.typeRune('a')
+
+	samples := state.wpmSamples(2)
+	if len(samples) != 2 || samples[0] != 60 || samples[1] != 18 {
+		t.Errorf("wpmSamples(2) = %v, want [60 18]", samples)
+	}
+	if got := len(state.wpmSamples(100)); got != 4 {
+		t.Errorf("wpmSamples(100) has %d samples, want 4 of a second each", got)
+	}
+}
+
+func TestWPMPerSecond(t *testing.T) {
+	clock := &fakeClock{}
+	state := newTestState("aaaaaaaaaaaa", "test.txt", clock)
+	// Five characters in the first second and one in the next
+	for i := 0; i < 5; i++ {
+		state.typeRune('a')
+		clock.advance(200 * time.Millisecond)
+	}
+	clock.advance(time.Second)
+	state.typeRune('a')
+
+	samples := state.wpmPerSecond()
+	if len(samples) != 2 || samples[0] != 60 || samples[1] != 12 {
+		t.Errorf("wpmPerSecond = %v, want [60 12]", samples)
+	}
+	if got := speedDeviation(samples); got != 24 {
+		t.Errorf("speedDeviation(%v) = %v, want 24", samples, got)
+	}
+	if got := speedDeviation([]float64{50, 50, 50}); got != 0 {
+		t.Errorf("speedDeviation of a steady run = %v", got)
+	}
+}
+
+func TestStenoScoring(t *testing.T) {
+	// Four strokes a second apart, each committing a word in a 2ms burst
+	text := "one two three four"
+	typeStrokes := func(steno bool) *TestState {
+		clock := &fakeClock{}
+		state := newTestState(text, "test.txt", clock)
+		state.steno = steno
+		for i, word := range strings.SplitAfter(text, " ") {
+			if i > 0 {
+				clock.advance(time.Second)
+			}
+			for _, r := range word {
+				state.typeRune(r)
+				clock.advance(2 * time.Millisecond)
+			}
+		}
+		return &state
+	}
+
+	state := typeStrokes(true)
+	if !state.testComplete {
+		t.Fatal("test not complete after the last stroke")
+	}
+	if got := state.consistency(); got != 100 {
+		t.Errorf("steno consistency = %v, want 100 for evenly spaced strokes", got)
+	}
+	// Four real words, however many characters they took
+	if got, want := state.wpm(state.elapsed()), 4/state.elapsed().Minutes(); math.Abs(got-want) > 1e-9 {
+		t.Errorf("steno WPM = %v, want %v words per minute", got, want)
+	}
+
+	if got := typeStrokes(false).consistency(); got >= 50 {
+		t.Errorf("per-character consistency = %v for bursty input, want it to read as erratic", got)
+	}
+}
+
+func TestSelectRandomTestIsDeterministic(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.txt":    "first\n",
+		"b.txt":    "  second  ",
+		"notes.md": "ignored",
+		"c.TXT":    "third",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clock := &fakeClock{}
+	engine := newEngine(clock, fixedRand(1), dir)
+	state, err := engine.selectRandomTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fs.ReadDir sorts by name, so index 1 of [a.txt b.txt c.TXT] is b.txt
+	if state.testFile != "b.txt" || state.referenceText != "second" {
+		t.Errorf("selected %q with text %q, want b.txt with text \"second\"", state.testFile, state.referenceText)
+	}
+	if state.clock != clock {
+		t.Error("selected test does not use the engine's clock")
+	}
+}
+
+func TestSelectBuiltinText(t *testing.T) {
+	// With no tests directory, texts come from the built-in corpus
+	engine := newEngine(&fakeClock{}, fixedRand(0), "")
+	state, err := engine.selectRandomTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := fs.ReadFile(corpusFiles, "corpus/"+state.testFile)
+	if err != nil || state.referenceText != strings.TrimSpace(string(content)) {
+		t.Errorf("selected %q with text %q, want a built-in text", state.testFile, state.referenceText)
+	}
+}
+
+func TestLoadTestWords(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("  one two,\n\tthree  four five\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	engine := newEngine(&fakeClock{}, fixedRand(0), dir)
+	for _, tt := range []struct {
+		words int
+		want  string
+	}{
+		{0, "one two,\n\tthree  four five"},
+		{3, "one two,\n\tthree"},
+		{5, "one two,\n\tthree  four five"},
+		{50, "one two,\n\tthree  four five"},
+	} {
+		engine.words = tt.words
+		state, err := engine.loadTest("a.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.referenceText != tt.want {
+			t.Errorf("with --words %d, text = %q, want %q", tt.words, state.referenceText, tt.want)
+		}
+	}
+}
+
+func TestStripEmojiOnEveryStart(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{"a.txt": "Ship it \U0001F680 today", "emoji.txt": "\U0001F680 \u2705"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	engine := newEngine(&fakeClock{}, fixedRand(0), dir)
+	engine.stripEmoji = true
+
+	if state, err := engine.loadTest("a.txt"); err != nil || state.referenceText != "Ship it today" {
+		t.Errorf("loaded %q (%v), want the emoji stripped", state.referenceText, err)
+	}
+	// Texts that don't come from files, such as a pack's or the control
+	// API's, are stripped when the test starts
+	if state := engine.newTest("Done \u2705", "pack"); state.referenceText != "Done" {
+		t.Errorf("started %q, want the emoji stripped", state.referenceText)
+	}
+	// A text of nothing but emoji can't be played
+	if _, err := engine.loadTest("emoji.txt"); err == nil {
+		t.Error("loaded a text of nothing but emoji")
+	}
+	if _, err := engine.prepareText("\U0001F469\u200d\U0001F4BB", controlFile); err == nil {
+		t.Error("prepared a text of nothing but emoji")
+	}
+}
+
+// TestSnapshotConcurrentReaders types a test while another goroutine polls
+// Snapshot. Run with -race to check the ownership model.
+func TestSnapshotConcurrentReaders(t *testing.T) {
+	dir := t.TempDir()
+	text := "the quick brown fox"
+	if err := os.WriteFile(filepath.Join(dir, "fox.txt"), []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := newEngine(systemClock{}, fixedRand(0), dir)
+	state, err := engine.selectRandomTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			snap, ok := engine.Snapshot()
+			if !ok {
+				t.Error("no snapshot after selecting a test")
+				return
+			}
+			if !strings.HasPrefix(snap.referenceText, snap.userInput) {
+				t.Errorf("snapshot input %q is not a prefix of the reference", snap.userInput)
+				return
+			}
+			if snap.testComplete {
+				return
+			}
+		}
+	}()
+
+	for _, r := range text {
+		state.typeRune(r)
+	}
+	<-done
+
+	if snap, _ := engine.Snapshot(); snap.userInput != text || !snap.testComplete {
+		t.Errorf("final snapshot = %q (complete=%v), want the full text, complete", snap.userInput, snap.testComplete)
+	}
+}
+
+// FuzzTypeRune types arbitrary keystrokes, with \b for a backspace, at an
+// arbitrary reference text and checks that scoring keeps its invariants
+// and that the result still draws
+func FuzzTypeRune(f *testing.F) {
+	f.Add("The quick brown fox", "The quikc\b\bck brown fox")
+	f.Add("naïve café", "nai\u0308ve cafe\u0301")
+	f.Add("👩‍💻 and 🇯🇵", "👩\b👩‍💻 and 🇯")
+	f.Add("", "extra")
+	f.Add("short", "shirt, and then some")
+	f.Fuzz(func(t *testing.T, reference, keys string) {
+		clock := &fakeClock{}
+		state := newTestState(reference, "fuzz.txt", clock)
+		pressed := 0
+		for _, r := range keys {
+			clock.advance(100 * time.Millisecond)
+			if r == backspaceKey {
+				state.backspace()
+				continue
+			}
+			pressed++
+			if state.typeRune(r) {
+				break
+			}
+		}
+
+		if state.errors > pressed {
+			t.Errorf("%d errors from %d keystrokes", state.errors, pressed)
+		}
+		if state.typed() > pressed {
+			t.Errorf("%d characters typed from %d keystrokes", state.typed(), pressed)
+		}
+		for i, start := range state.unitStarts {
+			if start >= len(state.userInput) || i > 0 && start <= state.unitStarts[i-1] {
+				t.Fatalf("cluster %d starts at %d in %q", i, start, state.userInput)
+			}
+		}
+		if accuracy := calculateAccuracy(state.errors, state.typed()); accuracy < 0 || accuracy > 100 {
+			t.Errorf("accuracy %.1f", accuracy)
+		}
+		if state.testComplete && state.userInput != reference {
+			t.Errorf("complete = %v with %q typed of %q", state.testComplete, state.userInput, reference)
+		}
+
+		screen := tcell.NewSimulationScreen("UTF-8")
+		if err := screen.Init(); err != nil {
+			t.Fatal(err)
+		}
+		defer screen.Fini()
+		screen.SetSize(60, 24)
+		renderScreen(screen, &state, 60, renderOptions{})
+	})
+}
+
+// setInput replaces the input without scoring it, for tests that start
+// part-way through a text
+func setInput(s *TestState, input string) {
+	s.userInput = input
+	s.unitStarts = s.unitStarts[:0]
+	offset := 0
+	for _, cluster := range splitGraphemes(input) {
+		s.unitStarts = append(s.unitStarts, offset)
+		offset += len(cluster)
+	}
+	s.lastUnit = s.scoreLast()
+}
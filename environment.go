@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// version is the release version, set at build time with
+// -ldflags "-X main.version=v1.2.3". Without it appVersion falls back to
+// the module version recorded by `go install`.
+var version = ""
+
+func appVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// runSettings is the snapshot of user-selected options a run was played
+// with. Add a field here whenever a new flag or config setting can change
+// how a test plays or is scored.
+type runSettings struct {
+	Bots          string `json:"bots,omitempty"`
+	Handicap      bool   `json:"handicap,omitempty"`
+	Spectators    bool   `json:"spectators,omitempty"`
+	ReducedMotion bool   `json:"reduced_motion,omitempty"`
+	LowPower      bool   `json:"low_power,omitempty"`
+	ScoreFormula  string `json:"score_formula"`
+	StripEmoji    bool   `json:"strip_emoji,omitempty"`
+	Transliterate string `json:"transliterate,omitempty"`
+	Steno         bool   `json:"steno,omitempty"`
+	WordByWord    bool   `json:"word_by_word,omitempty"`
+	StopOnError   bool   `json:"stop_on_error,omitempty"`
+	SuddenDeath   bool   `json:"sudden_death,omitempty"`
+	NoBackspace   bool   `json:"no_backspace,omitempty"`
+	Blind         bool   `json:"blind,omitempty"`
+	TimeLimit     int    `json:"time_limit,omitempty"` // seconds, for timed tests
+	Words         int    `json:"words,omitempty"`      // texts cut to this many words
+}
+
+// runEnvironment records the conditions a run was played under, so results
+// can be segmented later and odd runs reproduced from a bug report
+type runEnvironment struct {
+	TerminalWidth  int         `json:"terminal_width"`
+	TerminalHeight int         `json:"terminal_height"`
+	Term           string      `json:"term"`
+	ColorTerm      string      `json:"colorterm,omitempty"`
+	OS             string      `json:"os"`
+	Arch           string      `json:"arch"`
+	Version        string      `json:"version"`
+	Mode           string      `json:"mode"`
+	Settings       runSettings `json:"settings"`
+
+	// EchoLatency is the calibrated median echo latency of this terminal,
+	// if `keysmash calibrate` has been run in it
+	EchoLatency time.Duration `json:"echo_latency,omitempty"`
+}
+
+// captureEnvironment describes the current terminal and process. It reads
+// the screen size, so call it from the input loop.
+func captureEnvironment(screen tcell.Screen, mode string, settings runSettings, latency *latencyProfiles) runEnvironment {
+	width, height := screen.Size()
+	env := runEnvironment{
+		TerminalWidth:  width,
+		TerminalHeight: height,
+		Term:           os.Getenv("TERM"),
+		ColorTerm:      os.Getenv("COLORTERM"),
+		OS:             runtime.GOOS,
+		Arch:           runtime.GOARCH,
+		Version:        appVersion(),
+		Mode:           mode,
+		Settings:       settings,
+	}
+	if profile, ok := latency.current(); ok {
+		env.EchoLatency = profile.Median
+	}
+	return env
+}
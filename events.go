@@ -0,0 +1,161 @@
+package main
+
+import "time"
+
+// EventKind identifies something that happened during a typing test
+type EventKind int
+
+const (
+	// EventTestStarted fires on the first keystroke of a test
+	EventTestStarted EventKind = iota
+	// EventKeystrokeScored fires for every typed character once it has
+	// been compared against the reference text
+	EventKeystrokeScored
+	// EventTestCompleted fires when the input matches the reference
+	EventTestCompleted
+	// EventPBAchieved fires after EventTestCompleted when the run beat the
+	// previous best score for the same text
+	EventPBAchieved
+	// EventBadgeEarned fires after EventTestCompleted when the run earned
+	// a challenge pack badge
+	EventBadgeEarned
+	// EventBackspace fires for every backspace that removed a character
+	EventBackspace
+	// EventTestFailed fires instead of EventTestCompleted when a sudden
+	// death test ends on an error
+	EventTestFailed
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventTestStarted:
+		return "TestStarted"
+	case EventKeystrokeScored:
+		return "KeystrokeScored"
+	case EventTestCompleted:
+		return "TestCompleted"
+	case EventPBAchieved:
+		return "PBAchieved"
+	case EventBadgeEarned:
+		return "BadgeEarned"
+	case EventBackspace:
+		return "Backspace"
+	case EventTestFailed:
+		return "TestFailed"
+	}
+	return "Unknown"
+}
+
+// Event is the payload delivered to subscribers. Only the fields relevant
+// to Kind are set.
+type Event struct {
+	Kind     EventKind
+	Time     time.Time
+	TestFile string
+
+	// EventKeystrokeScored and EventBackspace. Expected is the
+	// reference's character at Position, empty past its end. For a
+	// backspace, Position is the character removed, Deleted what it was,
+	// and Correct whether it was needed: whether there was a mistake left
+	// to remove. For EventTestFailed, Position is where the error was.
+	Rune     rune
+	Expected string
+	Position int
+	Correct  bool
+	Deleted  string
+
+	// EventTestCompleted, EventTestFailed and EventPBAchieved. Score is what results are
+	// ranked by: the configured score formula, or WPM by default.
+	TextHash    string
+	WPM         float64
+	Accuracy    float64
+	Consistency float64
+	Score       float64
+	Errors      int
+	Uncorrected int     // of Errors, those still in the input at the end
+	NetWPM      float64 // WPM less a word a minute per uncorrected error
+	Duration    time.Duration
+	Estimate    time.Duration // expected Duration at the player's average speed
+	Flow        time.Duration // time spent in flow; see flowTracker
+	Keystrokes  int           // every key pressed; see typingEconomy
+	Backspaces  int
+	Efficiency  float64
+	Peeks       int // times a blind test was peeked at; see blind.go
+
+	// EventTestCompleted, for a correction drill: when the run it corrects
+	// ended
+	CorrectionOf time.Time
+
+	// EventBadgeEarned
+	Badge string
+}
+
+// EventBus fans engine events out to subscribers (UI, storage, logging,
+// integrations) so new consumers can be added without touching the input
+// loop. Handlers run synchronously on the publishing goroutine, in
+// subscription order, so they must be quick; anything slow should hand off
+// to its own goroutine.
+type EventBus struct {
+	handlers map[EventKind][]func(Event)
+}
+
+func newEventBus() *EventBus {
+	return &EventBus{handlers: make(map[EventKind][]func(Event))}
+}
+
+// Subscribe registers fn to be called for every event of the given kinds
+func (b *EventBus) Subscribe(fn func(Event), kinds ...EventKind) {
+	for _, kind := range kinds {
+		b.handlers[kind] = append(b.handlers[kind], fn)
+	}
+}
+
+// Publish delivers ev to its subscribers. Publishing on a nil bus is a
+// no-op, which lets TestStates run standalone in tests and benchmarks.
+func (b *EventBus) Publish(ev Event) {
+	if b == nil {
+		return
+	}
+	for _, fn := range b.handlers[ev.Kind] {
+		fn(ev)
+	}
+}
+
+// logEvents writes test lifecycle events to the debug log. Keystrokes are
+// logged at debug level since there is one per character.
+func logEvents(bus *EventBus) {
+	bus.Subscribe(func(ev Event) {
+		switch ev.Kind {
+		case EventKeystrokeScored:
+			logger.Debug("keystroke scored", "pos", ev.Position, "rune", string(ev.Rune), "correct", ev.Correct)
+		case EventTestStarted:
+			logger.Info("test started", "file", ev.TestFile)
+		default:
+			logger.Info(ev.Kind.String(), "file", ev.TestFile, "wpm", ev.WPM, "accuracy", ev.Accuracy, "consistency", ev.Consistency, "score", ev.Score, "errors", ev.Errors, "duration", ev.Duration)
+		}
+	}, EventTestStarted, EventKeystrokeScored, EventTestCompleted, EventTestFailed, EventPBAchieved)
+}
+
+// trackPersonalBests remembers the best score per text for the session and
+// publishes EventPBAchieved when a completed run beats it. The first run
+// of a text sets the baseline rather than counting as a PB. Texts go by
+// content where it's known, so a renamed file keeps its best.
+func trackPersonalBests(bus *EventBus) {
+	best := make(map[string]float64)
+	bus.Subscribe(func(ev Event) {
+		key := ev.TextHash
+		if key == "" {
+			key = ev.TestFile
+		}
+		previous, seen := best[key]
+		if seen && ev.Score <= previous {
+			return
+		}
+		best[key] = ev.Score
+		if seen {
+			pb := ev
+			pb.Kind = EventPBAchieved
+			bus.Publish(pb)
+		}
+	}, EventTestCompleted)
+}
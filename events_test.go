@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEventSequence(t *testing.T) {
+	bus := newEventBus()
+	var kinds []EventKind
+	bus.Subscribe(func(ev Event) { kinds = append(kinds, ev.Kind) },
+		EventTestStarted, EventKeystrokeScored, EventTestCompleted, EventPBAchieved)
+
+	state := newTestState("ab", "test.txt", &fakeClock{})
+	state.events = bus
+	state.typeRune('a')
+	state.typeRune('b')
+
+	want := []EventKind{EventTestStarted, EventKeystrokeScored, EventKeystrokeScored, EventTestCompleted}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("events = %v, want %v", kinds, want)
+	}
+}
+
+func TestTrackPersonalBests(t *testing.T) {
+	bus := newEventBus()
+	trackPersonalBests(bus)
+	var pbs []float64
+	bus.Subscribe(func(ev Event) { pbs = append(pbs, ev.Score) }, EventPBAchieved)
+
+	for _, score := range []float64{50, 40, 60, 60, 70} {
+		bus.Publish(Event{Kind: EventTestCompleted, TestFile: "a.txt", Score: score, Duration: time.Minute})
+	}
+	// A different text has its own baseline
+	bus.Publish(Event{Kind: EventTestCompleted, TestFile: "b.txt", Score: 100})
+
+	if want := []float64{60, 70}; !reflect.DeepEqual(pbs, want) {
+		t.Errorf("PBs = %v, want %v", pbs, want)
+	}
+}
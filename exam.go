@@ -0,0 +1,335 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// examExt marks signed exam reports, which keysmash verify checks like
+// .ksm recordings
+const examExt = ".kse"
+
+// Exam outcomes
+const (
+	examCompleted = "completed"
+	examAbandoned = "abandoned" // the candidate pressed Escape
+	examIdle      = "idle"      // no keystroke within the idle limit
+)
+
+// proctor enforces an exam's rules on a test: pasted input is dropped
+// and counted, and the exam ends if the candidate stops typing for longer
+// than idleLimit once they've started
+type proctor struct {
+	state     *TestState
+	idleLimit time.Duration
+
+	pasting      bool
+	pastes       int
+	lastKey      time.Time
+	longestPause time.Duration
+	outcome      string
+}
+
+// paste notes the start or end of a bracketed paste
+func (p *proctor) paste(start bool) {
+	if start && !p.pasting {
+		p.pastes++
+		logger.Warn("exam paste rejected")
+	}
+	p.pasting = start
+}
+
+// key applies a keystroke unless it's part of a paste, ending the exam
+// when it completes the text
+func (p *proctor) key(r rune, backspace bool) {
+	if p.pasting || p.outcome != "" {
+		return
+	}
+	now := p.state.clock.Now()
+	if p.state.testStarted {
+		p.longestPause = max(p.longestPause, now.Sub(p.lastKey))
+	}
+	p.lastKey = now
+	if backspace {
+		p.state.backspace()
+	} else if p.state.typeRune(r) {
+		p.outcome = examCompleted
+	}
+}
+
+// checkIdle ends the exam if the candidate has been idle too long
+func (p *proctor) checkIdle() {
+	if p.outcome == "" && p.state.testStarted && p.state.clock.Now().Sub(p.lastKey) > p.idleLimit {
+		p.longestPause = max(p.longestPause, p.state.clock.Now().Sub(p.lastKey))
+		p.outcome = examIdle
+	}
+}
+
+// examReport is the signed proctoring summary of one exam. A completed
+// exam includes its recording, so it can be replayed and its text checked.
+type examReport struct {
+	Version        int           `json:"version"`
+	Candidate      string        `json:"candidate"`
+	TestFile       string        `json:"test_file"`
+	TextHash       string        `json:"text_hash"`
+	Finished       time.Time     `json:"finished"`
+	Outcome        string        `json:"outcome"`
+	WPM            float64       `json:"wpm"`
+	Accuracy       float64       `json:"accuracy"`
+	Errors         int           `json:"errors"`
+	Typed          int           `json:"typed"`
+	Length         int           `json:"length"`
+	Duration       time.Duration `json:"duration"`
+	IdleLimit      time.Duration `json:"idle_limit"`
+	LongestPause   time.Duration `json:"longest_pause"`
+	PastesRejected int           `json:"pastes_rejected"`
+	Recording      *recording    `json:"recording,omitempty"`
+
+	PublicKey ed25519.PublicKey `json:"public_key"`
+	Signature []byte            `json:"signature"`
+}
+
+// newExamReport summarises a finished exam
+func newExamReport(candidate string, p *proctor, now time.Time) examReport {
+	state := p.state
+	report := examReport{
+		Version:        recordingVersion,
+		Candidate:      candidate,
+		TestFile:       state.testFile,
+		TextHash:       hashText(state.referenceText),
+		Finished:       now,
+		Outcome:        p.outcome,
+		WPM:            state.wpm(state.elapsed()),
+		Accuracy:       calculateAccuracy(state.errors, state.typed()),
+		Errors:         state.errors,
+		Typed:          state.typed(),
+		Length:         len(state.reference),
+		Duration:       state.elapsed(),
+		IdleLimit:      p.idleLimit,
+		LongestPause:   p.longestPause,
+		PastesRejected: p.pastes,
+	}
+	if p.outcome == examCompleted {
+		rec := newRecording(state)
+		report.Recording = &rec
+	}
+	return report
+}
+
+func (r examReport) signedBytes() ([]byte, error) {
+	r.Signature = nil
+	return json.Marshal(r)
+}
+
+// sign adds key's public half and signature; unlike recordings, exam
+// reports are always signed
+func (r *examReport) sign(key ed25519.PrivateKey) error {
+	r.PublicKey = key.Public().(ed25519.PublicKey)
+	data, err := r.signedBytes()
+	if err != nil {
+		return err
+	}
+	r.Signature = ed25519.Sign(key, data)
+	return nil
+}
+
+func (r examReport) verify() error {
+	if len(r.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("missing or malformed public key")
+	}
+	data, err := r.signedBytes()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(r.PublicKey, data, r.Signature) {
+		return errors.New("signature doesn't match; the report was changed after it was signed")
+	}
+	if r.Recording != nil {
+		if _, err := r.Recording.reference(); err != nil {
+			return err
+		}
+		if r.Recording.TextHash != r.TextHash {
+			return errors.New("recording is of a different text")
+		}
+	}
+	return nil
+}
+
+// saveExamReport writes a signed report to the exams directory, named
+// after when it finished and the candidate, and returns its path
+func saveExamReport(report examReport) (string, error) {
+	dir, err := dataFile("exams")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s%s", report.Finished.Format("20060102-150405"), fileSafe(report.Candidate), examExt))
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// fileSafe makes a person's name usable in a file name
+func fileSafe(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+func loadExamReport(path string) (examReport, error) {
+	var report examReport
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return report, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if report.Version > recordingVersion {
+		return report, fmt.Errorf("%s was made by a newer keysmash (format %d); please upgrade", path, report.Version)
+	}
+	return report, nil
+}
+
+// writeExamSummary prints the proctoring summary of a report
+func writeExamSummary(w io.Writer, report examReport, path string) {
+	fmt.Fprintf(w, "Exam report for %s\n", report.Candidate)
+	fmt.Fprintf(w, "  Text:            %s (%s)\n", report.TestFile, report.TextHash)
+	fmt.Fprintf(w, "  Outcome:         %s\n", report.Outcome)
+	fmt.Fprintf(w, "  Progress:        %d of %d characters\n", report.Typed, report.Length)
+	fmt.Fprintf(w, "  WPM:             %.1f\n", report.WPM)
+	fmt.Fprintf(w, "  Accuracy:        %.1f%% (%d errors)\n", report.Accuracy, report.Errors)
+	fmt.Fprintf(w, "  Time:            %.1fs\n", report.Duration.Seconds())
+	fmt.Fprintf(w, "  Longest pause:   %.1fs (limit %s)\n", report.LongestPause.Seconds(), report.IdleLimit)
+	fmt.Fprintf(w, "  Pastes rejected: %d\n", report.PastesRejected)
+	fmt.Fprintf(w, "  Signed by:       %s\n", keyFingerprint(report.PublicKey))
+	if path != "" {
+		fmt.Fprintf(w, "  Saved:           %s\n", path)
+	}
+}
+
+// runExam sits one exam: a single attempt at a fixed text with no
+// retries, pasting rejected and an idle limit, ignoring config.toml so
+// every candidate types under the same rules. It saves a signed report
+// and prints its summary. It returns the process exit code.
+func runExam(stdout, stderr io.Writer, args []string) int {
+	flags := flag.NewFlagSet("exam", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	candidate := flags.String("candidate", "", "name of the person sitting the exam (required)")
+	idleLimit := flags.Duration("idle-limit", 30*time.Second, "end the exam after this long without a keystroke")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || strings.TrimSpace(*candidate) == "" || *idleLimit <= 0 {
+		fmt.Fprintln(stderr, "Usage: keysmash exam --candidate NAME [--idle-limit 30s] FILE")
+		return 2
+	}
+
+	content, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	text := strings.TrimSpace(string(content))
+	if text == "" {
+		fmt.Fprintf(stderr, "Error: %s is empty\n", flags.Arg(0))
+		return 1
+	}
+	key, err := loadSigningKey()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: exam reports must be signed: %v\n", err)
+		return 1
+	}
+
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error creating screen: %v\n", err)
+		return 1
+	}
+	if err := screen.Init(); err != nil {
+		fmt.Fprintf(stderr, "Error initializing screen: %v\n", err)
+		return 1
+	}
+	screen.EnablePaste()
+
+	state := newTestState(text, filepath.Base(flags.Arg(0)), systemClock{})
+	state.mode = "exam"
+	p := &proctor{state: &state, idleLimit: *idleLimit}
+	logger.Info("exam started", "candidate", *candidate, "file", state.testFile, "idle_limit", *idleLimit)
+	sitExam(screen, p, *candidate)
+	screen.Fini()
+
+	report := newExamReport(*candidate, p, time.Now())
+	if err := report.sign(key); err != nil {
+		fmt.Fprintf(stderr, "Error signing exam report: %v\n", err)
+		return 1
+	}
+	path, err := saveExamReport(report)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error saving exam report: %v\n", err)
+		return 1
+	}
+	logger.Info("exam finished", "candidate", *candidate, "outcome", report.Outcome, "path", path)
+	writeExamSummary(stdout, report, path)
+	return 0
+}
+
+// sitExam runs the exam's typing screen until the proctor ends it
+func sitExam(screen tcell.Screen, p *proctor, candidate string) {
+	stopTicker := startTicker(screen, 100*time.Millisecond)
+	defer stopTicker()
+
+	footer := fmt.Sprintf("EXAM: %s - one attempt, no pasting, %s idle limit - ESC to abandon", candidate, p.idleLimit)
+	frames := newFrameScheduler(screen, defaultConfig().MaxFPS)
+	for p.outcome == "" {
+		if now := time.Now(); frames.ready(now) {
+			width, height := screen.Size()
+			renderScreen(screen, p.state, width, renderOptions{})
+			drawText(screen, min(4, width/10), height-1, tcell.StyleDefault, footer)
+			screen.Show()
+			frames.drawn(now)
+		}
+
+		ev := screen.PollEvent()
+		frames.request()
+		switch ev := ev.(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventPaste:
+			p.paste(ev.Start())
+		case *tcell.EventInterrupt:
+			p.checkIdle()
+		case *tcell.EventKey:
+			switch ev.Key() {
+			case tcell.KeyEscape:
+				if !p.pasting {
+					p.outcome = examAbandoned
+				}
+			case tcell.KeyBackspace, tcell.KeyBackspace2:
+				p.key(0, true)
+			case tcell.KeyEnter:
+				p.key('\n', false)
+			case tcell.KeyRune:
+				p.key(ev.Rune(), false)
+			}
+		}
+	}
+}
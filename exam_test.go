@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProctor(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}
+	state := newTestState("abc", "exam.txt", clock)
+	p := &proctor{state: &state, idleLimit: 10 * time.Second}
+
+	// Idle time before the first keystroke doesn't count
+	clock.advance(time.Minute)
+	p.checkIdle()
+	p.key('a', false)
+	if p.outcome != "" {
+		t.Fatalf("outcome = %q before the candidate was idle", p.outcome)
+	}
+
+	// Pasted keys are dropped and counted once per paste
+	p.paste(true)
+	p.key('b', false)
+	p.key('c', false)
+	p.paste(false)
+	if p.pastes != 1 || state.typed() != 1 {
+		t.Errorf("after a paste: %d pastes, %d typed; want 1 and 1", p.pastes, state.typed())
+	}
+
+	clock.advance(4 * time.Second)
+	p.key('b', false)
+	clock.advance(time.Second)
+	p.key('c', false)
+	if p.outcome != examCompleted || p.longestPause != 4*time.Second {
+		t.Errorf("outcome %q, longest pause %v; want completed after 4s", p.outcome, p.longestPause)
+	}
+
+	// A stall past the limit ends the exam
+	state = newTestState("abc", "exam.txt", clock)
+	p = &proctor{state: &state, idleLimit: 10 * time.Second}
+	p.key('a', false)
+	clock.advance(10 * time.Second)
+	p.checkIdle()
+	if p.outcome != "" {
+		t.Error("exam ended at exactly the idle limit")
+	}
+	clock.advance(time.Second)
+	p.checkIdle()
+	if p.outcome != examIdle || p.longestPause != 11*time.Second {
+		t.Errorf("outcome %q, longest pause %v; want idle after 11s", p.outcome, p.longestPause)
+	}
+}
+
+func TestExamReportSigning(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	key, err := loadSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}
+	state := newTestState("hi", "exam.txt", clock)
+	p := &proctor{state: &state, idleLimit: 30 * time.Second}
+	for _, r := range "hi" {
+		clock.advance(200 * time.Millisecond)
+		p.key(r, false)
+	}
+	report := newExamReport("Ada Lovelace", p, clock.Now())
+	if report.Recording == nil {
+		t.Fatal("completed exam has no recording")
+	}
+	if err := report.sign(key); err != nil {
+		t.Fatal(err)
+	}
+	path, err := saveExamReport(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(path, "20261016-090000-Ada_Lovelace"+examExt) {
+		t.Errorf("saved to %s", path)
+	}
+
+	var out bytes.Buffer
+	if code := runVerify(&out, []string{path}); code != 0 || !strings.Contains(out.String(), "OK, Ada Lovelace completed exam.txt") {
+		t.Errorf("verify = %d: %s", code, out.String())
+	}
+
+	// Any change to the report breaks the signature
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(string(data), `"candidate": "Ada Lovelace"`, `"candidate": "Ada Byron"`, 1)
+	if err := os.WriteFile(path, []byte(tampered), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if code := runVerify(&out, []string{path}); code != 1 || !strings.Contains(out.String(), "signature doesn't match") {
+		t.Errorf("verify of tampered report = %d: %s", code, out.String())
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// failureKind is what sort of thing went wrong, which decides what the
+// error screen suggests doing about it
+type failureKind int
+
+const (
+	failureOther         failureKind = iota
+	failureLibraryEmpty              // there's no text to make a test from
+	failureUnreadable                // a file is missing, unreadable or garbled
+	failureNetwork                   // a source on the network couldn't be reached
+	failureStorageLocked             // a data file is in use or can't be written
+)
+
+// emptyLibraryError means there's no text to pick a test from. archived is
+// set if there are texts, but they're all archived.
+type emptyLibraryError struct {
+	reason   string
+	archived bool
+}
+
+func (e *emptyLibraryError) Error() string { return e.reason }
+
+// classifyFailure works out what kind of failure err is
+func classifyFailure(err error) failureKind {
+	var empty *emptyLibraryError
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	var urlErr *url.Error
+	var pathErr *fs.PathError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &empty):
+		return failureLibraryEmpty
+	case errors.As(err, &opErr), errors.As(err, &dnsErr), errors.As(err, &urlErr):
+		return failureNetwork
+	case errors.Is(err, fs.ErrPermission), errors.Is(err, syscall.EBUSY), errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.EROFS):
+		return failureStorageLocked
+	case errors.As(err, &pathErr), errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return failureUnreadable
+	}
+	return failureOther
+}
+
+// failure is an error to show the player, with what keysmash was doing
+// when it happened and what it can offer to do next
+type failure struct {
+	doing   string // what failed, e.g. "loading a test"
+	err     error
+	logPath string // the --log-file, if any
+	retry   bool   // whether the player can try again, or only quit
+	canPick bool   // whether they can play another text instead
+}
+
+// heading names the kind of failure in a few words
+func (f failure) heading() string {
+	switch classifyFailure(f.err) {
+	case failureLibraryEmpty:
+		return "NO TEXTS TO PLAY"
+	case failureUnreadable:
+		return "COULDN'T READ A FILE"
+	case failureNetwork:
+		return "COULDN'T REACH A SOURCE"
+	case failureStorageLocked:
+		return "DATA FILES UNAVAILABLE"
+	}
+	return "ERROR"
+}
+
+// hint suggests how the player might fix the failure themselves
+func (f failure) hint() string {
+	switch classifyFailure(f.err) {
+	case failureLibraryEmpty:
+		return "Add .txt files to the tests directory, or unarchive or star some"
+	case failureUnreadable:
+		return "Check the file is there, readable, and not corrupted"
+	case failureNetwork:
+		return "Check your connection; the source may be down"
+	case failureStorageLocked:
+		return fmt.Sprintf("Check nothing else is using %s and that you can write to it", dataDir())
+	}
+	return ""
+}
+
+// actions are the commands the error screen offers, by key
+func (f failure) actions() []command {
+	var actions []command
+	kind := classifyFailure(f.err)
+	if f.retry {
+		actions = append(actions, command{title: "Retry", key: 'r'})
+	}
+	if f.retry && f.canPick {
+		if kind != failureLibraryEmpty {
+			actions = append(actions, command{title: "Play another text", key: 'n'})
+		}
+		actions = append(actions, command{title: "Pick a test by name", key: 'p'})
+		var empty *emptyLibraryError
+		if errors.As(f.err, &empty) && empty.archived {
+			actions = append(actions, command{title: "Manage archived texts", key: 'a'})
+		}
+	}
+	return append(actions, command{title: "Show the log file", key: 'l'})
+}
+
+// showFailure explains a failure and waits for the player to choose one
+// of its actions, returning its key, or false if they quit with Escape or
+// Q. The log path is shown in place rather than returned.
+func showFailure(screen tcell.Screen, f failure) (rune, bool) {
+	actions := f.actions()
+	message := ""
+	for {
+		screen.Clear()
+		width, height := screen.Size()
+		drawCenteredText(screen, width/2, height/2-5, tcell.StyleDefault, f.heading())
+		drawCenteredText(screen, width/2, height/2-3, tcell.StyleDefault, fmt.Sprintf("Error %s: %v", f.doing, f.err))
+		drawCenteredText(screen, width/2, height/2-2, tcell.StyleDefault, f.hint())
+		var keys []string
+		for _, a := range actions {
+			keys = append(keys, fmt.Sprintf("%c: %s", unicode.ToUpper(a.key), strings.ToLower(a.title)))
+		}
+		drawCenteredText(screen, width/2, height/2, tcell.StyleDefault, strings.Join(keys, "  "))
+		drawCenteredText(screen, width/2, height/2+2, tcell.StyleDefault, "ESC to quit")
+		drawCenteredText(screen, width/2, height/2+4, tcell.StyleDefault, message)
+		screen.Show()
+
+		key, ok := waitForStart(screen, actions)
+		key = unicode.ToLower(key)
+		if !ok || key == 'q' {
+			return 0, false
+		}
+		if key == 'l' {
+			message = logLocation(f.logPath)
+			continue
+		}
+		for _, a := range actions {
+			if a.key == key {
+				return key, true
+			}
+		}
+	}
+}
+
+// logLocation says where the log is, or how to get one
+func logLocation(path string) string {
+	if path == "" {
+		return "Not logging; run with --log-file PATH --log-level debug to keep a log"
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return "Log: " + path
+}
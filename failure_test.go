@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	_, missing := os.ReadFile(filepath.Join(t.TempDir(), "gone.txt"))
+	for _, tt := range []struct {
+		err  error
+		want failureKind
+	}{
+		{&emptyLibraryError{reason: "no .txt files"}, failureLibraryEmpty},
+		{fmt.Errorf("loading: %w", missing), failureUnreadable},
+		{fmt.Errorf("saving: %w", &os.PathError{Op: "open", Path: "results.json", Err: os.ErrPermission}), failureStorageLocked},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, failureNetwork},
+		{errors.New("something else"), failureOther},
+	} {
+		if got := classifyFailure(tt.err); got != tt.want {
+			t.Errorf("classifyFailure(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestFailureActions(t *testing.T) {
+	keys := func(f failure) string {
+		var s string
+		for _, a := range f.actions() {
+			s += string(a.key)
+		}
+		return s
+	}
+	archived := &emptyLibraryError{reason: "every text is archived", archived: true}
+	for _, tt := range []struct {
+		f    failure
+		want string
+	}{
+		{failure{err: archived}, "l"}, // at startup there's nothing to do but quit
+		{failure{err: archived, retry: true, canPick: true}, "rpal"},
+		{failure{err: os.ErrNotExist, retry: true, canPick: true}, "rnpl"},
+		{failure{err: os.ErrNotExist, retry: true}, "rl"},
+	} {
+		if got := keys(tt.f); got != tt.want {
+			t.Errorf("actions for %+v = %q, want %q", tt.f, got, tt.want)
+		}
+	}
+}
+
+func TestShowFailure(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(100, 24)
+
+	f := failure{doing: "loading a test", err: &emptyLibraryError{reason: "no .txt files found"}, logPath: "/tmp/k.log", retry: true}
+	// N isn't on offer for an empty library; L shows the log in place
+	screen.InjectKeyBytes([]byte("nlR"))
+	if key, ok := showFailure(screen, f); !ok || key != 'r' {
+		t.Errorf("showFailure = %q, %v; want retry", key, ok)
+	}
+	var shown strings.Builder
+	for y := 0; y < 24; y++ {
+		shown.WriteString(cellText(screen, 0, y, 100))
+	}
+	for _, want := range []string{"NO TEXTS TO PLAY", "no .txt files found", "R: retry", "Log: /tmp/k.log"} {
+		if !strings.Contains(shown.String(), want) {
+			t.Errorf("error screen doesn't show %q", want)
+		}
+	}
+
+	screen.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	if _, ok := showFailure(screen, f); ok {
+		t.Error("Escape didn't quit")
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+)
+
+// Styles for live feedback on the test screen. By default typed
+// characters are green when they match the text and red when they don't,
+// and the character that should have been typed is shown inverted in red
+// in the text, so a mistake can be seen from both sides. The text not yet
+// typed is dimmed. A theme can change them; see applyTheme.
+var (
+	styleCorrect   = themes["default"].correct
+	styleIncorrect = themes["default"].incorrect
+	styleMissed    = themes["default"].missed
+	styleUntyped   = themes["default"].untyped
+)
+
+// typedCorrectly reports whether the ith typed character matches the
+// reference. One still being composed counts as correct while it's a
+// prefix of the reference's, as it's scored only once finished.
+func (s *TestState) typedCorrectly(i int) bool {
+	if i >= len(s.reference) || s.isSkipped(i) {
+		return false // past the end of the text, or skipped word by word
+	}
+	typed := s.typedCluster(i)
+	if i == len(s.unitStarts)-1 && s.lastUnit == unitPending {
+		return strings.HasPrefix(s.reference[i], typed)
+	}
+	return typed == s.reference[i]
+}
+
+// typedCluster returns the ith typed grapheme cluster
+func (s *TestState) typedCluster(i int) string {
+	end := len(s.userInput)
+	if i+1 < len(s.unitStarts) {
+		end = s.unitStarts[i+1]
+	}
+	return s.userInput[s.unitStarts[i]:end]
+}
+
+// inputStyle styles the ith typed character
+func (s *TestState) inputStyle(i int) tcell.Style {
+	if s.typedCorrectly(i) {
+		return styleCorrect
+	}
+	return styleIncorrect
+}
+
+// referenceStyle styles the ith character of the reference: plain once
+// typed correctly, inverted red if typed wrong, and dimmed until typed.
+// Word by word, a submitted word is styled whole; see wordStyle. A blind
+// test gives nothing away until it's over; see blindStyle.
+func (s *TestState) referenceStyle(i int) tcell.Style {
+	if style, ok := s.blindStyle(i); ok {
+		return style
+	}
+	if style, ok := s.wordStyle(i); ok {
+		return style
+	}
+	switch {
+	case i >= s.typed():
+		return styleUntyped
+	case s.typedCorrectly(i):
+		return tcell.StyleDefault
+	}
+	return styleMissed
+}
+
+// clusterIndex is the index of the grapheme cluster starting at or after
+// byte offset off, given the offset each cluster starts at
+func clusterIndex(starts []int, off int) int {
+	return sort.SearchInts(starts, off)
+}
+
+// lineOffsets finds the byte offset in text where each of lines, wrapped
+// from it, starts, so any one of them can be drawn with drawWrappedLine
+// without the lines before it. Wrapping only drops whitespace and turns
+// it into spaces, so the lines are followed through text a byte at a time.
+func lineOffsets(text string, lines []string) []int {
+	offsets := make([]int, len(lines))
+	pos := 0
+	for n, line := range lines {
+		offsets[n] = pos
+		for i := 0; i < len(line) && pos < len(text); {
+			if text[pos] == line[i] {
+				pos++
+				i++
+				continue
+			}
+			r, size := utf8.DecodeRuneInString(text[pos:])
+			if !unicode.IsSpace(r) {
+				break // not from this text
+			}
+			if r, size := utf8.DecodeRuneInString(line[i:]); unicode.IsSpace(r) {
+				i += size // a tab or newline shown as a space
+			}
+			pos += size
+		}
+	}
+	return offsets
+}
+
+// drawWrappedLine is drawText for a line wrapped from text that starts at
+// byte offset from in it, drawing each grapheme cluster in style(offset)
+// for its offset in text. Whitespace wrapping dropped is skipped over.
+func drawWrappedLine(screen tcell.Screen, x, y int, line, text string, from int, style func(offset int) tcell.Style) {
+	pos := from
+	state := -1
+	for line != "" {
+		var cluster string
+		cluster, line, _, state = uniseg.FirstGraphemeClusterInString(line, state)
+		space := isSpace(cluster)
+		for pos < len(text) && !space && !strings.HasPrefix(text[pos:], cluster) {
+			r, size := utf8.DecodeRuneInString(text[pos:])
+			if !unicode.IsSpace(r) {
+				break // not from this text
+			}
+			pos += size
+		}
+		runes := []rune(cluster)
+		screen.SetContent(x, y, runes[0], runes[1:], style(pos))
+		x += max(1, runewidth.StringWidth(cluster))
+		if _, size := utf8.DecodeRuneInString(text[min(pos, len(text)):]); space && size > 0 {
+			pos += size // a tab or newline shown as a space
+		} else {
+			pos += len(cluster)
+		}
+	}
+}
+
+// isSpace reports whether a grapheme cluster is whitespace
+func isSpace(cluster string) bool {
+	r, _ := utf8.DecodeRuneInString(cluster)
+	return unicode.IsSpace(r)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestLineOffsets(t *testing.T) {
+	text := "one  two\tthree\n\nfour five"
+	lines := wrapText(text, 9)
+	offsets := lineOffsets(text, lines)
+	for i, line := range lines {
+		if line == "" {
+			continue // a blank line between paragraphs
+		}
+		first := strings.Fields(line)[0]
+		if !strings.HasPrefix(strings.TrimLeft(text[offsets[i]:], " \t\n"), first) {
+			t.Errorf("line %d %q starts at %d, %q", i, line, offsets[i], text[offsets[i]:])
+		}
+	}
+}
+
+func TestTypingFeedback(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(80, 24)
+
+	state := newTestState("the\tcat sat", "test.txt", &fakeClock{})
+	for _, r := range "the cot" {
+		state.typeRune(r)
+	}
+	renderScreen(screen, &state, 80, renderOptions{})
+
+	// Find the text and the typing on screen by their first characters
+	find := func(prefix string) (x, y int) {
+		for y := 0; y < 24; y++ {
+			if x := strings.Index(cellText(screen, 0, y, 80), prefix); x >= 0 {
+				return x, y
+			}
+		}
+		t.Fatalf("%q not on screen", prefix)
+		return 0, 0
+	}
+	styleAt := func(x, y int) tcell.Style {
+		_, _, style, _ := screen.GetContent(x, y)
+		return style
+	}
+
+	x, y := find("the cat sat")
+	for i, want := range []tcell.Style{
+		tcell.StyleDefault, tcell.StyleDefault, tcell.StyleDefault, // "the"
+		styleMissed,                                         // a tab typed as a space
+		tcell.StyleDefault, styleMissed, tcell.StyleDefault, // "cat" typed "cot"
+		styleUntyped, styleUntyped,
+	} {
+		if got := styleAt(x+i, y); got != want {
+			t.Errorf("text cell %d %q styled %v, want %v", i, cellText(screen, x+i, y, 1), got, want)
+		}
+	}
+
+	x, y = find("the cot")
+	for i, want := range []tcell.Style{
+		styleCorrect, styleCorrect, styleCorrect, styleIncorrect,
+		styleCorrect, styleIncorrect, styleCorrect,
+	} {
+		if got := styleAt(x+i, y); got != want {
+			t.Errorf("typing cell %d %q styled %v, want %v", i, cellText(screen, x+i, y, 1), got, want)
+		}
+	}
+}
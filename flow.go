@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Flow is typing both fast and evenly for a stretch: the last flowWindow
+// keystrokes at the player's average speed or better, with gaps that
+// hardly vary. The test screen shows when the player is in it, and the
+// time spent in it is added up per test and per session, rewarding
+// steadiness rather than bursts of speed.
+
+// flowWindow is how many keystroke gaps flow is judged over
+const flowWindow = 20
+
+// flowMaxVariation is the most the gaps can vary, as their standard
+// deviation over their mean, for the typing to count as flowing
+const flowMaxVariation = 0.4
+
+// flowBreak is the pause that ends flow outright, however even the typing
+// was before it
+const flowBreak = 2 * time.Second
+
+// styleFlow is the flow indicator on the test screen
+var styleFlow = tcell.StyleDefault.Foreground(tcell.ColorTeal)
+
+// flowTracker follows the gaps between keystrokes during a test. It's a
+// fixed-size ring so snapshots of the test state copy it whole.
+type flowTracker struct {
+	gaps  [flowWindow]time.Duration
+	next  int // where the next gap goes in gaps
+	count int // how many of gaps are filled, up to flowWindow
+
+	in    bool          // whether the player is in flow now
+	total time.Duration // time spent typing in flow so far
+}
+
+// add records the gap before a keystroke, judging whether the player is
+// now in flow at minWPM or faster, and if so counting the gap as time in
+// flow
+func (f *flowTracker) add(gap time.Duration, minWPM float64) {
+	if gap >= flowBreak {
+		*f = flowTracker{total: f.total}
+		return
+	}
+	f.gaps[f.next] = gap
+	f.next = (f.next + 1) % flowWindow
+	f.count = min(f.count+1, flowWindow)
+	if f.count < flowWindow {
+		return
+	}
+
+	var sum, sumSquares float64
+	for _, g := range f.gaps {
+		sum += g.Seconds()
+		sumSquares += g.Seconds() * g.Seconds()
+	}
+	mean := sum / flowWindow
+	if mean <= 0 {
+		f.in = false
+		return
+	}
+	stddev := math.Sqrt(max(0, sumSquares/flowWindow-mean*mean))
+	wpm := 60 / mean / 5 // a character per gap, five to a word
+	f.in = stddev/mean <= flowMaxVariation && wpm >= minWPM
+	if f.in {
+		f.total += gap
+	}
+}
+
+// sessionFlow adds up the time in flow of the session that was still
+// going at now: the results back from the latest, until a gap of
+// sessionGap or more between them
+func (s *resultStore) sessionFlow(now time.Time) time.Duration {
+	var total time.Duration
+	for i := len(s.Results) - 1; i >= 0; i-- {
+		r := s.Results[i]
+		if now.Sub(r.Completed) >= sessionGap {
+			break
+		}
+		total += r.Flow
+		now = r.Completed
+	}
+	return total
+}
+
+// flowText is the results screen's line on time in flow
+func flowText(test, session time.Duration) string {
+	return fmt.Sprintf("Flow: %s this test, %s this session", test.Round(time.Second), session.Round(time.Second))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlowTracker(t *testing.T) {
+	// 100ms a key is 120 WPM
+	var f flowTracker
+	for i := 0; i < flowWindow-1; i++ {
+		f.add(100*time.Millisecond, 60)
+	}
+	if f.in {
+		t.Fatal("in flow before a full window")
+	}
+	f.add(100*time.Millisecond, 60)
+	f.add(110*time.Millisecond, 60)
+	if !f.in || f.total != 210*time.Millisecond {
+		t.Fatalf("steady typing: in %v, total %v", f.in, f.total)
+	}
+
+	// Too slow for the player
+	var slow flowTracker
+	for i := 0; i < flowWindow; i++ {
+		slow.add(100*time.Millisecond, 150)
+	}
+	if slow.in {
+		t.Error("in flow below minWPM")
+	}
+
+	// Bursts and stalls
+	for i := 0; i < flowWindow; i++ {
+		f.add(time.Duration(30+i%2*400)*time.Millisecond, 60)
+	}
+	if f.in {
+		t.Error("in flow typing unevenly")
+	}
+
+	// A pause ends it, keeping the time so far
+	total := f.total
+	f.add(flowBreak, 60)
+	if f.in || f.count != 0 || f.total != total {
+		t.Errorf("after a pause: in %v, count %d, total %v", f.in, f.count, f.total)
+	}
+}
+
+func TestFlowInTest(t *testing.T) {
+	clock := &fakeClock{}
+	text := benchText(100)
+	state := newTestState(text, "test.txt", clock)
+	for _, r := range text {
+		state.typeRune(r)
+		clock.advance(100 * time.Millisecond)
+	}
+	if !state.testComplete || state.flow.total == 0 {
+		t.Fatalf("complete %v, flow %v", state.testComplete, state.flow.total)
+	}
+	state.reset()
+	if state.flow.total != 0 || state.flow.in {
+		t.Error("reset kept the flow")
+	}
+}
+
+func TestSessionFlow(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	store := &resultStore{Results: []result{
+		{Completed: start, Flow: time.Minute},
+		{Completed: start.Add(2 * time.Hour), Flow: 20 * time.Second},
+		{Completed: start.Add(2*time.Hour + 10*time.Minute), Flow: 30 * time.Second},
+	}}
+	if got := store.sessionFlow(start.Add(2*time.Hour + 15*time.Minute)); got != 50*time.Second {
+		t.Errorf("sessionFlow = %v, want the last two runs' 50s", got)
+	}
+	if got := store.sessionFlow(start.Add(4 * time.Hour)); got != 0 {
+		t.Errorf("sessionFlow after the session = %v", got)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// formulaVariables are the names a score formula may refer to
+var formulaVariables = []string{"wpm", "accuracy", "consistency", "length"}
+
+// formulaFunctions are the functions a score formula may call, by arity
+var formulaFunctions = map[string]int{
+	"min": 2, "max": 2, "pow": 2,
+	"sqrt": 1, "log": 1, "abs": 1,
+}
+
+// formula is a compiled score formula such as "wpm * (accuracy / 100) ^ 2".
+// The language has numbers, the variables in formulaVariables, + - * / ^,
+// unary minus, parentheses and the functions in formulaFunctions. All
+// errors are found at compile time; evaluation can't fail.
+type formula struct {
+	source string
+	root   formulaNode
+}
+
+type formulaNode interface {
+	eval(vars map[string]float64) float64
+}
+
+type numberNode float64
+
+func (n numberNode) eval(map[string]float64) float64 { return float64(n) }
+
+type variableNode string
+
+func (n variableNode) eval(vars map[string]float64) float64 { return vars[string(n)] }
+
+type negateNode struct{ operand formulaNode }
+
+func (n negateNode) eval(vars map[string]float64) float64 { return -n.operand.eval(vars) }
+
+type binaryNode struct {
+	op          byte
+	left, right formulaNode
+}
+
+func (n binaryNode) eval(vars map[string]float64) float64 {
+	l, r := n.left.eval(vars), n.right.eval(vars)
+	switch n.op {
+	case '+':
+		return l + r
+	case '-':
+		return l - r
+	case '*':
+		return l * r
+	case '/':
+		return l / r
+	default: // '^'
+		return math.Pow(l, r)
+	}
+}
+
+type callNode struct {
+	name string
+	args []formulaNode
+}
+
+func (n callNode) eval(vars map[string]float64) float64 {
+	a := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		a[i] = arg.eval(vars)
+	}
+	switch n.name {
+	case "min":
+		return math.Min(a[0], a[1])
+	case "max":
+		return math.Max(a[0], a[1])
+	case "pow":
+		return math.Pow(a[0], a[1])
+	case "sqrt":
+		return math.Sqrt(a[0])
+	case "log":
+		return math.Log(a[0])
+	default: // "abs"
+		return math.Abs(a[0])
+	}
+}
+
+// evaluate computes the score. Results that aren't finite numbers (from
+// dividing by zero, say) score 0 so they can't top a leaderboard.
+func (f *formula) evaluate(vars map[string]float64) float64 {
+	score := f.root.eval(vars)
+	if math.IsNaN(score) || math.IsInf(score, 0) {
+		return 0
+	}
+	return score
+}
+
+// compileFormula parses source into a formula
+func compileFormula(source string) (*formula, error) {
+	p := &formulaParser{input: source}
+	p.next()
+	root, err := p.parseExpression()
+	if err != nil {
+		return nil, fmt.Errorf("score formula %q: %w", source, err)
+	}
+	if p.token != "" {
+		return nil, fmt.Errorf("score formula %q: unexpected %q at offset %d", source, p.token, p.tokenPos)
+	}
+	return &formula{source: source, root: root}, nil
+}
+
+// formulaParser is a recursive-descent parser with the usual precedence:
+// + - below * / below unary minus below ^ (right-associative)
+type formulaParser struct {
+	input    string
+	pos      int
+	token    string // current token; "" at end of input
+	tokenPos int
+}
+
+func (p *formulaParser) next() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+	p.tokenPos = p.pos
+	if p.pos >= len(p.input) {
+		p.token = ""
+		return
+	}
+
+	c := rune(p.input[p.pos])
+	end := p.pos + 1
+	switch {
+	case unicode.IsDigit(c) || c == '.':
+		for end < len(p.input) && (unicode.IsDigit(rune(p.input[end])) || p.input[end] == '.') {
+			end++
+		}
+	case unicode.IsLetter(c) || c == '_':
+		for end < len(p.input) && (unicode.IsLetter(rune(p.input[end])) || unicode.IsDigit(rune(p.input[end])) || p.input[end] == '_') {
+			end++
+		}
+	}
+	p.token = p.input[p.pos:end]
+	p.pos = end
+}
+
+func (p *formulaParser) parseExpression() (formulaNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.token == "+" || p.token == "-" {
+		op := p.token[0]
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) parseTerm() (formulaNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.token == "*" || p.token == "/" {
+		op := p.token[0]
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) parseUnary() (formulaNode, error) {
+	if p.token == "-" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negateNode{operand}, nil
+	}
+	return p.parsePower()
+}
+
+func (p *formulaParser) parsePower() (formulaNode, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.token != "^" {
+		return base, nil
+	}
+	p.next()
+	exponent, err := p.parseUnary() // right-associative: 2^3^2 = 2^(3^2)
+	if err != nil {
+		return nil, err
+	}
+	return binaryNode{op: '^', left: base, right: exponent}, nil
+}
+
+func (p *formulaParser) parsePrimary() (formulaNode, error) {
+	token, pos := p.token, p.tokenPos
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of formula")
+
+	case token == "(":
+		p.next()
+		inner, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if p.token != ")" {
+			return nil, fmt.Errorf("missing ) at offset %d", p.tokenPos)
+		}
+		p.next()
+		return inner, nil
+
+	case unicode.IsDigit(rune(token[0])) || token[0] == '.':
+		value, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at offset %d", token, pos)
+		}
+		p.next()
+		return numberNode(value), nil
+
+	case unicode.IsLetter(rune(token[0])) || token[0] == '_':
+		p.next()
+		if arity, ok := formulaFunctions[token]; ok {
+			return p.parseCall(token, arity, pos)
+		}
+		for _, name := range formulaVariables {
+			if token == name {
+				return variableNode(token), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown name %q at offset %d (variables: %s)", token, pos, strings.Join(formulaVariables, ", "))
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", token, pos)
+}
+
+func (p *formulaParser) parseCall(name string, arity, pos int) (formulaNode, error) {
+	if p.token != "(" {
+		return nil, fmt.Errorf("%s at offset %d must be called with (", name, pos)
+	}
+	p.next()
+
+	var args []formulaNode
+	for {
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.token != "," {
+			break
+		}
+		p.next()
+	}
+	if p.token != ")" {
+		return nil, fmt.Errorf("missing ) after arguments to %s", name)
+	}
+	p.next()
+
+	if len(args) != arity {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", name, arity, len(args))
+	}
+	return callNode{name: name, args: args}, nil
+}
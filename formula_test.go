@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormulaEvaluate(t *testing.T) {
+	vars := map[string]float64{"wpm": 80, "accuracy": 90, "consistency": 75, "length": 200}
+	cases := []struct {
+		source string
+		want   float64
+	}{
+		{"wpm", 80},
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"2 ^ 3 ^ 2", 512},
+		{"-2 ^ 2", -4},
+		{"wpm * (accuracy / 100) ^ 2", 80 * 0.81},
+		{"min(wpm, consistency) + max(1, 2)", 77},
+		{"sqrt(pow(3, 2) + 16)", 5},
+		{"abs(-length) / 4", 50},
+		{"1 / 0", 0},
+		{"log(0)", 0},
+	}
+	for _, c := range cases {
+		f, err := compileFormula(c.source)
+		if err != nil {
+			t.Errorf("compileFormula(%q): %v", c.source, err)
+			continue
+		}
+		if got := f.evaluate(vars); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("%q = %v, want %v", c.source, got, c.want)
+		}
+	}
+}
+
+func TestFormulaCompileErrors(t *testing.T) {
+	for _, source := range []string{
+		"",
+		"speed",
+		"wpm +",
+		"(wpm",
+		"wpm)",
+		"min(wpm)",
+		"sqrt wpm",
+		"1.2.3",
+		"wpm $ 2",
+	} {
+		if _, err := compileFormula(source); err == nil {
+			t.Errorf("compileFormula(%q) succeeded, want error", source)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg, err := loadConfig(filepath.Join(dir, "missing.toml"))
+	if err != nil || !reflect.DeepEqual(cfg, defaultConfig()) {
+		t.Fatalf("missing file: got %+v, %v; want defaults", cfg, err)
+	}
+
+	write := func(content string) string {
+		path := filepath.Join(dir, "config.toml")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cfg, err = loadConfig(write(`score_formula = "wpm * accuracy / 100"`))
+	if err != nil || cfg.ScoreFormula != "wpm * accuracy / 100" {
+		t.Errorf("valid file: got %+v, %v", cfg, err)
+	}
+
+	if _, err := loadConfig(write(`score_fromula = "wpm"`)); err == nil || !strings.Contains(err.Error(), "score_fromula") {
+		t.Errorf("unknown key: got %v, want error naming it", err)
+	}
+	if _, err := loadConfig(write(`score_formula = "wpm +"`)); err == nil {
+		t.Error("invalid formula: got no error")
+	}
+}
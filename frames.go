@@ -0,0 +1,62 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// frameScheduler decides when an event loop redraws. Keystrokes, ticks
+// and resizes only request a frame; the frame is drawn once the events
+// already queued have been handled, and no sooner than 1/maxFPS after the
+// last one, so a burst of fast typing costs one render instead of one per
+// key.
+type frameScheduler struct {
+	screen   tcell.Screen
+	interval time.Duration // between frames; 0 is no cap
+	last     time.Time
+	dirty    bool
+	waking   atomic.Bool // a wake-up interrupt is on its way
+}
+
+// newFrameScheduler caps screen at maxFPS frames per second, or with 0 at
+// as many as events ask for. The first frame is due straight away.
+func newFrameScheduler(screen tcell.Screen, maxFPS int) *frameScheduler {
+	f := &frameScheduler{screen: screen, dirty: true}
+	if maxFPS > 0 {
+		f.interval = time.Second / time.Duration(maxFPS)
+	}
+	return f
+}
+
+// request asks for a frame
+func (f *frameScheduler) request() {
+	f.dirty = true
+}
+
+// ready reports whether to draw a frame now. When a frame is wanted but
+// the cap holds it back, it arranges an interrupt for when it's due, so
+// the loop wakes up to draw it even if nothing else happens.
+func (f *frameScheduler) ready(now time.Time) bool {
+	if !f.dirty || f.screen.HasPendingEvent() {
+		return false
+	}
+	if wait := f.last.Add(f.interval).Sub(now); wait > 0 {
+		if f.waking.CompareAndSwap(false, true) {
+			time.AfterFunc(wait, func() {
+				f.waking.Store(false)
+				// A full event queue just means a redraw is already pending
+				_ = f.screen.PostEvent(tcell.NewEventInterrupt(nil))
+			})
+		}
+		return false
+	}
+	return true
+}
+
+// drawn records that a frame was drawn at now
+func (f *frameScheduler) drawn(now time.Time) {
+	f.last = now
+	f.dirty = false
+}
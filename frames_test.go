@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestFrameScheduler(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+
+	frames := newFrameScheduler(screen, 50) // a frame every 20ms
+	start := time.Now()
+	if !frames.ready(start) {
+		t.Fatal("first frame not ready")
+	}
+	frames.drawn(start)
+	if frames.ready(start.Add(time.Second)) {
+		t.Error("frame ready with nothing requested")
+	}
+
+	// Keys typed within one frame interval are drawn together
+	frames.request()
+	frames.request()
+	if frames.ready(start.Add(5 * time.Millisecond)) {
+		t.Error("frame ready before the cap allows")
+	}
+	// ...and the scheduler wakes the loop up when the frame is due
+	done := make(chan tcell.Event)
+	go func() { done <- screen.PollEvent() }()
+	select {
+	case ev := <-done:
+		if _, ok := ev.(*tcell.EventInterrupt); !ok {
+			t.Errorf("woken by %T, want an interrupt", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no wake-up for the held-back frame")
+	}
+	if !frames.ready(start.Add(20 * time.Millisecond)) {
+		t.Error("frame not ready once due")
+	}
+
+	// Queued events are handled before drawing
+	screen.InjectKey(tcell.KeyRune, 'a', tcell.ModNone)
+	if frames.ready(start.Add(time.Second)) {
+		t.Error("frame ready with an event pending")
+	}
+	screen.PollEvent()
+	if !frames.ready(start.Add(time.Second)) {
+		t.Error("frame not ready once events are handled")
+	}
+}
+
+func TestFrameSchedulerUncapped(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+
+	frames := newFrameScheduler(screen, 0)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		frames.request()
+		if !frames.ready(now) {
+			t.Fatalf("uncapped frame %d not ready", i)
+		}
+		frames.drawn(now)
+	}
+}
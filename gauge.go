@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// gauge is a dial for live typing speed: a half-circle arc of block
+// characters that fills from left to right as WPM rises. Its track is
+// banded by the player's own numbers, plain below their average, yellow
+// from there to their personal best and green beyond it, so a glance says
+// how the run is going without reading a number.
+type gauge struct {
+	average float64 // the player's recent average WPM
+	best    float64 // their best WPM, or 0 before they have one
+	full    float64 // the WPM that fills the arc
+}
+
+// Gauge track styles, by band
+var (
+	gaugeStyleBelow   = tcell.StyleDefault
+	gaugeStyleAverage = tcell.StyleDefault.Foreground(tcell.ColorYellow)
+	gaugeStyleBest    = tcell.StyleDefault.Foreground(tcell.ColorGreen)
+)
+
+// newGauge returns a gauge for a player with the given average and best,
+// scaled to leave room above whichever is higher
+func newGauge(average, best float64) gauge {
+	top := max(average, best, 40)
+	return gauge{average: average, best: best, full: math.Ceil(top*1.25/10) * 10}
+}
+
+// gaugeRadius is the size of the gauge on the test screen
+const gaugeRadius = 3
+
+// gaugeHeight is how many rows a gauge of radius rows takes, and
+// gaugeWidth how many columns; cells are about twice as tall as they are
+// wide, so the arc is twice as wide as it is high to look round
+func gaugeHeight(radius int) int { return radius + 1 }
+func gaugeWidth(radius int) int  { return 4*radius + 1 }
+
+// style is the track style at speed wpm
+func (g gauge) style(wpm float64) tcell.Style {
+	switch {
+	case g.best > 0 && wpm >= g.best:
+		return gaugeStyleBest
+	case wpm >= g.average:
+		return gaugeStyleAverage
+	}
+	return gaugeStyleBelow
+}
+
+// draw shows the gauge at wpm with its top left corner at x, y, with the
+// reading between the ends of the arc
+func (g gauge) draw(screen tcell.Screen, x, y, radius int, wpm float64) {
+	cx, cy := x+2*radius, y+radius
+	r := float64(radius)
+	for row := 0; row <= radius; row++ {
+		for col := -2 * radius; col <= 2*radius; col++ {
+			// Distance and angle from the center, in rows
+			dx, dy := float64(col)/2, float64(radius-row)
+			if d := math.Hypot(dx, dy); d < r-0.5 || d > r+0.5 {
+				continue
+			}
+			at := g.full * (1 - math.Atan2(dy, dx)/math.Pi)
+			glyph := '░'
+			if at <= wpm {
+				glyph = '█'
+			}
+			screen.SetContent(cx+col, y+row, glyph, nil, g.style(at))
+		}
+	}
+	drawCenteredText(screen, cx, cy, g.style(wpm).Bold(true), fmt.Sprintf("%.0f WPM", wpm))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestGauge(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(20, 6)
+
+	g := newGauge(40, 60)
+	if g.full != 80 {
+		t.Fatalf("gauge fills at %v WPM, want 80", g.full)
+	}
+	g.draw(screen, 0, 0, 3, 40)
+	for y, want := range []string{
+		"   ████░░░   ",
+		" ███     ░░░ ",
+		"██         ░░",
+		"██ 40 WPM  ░░",
+	} {
+		if got := cellText(screen, 0, y, 13); got != want {
+			t.Errorf("row %d = %q, want %q", y, got, want)
+		}
+	}
+
+	// The track is banded by the player's average and best
+	for _, tt := range []struct {
+		x, y int
+		want tcell.Style
+	}{
+		{0, 3, gaugeStyleBelow},
+		{6, 0, gaugeStyleAverage},
+		{11, 1, gaugeStyleBest},
+	} {
+		if _, _, style, _ := screen.GetContent(tt.x, tt.y); style != tt.want {
+			t.Errorf("cell %d,%d styled %v, want %v", tt.x, tt.y, style, tt.want)
+		}
+	}
+}
+
+func TestSpeedGaugeReplacesWPM(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(80, 24)
+
+	state := newTestState("the cat sat", "test.txt", &fakeClock{})
+	state.averageWPM = 40
+	state.typeRune('t')
+	screenText := func() string {
+		var rows []string
+		for y := 0; y < 24; y++ {
+			rows = append(rows, cellText(screen, 0, y, 80))
+		}
+		return strings.Join(rows, "\n")
+	}
+
+	renderScreen(screen, &state, 80, renderOptions{speedGauge: true})
+	if text := screenText(); !strings.Contains(text, "0 WPM") || strings.Contains(text, "WPM: ") {
+		t.Errorf("with the gauge on, the screen shows\n%s", text)
+	}
+	renderScreen(screen, &state, 80, renderOptions{})
+	if text := screenText(); strings.Contains(text, "░") || !strings.Contains(text, "WPM: ") {
+		t.Errorf("with the gauge off, the screen shows\n%s", text)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+)
+
+// splitGraphemes splits text into grapheme clusters: what a reader sees as
+// one character, such as "e" plus a combining accent, a flag, or an emoji
+// with a skin tone or joined by ZWJs. Clusters are the unit of scoring, so
+// an emoji that takes several code points to enter is still one character.
+func splitGraphemes(text string) []string {
+	// At most one cluster per rune; one per byte would overallocate a long
+	// non-ASCII text several times over
+	clusters := make([]string, 0, utf8.RuneCountInString(text))
+	state := -1
+	for text != "" {
+		var cluster string
+		cluster, text, _, state = uniseg.FirstGraphemeClusterInString(text, state)
+		clusters = append(clusters, cluster)
+	}
+	return clusters
+}
+
+// displayWidth is how many cells text takes as drawText draws it: each
+// grapheme cluster at its display width, but never less than a cell, so a
+// stray combining mark or zero-width character still moves the cursor on
+func displayWidth(text string) int {
+	width := 0
+	state := -1
+	for text != "" {
+		var cluster string
+		cluster, text, _, state = uniseg.FirstGraphemeClusterInString(text, state)
+		width += max(1, runewidth.StringWidth(cluster))
+	}
+	return width
+}
+
+// extendsCluster reports whether appending r to a string ending in the
+// cluster last would make it part of that cluster rather than start a new one
+func extendsCluster(last string, r rune) bool {
+	if last == "" {
+		return false
+	}
+	joined := last + string(r)
+	cluster, _, _, _ := uniseg.FirstGraphemeClusterInString(joined, -1)
+	return len(cluster) == len(joined)
+}
+
+// isEmoji reports whether a grapheme cluster is an emoji: anything from the
+// pictograph blocks (which include flags and skin tones), anything asking
+// for emoji presentation or forming a keycap, and the wide symbols from
+// the Miscellaneous Symbols and Dingbats blocks such as ☕ and ✅
+func isEmoji(cluster string) bool {
+	r, _ := utf8.DecodeRuneInString(cluster)
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF:
+		return true
+	case strings.ContainsAny(cluster, "\ufe0f\u20e3"):
+		return true
+	case r >= 0x2600 && r <= 0x27BF:
+		return runewidth.RuneWidth(r) == 2
+	}
+	return false
+}
+
+// stripEmoji removes emoji from text for players who can't type them.
+// Lines that lose an emoji have their spacing tidied so no double or
+// trailing spaces are left where it was.
+func stripEmoji(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		var kept strings.Builder
+		stripped := false
+		for _, cluster := range splitGraphemes(line) {
+			if isEmoji(cluster) {
+				stripped = true
+				continue
+			}
+			kept.WriteString(cluster)
+		}
+		if stripped {
+			lines[i] = strings.Join(strings.Fields(kept.String()), " ")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
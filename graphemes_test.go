@@ -0,0 +1,108 @@
+package main
+
+import "testing"
+
+func TestTypeRuneGraphemeClusters(t *testing.T) {
+	const thumbs = "\U0001F44D\U0001F3FD"      // thumbs up, medium skin tone
+	const coder = "\U0001F469\u200d\U0001F4BB" // woman technologist (ZWJ)
+
+	tests := []struct {
+		name   string
+		ref    string
+		typed  string
+		errors int
+		typedN int
+	}{
+		{"skin tone is one character", "a" + thumbs + "b", "a" + thumbs + "b", 0, 3},
+		{"ZWJ sequence is one character", coder + "!", coder + "!", 0, 2},
+		{"combining accent joins its letter", "cafe\u0301", "cafe\u0301", 0, 4},
+		{"abandoned sequence is one error", thumbs + " ok", "\U0001F44D ok", 1, 4},
+		{"wrong accent counts once", "ex", "e\u0300\u0301", 1, 1},
+		{"wrong emoji", "\U0001F600", "\U0001F601", 1, 1},
+		{"typographic punctuation", "“Naïve”—it’s", "“Naïve”—it’s", 0, 12},
+		{"straight quote for a curly one", "it’s", "it's", 1, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := newTestState(tt.ref, "test.txt", &fakeClock{})
+			for _, r := range tt.typed {
+				state.typeRune(r)
+			}
+			if state.errors != tt.errors {
+				t.Errorf("errors = %d, want %d", state.errors, tt.errors)
+			}
+			if state.typed() != tt.typedN {
+				t.Errorf("typed = %d, want %d", state.typed(), tt.typedN)
+			}
+			if complete := tt.typed == tt.ref; state.testComplete != complete {
+				t.Errorf("complete = %v, want %v", state.testComplete, complete)
+			}
+		})
+	}
+}
+
+func TestBackspaceRemovesCluster(t *testing.T) {
+	flag := "\U0001F1EC\U0001F1E7" // GB
+	state := newTestState("a"+flag, "test.txt", &fakeClock{})
+	for _, r := range "a" + flag {
+		state.typeRune(r)
+	}
+	if !state.testComplete {
+		t.Fatal("test not complete after typing the flag")
+	}
+
+	state = newTestState("a"+flag+"b", "test.txt", &fakeClock{})
+	for _, r := range "a" + flag {
+		state.typeRune(r)
+	}
+	state.backspace()
+	if state.userInput != "a" || state.typed() != 1 {
+		t.Errorf("after backspace input = %q (%d typed), want \"a\" (1)", state.userInput, state.typed())
+	}
+
+	// A multi-byte character typed wrong is removed whole, and the text
+	// can then be finished without further errors
+	state = newTestState("é—ok", "test.txt", &fakeClock{})
+	for _, r := range "é-" {
+		state.typeRune(r)
+	}
+	state.backspace()
+	for _, r := range "—ok" {
+		state.typeRune(r)
+	}
+	if !state.testComplete || state.errors != 1 {
+		t.Errorf("complete = %v with %d errors, want true with 1", state.testComplete, state.errors)
+	}
+}
+
+func TestStripEmoji(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Ship it \U0001F680 today", "Ship it today"},
+		{"Done \u2705\nNext line  kept", "Done\nNext line  kept"},
+		{"\U0001F469\u200d\U0001F4BB coding", "coding"},
+		{"cafe\u0301 \u65e5\u672c", "cafe\u0301 \u65e5\u672c"},
+		{"1\ufe0f\u20e3 first", "first"},
+	}
+	for _, tt := range tests {
+		if got := stripEmoji(tt.in); got != tt.want {
+			t.Errorf("stripEmoji(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"cafe\u0301", 4},
+		{"\U0001F469\u200d\U0001F4BB!", 3},
+		{"中文", 4},
+		{"\u0301", 1}, // a stray combining mark still takes a cell
+	}
+	for _, tt := range tests {
+		if got := displayWidth(tt.text); got != tt.want {
+			t.Errorf("displayWidth(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/phaedrus/keysmash/internal/chart"
+)
+
+// historySort is an order for the history screen, best first
+type historySort struct {
+	name string
+	less func(a, b result) bool
+}
+
+// historySorts are the orders the history screen cycles through
+var historySorts = []historySort{
+	{"date", func(a, b result) bool { return a.Completed.After(b.Completed) }},
+	{"WPM", func(a, b result) bool { return a.WPM > b.WPM }},
+	{"accuracy", func(a, b result) bool { return a.Accuracy > b.Accuracy }},
+	{"duration", func(a, b result) bool { return a.Duration < b.Duration }},
+}
+
+// sortedHistory returns the indexes of results in the given order; ties
+// keep the most recent first
+func sortedHistory(results []result, order historySort) []int {
+	indexes := make([]int, len(results))
+	for i := range indexes {
+		indexes[i] = len(results) - 1 - i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool { return order.less(results[indexes[i]], results[indexes[j]]) })
+	return indexes
+}
+
+// historyRow formats one result for the history screen
+func historyRow(r result, marked bool, width int) string {
+	mark := "  "
+	if marked {
+		mark = "* "
+	}
+	name := runewidth.FillRight(runewidth.Truncate(r.TestFile, 24, "..."), 24)
+	row := fmt.Sprintf("%s%s  %s  %5.1f WPM  %5.1f%%  %6.1fs", mark, r.Completed.Local().Format("2006-01-02 15:04"), name, r.WPM, r.Accuracy, r.Duration.Seconds())
+	for _, tag := range r.Tags {
+		row += "  #" + tag
+	}
+	if r.Failed {
+		row += fmt.Sprintf("  (failed at %d)", r.FailedAt+1)
+	}
+	if r.Peeks > 0 {
+		row += fmt.Sprintf("  (%d peeks)", r.Peeks)
+	}
+	if r.Excluded {
+		row += "  (excluded)"
+	}
+	return runewidth.Truncate(row, width, "")
+}
+
+// remove deletes the results at the given indexes
+func (s *resultStore) remove(indexes []int) {
+	doomed := make(map[int]bool, len(indexes))
+	var removed []result
+	for _, i := range indexes {
+		doomed[i] = true
+		removed = append(removed, s.Results[i])
+	}
+	s.audit("delete", "", "", removed)
+	kept := s.Results[:0]
+	for i, r := range s.Results {
+		if !doomed[i] {
+			kept = append(kept, r)
+		}
+	}
+	s.Results = kept
+}
+
+// tag adds tag to the results at the given indexes that don't have it
+func (s *resultStore) tag(indexes []int, tag string) {
+	var tagged []result
+	for _, i := range indexes {
+		r := &s.Results[i]
+		if !slices.Contains(r.Tags, tag) {
+			r.Tags = append(r.Tags, tag)
+			tagged = append(tagged, *r)
+		}
+	}
+	s.audit("tag", "", tag, tagged)
+}
+
+// setExcluded excludes the results at the given indexes from stats, or
+// with excluded false counts them again
+func (s *resultStore) setExcluded(indexes []int, excluded bool) {
+	var changed []result
+	for _, i := range indexes {
+		if s.Results[i].Excluded != excluded {
+			s.Results[i].Excluded = excluded
+			changed = append(changed, s.Results[i])
+		}
+	}
+	action := "include"
+	if excluded {
+		action = "exclude"
+	}
+	s.audit(action, "", "", changed)
+}
+
+// exportResults writes results to the exports directory as JSON, in the
+// results.json format so keysmash import reads it back, or as CSV for
+// spreadsheets. It returns the file's path.
+func exportResults(results []result, format string, now time.Time) (string, error) {
+	dir, err := dataFile("exports")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "history-"+now.Format("20060102-150405")+"."+format)
+	if format == "json" {
+		return path, writeJSONFile(path, resultStore{Results: results})
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	out := csv.NewWriter(f)
+	out.Write([]string{"completed", "test_file", "wpm", "accuracy", "errors", "duration_seconds", "tags", "excluded"})
+	for _, r := range results {
+		out.Write([]string{
+			r.Completed.Format(time.RFC3339),
+			r.TestFile,
+			strconv.FormatFloat(r.WPM, 'f', 1, 64),
+			strconv.FormatFloat(r.Accuracy, 'f', 1, 64),
+			strconv.Itoa(r.Errors),
+			strconv.FormatFloat(r.Duration.Seconds(), 'f', 1, 64),
+			strings.Join(r.Tags, ";"),
+			strconv.FormatBool(r.Excluded),
+		})
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// wpmTrend is the WPM of each result counted in stats, oldest first, for
+// the history screen's sparkline
+func wpmTrend(results []result) []float64 {
+	var trend []float64
+	for _, r := range results {
+		if !r.Excluded && !r.Failed {
+			trend = append(trend, r.WPM)
+		}
+	}
+	return trend
+}
+
+// showHistory lists every completed test until the player leaves with
+// Escape or Q. Tab or S changes the order. Space marks results for the bulk
+// actions, which otherwise act on the highlighted one: D deletes, T tags,
+// X excludes from stats (or counts them again), and J and C export to JSON
+// and CSV.
+func showHistory(screen tcell.Screen, results *resultStore) {
+	sortBy := 0
+	var list menu
+	marked := make(map[int]bool) // indexes into results.Results
+	message := ""
+	for {
+		order := historySorts[sortBy]
+		indexes := sortedHistory(results.Results, order)
+		list.clamp(len(indexes))
+
+		screen.Clear()
+		width, height := screen.Size()
+		drawCenteredText(screen, width/2, 1, tcell.StyleDefault, "HISTORY")
+		drawCenteredText(screen, width/2, 2, tcell.StyleDefault, fmt.Sprintf("%d tests, %d marked, sorted by %s", len(indexes), len(marked), order.name))
+		if len(indexes) == 0 {
+			drawCenteredText(screen, width/2, 4, tcell.StyleDefault, "No tests completed yet")
+		}
+		if trend := wpmTrend(results.Results); len(trend) > 1 {
+			drawCenteredText(screen, width/2, 3, tcell.StyleDefault, "WPM trend "+chart.Sparkline(trend, min(len(trend), width/2)))
+		}
+
+		left := min(4, width/10)
+		rows := max(1, height-7)
+		items := make([]string, len(indexes))
+		for i, index := range indexes {
+			items[i] = historyRow(results.Results[index], marked[index], width-2*left)
+		}
+		list.draw(screen, left, 4, rows, items)
+		drawCenteredText(screen, width/2, height-2, tcell.StyleDefault, "Space: mark  D: delete  T: tag  X: exclude  J/C: export  Tab/S: sort  ESC: back")
+		drawCenteredText(screen, width/2, height-1, tcell.StyleDefault, message)
+		screen.Show()
+		message = ""
+
+		// targets are the marked results, or else the highlighted one
+		targets := func() []int {
+			var chosen []int
+			for i := range marked {
+				chosen = append(chosen, i)
+			}
+			if len(chosen) == 0 && len(indexes) > 0 {
+				chosen = append(chosen, indexes[list.selected])
+			}
+			sort.Ints(chosen)
+			return chosen
+		}
+		save := func(done string) {
+			message = done
+			if err := results.save(); err != nil {
+				logger.Error("saving results failed", "err", err)
+				message = fmt.Sprintf("Error saving results: %v", err)
+			}
+		}
+
+		switch ev := screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			if list.handle(ev, len(indexes), rows) {
+				break
+			}
+			switch ev.Key() {
+			case tcell.KeyEscape:
+				return
+			case tcell.KeyTab:
+				sortBy = (sortBy + 1) % len(historySorts)
+				list.selected = 0
+			case tcell.KeyRune:
+				switch ev.Rune() {
+				case 'q', 'Q':
+					return
+				case 's', 'S':
+					sortBy = (sortBy + 1) % len(historySorts)
+					list.selected = 0
+				case ' ':
+					if len(indexes) > 0 {
+						i := indexes[list.selected]
+						if marked[i] {
+							delete(marked, i)
+						} else {
+							marked[i] = true
+						}
+						list.selected++
+					}
+				case 'd', 'D':
+					chosen := targets()
+					if len(chosen) == 0 {
+						break
+					}
+					drawCenteredText(screen, width/2, height-1, tcell.StyleDefault, fmt.Sprintf("Delete %d result(s) for good? Y to confirm", len(chosen)))
+					screen.Show()
+					if key, ok := waitForStart(screen, nil); !ok || key != 'y' && key != 'Y' {
+						break
+					}
+					results.remove(chosen)
+					marked = make(map[int]bool)
+					logger.Info("deleted results", "count", len(chosen))
+					save(fmt.Sprintf("Deleted %d result(s)", len(chosen)))
+				case 't', 'T':
+					chosen := targets()
+					if len(chosen) == 0 {
+						break
+					}
+					tag, ok := promptLine(screen, height-1, fmt.Sprintf("Tag %d result(s): ", len(chosen)))
+					if tag = strings.TrimSpace(tag); !ok || tag == "" {
+						break
+					}
+					results.tag(chosen, tag)
+					save(fmt.Sprintf("Tagged %d result(s) #%s", len(chosen), tag))
+				case 'x', 'X':
+					chosen := targets()
+					if len(chosen) == 0 {
+						break
+					}
+					// Exclude unless every target is excluded already
+					excluded := false
+					for _, i := range chosen {
+						excluded = excluded || !results.Results[i].Excluded
+					}
+					results.setExcluded(chosen, excluded)
+					if excluded {
+						save(fmt.Sprintf("Excluded %d result(s) from stats", len(chosen)))
+					} else {
+						save(fmt.Sprintf("Counting %d result(s) in stats again", len(chosen)))
+					}
+				case 'j', 'J', 'c', 'C':
+					chosen := targets()
+					if len(chosen) == 0 {
+						break
+					}
+					format := "json"
+					if ev.Rune() == 'c' || ev.Rune() == 'C' {
+						format = "csv"
+					}
+					exported := make([]result, len(chosen))
+					for n, i := range chosen {
+						exported[n] = results.Results[i]
+					}
+					path, err := exportResults(exported, format, time.Now())
+					message = fmt.Sprintf("Exported %d result(s) to %s", len(chosen), path)
+					if err != nil {
+						logger.Error("exporting results failed", "err", err)
+						message = fmt.Sprintf("Error exporting results: %v", err)
+					}
+				}
+			}
+		}
+	}
+}
+
+// promptLine reads a line of text typed at the bottom of the screen,
+// returning false if the player cancels with Escape
+func promptLine(screen tcell.Screen, y int, prompt string) (string, bool) {
+	var input inputField
+	for {
+		width, _ := screen.Size()
+		clearBox(screen, 0, y, 2, 1)
+		input.draw(screen, 2, y, width-2, prompt)
+		screen.Show()
+
+		switch ev := screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			switch ev.Key() {
+			case tcell.KeyEscape:
+				screen.HideCursor()
+				return "", false
+			case tcell.KeyEnter:
+				screen.HideCursor()
+				return input.String(), true
+			default:
+				input.handle(ev)
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSortedHistory(t *testing.T) {
+	day := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	results := []result{
+		{TestFile: "a.txt", Completed: day, WPM: 60, Accuracy: 99, Duration: 30 * time.Second},
+		{TestFile: "b.txt", Completed: day.Add(time.Hour), WPM: 80, Accuracy: 95, Duration: 40 * time.Second},
+		{TestFile: "c.txt", Completed: day.Add(2 * time.Hour), WPM: 60, Accuracy: 97, Duration: 20 * time.Second},
+	}
+	for _, tt := range []struct {
+		by   string
+		want []string
+	}{
+		{"date", []string{"c.txt", "b.txt", "a.txt"}},
+		{"WPM", []string{"b.txt", "c.txt", "a.txt"}}, // a tie keeps the later run first
+		{"accuracy", []string{"a.txt", "c.txt", "b.txt"}},
+		{"duration", []string{"c.txt", "a.txt", "b.txt"}},
+	} {
+		var order historySort
+		for _, o := range historySorts {
+			if o.name == tt.by {
+				order = o
+			}
+		}
+		var got []string
+		for _, i := range sortedHistory(results, order) {
+			got = append(got, results[i].TestFile)
+		}
+		if len(got) != len(tt.want) || got[0] != tt.want[0] || got[1] != tt.want[1] || got[2] != tt.want[2] {
+			t.Errorf("by %s: %v, want %v", tt.by, got, tt.want)
+		}
+	}
+}
+
+func TestHistoryBulkActions(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	day := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	store := &resultStore{Results: []result{
+		{TestFile: "a.txt", Completed: day, WPM: 60},
+		{TestFile: "b.txt", Completed: day.Add(time.Hour), WPM: 200}, // a cat on the keyboard
+		{TestFile: "c.txt", Completed: day.Add(2 * time.Hour), WPM: 70},
+		{TestFile: "d.txt", Completed: day.Add(3 * time.Hour), WPM: 80},
+	}}
+
+	store.setExcluded([]int{1}, true)
+	if got := store.recentWPM(10); len(got) != 3 || got[1] != 70 {
+		t.Errorf("recentWPM with b excluded = %v", got)
+	}
+	if _, ok := store.textStats(textIndex{})["b.txt"]; ok {
+		t.Error("an excluded run counted in the picker's stats")
+	}
+
+	store.tag([]int{2, 3}, "travel")
+	store.tag([]int{3}, "travel")
+	if tags := store.Results[3].Tags; len(tags) != 1 || tags[0] != "travel" {
+		t.Errorf("tags = %v after tagging twice", tags)
+	}
+
+	path, err := exportResults(store.Results[2:], "json", day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := loadResultStore(path)
+	if err != nil || len(exported.Results) != 2 || exported.Results[0].Tags[0] != "travel" {
+		t.Errorf("exported JSON = %+v, %v", exported, err)
+	}
+	path, err = exportResults(store.Results[2:], "csv", day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 3 || !strings.HasSuffix(lines[1], ",travel,false") {
+		t.Errorf("exported CSV:\n%s", data)
+	}
+
+	store.remove([]int{0, 2})
+	if len(store.Results) != 2 || store.Results[0].TestFile != "b.txt" || store.Results[1].TestFile != "d.txt" {
+		t.Errorf("after removing a and c: %+v", store.Results)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"time"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// needsIME reports whether text is written in a script that's typed
+// through an input method: Chinese, Japanese or Korean
+func needsIME(text string) bool {
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return true
+		}
+	}
+	return false
+}
+
+// drawCursor marks the insertion point at (x, y).
+//
+// With an input method the terminal, not keysmash, owns the text being
+// composed: it draws the pre-edit string at its own cursor and only sends
+// the characters once they're committed, so only committed characters are
+// ever scored. The terminal cursor is therefore moved to the insertion
+// point, where the pre-edit appears in the terminal's own composing style,
+// and no cursor is drawn over it. Otherwise the cursor is drawn, blinking
+// unless motion is reduced.
+func drawCursor(screen tcell.Screen, x, y int, now time.Time, opts renderOptions) {
+	if opts.inputMethod {
+		screen.ShowCursor(x, y)
+		return
+	}
+	if opts.reducedMotion || cursorBlinkOn(now) {
+		screen.SetContent(x, y, ' ', nil, tcell.StyleDefault.Reverse(true))
+	} else {
+		screen.SetContent(x, y, '_', nil, tcell.StyleDefault)
+	}
+}
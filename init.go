@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// runInit creates the tests directory dir, with its parents, and copies
+// the built-in texts into it. Texts already there are left alone, so it
+// can be run again to restore any that were deleted.
+func runInit(w io.Writer, dir string) int {
+	if dir == "" {
+		fmt.Fprintln(w, "Error: no data directory: set XDG_DATA_HOME or HOME")
+		return 1
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	entries, err := fs.ReadDir(builtinTexts(), ".")
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	added := 0
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if _, err := os.Stat(path); err == nil {
+			continue
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(w, "Error: %v\n", err)
+			return 1
+		}
+		content, err := fs.ReadFile(builtinTexts(), entry.Name())
+		if err == nil {
+			err = os.WriteFile(path, content, 0o644)
+		}
+		if err != nil {
+			fmt.Fprintf(w, "Error: %v\n", err)
+			return 1
+		}
+		added++
+	}
+	fmt.Fprintf(w, "Tests directory: %s (%d built-in texts added)\n", dir, added)
+	fmt.Fprintln(w, "Add .txt files of your own there to practise on them")
+	return 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunInit(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keysmash", "tests")
+	var out strings.Builder
+	if code := runInit(&out, dir); code != 0 {
+		t.Fatalf("exit %d: %s", code, out.String())
+	}
+	files, err := listTextFiles(dir)
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no texts seeded: %v, %v", files, err)
+	}
+
+	// Running it again restores deleted texts and keeps edited ones
+	if err := os.Remove(filepath.Join(dir, files[0].Name())); err != nil {
+		t.Fatal(err)
+	}
+	edited := filepath.Join(dir, files[1].Name())
+	if err := os.WriteFile(edited, []byte("mine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if code := runInit(&out, dir); code != 0 || !strings.Contains(out.String(), "1 built-in texts added") {
+		t.Errorf("second run: exit %d: %s", code, out.String())
+	}
+	if content, _ := os.ReadFile(edited); string(content) != "mine" {
+		t.Errorf("edited text overwritten: %q", content)
+	}
+
+	if code := runInit(io.Discard, ""); code == 0 {
+		t.Error("no data directory: exit 0")
+	}
+}
+
+func TestFindTestsDir(t *testing.T) {
+	data := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", data)
+	t.Setenv(testsDirEnv, "")
+	seeded := filepath.Join(data, "keysmash", "tests")
+	if err := os.MkdirAll(seeded, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got := findTestsDir(); got != seeded {
+		t.Errorf("findTestsDir() = %q, want the data directory's %q", got, seeded)
+	}
+
+	chosen := t.TempDir()
+	t.Setenv(testsDirEnv, chosen)
+	if got := findTestsDir(); got != chosen {
+		t.Errorf("findTestsDir() = %q, want $%s's %q", got, testsDirEnv, chosen)
+	}
+}
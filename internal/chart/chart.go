@@ -0,0 +1,206 @@
+// Package chart draws graphs as lines of text for the terminal: line
+// charts in braille dots, bar charts and sparklines in block elements, and
+// heatmaps in shades. Each chart fits any size it's given in cells,
+// averaging values together when there are more than fit and stretching
+// them when there are fewer, and returns its rows top first, for the
+// caller to draw wherever it likes.
+package chart
+
+import (
+	"math"
+	"strings"
+)
+
+// blocks are the eighths of a cell a bar can fill, from empty to full
+var blocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// shades are a heatmap's cells, from lowest to highest
+var shades = []rune(" ░▒▓█")
+
+// braille dot bits by column and by row down the cell; a cell holds 2 by 4
+// dots on top of U+2800
+var brailleDots = [2][4]rune{
+	{0x01, 0x02, 0x04, 0x40},
+	{0x08, 0x10, 0x20, 0x80},
+}
+
+// Line draws values as a line through width by height cells, with two
+// points across and four down each cell, scaled from the lowest value at
+// the bottom to the highest at the top. Consecutive points are joined, so
+// steep changes still read as one line.
+func Line(values []float64, width, height int) []string {
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+	dots := make([][]rune, height)
+	for y := range dots {
+		dots[y] = make([]rune, width)
+	}
+	points := resample(values, width*2)
+	lo, hi := span(points)
+	rows := height * 4
+	prev := -1
+	for x, v := range points {
+		if math.IsNaN(v) {
+			prev = -1
+			continue
+		}
+		y := scale(v, lo, hi, rows-1)
+		from, to := y, y
+		if prev >= 0 {
+			from, to = min(y, prev), max(y, prev)
+		}
+		for dot := from; dot <= to; dot++ {
+			row := rows - 1 - dot // dots count up, rows count down
+			dots[row/4][x/2] |= brailleDots[x%2][row%4]
+		}
+		prev = y
+	}
+	lines := make([]string, height)
+	for y, row := range dots {
+		var b strings.Builder
+		for _, d := range row {
+			b.WriteRune(0x2800 + d)
+		}
+		lines[y] = b.String()
+	}
+	return lines
+}
+
+// Bar draws values as bars up from zero through width by height cells,
+// one column to a bar, scaled so the highest reaches the top. Bars rise in
+// eighths of a cell; negative values draw nothing.
+func Bar(values []float64, width, height int) []string {
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+	bars := resample(values, width)
+	_, hi := span(bars)
+	hi = max(hi, 0)
+	lines := make([][]rune, height)
+	for y := range lines {
+		lines[y] = []rune(strings.Repeat(" ", width))
+	}
+	for x, v := range bars {
+		if math.IsNaN(v) || v <= 0 {
+			continue
+		}
+		eighths := scale(v, 0, hi, height*8)
+		for y := 0; y < height; y++ {
+			fill := eighths - (height-1-y)*8
+			lines[y][x] = blocks[max(0, min(8, fill))]
+		}
+	}
+	return joinRows(lines)
+}
+
+// Sparkline draws values in a single row of width cells, scaled from the
+// lowest value to the highest so that small changes still show. Unlike a
+// bar chart every value gets at least the lowest block, so a dip to the
+// minimum doesn't read as a gap.
+func Sparkline(values []float64, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	points := resample(values, width)
+	lo, hi := span(points)
+	line := make([]rune, len(points))
+	for x, v := range points {
+		line[x] = ' '
+		if !math.IsNaN(v) {
+			line[x] = blocks[1+scale(v, lo, hi, 7)]
+		}
+	}
+	return string(line) + strings.Repeat(" ", width-len(points))
+}
+
+// Heatmap draws a grid of values a cell each, shaded by how high each is
+// from zero to the highest in the grid. NaN marks a cell with no value,
+// which is left blank.
+func Heatmap(grid [][]float64) []string {
+	hi := 0.0
+	for _, row := range grid {
+		_, rowHi := span(row)
+		if !math.IsNaN(rowHi) {
+			hi = max(hi, rowHi)
+		}
+	}
+	lines := make([][]rune, len(grid))
+	for y, row := range grid {
+		lines[y] = make([]rune, len(row))
+		for x, v := range row {
+			lines[y][x] = ' '
+			switch {
+			case math.IsNaN(v):
+			case hi <= 0:
+				lines[y][x] = shades[0] // nothing above zero to compare against
+			default:
+				lines[y][x] = shades[scale(max(0, v), 0, hi, len(shades)-1)]
+			}
+		}
+	}
+	return joinRows(lines)
+}
+
+// resample fits values to n points: averaging runs of them when there are
+// more than n, and repeating each when there are fewer. NaNs are left out
+// of averages, and a run of nothing but NaN averages to NaN.
+func resample(values []float64, n int) []float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	points := make([]float64, n)
+	for i := range points {
+		from := i * len(values) / n
+		to := max(from+1, (i+1)*len(values)/n)
+		sum, count := 0.0, 0
+		for _, v := range values[from:to] {
+			if !math.IsNaN(v) {
+				sum += v
+				count++
+			}
+		}
+		points[i] = math.NaN()
+		if count > 0 {
+			points[i] = sum / float64(count)
+		}
+	}
+	return points
+}
+
+// span is the lowest and highest of values, ignoring NaN, or NaN if
+// there's nothing else
+func span(values []float64) (lo, hi float64) {
+	lo, hi = math.NaN(), math.NaN()
+	for _, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		if math.IsNaN(lo) || v < lo {
+			lo = v
+		}
+		if math.IsNaN(hi) || v > hi {
+			hi = v
+		}
+	}
+	return lo, hi
+}
+
+// scale maps v from lo..hi onto 0..steps, rounding to the nearest step.
+// When every value is the same there's nothing to compare, so it goes in
+// the middle.
+func scale(v, lo, hi float64, steps int) int {
+	if !(hi > lo) {
+		return steps / 2
+	}
+	step := int(math.Round((v - lo) / (hi - lo) * float64(steps)))
+	return max(0, min(steps, step))
+}
+
+func joinRows(rows [][]rune) []string {
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = string(row)
+	}
+	return lines
+}
@@ -0,0 +1,86 @@
+package chart
+
+import (
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden compares a chart with testdata/name.golden, or rewrites the file
+// with -update
+func golden(t *testing.T, name string, lines []string) {
+	t.Helper()
+	got := strings.Join(lines, "\n") + "\n"
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from %s:\n%s\nwant:\n%s", name, path, got, want)
+	}
+}
+
+// wpm is a run that starts slow, settles, and stumbles near the end
+var wpm = []float64{20, 35, 48, 55, 61, 64, 62, 66, 70, 68, 71, 40, 52, 67, 72, 74}
+
+func TestLine(t *testing.T) {
+	golden(t, "line", Line(wpm, 16, 4))
+	golden(t, "line-narrow", Line(wpm, 3, 2)) // more values than points
+	golden(t, "line-gap", Line([]float64{1, 2, math.NaN(), 3, 1}, 5, 2))
+}
+
+func TestBar(t *testing.T) {
+	golden(t, "bar", Bar(wpm, 16, 3))
+	golden(t, "bar-wide", Bar([]float64{1, 3, 2}, 9, 2)) // fewer values than columns
+}
+
+func TestSparkline(t *testing.T) {
+	golden(t, "sparkline", []string{Sparkline(wpm, 16), Sparkline(wpm, 8), Sparkline([]float64{5, 5, 5}, 3)})
+}
+
+func TestHeatmap(t *testing.T) {
+	golden(t, "heatmap", Heatmap([][]float64{
+		{0, 1, 2, 3, 4},
+		{4, 3, math.NaN(), 1, 0},
+		{0, 0, 0, 0, 8},
+	}))
+}
+
+// Charts fill exactly the size they're given, whatever the data
+func TestChartSizes(t *testing.T) {
+	for _, values := range [][]float64{nil, {7}, {math.NaN()}, wpm, {-3, 0, 3}} {
+		for _, size := range [][2]int{{1, 1}, {5, 2}, {40, 6}} {
+			width, height := size[0], size[1]
+			for name, lines := range map[string][]string{
+				"Line":      Line(values, width, height),
+				"Bar":       Bar(values, width, height),
+				"Sparkline": {Sparkline(values, width)},
+			} {
+				if name != "Sparkline" && len(lines) != height {
+					t.Errorf("%s(%v, %d, %d) has %d rows", name, values, width, height, len(lines))
+				}
+				for _, line := range lines {
+					if n := utf8.RuneCountInString(line); n != width {
+						t.Errorf("%s(%v, %d, %d) row %q is %d cells wide", name, values, width, height, line, n)
+					}
+				}
+			}
+		}
+	}
+	if lines := Line(wpm, 0, 4); lines != nil {
+		t.Errorf("Line with no width = %q", lines)
+	}
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+
+	"github.com/phaedrus/keysmash/internal/chart"
+)
+
+// keyLogEntry is one keystroke as kept in the keystroke log: a scored
+// character, or a backspace, typed as \b. The log is only ever appended
+// to, and everything about which keys the player misses or is slow on is
+// worked out from it when asked.
+type keyLogEntry struct {
+	Time     time.Time `json:"t"`
+	TestFile string    `json:"file"`
+	Position int       `json:"pos"`
+	Typed    string    `json:"typed"`
+	Expected string    `json:"want,omitempty"` // empty past the end of the text
+	Correct  bool      `json:"ok"`             // for a backspace, whether it was needed
+	Deleted  string    `json:"deleted,omitempty"`
+	GapMs    float64   `json:"ms,omitempty"` // since the keystroke before it, 0 for a test's first
+}
+
+// keyLog collects a test's keystrokes and appends them to the log at
+// path, keeping a tally of everything logged
+type keyLog struct {
+	path    string
+	pending []keyLogEntry
+	tally   *keyTally
+	daemon  *daemonClient // if set, the log is the daemon's, appended to through it
+}
+
+func keyLogPath() (string, error) {
+	return dataFile("keystrokes.jsonl")
+}
+
+func openKeyLog() (*keyLog, error) {
+	path, err := keyLogPath()
+	if err != nil {
+		return nil, err
+	}
+	return loadKeyLog(path)
+}
+
+// loadKeyLog reads the log at path to start its tally
+func loadKeyLog(path string) (*keyLog, error) {
+	entries, err := readKeyLog(path)
+	if err != nil {
+		return nil, err
+	}
+	log := &keyLog{path: path, tally: newKeyTally()}
+	for _, e := range entries {
+		log.tally.add(e)
+	}
+	return log, nil
+}
+
+// flush appends the keystrokes collected since the last flush to the log,
+// one JSON object per line
+func (l *keyLog) flush() error {
+	if l.path == "" || len(l.pending) == 0 {
+		return nil
+	}
+	if l.daemon != nil {
+		if err := l.daemon.appendKeys(l.pending); err != nil {
+			return err
+		}
+	}
+	if l.daemon != nil || readOnly {
+		// Not written here, but still counted for this session's analytics
+		for _, entry := range l.pending {
+			l.tally.add(entry)
+		}
+		l.pending = nil
+		return nil
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+	for _, entry := range l.pending {
+		if err := encoder.Encode(entry); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	for _, entry := range l.pending {
+		l.tally.add(entry)
+	}
+	l.pending = nil
+	return f.Close()
+}
+
+// trackKeystrokes logs every scored keystroke and backspace. They're written out when a
+// test completes, and those of an abandoned test when the next one starts
+// or keysmash quits, so the input loop never waits on the disk mid-test.
+func trackKeystrokes(bus *EventBus, log *keyLog) {
+	var prev time.Time
+	flush := func() {
+		if err := log.flush(); err != nil {
+			logger.Error("saving keystroke log failed", "err", err)
+		}
+	}
+	bus.Subscribe(func(ev Event) {
+		switch ev.Kind {
+		case EventTestStarted:
+			flush()
+			prev = time.Time{}
+
+		case EventKeystrokeScored, EventBackspace:
+			entry := keyLogEntry{
+				Time:     ev.Time,
+				TestFile: ev.TestFile,
+				Position: ev.Position,
+				Typed:    string(ev.Rune),
+				Expected: ev.Expected,
+				Correct:  ev.Correct,
+				Deleted:  ev.Deleted,
+			}
+			if !prev.IsZero() {
+				entry.GapMs = float64(ev.Time.Sub(prev).Microseconds()) / 1000
+			}
+			log.pending = append(log.pending, entry)
+			prev = ev.Time
+
+		case EventTestCompleted, EventTestFailed:
+			flush()
+		}
+	}, EventTestStarted, EventKeystrokeScored, EventBackspace, EventTestCompleted, EventTestFailed)
+}
+
+// readKeyLog reads the keystroke log at path, oldest first. A missing log
+// is empty.
+func readKeyLog(path string) ([]keyLogEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []keyLogEntry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		var entry keyLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// keyStatMinSamples is how many times a key must have come up before its
+// miss rate or speed is ranked, so one slip on a rare key doesn't top the
+// list
+const keyStatMinSamples = 10
+
+// keyStat is how the player does on one key of the reference text
+type keyStat struct {
+	Key     string
+	Count   int     // times it came up
+	Misses  int     // times it was typed wrong
+	TotalMs float64 // time taken over the Timed correct keystrokes
+	Timed   int
+}
+
+func (k keyStat) missRate() float64 { return float64(k.Misses) / float64(k.Count) }
+
+func (k keyStat) meanMs() float64 { return k.TotalMs / float64(k.Timed) }
+
+// substitution is a key typed in place of another
+type substitution struct {
+	Expected, Typed string
+	Count           int
+}
+
+// keyAnalytics is what the keystroke log says about each key
+type keyAnalytics struct {
+	Keystrokes    int
+	Keys          map[string]*keyStat
+	Missed        []keyStat // highest miss rate first
+	Slowest       []keyStat // slowest correct keystrokes first
+	MissedBigrams []keyStat // highest miss rate first
+	Substitutions []substitution
+	Backspacing   backspacing
+}
+
+// keyTally adds up logged keystrokes by the key the text asked for, as
+// they're logged, so the analytics never need the whole log reread. A
+// key's time is the gap before it was typed correctly straight after
+// another correct keystroke; gaps longer than skillMaxGap are pauses, and
+// aren't counted. A bigram, two keys in a row within a word, is missed
+// when its second key is.
+type keyTally struct {
+	keystrokes int
+	keys       map[string]*keyStat
+	bigrams    map[string]*keyStat
+	subs       map[[2]string]int
+	prev       keyLogEntry
+
+	backspacing backspacing
+}
+
+func newKeyTally() *keyTally {
+	return &keyTally{
+		keys:    make(map[string]*keyStat),
+		bigrams: make(map[string]*keyStat),
+		subs:    make(map[[2]string]int),
+	}
+}
+
+// stat returns the entry for key in stats, adding it if it's new
+func stat(stats map[string]*keyStat, key string) *keyStat {
+	s := stats[key]
+	if s == nil {
+		s = &keyStat{Key: key}
+		stats[key] = s
+	}
+	return s
+}
+
+func (t *keyTally) add(e keyLogEntry) {
+	t.keystrokes++
+	prev := t.prev
+	t.prev = e
+	if e.Typed == string(backspaceKey) {
+		t.backspacing.backspace(e)
+		return
+	}
+	t.backspacing.end()
+	if e.Expected == "" {
+		return // typed past the end of the text
+	}
+	follows := prev.TestFile == e.TestFile && prev.Position == e.Position-1
+
+	key := stat(t.keys, e.Expected)
+	key.Count++
+	if !e.Correct {
+		key.Misses++
+		t.subs[[2]string{e.Expected, e.Typed}]++
+	} else if follows && prev.Correct && e.GapMs > 0 && e.GapMs <= float64(skillMaxGap.Milliseconds()) {
+		key.TotalMs += e.GapMs
+		key.Timed++
+	}
+
+	if follows && prev.Expected != "" && !isBlank(prev.Expected) && !isBlank(e.Expected) {
+		bigram := stat(t.bigrams, prev.Expected+e.Expected)
+		bigram.Count++
+		if !e.Correct {
+			bigram.Misses++
+		}
+	}
+}
+
+// isBlank reports whether key is whitespace, which separates words
+func isBlank(key string) bool {
+	return strings.TrimSpace(key) == ""
+}
+
+// analytics ranks what's been tallied so far
+func (t *keyTally) analytics() keyAnalytics {
+	a := keyAnalytics{Keystrokes: t.keystrokes, Keys: t.keys, Backspacing: t.backspacing.report()}
+	for _, stat := range t.keys {
+		if stat.Count >= keyStatMinSamples && stat.Misses > 0 {
+			a.Missed = append(a.Missed, *stat)
+		}
+		if stat.Timed >= keyStatMinSamples {
+			a.Slowest = append(a.Slowest, *stat)
+		}
+	}
+	for _, stat := range t.bigrams {
+		if stat.Count >= keyStatMinSamples && stat.Misses > 0 {
+			a.MissedBigrams = append(a.MissedBigrams, *stat)
+		}
+	}
+	byMissRate := func(stats []keyStat) func(i, j int) bool {
+		return func(i, j int) bool {
+			if ri, rj := stats[i].missRate(), stats[j].missRate(); ri != rj {
+				return ri > rj
+			}
+			return stats[i].Key < stats[j].Key
+		}
+	}
+	sort.Slice(a.Missed, byMissRate(a.Missed))
+	sort.Slice(a.MissedBigrams, byMissRate(a.MissedBigrams))
+	sort.Slice(a.Slowest, func(i, j int) bool {
+		if mi, mj := a.Slowest[i].meanMs(), a.Slowest[j].meanMs(); mi != mj {
+			return mi > mj
+		}
+		return a.Slowest[i].Key < a.Slowest[j].Key
+	})
+	for pair, n := range t.subs {
+		a.Substitutions = append(a.Substitutions, substitution{Expected: pair[0], Typed: pair[1], Count: n})
+	}
+	sort.Slice(a.Substitutions, func(i, j int) bool {
+		si, sj := a.Substitutions[i], a.Substitutions[j]
+		if si.Count != sj.Count {
+			return si.Count > sj.Count
+		}
+		if si.Expected != sj.Expected {
+			return si.Expected < sj.Expected
+		}
+		return si.Typed < sj.Typed
+	})
+	return a
+}
+
+// analyzeKeys tallies entries and ranks the result
+func analyzeKeys(entries []keyLogEntry) keyAnalytics {
+	t := newKeyTally()
+	for _, e := range entries {
+		t.add(e)
+	}
+	return t.analytics()
+}
+
+// keyName shows a key readably, naming the ones that are blank on screen
+func keyName(key string) string {
+	switch key {
+	case " ":
+		return "space"
+	case "\n":
+		return "enter"
+	case "\t":
+		return "tab"
+	case string(backspaceKey):
+		return "backspace"
+	}
+	return key
+}
+
+// keyboardRows is the layout the analytics heat map is drawn on
+var keyboardRows = []string{"1234567890-=", "qwertyuiop[]", "asdfghjkl;'", "zxcvbnm,./"}
+
+// missHeatmap lays the miss rate of each key out on keyboardRows, a row
+// of key labels above a row of shading for each. Upper and lower case
+// share a key; keys never missed, or not typed yet, are left unshaded.
+func (a keyAnalytics) missHeatmap() []string {
+	counts := make(map[rune][2]int) // count, misses
+	for key, stat := range a.Keys {
+		r := []rune(key)
+		if len(r) != 1 {
+			continue
+		}
+		k := unicode.ToLower(r[0])
+		c := counts[k]
+		counts[k] = [2]int{c[0] + stat.Count, c[1] + stat.Misses}
+	}
+	grid := make([][]float64, len(keyboardRows))
+	for y, row := range keyboardRows {
+		grid[y] = make([]float64, 2*len(row)-1)
+		for x := range grid[y] {
+			grid[y][x] = math.NaN()
+		}
+		for x, k := range row {
+			if c := counts[k]; c[0] > 0 {
+				grid[y][2*x] = float64(c[1]) / float64(c[0])
+			}
+		}
+	}
+	shading := chart.Heatmap(grid)
+	lines := make([]string, 0, 2*len(keyboardRows))
+	for y, row := range keyboardRows {
+		indent := strings.Repeat(" ", y)
+		lines = append(lines, indent+strings.Join(strings.Split(row, ""), " "), indent+shading[y])
+	}
+	return lines
+}
+
+// showKeyAnalytics shows which keys the player misses most, which are
+// slowest, what they type instead and how they backspace, until any key
+// is pressed
+func showKeyAnalytics(screen tcell.Screen, a keyAnalytics) {
+	for {
+		screen.Clear()
+		width, height := screen.Size()
+		drawCenteredText(screen, width/2, 1, tcell.StyleDefault, "KEY ANALYTICS")
+		drawCenteredText(screen, width/2, 2, tcell.StyleDefault, fmt.Sprintf("%d keystrokes logged", a.Keystrokes))
+
+		y := 4
+		if len(a.Keys) == 0 {
+			drawCenteredText(screen, width/2, y, tcell.StyleDefault, "Complete a test to start the log")
+		} else {
+			drawCenteredText(screen, width/2, y, tcell.StyleDefault, "Misses by key (darker is worse)")
+			for i, line := range a.missHeatmap() {
+				drawText(screen, (width-len(keyboardRows[0])*2)/2, y+1+i, tcell.StyleDefault, line)
+			}
+			y += 2 + 2*len(keyboardRows)
+		}
+
+		// Backspacing advice goes along the bottom, the lists above it
+		advice := a.Backspacing.advice()
+		for i, line := range advice {
+			drawText(screen, max(0, width/6-10), height-2-len(advice)+i, tcell.StyleDefault, line)
+		}
+		rows := max(0, height-y-3)
+		if advice != nil {
+			rows = max(0, rows-len(advice)-1)
+		}
+		columns := [3][]string{{"Most missed"}, {"Slowest"}, {"Typed instead"}}
+		for _, k := range a.Missed[:min(rows, len(a.Missed))] {
+			columns[0] = append(columns[0], fmt.Sprintf("%-9s %3.0f%% of %d", keyName(k.Key), k.missRate()*100, k.Count))
+		}
+		for _, k := range a.Slowest[:min(rows, len(a.Slowest))] {
+			columns[1] = append(columns[1], fmt.Sprintf("%-9s %4.0f ms", keyName(k.Key), k.meanMs()))
+		}
+		for _, s := range a.Substitutions[:min(rows, len(a.Substitutions))] {
+			columns[2] = append(columns[2], fmt.Sprintf("%s for %s  ×%d", keyName(s.Typed), keyName(s.Expected), s.Count))
+		}
+		for i, column := range columns {
+			x := width/6 + i*width/3 - 10
+			for j, line := range column {
+				drawText(screen, max(0, x), y+j, tcell.StyleDefault, line)
+			}
+		}
+		drawCenteredText(screen, width/2, height-1, tcell.StyleDefault, "Press any key to go back")
+		screen.Show()
+
+		switch screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			return
+		}
+	}
+}
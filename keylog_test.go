@@ -0,0 +1,135 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrackKeystrokes(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)}
+	engine := newEngine(clock, fixedRand(0), t.TempDir())
+	log, err := loadKeyLog(filepath.Join(t.TempDir(), "keystrokes.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trackKeystrokes(engine.events, log)
+
+	// An abandoned test is kept once the next one starts
+	abandoned := engine.newTest("hello", "a.txt")
+	abandoned.typeRune('h')
+	state := engine.newTest("the cat", "b.txt")
+	for _, r := range "thw" {
+		clock.advance(100 * time.Millisecond)
+		state.typeRune(r)
+	}
+	state.backspace()
+	for _, r := range "e cat" {
+		clock.advance(100 * time.Millisecond)
+		state.typeRune(r)
+	}
+	if !state.testComplete {
+		t.Fatal("test didn't complete")
+	}
+
+	entries, err := readKeyLog(log.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1+9 {
+		t.Fatalf("logged %d keystrokes, want 10: %+v", len(entries), entries)
+	}
+	if e := entries[0]; e.TestFile != "a.txt" || e.Typed != "h" || !e.Correct || e.GapMs != 0 {
+		t.Errorf("abandoned test's keystroke = %+v", e)
+	}
+	if e := entries[3]; e.Typed != "w" || e.Expected != "e" || e.Correct || e.GapMs != 100 {
+		t.Errorf("mistake = %+v", e)
+	}
+	if e := entries[4]; e.Typed != "\b" || e.Deleted != "w" || !e.Correct {
+		t.Errorf("correction = %+v", e)
+	}
+	if got := log.tally.analytics().Keystrokes; got != len(entries) {
+		t.Errorf("tallied %d keystrokes, logged %d", got, len(entries))
+	}
+}
+
+func TestAnalyzeKeys(t *testing.T) {
+	var entries []keyLogEntry
+	// "ab" twenty times: a always right, b slow and typed as v one time
+	// in four
+	for i := 0; i < 20; i++ {
+		entries = append(entries,
+			keyLogEntry{TestFile: "t", Position: 2 * i, Typed: "a", Expected: "a", Correct: true, GapMs: 100},
+			keyLogEntry{TestFile: "t", Position: 2*i + 1, Typed: "b", Expected: "b", Correct: i%4 != 0, GapMs: 300},
+		)
+		if i%4 == 0 {
+			entries[len(entries)-1].Typed = "v"
+		}
+	}
+	entries = append(entries, keyLogEntry{TestFile: "t", Position: 40, Typed: "x", Correct: false})
+
+	a := analyzeKeys(entries)
+	if a.Keystrokes != 41 {
+		t.Errorf("keystrokes = %d", a.Keystrokes)
+	}
+	if len(a.Missed) != 1 || a.Missed[0].Key != "b" || a.Missed[0].missRate() != 0.25 {
+		t.Errorf("missed = %+v", a.Missed)
+	}
+	if len(a.Slowest) != 2 || a.Slowest[0].Key != "b" || a.Slowest[0].meanMs() != 300 {
+		t.Errorf("slowest = %+v", a.Slowest)
+	}
+	if len(a.Substitutions) != 1 || a.Substitutions[0] != (substitution{Expected: "b", Typed: "v", Count: 5}) {
+		t.Errorf("substitutions = %+v", a.Substitutions)
+	}
+
+	heatmap := a.missHeatmap()
+	if !strings.HasPrefix(heatmap[4], "  a s d") {
+		t.Errorf("home row labels = %q", heatmap[4])
+	}
+	if shading := []rune(heatmap[7]); shading[3+2*4] != '█' || shading[3+2*3] != ' ' {
+		t.Errorf("bottom row shading = %q, want only b dark", heatmap[7])
+	}
+}
+
+func TestBackspacingAdvice(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)}
+	engine := newEngine(clock, fixedRand(0), t.TempDir())
+	log, err := loadKeyLog(filepath.Join(t.TempDir(), "keystrokes.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trackKeystrokes(engine.events, log)
+
+	// "quikc": two backspaces would reach the k, but the whole word goes.
+	// Then "brw" is fixed with the one backspace needed.
+	state := engine.newTest("quick brown", "a.txt")
+	typeAndErase := func(typed string, erase int) {
+		for _, r := range typed {
+			state.typeRune(r)
+		}
+		for i := 0; i < erase; i++ {
+			state.backspace()
+		}
+	}
+	typeAndErase("quikc", 5)
+	typeAndErase("quick brw", 1)
+	typeAndErase("own", 0)
+	if !state.testComplete {
+		t.Fatal("test didn't complete")
+	}
+
+	b := log.tally.analytics().Backspacing
+	if b.Backspaces != 6 || b.Unneeded != 3 || b.Overdone != 1 {
+		t.Errorf("backspacing = %+v", b)
+	}
+	advice := b.advice()
+	want := []string{
+		"Backspacing: 50% of 6 backspaces weren't needed",
+		"1 correction(s) erased 3 or more past the mistake; delete back to it, not the whole word",
+		`  erased "quikc" (5) where 2 would do`,
+	}
+	if strings.Join(advice, "\n") != strings.Join(want, "\n") {
+		t.Errorf("advice = %q, want %q", advice, want)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// keyActions are the welcome and results screen commands that can be
+// given other keys in config.toml's [keys] table, by name, with their
+// usual keys. The usual key keeps working alongside the new one.
+var keyActions = map[string]rune{
+	"retry":          'r',
+	"new_test":       'n',
+	"review":         'v',
+	"save_recording": 's',
+	"certificate":    'c',
+	"star":           'f',
+	"never_again":    'x',
+	"quit":           'q',
+	"history":        'h',
+	"key_stats":      'k',
+	"pick":           'p',
+	"archive":        'a',
+	"drill":          'd',
+	"weaknesses":     'w',
+	"queue":          'b',
+	"reduced_motion": 'm',
+	"help":           '?',
+}
+
+// keyBindings maps keys the player chose to the usual keys of the
+// commands they stand for
+type keyBindings map[rune]rune
+
+// bindings are the player's key bindings, set once at startup
+var bindings keyBindings
+
+// parseKeyBindings checks a [keys] table: each action known, each key a
+// single character, and no key given to two actions
+func parseKeyBindings(keys map[string]string) (keyBindings, error) {
+	b := make(keyBindings, len(keys))
+	actions := make(map[rune]string, len(keys))
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names) // for a stable error
+	for _, name := range names {
+		usual, ok := keyActions[name]
+		if !ok {
+			return nil, fmt.Errorf("keys: unknown action %q (want one of %s)", name, keyActionNames())
+		}
+		key, size := utf8.DecodeRuneInString(keys[name])
+		if size == 0 || size != len(keys[name]) {
+			return nil, fmt.Errorf("keys: %s must be a single character, not %q", name, keys[name])
+		}
+		if other, ok := actions[key]; ok {
+			return nil, fmt.Errorf("keys: %q is given to both %s and %s", keys[name], other, name)
+		}
+		for other, u := range keyActions {
+			if u == key && other != name {
+				return nil, fmt.Errorf("keys: %q is already the key for %s", keys[name], other)
+			}
+		}
+		actions[key] = name
+		b[key] = usual
+	}
+	return b, nil
+}
+
+// keyActionNames lists keyActions for messages
+func keyActionNames() string {
+	names := make([]string, 0, len(keyActions))
+	for name := range keyActions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// key returns the usual key of the command r is bound to, or r if it isn't
+// bound to one
+func (b keyBindings) key(r rune) rune {
+	if usual, ok := b[r]; ok {
+		return usual
+	}
+	return r
+}
+
+// boundTo returns the key the player bound to the command usually on
+// usual, or usual if there isn't one
+func (b keyBindings) boundTo(usual rune) rune {
+	for key, u := range b {
+		if u == usual {
+			return key
+		}
+	}
+	return usual
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseKeyBindings(t *testing.T) {
+	b, err := parseKeyBindings(map[string]string{"retry": "t", "quit": "Q"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for typed, want := range map[rune]rune{'t': 'r', 'Q': 'q', 'r': 'r', 'z': 'z'} {
+		if got := b.key(typed); got != want {
+			t.Errorf("key(%q) = %q, want %q", typed, got, want)
+		}
+	}
+	if got := b.boundTo('r'); got != 't' {
+		t.Errorf("boundTo('r') = %q, want 't'", got)
+	}
+	if got := b.boundTo('n'); got != 'n' {
+		t.Errorf("boundTo('n') = %q, want 'n'", got)
+	}
+
+	for _, bad := range []map[string]string{
+		{"retyr": "t"},
+		{"retry": "tt"},
+		{"retry": ""},
+		{"retry": "t", "quit": "t"},
+		{"retry": "n"},
+	} {
+		if _, err := parseKeyBindings(bad); err == nil {
+			t.Errorf("parseKeyBindings(%q) accepted an invalid table", bad)
+		}
+	}
+
+	var none keyBindings
+	if got := none.key('r'); got != 'r' {
+		t.Errorf("no bindings changed r to %q", got)
+	}
+}
+
+func TestLoadConfigSettings(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("tests_dir = \"" + filepath.ToSlash(dir) + "\"\nmode = \"words\"\ntheme = \"mono\"\ntarget_wpm = 80\n[keys]\nretry = \"t\"\n")
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TestsDir != dir || cfg.Mode != modeWords || cfg.Theme != "mono" || cfg.TargetWPM != 80 || cfg.Keys["retry"] != "t" {
+		t.Errorf("got %+v", cfg)
+	}
+
+	for _, bad := range []struct{ content, want string }{
+		{"tests_dir = \"" + filepath.ToSlash(path) + "\"", "tests_dir"},
+		{"mode = \"pack\"", "mode"},
+		{"theme = \"neon\"", "theme"},
+		{"[keys]\nretry = \"q\"", "keys"},
+		{"target_wpm = -1", "target_wpm"},
+	} {
+		write(bad.content)
+		if _, err := loadConfig(path); err == nil || !strings.Contains(err.Error(), bad.want) {
+			t.Errorf("%q: got %v, want an error about %s", bad.content, err, bad.want)
+		}
+	}
+}
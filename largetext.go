@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// Large text is a strip above the text, for players who find the usual
+// lines hard to read, showing the characters around the typing position
+// spaced out a cell apart and in bold, with the next one to type inverted
+// and pointed at. Terminals have no bigger font to draw in, so spacing and
+// weight are what's left; the strip takes rows from the text below.
+
+// largeTextHeight is how many rows the strip takes, with the gap below it
+const largeTextHeight = 3
+
+// drawLargeText draws the strip in width cells from x, y. The next
+// character sits a third of the way along, so most of what's shown is
+// still to come.
+func drawLargeText(screen tcell.Screen, state *TestState, x, y, width int) {
+	next := state.typed()
+	cells := func(i int) int {
+		return max(1, runewidth.StringWidth(state.reference[i])) + 1
+	}
+
+	// Back up from the next character a third of the width
+	first := min(next, len(state.reference))
+	for used := 0; first > 0 && used+cells(first-1) <= width/3; first-- {
+		used += cells(first - 1)
+	}
+
+	cx := x
+	for i := first; i < len(state.reference) && cx+cells(i) <= x+width; i++ {
+		cluster := state.reference[i]
+		switch cluster {
+		case "\n":
+			cluster = "⏎"
+		case "\t":
+			cluster = "⇥"
+		}
+		style := state.referenceStyle(i).Bold(true)
+		if i == next {
+			style = style.Reverse(true)
+			screen.SetContent(cx, y+1, '▲', nil, tcell.StyleDefault)
+		}
+		runes := []rune(cluster)
+		screen.SetContent(cx, y, runes[0], runes[1:], style)
+		cx += cells(i)
+	}
+}
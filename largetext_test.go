@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestLargeText(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(24, 2)
+
+	state := newTestState("one two\nthree four", "test.txt", &fakeClock{})
+	for _, r := range "one tw" {
+		state.typeRune(r)
+	}
+	drawLargeText(screen, &state, 0, 0, 24)
+
+	// The next character is a third of the way along, with the typed
+	// ones before it
+	if got, want := cellText(screen, 0, 0, 24), "e   t w o ⏎ t h r e e   "; got != want {
+		t.Errorf("strip = %q, want %q", got, want)
+	}
+	if got := strings.Index(cellText(screen, 0, 1, 24), "▲"); got != 8 {
+		t.Errorf("pointer at %d, want 8", got)
+	}
+	if _, _, style, _ := screen.GetContent(8, 0); style != styleUntyped.Bold(true).Reverse(true) {
+		t.Errorf("next character styled %v", style)
+	}
+	if _, _, style, _ := screen.GetContent(4, 0); style != tcell.StyleDefault.Bold(true) {
+		t.Errorf("typed character styled %v", style)
+	}
+}
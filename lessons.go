@@ -0,0 +1,285 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gdamore/tcell/v2"
+)
+
+// lessonFilePrefix marks lesson tests in TestState.testFile; the rest of
+// the name is the lesson's
+const lessonFilePrefix = "lesson-"
+
+// lesson is one step of a learn mode curriculum, read from a .toml file
+// in the lessons directory so teachers can write and share their own:
+//
+//	name = "Home row"
+//	description = "Fingers rest on asdf and jkl;"
+//	order = 1
+//	drills = ["asdf jkl; asdf jkl;", "a sad lad; a fall"]
+//
+//	[pass]
+//	wpm = 15
+//	accuracy = 95
+type lesson struct {
+	Name        string     `toml:"name"`
+	Description string     `toml:"description"`
+	Order       int        `toml:"order"`
+	Drills      []string   `toml:"drills"`
+	Pass        lessonPass `toml:"pass"`
+
+	file string
+}
+
+// lessonPass is what a completed drill needs to pass its lesson. Zero
+// values pass anything, so a lesson without them passes on completion.
+type lessonPass struct {
+	WPM      float64 `toml:"wpm"`
+	Accuracy float64 `toml:"accuracy"`
+}
+
+func (p lessonPass) met(ev Event) bool {
+	return ev.WPM >= p.WPM && ev.Accuracy >= p.Accuracy
+}
+
+func (p lessonPass) String() string {
+	switch {
+	case p.WPM > 0 && p.Accuracy > 0:
+		return fmt.Sprintf("%.0f WPM at %.0f%% accuracy", p.WPM, p.Accuracy)
+	case p.WPM > 0:
+		return fmt.Sprintf("%.0f WPM", p.WPM)
+	case p.Accuracy > 0:
+		return fmt.Sprintf("%.0f%% accuracy", p.Accuracy)
+	}
+	return "complete a drill"
+}
+
+// lessonsDir is where learn mode looks for lesson files
+func lessonsDir() (string, error) {
+	return dataFile("lessons")
+}
+
+// loadLessons reads every .toml file in dir as a lesson, in curriculum
+// order: by order, then by name. Like config.toml, unknown keys are errors
+// so a misspelt setting doesn't silently go missing.
+func loadLessons(dir string) ([]lesson, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return nil, err
+	}
+	var lessons []lesson
+	names := make(map[string]string)
+	for _, path := range paths {
+		var l lesson
+		meta, err := toml.DecodeFile(path, &l)
+		if err != nil {
+			return nil, fmt.Errorf("reading lesson %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, key := range undecoded {
+				keys[i] = key.String()
+			}
+			return nil, fmt.Errorf("lesson %s: unknown setting(s): %s", path, strings.Join(keys, ", "))
+		}
+		if err := l.validate(); err != nil {
+			return nil, fmt.Errorf("lesson %s: %w", path, err)
+		}
+		if other, dup := names[l.Name]; dup {
+			return nil, fmt.Errorf("lesson %s: name %q is already used by %s", path, l.Name, other)
+		}
+		names[l.Name] = path
+		l.file = path
+		lessons = append(lessons, l)
+	}
+	sort.SliceStable(lessons, func(i, j int) bool {
+		if lessons[i].Order != lessons[j].Order {
+			return lessons[i].Order < lessons[j].Order
+		}
+		return lessons[i].Name < lessons[j].Name
+	})
+	return lessons, nil
+}
+
+func (l lesson) validate() error {
+	if strings.TrimSpace(l.Name) == "" {
+		return errors.New("name is required")
+	}
+	if len(l.Drills) == 0 {
+		return errors.New("at least one drill is required")
+	}
+	for i, drill := range l.Drills {
+		if strings.TrimSpace(drill) == "" {
+			return fmt.Errorf("drill %d is empty", i+1)
+		}
+	}
+	if l.Pass.WPM < 0 || l.Pass.Accuracy < 0 || l.Pass.Accuracy > 100 {
+		return errors.New("pass wpm must not be negative and pass accuracy must be between 0 and 100")
+	}
+	return nil
+}
+
+// lessonProgress is kept in its own file, like daily challenge history
+type lessonProgress struct {
+	Lessons map[string]lessonRecord `json:"lessons"` // keyed by lesson name
+	path    string
+}
+
+// lessonRecord is how far the player has got with one lesson
+type lessonRecord struct {
+	Attempts int       `json:"attempts"`
+	Passed   time.Time `json:"passed,omitempty"`
+}
+
+func loadLessonProgress() (*lessonProgress, error) {
+	path, err := dataFile("lessons.json")
+	if err != nil {
+		return nil, err
+	}
+	progress := &lessonProgress{Lessons: make(map[string]lessonRecord), path: path}
+	if err := readJSONFile(path, progress); err != nil {
+		return nil, err
+	}
+	if progress.Lessons == nil {
+		progress.Lessons = make(map[string]lessonRecord)
+	}
+	return progress, nil
+}
+
+func (p *lessonProgress) save() error {
+	if p.path == "" {
+		return nil
+	}
+	return saveDataFile(p.path, p)
+}
+
+// record notes a completed drill of a lesson, passing the lesson if the
+// run met its criteria
+func (p *lessonProgress) record(l lesson, ev Event) {
+	record := p.Lessons[l.Name]
+	record.Attempts++
+	if record.Passed.IsZero() && l.Pass.met(ev) {
+		record.Passed = ev.Time
+	}
+	p.Lessons[l.Name] = record
+}
+
+// course is learn mode's curriculum and the player's progress through it
+type course struct {
+	lessons  []lesson
+	progress *lessonProgress
+}
+
+// current returns the index of the first lesson not yet passed, or
+// len(lessons) when every one has been
+func (c *course) current() int {
+	for i, l := range c.lessons {
+		if c.progress.Lessons[l.Name].Passed.IsZero() {
+			return i
+		}
+	}
+	return len(c.lessons)
+}
+
+func (c *course) lesson(name string) (lesson, bool) {
+	for _, l := range c.lessons {
+		if l.Name == name {
+			return l, true
+		}
+	}
+	return lesson{}, false
+}
+
+// lessonTest returns the next drill of the current lesson, taking its
+// drills in turn. Once every lesson is passed the last one is practised.
+func (e *Engine) lessonTest() (TestState, error) {
+	if len(e.course.lessons) == 0 {
+		dir, _ := lessonsDir()
+		return TestState{}, fmt.Errorf("no lessons found; add .toml lesson files to %s", dir)
+	}
+	l := e.course.lessons[min(e.course.current(), len(e.course.lessons)-1)]
+	attempts := e.course.progress.Lessons[l.Name].Attempts
+	logger.Info("selected lesson", "lesson", l.Name, "file", l.file, "drill", attempts%len(l.Drills))
+	text, err := e.prepareText(l.Drills[attempts%len(l.Drills)], lessonFilePrefix+l.Name)
+	if err != nil {
+		return TestState{}, err
+	}
+	return e.newTest(text, lessonFilePrefix+l.Name), nil
+}
+
+// recordLessonResults updates progress whenever a lesson drill completes
+func recordLessonResults(bus *EventBus, c *course) {
+	bus.Subscribe(func(ev Event) {
+		name, ok := strings.CutPrefix(ev.TestFile, lessonFilePrefix)
+		if !ok {
+			return
+		}
+		l, ok := c.lesson(name)
+		if !ok {
+			return
+		}
+		c.progress.record(l, ev)
+		if err := c.progress.save(); err != nil {
+			logger.Error("saving lesson progress failed", "err", err)
+		}
+	}, EventTestCompleted)
+}
+
+// loadCourse reads the lessons directory and the player's progress
+func loadCourse() (*course, error) {
+	dir, err := lessonsDir()
+	if err != nil {
+		return nil, err
+	}
+	// Created up front so players can see where lesson files go
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	lessons, err := loadLessons(dir)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := loadLessonProgress()
+	if err != nil {
+		return nil, err
+	}
+	return &course{lessons: lessons, progress: progress}, nil
+}
+
+// showLearnWelcomeScreen replaces the welcome screen in learn mode with
+// the current lesson and what it takes to pass
+func showLearnWelcomeScreen(screen tcell.Screen, c *course) {
+	screen.Clear()
+	width, height := screen.Size()
+
+	drawCenteredText(screen, width/2, height/2-7, tcell.StyleDefault, "KEYSMASH")
+	drawCenteredText(screen, width/2, height/2-5, tcell.StyleDefault, "LEARN")
+
+	current := c.current()
+	switch {
+	case len(c.lessons) == 0:
+		dir, _ := lessonsDir()
+		drawCenteredText(screen, width/2, height/2-2, tcell.StyleDefault, "No lessons yet; add .toml lesson files to "+dir)
+	case current == len(c.lessons):
+		drawCenteredText(screen, width/2, height/2-2, tcell.StyleDefault, fmt.Sprintf("All %d lessons passed! Keep practising the last one", len(c.lessons)))
+	default:
+		l := c.lessons[current]
+		drawCenteredText(screen, width/2, height/2-2, tcell.StyleDefault, fmt.Sprintf("Lesson %d of %d: %s", current+1, len(c.lessons), l.Name))
+		drawCenteredText(screen, width/2, height/2-1, tcell.StyleDefault, l.Description)
+		status := "To pass: " + l.Pass.String()
+		if attempts := c.progress.Lessons[l.Name].Attempts; attempts > 0 {
+			status += fmt.Sprintf(" (%d attempts so far)", attempts)
+		}
+		drawCenteredText(screen, width/2, height/2+1, tcell.StyleDefault, status)
+	}
+	drawCenteredText(screen, width/2, height/2+3, tcell.StyleDefault, "Press any key to start, Ctrl+P for commands, ESC to quit")
+
+	screen.Show()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeLesson(t *testing.T, dir, name, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadLessons(t *testing.T) {
+	dir := t.TempDir()
+	writeLesson(t, dir, "b.toml", `
+name = "Top row"
+order = 2
+drills = ["qwer uiop"]
+`)
+	writeLesson(t, dir, "a.toml", `
+name = "Home row"
+description = "Fingers on asdf and jkl;"
+order = 1
+drills = ["asdf jkl;", "a sad lad"]
+
+[pass]
+wpm = 15
+accuracy = 95
+`)
+	writeLesson(t, dir, "notes.txt", "not a lesson")
+
+	lessons, err := loadLessons(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lessons) != 2 || lessons[0].Name != "Home row" || lessons[1].Name != "Top row" {
+		t.Fatalf("lessons = %+v, want Home row then Top row", lessons)
+	}
+	if lessons[0].Pass != (lessonPass{WPM: 15, Accuracy: 95}) || len(lessons[0].Drills) != 2 {
+		t.Errorf("Home row = %+v", lessons[0])
+	}
+}
+
+func TestLoadLessonsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"unknown key", "name = \"x\"\ndrills = [\"a\"]\npass_wpm = 10", "unknown setting(s): pass_wpm"},
+		{"no name", "drills = [\"a\"]", "name is required"},
+		{"no drills", "name = \"x\"", "at least one drill"},
+		{"bad accuracy", "name = \"x\"\ndrills = [\"a\"]\n[pass]\naccuracy = 120", "accuracy must be between"},
+		{"syntax", "name = ", "reading lesson"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeLesson(t, dir, "lesson.toml", tt.body)
+			if _, err := loadLessons(dir); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+
+	dir := t.TempDir()
+	writeLesson(t, dir, "a.toml", "name = \"x\"\ndrills = [\"a\"]")
+	writeLesson(t, dir, "b.toml", "name = \"x\"\ndrills = [\"b\"]")
+	if _, err := loadLessons(dir); err == nil || !strings.Contains(err.Error(), "already used") {
+		t.Errorf("duplicate names: err = %v", err)
+	}
+}
+
+func TestLessonProgression(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}
+	engine := newEngine(clock, fixedRand(0), t.TempDir())
+	engine.mode = modeLearn
+	engine.course = &course{
+		lessons: []lesson{
+			{Name: "one", Drills: []string{"first", "second"}, Pass: lessonPass{WPM: 30, Accuracy: 90}},
+			{Name: "two", Drills: []string{"third"}},
+		},
+		progress: &lessonProgress{Lessons: make(map[string]lessonRecord)},
+	}
+	recordLessonResults(engine.events, engine.course)
+
+	// complete runs the next drill with the given results
+	complete := func(wpm, accuracy float64) string {
+		t.Helper()
+		state, err := engine.nextTest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		engine.events.Publish(Event{Kind: EventTestCompleted, Time: clock.now, TestFile: state.testFile, WPM: wpm, Accuracy: accuracy})
+		return state.referenceText
+	}
+
+	// A run that misses the criteria moves on to the lesson's next drill
+	if got := complete(40, 80); got != "first" {
+		t.Errorf("first drill = %q", got)
+	}
+	if got := complete(40, 95); got != "second" {
+		t.Errorf("second drill = %q", got)
+	}
+	if engine.course.current() != 1 {
+		t.Fatalf("current lesson = %d after passing, want 1", engine.course.current())
+	}
+	// No criteria passes on completion, and the last lesson is then repeated
+	complete(1, 1)
+	if engine.course.current() != 2 {
+		t.Errorf("current lesson = %d, want all passed", engine.course.current())
+	}
+	if got := complete(1, 1); got != "third" {
+		t.Errorf("drill after passing everything = %q, want the last lesson's", got)
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// libraryEntry is what keysmash remembers about a text beyond its contents
+type libraryEntry struct {
+	// Archived texts stay on disk, so past results still resolve, but are
+	// left out of random selection and listings unless --include-archived
+	// is given
+	Archived   bool      `json:"archived,omitempty"`
+	ArchivedAt time.Time `json:"archived_at,omitempty"`
+
+	// Starred texts are the player's favorites, which --favorites limits
+	// random selection to
+	Starred bool `json:"starred,omitempty"`
+
+	// Provenance, shown on the results screen so credit travels with the
+	// text. Set with `keysmash attribute`; anything that imports texts from
+	// the web should fill these in too.
+	Author  string `json:"author,omitempty"`
+	Source  string `json:"source,omitempty"` // usually a URL
+	License string `json:"license,omitempty"`
+
+	// Title and tags describe the text for the test picker's search
+	Title string   `json:"title,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// attribution formats the entry's provenance as a single line, or "" if
+// none is recorded
+func (e libraryEntry) attribution() string {
+	var parts []string
+	if e.Author != "" {
+		parts = append(parts, e.Author)
+	}
+	if e.Source != "" {
+		parts = append(parts, e.Source)
+	}
+	line := strings.Join(parts, ", ")
+	if e.License != "" {
+		if line == "" {
+			return "License: " + e.License
+		}
+		line += " (" + e.License + ")"
+	}
+	return line
+}
+
+// library holds per-text metadata keyed by file name in the tests
+// directory. It lives in the data directory rather than next to the texts,
+// so a read-only or shared tests directory works too.
+type library struct {
+	Texts map[string]libraryEntry `json:"texts"`
+	path  string
+}
+
+func loadLibrary() (*library, error) {
+	path, err := dataFile("library.json")
+	if err != nil {
+		return nil, err
+	}
+	return loadLibraryFile(path)
+}
+
+func loadLibraryFile(path string) (*library, error) {
+	lib := &library{Texts: make(map[string]libraryEntry), path: path}
+	if err := readJSONFile(path, lib); err != nil {
+		return nil, err
+	}
+	if lib.Texts == nil {
+		lib.Texts = make(map[string]libraryEntry)
+	}
+	return lib, nil
+}
+
+func (l *library) save() error {
+	return saveDataFile(l.path, l)
+}
+
+// isArchived reports whether name is archived. A nil library has nothing
+// archived, so an Engine without one selects from every text.
+func (l *library) isArchived(name string) bool {
+	if l == nil {
+		return false
+	}
+	return l.Texts[name].Archived
+}
+
+// entry returns the metadata for name. A nil library has none.
+func (l *library) entry(name string) libraryEntry {
+	if l == nil {
+		return libraryEntry{}
+	}
+	return l.Texts[name]
+}
+
+// isStarred reports whether name is a favorite. A nil library has none.
+func (l *library) isStarred(name string) bool {
+	if l == nil {
+		return false
+	}
+	return l.Texts[name].Starred
+}
+
+func (l *library) setArchived(name string, archived bool, now time.Time) {
+	entry := l.Texts[name]
+	entry.Archived = archived
+	entry.ArchivedAt = time.Time{}
+	if archived {
+		entry.ArchivedAt = now
+	}
+	l.set(name, entry)
+}
+
+func (l *library) setStarred(name string, starred bool) {
+	entry := l.Texts[name]
+	entry.Starred = starred
+	l.set(name, entry)
+}
+
+// set stores entry for name, leaving no trace of an empty one
+func (l *library) set(name string, entry libraryEntry) {
+	if reflect.ValueOf(entry).IsZero() {
+		delete(l.Texts, name)
+		return
+	}
+	l.Texts[name] = entry
+}
+
+// runArchive archives (or with archived false, restores) the named texts
+// in testsDir and returns the process exit code
+func runArchive(w io.Writer, testsDir string, names []string, archived bool) int {
+	lib, err := loadLibrary()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	for _, name := range names {
+		name = filepath.Base(name) // accept tests/foo.txt as well as foo.txt
+		if _, err := os.Stat(filepath.Join(testsDir, name)); err != nil && !lib.isArchived(name) {
+			fmt.Fprintf(w, "Error: no text %q in %s\n", name, testsDir)
+			return 1
+		}
+		lib.setArchived(name, archived, time.Now())
+		logger.Info("library updated", "file", name, "archived", archived)
+	}
+	if err := lib.save(); err != nil {
+		fmt.Fprintf(w, "Error saving library: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runStar stars (or with starred false, unstars) the named texts in
+// testsDir and returns the process exit code
+func runStar(w io.Writer, testsDir string, names []string, starred bool) int {
+	lib, err := loadLibrary()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	for _, name := range names {
+		name = filepath.Base(name)
+		if _, err := os.Stat(filepath.Join(testsDir, name)); err != nil && !lib.isStarred(name) {
+			fmt.Fprintf(w, "Error: no text %q in %s\n", name, testsDir)
+			return 1
+		}
+		lib.setStarred(name, starred)
+		logger.Info("library updated", "file", name, "starred", starred)
+	}
+	if err := lib.save(); err != nil {
+		fmt.Fprintf(w, "Error saving library: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// archivedNames returns the archived texts, by name
+func (l *library) archivedNames() []string {
+	var names []string
+	for name, entry := range l.Texts {
+		if entry.Archived {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// manageArchive lists archived texts so they can be restored without the
+// command line. Enter toggles the selected one, so a mistaken restore can
+// be undone before leaving; Escape goes back.
+func manageArchive(screen tcell.Screen, lib *library) {
+	names := lib.archivedNames()
+	list := menu{centered: true}
+	for {
+		screen.Clear()
+		width, height := screen.Size()
+		drawCenteredText(screen, width/2, 1, tcell.StyleDefault, "ARCHIVED TEXTS")
+		drawCenteredText(screen, width/2, 3, tcell.StyleDefault, "Up/Down to choose, Enter to restore or re-archive, ESC to go back")
+		if len(names) == 0 {
+			drawCenteredText(screen, width/2, 5, tcell.StyleDefault, "Nothing is archived")
+		}
+
+		rows := max(1, height-6)
+		items := make([]string, len(names))
+		for i, name := range names {
+			entry := lib.entry(name)
+			items[i] = " " + name + "  (restored) "
+			if entry.Archived {
+				items[i] = fmt.Sprintf(" %s  archived %s ", name, entry.ArchivedAt.Format(dateLayout))
+			}
+		}
+		list.draw(screen, width/2, 5, rows, items)
+		screen.Show()
+
+		switch ev := screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			if list.handle(ev, len(names), rows) {
+				break
+			}
+			switch ev.Key() {
+			case tcell.KeyEscape:
+				return
+			case tcell.KeyEnter:
+				if len(names) == 0 {
+					break
+				}
+				name := names[list.selected]
+				lib.setArchived(name, !lib.isArchived(name), time.Now())
+				logger.Info("library updated", "file", name, "archived", lib.isArchived(name))
+				if err := lib.save(); err != nil {
+					logger.Error("saving library failed", "err", err)
+				}
+			}
+		}
+	}
+}
+
+// runList prints the texts in testsDir, skipping archived ones unless
+// includeArchived, and returns the process exit code
+func runList(w io.Writer, testsDir string, includeArchived bool) int {
+	lib, err := loadLibrary()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	files, err := listTextFiles(testsDir)
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+
+	hidden := 0
+	for _, file := range files {
+		archived := lib.isArchived(file.Name())
+		if archived && !includeArchived {
+			hidden++
+			continue
+		}
+		line := file.Name()
+		if attribution := lib.entry(file.Name()).attribution(); attribution != "" {
+			line += "  " + attribution
+		}
+		if lib.isStarred(file.Name()) {
+			line += "  (starred)"
+		}
+		if archived {
+			line += "  (archived)"
+		}
+		fmt.Fprintln(w, line)
+	}
+	if hidden > 0 {
+		fmt.Fprintf(w, "%d archived text(s) hidden; use --include-archived to show them\n", hidden)
+	}
+	return 0
+}
+
+// runAttribute records who wrote a text, where it came from and its
+// license, and the title and tags the test picker searches. args are the
+// subcommand's flags followed by the file name.
+func runAttribute(w io.Writer, testsDir string, args []string) int {
+	flags := flag.NewFlagSet("attribute", flag.ContinueOnError)
+	flags.SetOutput(w)
+	author := flags.String("author", "", "who wrote the text")
+	source := flags.String("source", "", "where the text came from, usually a URL")
+	license := flags.String("license", "", "the text's license, e.g. \"CC BY-SA 4.0\" or \"public domain\"")
+	title := flags.String("title", "", "the text's title")
+	tags := flags.String("tags", "", "comma-separated tags, e.g. \"speech,history\"")
+	flags.Usage = func() {
+		fmt.Fprintln(w, "Usage: keysmash attribute [--author NAME] [--source URL] [--license LICENSE] [--title TITLE] [--tags TAG,...] FILE")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	name := filepath.Base(flags.Arg(0))
+	if _, err := os.Stat(filepath.Join(testsDir, name)); err != nil {
+		fmt.Fprintf(w, "Error: no text %q in %s\n", name, testsDir)
+		return 1
+	}
+	lib, err := loadLibrary()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+
+	// Only the flags given change, so fields can be filled in one at a time
+	entry := lib.entry(name)
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "author":
+			entry.Author = *author
+		case "source":
+			entry.Source = *source
+		case "license":
+			entry.License = *license
+		case "title":
+			entry.Title = *title
+		case "tags":
+			entry.Tags = nil
+			for _, tag := range strings.Split(*tags, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					entry.Tags = append(entry.Tags, tag)
+				}
+			}
+		}
+	})
+	lib.set(name, entry)
+	if err := lib.save(); err != nil {
+		fmt.Fprintf(w, "Error saving library: %v\n", err)
+		return 1
+	}
+	logger.Info("library updated", "file", name, "author", entry.Author, "source", entry.Source, "license", entry.License)
+	return 0
+}
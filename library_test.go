@@ -0,0 +1,132 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelectRandomTestSkipsArchived(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lib, err := loadLibraryFile(filepath.Join(t.TempDir(), "library.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib.setArchived("a.txt", true, time.Now())
+
+	engine := newEngine(&fakeClock{}, fixedRand(0), dir)
+	engine.library = lib
+	for i := 0; i < 3; i++ {
+		state, err := engine.selectRandomTest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.testFile != "b.txt" {
+			t.Errorf("selected %s, want the only unarchived text b.txt", state.testFile)
+		}
+	}
+
+	lib.setArchived("b.txt", true, time.Now())
+	if got := strings.Join(lib.archivedNames(), ","); got != "a.txt,b.txt" {
+		t.Errorf("archivedNames = %q", got)
+	}
+	if _, err := engine.selectRandomTest(); err == nil || !strings.Contains(err.Error(), "archived") {
+		t.Errorf("selecting with everything archived: err = %v, want an archived error", err)
+	}
+
+	engine.includeArchived = true
+	if state, err := engine.selectRandomTest(); err != nil || state.testFile != "a.txt" {
+		t.Errorf("with includeArchived selected %q, %v; want a.txt", state.testFile, err)
+	}
+
+	// Unarchiving leaves no trace in the file
+	lib.setArchived("a.txt", false, time.Now())
+	if _, ok := lib.Texts["a.txt"]; ok {
+		t.Error("unarchived text still has a library entry")
+	}
+}
+
+func TestRunAttribute(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "gettysburg.txt"), []byte("Four score"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fields given in separate runs accumulate
+	for _, args := range [][]string{
+		{"--author", "Abraham Lincoln", "gettysburg.txt"},
+		{"--source", "https://en.wikisource.org/wiki/Gettysburg_Address", "--license", "public domain", "tests/gettysburg.txt"},
+		{"--title", "The Gettysburg Address", "--tags", "speech, history,", "gettysburg.txt"},
+	} {
+		if code := runAttribute(io.Discard, dir, args); code != 0 {
+			t.Fatalf("runAttribute(%q) exit code %d", args, code)
+		}
+	}
+	if code := runAttribute(io.Discard, dir, []string{"--author", "x", "missing.txt"}); code == 0 {
+		t.Error("attributing a missing text succeeded")
+	}
+
+	lib, err := loadLibrary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Abraham Lincoln, https://en.wikisource.org/wiki/Gettysburg_Address (public domain)"
+	if got := lib.entry("gettysburg.txt").attribution(); got != want {
+		t.Errorf("attribution = %q, want %q", got, want)
+	}
+	if got := pickerTitle("gettysburg.txt", lib.entry("gettysburg.txt")); got != "gettysburg.txt  The Gettysburg Address  #speech  #history" {
+		t.Errorf("picker title = %q", got)
+	}
+
+	engine := newEngine(&fakeClock{}, fixedRand(0), dir)
+	engine.library = lib
+	state, err := engine.selectRandomTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.attribution != want {
+		t.Errorf("test attribution = %q, want %q", state.attribution, want)
+	}
+}
+
+func TestFavoritesOnly(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	engine := newEngine(&fakeClock{}, fixedRand(0), dir)
+	engine.favoritesOnly = true
+	if _, err := engine.selectRandomTest(); err == nil || !strings.Contains(err.Error(), "no starred texts") {
+		t.Errorf("with nothing starred: err = %v", err)
+	}
+
+	if code := runStar(io.Discard, dir, []string{"tests/b.txt", "c.txt"}, true); code != 0 {
+		t.Fatalf("runStar exit code %d", code)
+	}
+	if code := runStar(io.Discard, dir, []string{"c.txt"}, false); code != 0 {
+		t.Fatalf("runStar exit code %d", code)
+	}
+	if code := runStar(io.Discard, dir, []string{"missing.txt"}, true); code == 0 {
+		t.Error("starring a missing text succeeded")
+	}
+	engine.library, _ = loadLibrary()
+	if state, err := engine.selectRandomTest(); err != nil || state.testFile != "b.txt" {
+		t.Errorf("selected %q, %v; want the only starred text b.txt", state.testFile, err)
+	}
+	if _, ok := engine.library.Texts["c.txt"]; ok {
+		t.Error("unstarred text still has a library entry")
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// logger is the application-wide structured logger. While a test is running
+// tcell owns the terminal, so printing to stdout or stderr would corrupt the
+// screen; logs can only go to a file. Without --log-file everything is
+// discarded.
+var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// setupLogging points logger at path, appending to any existing log so
+// several sessions can be compared. The returned file must be closed on
+// exit. An empty path leaves logging disabled and returns a nil file.
+func setupLogging(path, level string) (*os.File, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q (want debug, info, warn or error)", level)
+	}
+
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening log file: %w", err)
+	}
+
+	logger = slog.New(slog.NewTextHandler(file, &slog.HandlerOptions{Level: lvl}))
+	return file, nil
+}
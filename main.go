@@ -1,68 +1,248 @@
 package main
 
 import (
+	"crypto/ed25519"
+	"flag"
 	"fmt"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+
+	"github.com/phaedrus/keysmash/internal/chart"
 )
 
-// Global variable to store the path to the tests directory
-var testsDir string
-
-type TestState struct {
-	referenceText string
-	userInput     string
-	errors        int
-	startTime     time.Time
-	endTime       time.Time
-	testStarted   bool
-	testComplete  bool
-	testFile      string
+// requireTestsDir returns the tests directory for subcommands: dir, as
+// given with --dir, or else the one findTestsDir finds, exiting if there
+// is none
+func requireTestsDir(dir string) string {
+	if dir != "" {
+		return dir
+	}
+	dir = findTestsDir()
+	if dir == "" {
+		fmt.Fprintln(os.Stderr, "Error: tests directory not found; run keysmash init to create one")
+		os.Exit(1)
+	}
+	return dir
 }
 
-// findTestsDir tries to locate the tests directory in various locations
-func findTestsDir() string {
-	// Try current directory first
-	if _, err := os.Stat("tests"); err == nil {
-		return "tests"
+func main() {
+	mode := flag.String("mode", modeRandom, "test mode: random (a file from the tests directory), daily (the challenge of the day), learn (lessons from the lessons directory), class (assigned tests for a roster of students), pack (a challenge pack; see --pack) or words (random common words; see --count)")
+	count := flag.Int("count", defaultWordCount, "how many words a words mode test has")
+	pack := flag.String("pack", "", "challenge pack to play in pack mode, by name")
+	file := flag.String("file", "", "start straight away on this text file, skipping the welcome screen; new tests come from its directory")
+	dir := flag.String("dir", "", "directory of .txt texts to use instead of the tests directory")
+	words := flag.Int("words", 0, "cut each text after this many words (0 types it all)")
+	timeLimit := flag.Int("time", 0, "timed tests: stop after 30, 60 or 120 seconds and score what was typed (0 types the whole text)")
+	bots := flag.String("bots", "", "race against bots: comma-separated steady, bursty and self, with optional :WPM (e.g. steady:75,self)")
+	handicap := flag.Bool("handicap", false, "give slower racers a head start so everyone is expected to finish together")
+	reducedMotion := flag.Bool("reduced-motion", false, "steady cursor and slower stat refresh, for slow or remote terminals (on automatically over SSH and mosh)")
+	lowPower := flag.Bool("low-power", false, "reduced motion with a refresh every few seconds, to save battery (on automatically below low_power_battery)")
+	includeArchived := flag.Bool("include-archived", false, "include archived texts in random selection and listings")
+	favorites := flag.Bool("favorites", false, "only pick from starred texts")
+	transliterate := flag.String("transliterate", "", "show texts in their own script but type them romanized: "+transliterationNames())
+	spectators := flag.String("spectators", "", "let spectators watch your races from this address (e.g. :7777)")
+	logLevel := flag.String("log-level", "info", "log verbosity: debug, info, warn or error")
+	logFile := flag.String("log-file", "", "append a structured log to this file")
+	pprofAddr := flag.String("pprof", "", "serve runtime profiles for go tool pprof on this localhost address (e.g. :6060)")
+	target := flag.Float64("target", 0, "WPM to measure each run against on the results screen (overrides target_wpm)")
+	overlay := flag.String("overlay", "", "serve a stream overlay of the live test for an OBS browser source on this address (e.g. localhost:7780)")
+	control := flag.Bool("control", false, "serve the control API for editor plugins and overlays on control.sock in the data directory")
+	wordByWord := flag.Bool("word-by-word", false, "space submits each word, right or not, and moves on to the next (overrides word_by_word)")
+	stopOnError := flag.Bool("stop-on-error", false, "don't move past a wrong key until the right one is typed (overrides stop_on_error)")
+	suddenDeath := flag.Bool("sudden-death", false, "end the test on the first error (overrides sudden_death)")
+	noBackspace := flag.Bool("no-backspace", false, "typewriter mode: backspace does nothing and every keystroke is final; T on the welcome screen toggles it (overrides no_backspace)")
+	blind := flag.Bool("blind", false, "hide your typing until the test ends, then show it marked against the text (overrides blind)")
+	flag.Parse()
+
+	logOutput, err := setupLogging(*logFile, *logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if logOutput != nil {
+		defer logOutput.Close()
 	}
 
-	// Try executable directory
-	execPath, err := os.Executable()
-	if err == nil {
-		execDir := filepath.Dir(execPath)
-		testsInExecDir := filepath.Join(execDir, "tests")
-		if _, err := os.Stat(testsInExecDir); err == nil {
-			return testsInExecDir
+	// doctor reports a broken config itself
+	cfg, err := loadConfig(configPath())
+	if err != nil && flag.Arg(0) != "doctor" {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	// The config fills in for flags that weren't given
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	if !given["mode"] && !given["file"] && cfg.Mode != "" {
+		*mode = cfg.Mode
+	}
+	if !given["dir"] && !given["file"] {
+		*dir = cfg.TestsDir
+	}
+	if !given["target"] {
+		*target = cfg.TargetWPM
+	}
+	if !given["word-by-word"] {
+		*wordByWord = cfg.WordByWord
+	}
+	if !given["stop-on-error"] {
+		*stopOnError = cfg.StopOnError
+	}
+	if !given["sudden-death"] {
+		*suddenDeath = cfg.SuddenDeath
+	}
+	if !given["no-backspace"] {
+		*noBackspace = cfg.NoBackspace
+	}
+	if !given["blind"] {
+		*blind = cfg.Blind
+	}
+
+	switch *mode {
+	case modeRandom, modeDaily, modeLearn, modeClass, modePack, modeWords:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown mode %q (want random, daily, learn, class, pack or words)\n", *mode)
+		os.Exit(2)
+	}
+	if *count <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --count must be positive")
+		os.Exit(2)
+	}
+	if (*mode == modePack) != (*pack != "") {
+		fmt.Fprintln(os.Stderr, "Error: --pack NAME goes with --mode pack")
+		os.Exit(2)
+	}
+
+	switch *timeLimit {
+	case 0, 30, 60, 120:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: --time %d is not a test length (want 30, 60 or 120)\n", *timeLimit)
+		os.Exit(2)
+	}
+	if *words < 0 || *target < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --words and --target must not be negative")
+		os.Exit(2)
+	}
+	if *file != "" {
+		if *dir != "" || *mode != modeRandom {
+			fmt.Fprintln(os.Stderr, "Error: --file can't be combined with --dir or --mode")
+			os.Exit(2)
 		}
-		
-		// Check one level up (for GOPATH/bin scenario)
-		parentDir := filepath.Dir(execDir)
-		testsInParentDir := filepath.Join(parentDir, "tests")
-		if _, err := os.Stat(testsInParentDir); err == nil {
-			return testsInParentDir
+		if info, err := os.Stat(*file); err != nil || info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: --file %s is not a text file\n", *file)
+			os.Exit(2)
 		}
 	}
-	
-	// Try the source directory where keysmash was built
-	sourceDir := "/Users/phaedrus/Development/keysmash/tests"
-	if _, err := os.Stat(sourceDir); err == nil {
-		return sourceDir
+	if *dir != "" {
+		if info, err := os.Stat(*dir); err != nil || !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: --dir %s is not a directory\n", *dir)
+			os.Exit(2)
+		}
 	}
 
-	// Not found
-	return ""
-}
+	botProfiles, err := parseBots(*bots)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
 
-func main() {
-	// Initialize random seed
-	rand.Seed(time.Now().UnixNano())
+	romanize, ok := transliterations[*transliterate]
+	if *transliterate != "" && !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown transliteration %q (want %s)\n", *transliterate, transliterationNames())
+		os.Exit(2)
+	}
+
+	// Subcommands that change the data wait for the session using it
+	switch flag.Arg(0) {
+	case "import", "sync", "archive", "unarchive", "star", "unstar", "attribute", "calibrate", "vacation":
+		lock := requireDataLock()
+		defer lock.release()
+	}
+
+	// Subcommands run without taking over the terminal
+	switch flag.Arg(0) {
+	case "":
+	case "doctor":
+		os.Exit(runDoctor(os.Stdout))
+	case "init":
+		os.Exit(runInit(os.Stdout, defaultTestsDir()))
+	case "spectate":
+		if flag.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "Usage: keysmash spectate HOST:PORT")
+			os.Exit(2)
+		}
+		os.Exit(runSpectator(flag.Arg(1)))
+	case "import":
+		if flag.NArg() < 2 {
+			fmt.Fprintln(os.Stderr, "Usage: keysmash import RESULTS.json...")
+			os.Exit(2)
+		}
+		os.Exit(runImport(os.Stdout, flag.Args()[1:]))
+	case "list":
+		os.Exit(runList(os.Stdout, requireTestsDir(*dir), *includeArchived))
+	case "archive", "unarchive":
+		if flag.NArg() < 2 {
+			fmt.Fprintf(os.Stderr, "Usage: keysmash %s FILE...\n", flag.Arg(0))
+			os.Exit(2)
+		}
+		os.Exit(runArchive(os.Stdout, requireTestsDir(*dir), flag.Args()[1:], flag.Arg(0) == "archive"))
+	case "star", "unstar":
+		if flag.NArg() < 2 {
+			fmt.Fprintf(os.Stderr, "Usage: keysmash %s FILE...\n", flag.Arg(0))
+			os.Exit(2)
+		}
+		os.Exit(runStar(os.Stdout, requireTestsDir(*dir), flag.Args()[1:], flag.Arg(0) == "star"))
+	case "attribute":
+		os.Exit(runAttribute(os.Stderr, requireTestsDir(*dir), flag.Args()[1:]))
+	case "calibrate":
+		os.Exit(runCalibrate(os.Stdout))
+	case "readline":
+		os.Exit(runReadline(os.Stdout))
+	case "replay":
+		os.Exit(runReplay(os.Stderr, flag.Args()[1:]))
+	case "verify":
+		if flag.NArg() < 2 {
+			fmt.Fprintln(os.Stderr, "Usage: keysmash verify FILE.ksm...")
+			os.Exit(2)
+		}
+		os.Exit(runVerify(os.Stdout, flag.Args()[1:]))
+	case "audit":
+		os.Exit(runAudit(os.Stdout, flag.Args()[1:]))
+	case "sync":
+		if flag.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "Usage: keysmash sync RESULTS.json")
+			os.Exit(2)
+		}
+		os.Exit(runSync(os.Stdout, flag.Arg(1)))
+	case "exam":
+		os.Exit(runExam(os.Stdout, os.Stderr, flag.Args()[1:]))
+	case "certificate":
+		if flag.NArg() < 2 {
+			fmt.Fprintln(os.Stderr, "Usage: keysmash certificate REPORT.kse...")
+			os.Exit(2)
+		}
+		os.Exit(runExamCertificate(os.Stdout, flag.Args()[1:]))
+	case "pack":
+		os.Exit(runPack(os.Stdout, flag.Args()[1:]))
+	case "vacation":
+		os.Exit(runVacation(os.Stdout, flag.Args()[1:]))
+	case "class-report":
+		os.Exit(runClassReport(os.Stdout, os.Stderr))
+	case "daemon":
+		os.Exit(runDaemon(os.Stdout))
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
 
 	// Initialize screen
 	screen, err := tcell.NewScreen()
@@ -76,46 +256,313 @@ func main() {
 		os.Exit(1)
 	}
 	defer screen.Fini()
+	// Toasts are drawn over every screen; see toastScreen
+	toastLayer := newToastScreen(screen, newToasts(systemClock{}, cfg.ToastDuration))
+	screen = toastLayer
 
 	// Set default style
 	defStyle := tcell.StyleDefault
 	screen.SetStyle(defStyle)
 
-	// Find tests directory
-	testsDir = findTestsDir()
+	// Find tests directory, unless one was given
+	testsDir := *dir
+	if *file != "" {
+		testsDir = filepath.Dir(*file)
+	}
 	if testsDir == "" {
-		drawError(screen, "Tests directory not found. Please create a 'tests' directory with text files.")
-		waitForKey(screen)
-		return
+		testsDir = findTestsDir()
+	}
+	if testsDir == "" {
+		logger.Info("tests directory not found; using the built-in texts")
+	} else {
+		logger.Info("tests directory found", "path", testsDir)
 	}
 
-	// Main application loop
-	for {
-		// Show welcome screen
-		showWelcomeScreen(screen)
-		if !waitForKey(screen) {
-			// User pressed Escape, exit the program
-			return
+	lock, err := lockData()
+	var daemon *daemonClient
+	switch {
+	case err != nil:
+		// A file system without locks shouldn't stop play
+		logger.Warn("locking the data directory failed", "err", err)
+	case lock.held():
+		defer lock.release()
+	default:
+		readOnly = true
+		if daemon, err = dialDaemon(); err != nil {
+			logger.Warn("data directory in use; nothing will be saved", "holder", lock.holder, "err", err)
+			daemon = nil
+		} else {
+			logger.Info("connected to the daemon; results and keystrokes are saved through it")
+			defer daemon.Close()
 		}
+	}
+
+	engine := newEngine(systemClock{}, rand.New(rand.NewSource(time.Now().UnixNano())), testsDir)
+	engine.bots = botProfiles
+	engine.handicap = *handicap
+	engine.includeArchived = *includeArchived
+	engine.favoritesOnly = *favorites
+	engine.library, err = loadLibrary()
+	if err != nil {
+		logger.Error("loading library failed", "err", err)
+		showFailure(screen, failure{doing: "loading library", err: err, logPath: *logFile})
+		return
+	}
+	if cfg.ScoreFormula != defaultConfig().ScoreFormula {
+		// loadConfig has already validated the formula
+		engine.scorer, _ = compileFormula(cfg.ScoreFormula)
+		logger.Info("using custom score formula", "formula", cfg.ScoreFormula)
+	}
+	engine.mode = *mode
+	engine.timeLimit = time.Duration(*timeLimit) * time.Second
+	engine.words = *words
+	engine.wordCount = *count
+	engine.targetWPM = *target
+	engine.targetAccuracy = cfg.TargetAccuracy
+	engine.stripEmoji = cfg.StripEmoji
+	engine.steno = cfg.Steno
+	engine.wordByWord = *wordByWord
+	engine.stopOnError = *stopOnError
+	engine.suddenDeath = *suddenDeath
+	engine.noBackspace = *noBackspace
+	engine.blind = *blind
+	engine.transliterate = romanize
 
-		// Select and load a test
-		state, err := selectRandomTest()
+	var daily *dailyHistory
+	if *mode == modeDaily {
+		daily, err = loadDailyHistory()
 		if err != nil {
-			drawError(screen, fmt.Sprintf("Error loading test: %v", err))
-			if !waitForKey(screen) {
-				return // User pressed Escape to quit
+			logger.Error("loading daily history failed", "err", err)
+			showFailure(screen, failure{doing: "loading daily challenge history", err: err, logPath: *logFile})
+			return
+		}
+		if spent := daily.applyFreezes(engine.clock.Now()); spent > 0 {
+			logger.Info("spent streak freezes", "count", spent)
+			if err := daily.save(); err != nil {
+				logger.Error("saving daily history failed", "err", err)
 			}
-			continue
 		}
+		recordDailyResults(engine.events, daily)
+	}
+	if *mode == modeLearn {
+		engine.course, err = loadCourse()
+		if err != nil {
+			logger.Error("loading lessons failed", "err", err)
+			showFailure(screen, failure{doing: "loading lessons", err: err, logPath: *logFile})
+			return
+		}
+		recordLessonResults(engine.events, engine.course)
+	}
+	if *mode == modeClass {
+		engine.class, err = loadClassSession()
+		if err != nil {
+			logger.Error("loading class failed", "err", err)
+			showFailure(screen, failure{doing: "loading class", err: err, logPath: *logFile})
+			return
+		}
+		recordClassResults(engine.events, engine.class)
+	}
+	if *mode == modePack {
+		engine.pack, err = loadPackSession(*pack)
+		if err != nil {
+			logger.Error("loading challenge pack failed", "err", err)
+			showFailure(screen, failure{doing: "loading challenge pack", err: err, logPath: *logFile})
+			return
+		}
+		recordPackResults(engine.events, engine.pack)
+	}
 
-		// Run the typing test
-		testResult := runTypingTest(screen, &state)
+	results, err := loadResults()
+	if err == nil && daemon != nil {
+		results.daemon = daemon
+		results.Results, err = daemon.results()
+	}
+	if err != nil {
+		logger.Error("loading results failed", "err", err)
+		showFailure(screen, failure{doing: "loading results", err: err, logPath: *logFile})
+		return
+	}
+	if engine.index, err = engine.indexTexts(); err != nil {
+		// Stats fall back to going by file name
+		logger.Error("indexing texts failed", "err", err)
+	}
+	if filled := results.backfillHashes(engine.index); filled > 0 {
+		logger.Info("backfilled text hashes", "results", filled)
+		if err := results.save(); err != nil {
+			logger.Error("saving results failed", "err", err)
+		}
+	}
+	latency, err := loadLatencyProfiles()
+	if err != nil {
+		// Only the environment record and remote detection lose out, so
+		// carry on without
+		logger.Warn("loading latency profiles failed", "err", err)
+	}
+	render := renderOptions{inputMethod: cfg.InputMethod, maxFPS: cfg.MaxFPS, speedGauge: cfg.SpeedGauge, split: cfg.SplitScreen, largeText: cfg.LargeText, lineNumbers: cfg.LineNumbers}
+	remap, _ := parseRemap(cfg.Remap) // validated by loadConfig
+	bindings, _ = parseKeyBindings(cfg.Keys)
+	_ = applyTheme(cfg.Theme)
+	playerName := certificateName(cfg)
+	var signingKey ed25519.PrivateKey
+	if cfg.SignRecordings {
+		if signingKey, err = loadSigningKey(); err != nil {
+			// Recordings are still worth saving unsigned
+			logger.Warn("loading signing key failed", "err", err)
+		}
+	}
+	if reason := reducedMotionReason(*reducedMotion, remoteSession(), latency); reason != "" {
+		render.reducedMotion = true
+		logger.Info("reduced motion on", "reason", reason)
+	}
+	batteryState, hasBattery := readBattery()
+	if reason := lowPowerReason(*lowPower, cfg.LowPowerBattery, batteryState, hasBattery); reason != "" {
+		render.reducedMotion, render.lowPower = true, true
+		logger.Info("low power on", "reason", reason)
+	}
+	settings := runSettings{
+		Bots:          *bots,
+		Handicap:      *handicap,
+		Spectators:    *spectators != "",
+		ReducedMotion: render.reducedMotion,
+		LowPower:      render.lowPower,
+		ScoreFormula:  cfg.ScoreFormula,
+		StripEmoji:    cfg.StripEmoji,
+		Transliterate: *transliterate,
+		Steno:         cfg.Steno,
+		WordByWord:    *wordByWord,
+		StopOnError:   *stopOnError,
+		SuddenDeath:   *suddenDeath,
+		NoBackspace:   *noBackspace,
+		Blind:         *blind,
+		TimeLimit:     *timeLimit,
+		Words:         *words,
+	}
+	engine.recentWPM = results.recentWPM(averageWindow)
+	engine.bestWPM = results.bestWPM()
+	if cfg.ComfortCheckin {
+		// Deferred after screen.Fini, so it runs while the screen is up
+		defer checkInComfort(screen, results)
+	}
+
+	breaks := newBreakTracker(cfg.BreakAfter, cfg.BreakSnooze, &breakLog{})
+	if cfg.BreakAfter > 0 {
+		breaks.log, err = loadBreakLog()
+		if err != nil {
+			logger.Error("loading break log failed", "err", err)
+			showFailure(screen, failure{doing: "loading break log", err: err, logPath: *logFile})
+			return
+		}
+		breaks.subscribe(engine.events)
+	}
+	var metrics map[string]float64
+	if len(cfg.SessionMetrics) > 0 || cfg.MetricsHook != "" {
+		metrics = sessionMetrics(screen, cfg)
+	}
+	recordResults(engine.events, results, func() runEnvironment {
+		return captureEnvironment(screen, *mode, settings, latency)
+	}, metrics)
 
-		// Handle post-test options
-		if !handlePostTest(screen, testResult, &state) {
-			break // User chose to quit
+	if *spectators != "" {
+		listener, err := serveSpectators(*spectators, engine)
+		if err != nil {
+			logger.Error("spectator server failed", "err", err)
+			showFailure(screen, failure{doing: "starting the spectator server", err: err, logPath: *logFile})
+			return
+		}
+		defer listener.Close()
+		logger.Info("accepting spectators", "addr", listener.Addr())
+	}
+	if *pprofAddr != "" {
+		listener, err := servePprof(*pprofAddr)
+		if err != nil {
+			logger.Error("profile server failed", "err", err)
+			showFailure(screen, failure{doing: "starting the profile server", err: err, logPath: *logFile})
+			return
 		}
+		defer listener.Close()
+		logger.Info("serving profiles", "addr", listener.Addr())
+	}
+	if *overlay != "" {
+		listener, err := serveOverlay(*overlay, engine)
+		if err != nil {
+			logger.Error("overlay server failed", "err", err)
+			showFailure(screen, failure{doing: "starting the overlay server", err: err, logPath: *logFile})
+			return
+		}
+		defer listener.Close()
+		logger.Info("serving the overlay", "addr", listener.Addr())
+	}
+	skills, err := loadSkillLog()
+	if err != nil {
+		logger.Error("loading skill log failed", "err", err)
+		showFailure(screen, failure{doing: "loading skill log", err: err, logPath: *logFile})
+		return
 	}
+	trackSkills(engine.events, skills)
+	keyLog, err := openKeyLog()
+	if err != nil {
+		logger.Error("loading keystroke log failed", "err", err)
+		showFailure(screen, failure{doing: "loading the keystroke log", err: err, logPath: *logFile})
+		return
+	}
+	keyLog.daemon = daemon
+	defer func() {
+		// Keep the keystrokes of a test abandoned before quitting
+		if err := keyLog.flush(); err != nil {
+			logger.Error("saving keystroke log failed", "err", err)
+		}
+	}()
+	trackKeystrokes(engine.events, keyLog)
+	queue, err := loadPracticeQueue()
+	if err != nil {
+		logger.Error("loading practice queue failed", "err", err)
+		showFailure(screen, failure{doing: "loading the practice queue", err: err, logPath: *logFile})
+		return
+	}
+	trackPracticeQueue(engine.events, queue, results)
+	logEvents(engine.events)
+	trackPersonalBests(engine.events)
+	announceEvents(engine.events, toastLayer)
+	var controlServer *controlServer
+	if *control {
+		server, listener, err := serveControl(engine, screen, results)
+		if err != nil {
+			// The session is still playable without it
+			logger.Error("control API failed", "err", err)
+		} else {
+			defer listener.Close()
+			controlServer = server
+		}
+	}
+
+	a := &app{
+		engine:     engine,
+		results:    results,
+		daily:      daily,
+		skills:     skills,
+		keyLog:     keyLog,
+		queue:      queue,
+		breaks:     breaks,
+		render:     render,
+		settings:   &settings,
+		remap:      remap,
+		signingKey: signingKey,
+		playerName: playerName,
+		logPath:    *logFile,
+		dataLock:   lock,
+		daemon:     daemon,
+		control:    controlServer,
+	}
+	// Remember whether the last run set a personal best so the results
+	// screen can announce it
+	engine.events.Subscribe(func(Event) { a.pbAchieved = true }, EventPBAchieved)
+
+	welcome := &welcomeView{app: a}
+	if *file != "" {
+		name := filepath.Base(*file)
+		welcome.first = func() (TestState, error) { return engine.loadTest(name) }
+	}
+	runViews(screen, welcome)
 }
 
 func showWelcomeScreen(screen tcell.Screen) {
@@ -129,117 +576,111 @@ func showWelcomeScreen(screen tcell.Screen) {
 	subtitle := "TYPING TEST"
 	drawCenteredText(screen, width/2, height/2-1, tcell.StyleDefault, subtitle)
 	
-	prompt := "Press any key to start, ESC to quit"
+	prompt := "Press any key to start, Ctrl+P for commands, ESC to quit"
 	drawCenteredText(screen, width/2, height/2+3, tcell.StyleDefault, prompt)
 
 	screen.Show()
 }
 
-func selectRandomTest() (TestState, error) {
-	// Read test files from the identified tests directory
-	files, err := os.ReadDir(testsDir)
-	if err != nil {
-		return TestState{}, err
-	}
+// recentShown is how many recent tests the welcome screen offers
+const recentShown = 5
 
-	// Filter for .txt files
-	var textFiles []os.DirEntry
-	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(strings.ToLower(file.Name()), ".txt") {
-			textFiles = append(textFiles, file)
-		}
+// drawRecentTests lists recent tests on the welcome screen, numbered for
+// waitForStart's shortcuts
+func drawRecentTests(screen tcell.Screen, recent []result) {
+	if len(recent) == 0 {
+		return
 	}
-
-	if len(textFiles) == 0 {
-		return TestState{}, fmt.Errorf("no .txt files found in %s directory", testsDir)
+	width, height := screen.Size()
+	drawCenteredText(screen, width/2, height/2+7, tcell.StyleDefault, "Recent - press a number to play again:")
+	for i, r := range recent {
+		drawCenteredText(screen, width/2, height/2+8+i, tcell.StyleDefault, fmt.Sprintf("%d  %s  (%.0f WPM)", i+1, r.TestFile, r.WPM))
 	}
+	screen.Show()
+}
 
-	// Select random file
-	randomFile := textFiles[rand.Intn(len(textFiles))]
-
-	// Read file content using the full path
-	content, err := os.ReadFile(filepath.Join(testsDir, randomFile.Name()))
-	if err != nil {
-		return TestState{}, err
+// drawWarmupHint adds the player's warm-up factor to the bottom of the
+// welcome screen, once there's enough history to know it
+func drawWarmupHint(screen tcell.Screen, results *resultStore, now time.Time) {
+	factor, advice := results.warmupHint(now)
+	if factor == "" {
+		return
 	}
-
-	return TestState{
-		referenceText: strings.TrimSpace(string(content)),
-		userInput:     "",
-		errors:        0,
-		testStarted:   false,
-		testComplete:  false,
-		testFile:      randomFile.Name(),
-	}, nil
+	width, height := screen.Size()
+	drawCenteredText(screen, width/2, height-3, tcell.StyleDefault, factor)
+	drawCenteredText(screen, width/2, height-2, tcell.StyleDefault, advice)
+	screen.Show()
 }
 
-func runTypingTest(screen tcell.Screen, state *TestState) TestState {
+func runTypingTest(screen tcell.Screen, state *TestState, opts renderOptions, remap keyRemap) TestState {
 	width, _ := screen.Size()
 
+	// Redraw on a timer as well as on input, so the clock, the cursor
+	// blink and any opponents keep moving while the player pauses
+	tick := 100 * time.Millisecond
+	if opts.reducedMotion {
+		// Stats only move on the tick, so keystrokes redraw little more
+		// than the typed character
+		tick = reducedMotionTick
+		if opts.lowPower {
+			tick = lowPowerTick
+		}
+		opts.stats = sampleStats(state)
+	}
+	stopTicker := startTicker(screen, tick)
+	defer stopTicker()
+	if opts.inputMethod || needsIME(state.referenceText) {
+		opts.inputMethod = true
+		defer screen.HideCursor()
+	}
+	opts.reference = &wrapCache{}
+	frames := newFrameScheduler(screen, opts.maxFPS)
+
 	for {
-		// Render current state
-		renderScreen(screen, state, width)
+		// Render current state, once queued events are handled and the
+		// frame cap allows
+		if renderStart := time.Now(); frames.ready(renderStart) {
+			renderScreen(screen, state, width, opts)
+			frames.drawn(renderStart)
+			logger.Debug("rendered frame", "duration", time.Since(renderStart))
+		}
 
-		// Poll for events
+		// Poll for events; any of them may change what's shown
 		ev := screen.PollEvent()
+		frames.request()
+
+		// A timed test ends when its time is up, whatever the event, and
+		// keys that arrive after that don't count
+		if state.expire() {
+			return *state
+		}
 
 		switch ev := ev.(type) {
+		case *tcell.EventInterrupt:
+			if opts.reducedMotion {
+				opts.stats = sampleStats(state)
+			}
 		case *tcell.EventResize:
 			screen.Sync()
 			width, _ = screen.Size()
+			logger.Debug("resize", "width", width)
 		case *tcell.EventKey:
+			logger.Debug("key", "name", ev.Name(), "input_len", state.typed(), "errors", state.errors)
 			// Handle key event
 			if ev.Key() == tcell.KeyEscape {
 				// Exit test
 				return *state
 			} else if ev.Key() == tcell.KeyBackspace || ev.Key() == tcell.KeyBackspace2 {
-				// Handle backspace
-				if len(state.userInput) > 0 {
-					state.userInput = state.userInput[:len(state.userInput)-1]
-				}
+				state.backspace()
+			} else if ev.Key() == tcell.KeyF1 {
+				state.peek()
 			} else if ev.Key() == tcell.KeyEnter {
 				// Always allow Enter key to add a newline
-				if !state.testStarted {
-					state.testStarted = true
-					state.startTime = time.Now()
-				}
-				
-				// Add the newline
-				state.userInput += "\n"
-				
-				// Check if the newline matches the reference text
-				if len(state.userInput) <= len(state.referenceText) {
-					if state.referenceText[len(state.userInput)-1] != '\n' {
-						state.errors++
-					}
-				} else {
-					// Extra character is an error
-					state.errors++
+				if r, ok := remap.apply('\n'); ok {
+					state.typeRune(r)
 				}
 			} else if r := ev.Rune(); r != 0 {
-				// Handle character input
-				if !state.testStarted {
-					state.testStarted = true
-					state.startTime = time.Now()
-				}
-
-				state.userInput += string(r)
-
-				// Check for error
-				if len(state.userInput) <= len(state.referenceText) {
-					// Check if character matches
-					if state.userInput[len(state.userInput)-1] != state.referenceText[len(state.userInput)-1] {
-						state.errors++
-					}
-				} else {
-					// Extra character is an error
-					state.errors++
-				}
-
-				// Check if test is complete
-				if len(state.userInput) == len(state.referenceText) && state.userInput == state.referenceText {
-					state.testComplete = true
-					state.endTime = time.Now()
+				if r, ok := remap.apply(r); ok && state.typeRune(r) {
 					return *state
 				}
 			}
@@ -247,9 +688,14 @@ func runTypingTest(screen tcell.Screen, state *TestState) TestState {
 	}
 }
 
-// renderScreen handles the UI drawing with adaptive layout
-func renderScreen(screen tcell.Screen, state *TestState, width int) {
+// renderScreen handles the UI drawing with adaptive layout. Each frame
+// clears and redraws the whole back buffer, which is cheap: tcell's Show
+// compares it with the previous frame and only sends the cells that
+// changed, so a keystroke costs tens of bytes on the wire however large
+// the screen (see TestKeystrokeBandwidth).
+func renderScreen(screen tcell.Screen, state *TestState, width int, opts renderOptions) {
 	screen.Clear()
+	screen.HideCursor()
 
 	// Get screen dimensions
 	width, screenHeight := screen.Size()
@@ -269,6 +715,20 @@ func renderScreen(screen tcell.Screen, state *TestState, width int) {
 	
 	// Calculate content width for wrapping
 	contentWidth := max(20, width - (hPadding * 2))
+	split := useSplit(state, contentWidth, opts)
+	wrapWidth := contentWidth
+	if split {
+		wrapWidth = splitColumnWidth(contentWidth)
+	}
+	
+	// Line numbers take a gutter from the text's width; the split layout
+	// has no room to spare for one
+	shown := state.shownText()
+	gutter := 0
+	if opts.lineNumbers && !split {
+		gutter = lineNumberWidth(strings.Count(shown, "\n") + 1)
+		wrapWidth = max(20, wrapWidth-gutter)
+	}
 	
 	// Draw header (adaptive based on space)
 	if screenHeight >= 18 {
@@ -284,11 +744,43 @@ func renderScreen(screen tcell.Screen, state *TestState, width int) {
 		drawCenteredText(screen, width/2, 0, tcell.StyleDefault, headerText)
 	}
 	
-	// Wrap all text first
-	refLines := wrapText(state.referenceText, contentWidth)
+	// Wrap the text around the typing position first; see textWindow
+	typedPos := 0.0 // how far into shown the player is, in bytes
+	if len(state.reference) > 0 {
+		typedPos = float64(state.typed()) / float64(len(state.reference)) * float64(len(shown))
+	}
+	refStart, refEnd := textWindow(shown, int(typedPos))
+	refLines := opts.reference.wrap(shown[refStart:refEnd], wrapWidth)
+	inputStart, _ := textWindow(state.userInput, len(state.userInput))
 	inputLines := []string{}
-	if len(state.userInput) > 0 {
-		inputLines = wrapText(state.userInput, contentWidth)
+	if len(state.userInput) > 0 && !state.hidden() {
+		inputLines = wrapText(state.userInput[inputStart:], contentWidth)
+	}
+
+	// Both are drawn a character at a time, styled by how it was typed; a
+	// transliteration's characters aren't the ones typed, so it's dimmed
+	refOffsets := opts.reference.lineOffsets(shown[refStart:refEnd], refLines)
+	var refNumbers []int
+	if gutter > 0 {
+		refNumbers = lineNumbers(shown, refStart, shown[refStart:refEnd], refOffsets)
+	}
+	drawRefLine := func(y, i int) {
+		if gutter > 0 && refNumbers[i] > 0 {
+			number := strconv.Itoa(refNumbers[i])
+			drawText(screen, hPadding+gutter-1-len(number), y, styleLineNumber, number)
+		}
+		drawWrappedLine(screen, hPadding+gutter, y, refLines[i], shown[refStart:refEnd], refOffsets[i], func(offset int) tcell.Style {
+			if state.display != "" {
+				return styleUntyped
+			}
+			return state.referenceStyle(clusterIndex(state.refStarts, refStart+offset))
+		})
+	}
+	inputOffsets := lineOffsets(state.userInput[inputStart:], inputLines)
+	drawInputLine := func(y, i int) {
+		drawWrappedLine(screen, hPadding, y, inputLines[i], state.userInput[inputStart:], inputOffsets[i], func(offset int) tcell.Style {
+			return state.inputStyle(clusterIndex(state.unitStarts, inputStart+offset))
+		})
 	}
 	
 	// Calculate cursor position
@@ -296,7 +788,7 @@ func renderScreen(screen tcell.Screen, state *TestState, width int) {
 	cursorLine := 0
 	if len(inputLines) > 0 {
 		lastLine := inputLines[len(inputLines)-1]
-		cursorPos = runewidth.StringWidth(lastLine)
+		cursorPos = displayWidth(lastLine)
 		cursorLine = len(inputLines) - 1
 	}
 	
@@ -328,42 +820,109 @@ func renderScreen(screen tcell.Screen, state *TestState, width int) {
 		inputHeaderHeight = 1
 	}
 	
+	// The speed gauge goes between the stats and the text, taking over
+	// from the WPM figure, but only if the text still gets a usable amount
+	// of room
+	contentStartY := topMargin + statsHeight + 1
+	contentEndY := screenHeight - bottomMargin
+	contentHeight := contentEndY - contentStartY
+	showGauge := opts.speedGauge && state.testStarted && screenHeight >= 18 && contentHeight-gaugeHeight(gaugeRadius)-1 >= 8
+	
 	// Draw stats if test started
 	statsY := topMargin
 	if state.testStarted {
-		elapsed := time.Since(state.startTime).Seconds()
+		stats := opts.stats
+		if stats == nil {
+			stats = sampleStats(state)
+		}
+		elapsed := stats.elapsed.Seconds()
 		
 		// Calculate stats
-		wpm := float64(len(state.userInput)/5) / (elapsed / 60.0)
-		if wpm < 0 || elapsed < 1 {
-			wpm = 0
+		wpm := stats.wpm
+		
+		// A timed test counts down instead
+		timeLabel := "Time"
+		if state.timeLimit > 0 {
+			timeLabel, elapsed = "Time left", max(0, (state.timeLimit-stats.elapsed).Seconds())
 		}
 		
 		// Display stats (adaptive based on space)
 		if screenHeight >= 18 {
-			statsText := fmt.Sprintf("Time: %.1fs | WPM: %.1f | Errors: %d", 
-				elapsed, wpm, state.errors)
+			statsText := fmt.Sprintf("%s: %.1fs | WPM: %.1f | Errors: %s", 
+				timeLabel, elapsed, wpm, state.shownErrors(stats.errors))
+			if showGauge {
+				statsText = fmt.Sprintf("%s: %.1fs | Errors: %s", timeLabel, elapsed, state.shownErrors(stats.errors))
+			}
 			drawCenteredText(screen, width/2, statsY, tcell.StyleDefault, statsText)
 			
 			// Display progress percentage
-			completionPct := float64(len(state.userInput)) / float64(len(state.referenceText))
+			completionPct := float64(state.typed()) / float64(len(state.reference))
 			if completionPct > 1.0 {
 				completionPct = 1.0
 			}
 			
 			pctText := fmt.Sprintf("Progress: %d%%", int(completionPct*100))
 			drawText(screen, hPadding, statsY+1, tcell.StyleDefault, pctText)
+			if state.flow.in {
+				drawCenteredText(screen, width/2, statsY+1, styleFlow, "~ in flow ~")
+			}
+			if opts.lineNumbers {
+				posText := positionText(state)
+				drawText(screen, width-hPadding-len(posText), statsY+1, tcell.StyleDefault, posText)
+			}
 		} else {
 			// Compact stats for smaller screens
-			statsText := fmt.Sprintf("WPM: %.1f | Err: %d", wpm, state.errors)
+			statsText := fmt.Sprintf("WPM: %.1f | Err: %s", wpm, state.shownErrors(stats.errors))
+			if state.timeLimit > 0 {
+				statsText = fmt.Sprintf("Left: %.0fs | %s", elapsed, statsText)
+			}
 			drawCenteredText(screen, width/2, statsY, tcell.StyleDefault, statsText)
 		}
+	} else {
+		// Until the first keystroke, say what the player is committing to
+		estimateText := fmt.Sprintf("Estimated time: %.0fs at your average %.0f WPM", state.estimate().Seconds(), state.averageWPM)
+		if screenHeight < 18 {
+			estimateText = fmt.Sprintf("Est: %.0fs", state.estimate().Seconds())
+		}
+		if state.estimate() == 0 {
+			estimateText = ""
+		}
+		if state.timeLimit > 0 {
+			estimateText = fmt.Sprintf("Timed test: type as much as you can in %.0fs", state.timeLimit.Seconds())
+			if screenHeight < 18 {
+				estimateText = fmt.Sprintf("Timed: %.0fs", state.timeLimit.Seconds())
+			}
+		}
+		drawCenteredText(screen, width/2, statsY, tcell.StyleDefault, estimateText)
 	}
 	
-	// Calculate main content area boundaries
-	contentStartY := topMargin + statsHeight + 1
-	contentEndY := screenHeight - bottomMargin
-	contentHeight := contentEndY - contentStartY
+	if showGauge {
+		stats := opts.stats
+		if stats == nil {
+			stats = sampleStats(state)
+		}
+		newGauge(state.averageWPM, state.bestWPM).draw(screen, width/2-gaugeWidth(gaugeRadius)/2, contentStartY, gaugeRadius, stats.wpm)
+		contentStartY += gaugeHeight(gaugeRadius) + 1
+		contentHeight -= gaugeHeight(gaugeRadius) + 1
+	}
+	
+	// Race panel goes between the stats and the text, but only if the
+	// text still gets a usable amount of room
+	if len(state.opponents) > 0 {
+		racers := state.racers()
+		if contentHeight-len(racers)-1 >= 8 {
+			drawRacePanel(screen, hPadding, contentStartY, width-2*hPadding, racers)
+			contentStartY += len(racers) + 1
+			contentHeight -= len(racers) + 1
+		}
+	}
+	
+	// Large text goes last, right above the text, on the same terms
+	if opts.largeText && contentHeight-largeTextHeight >= 8 {
+		drawLargeText(screen, state, hPadding, contentStartY, contentWidth)
+		contentStartY += largeTextHeight
+		contentHeight -= largeTextHeight
+	}
 	
 	// Safety check - ensure we have minimum content space
 	if contentHeight < 4 {
@@ -384,6 +943,13 @@ func renderScreen(screen tcell.Screen, state *TestState, width int) {
 	// Draw divider between stats and content
 	drawText(screen, 0, contentStartY-1, tcell.StyleDefault, strings.Repeat("-", width))
 	
+	if split {
+		drawSplit(screen, state, hPadding, contentStartY, contentWidth, contentHeight, shown[refStart:refEnd], refStart, refLines, refOffsets, opts)
+		drawProgress(screen, state, width, screenHeight, hPadding)
+		screen.Show()
+		return
+	}
+	
 	// Draw reference text title
 	drawText(screen, hPadding, refTextTitleY, tcell.StyleDefault, "Text to type:")
 	
@@ -393,8 +959,8 @@ func renderScreen(screen tcell.Screen, state *TestState, width int) {
 		if len(refLines) > refSectionHeight {
 			// Calculate which portion to display based on typing progress
 			refProgress := 0.0
-			if len(state.referenceText) > 0 {
-				refProgress = float64(len(state.userInput)) / float64(len(state.referenceText))
+			if refEnd > refStart {
+				refProgress = (typedPos - float64(refStart)) / float64(refEnd-refStart)
 			}
 			refMidpoint := int(refProgress * float64(len(refLines)))
 			
@@ -411,23 +977,23 @@ func renderScreen(screen tcell.Screen, state *TestState, width int) {
 			// Safety check for array bounds
 			if refStartLine < refEndLine && refStartLine >= 0 && refEndLine <= len(refLines) {
 				// Draw only the visible portion
-				for i, line := range refLines[refStartLine:refEndLine] {
-					drawText(screen, hPadding, refTextStartY+i, tcell.StyleDefault, line)
+				for i := range refLines[refStartLine:refEndLine] {
+					drawRefLine(refTextStartY+i, refStartLine+i)
 				}
 				
 				// Add scroll indicators if needed (if we have room)
-				if refStartLine > 0 && width > 20 {
+				if (refStartLine > 0 || refStart > 0) && width > 20 {
 					drawText(screen, width-6, refTextStartY, tcell.StyleDefault, "↑")
 				}
-				if refEndLine < len(refLines) && width > 20 {
+				if (refEndLine < len(refLines) || refEnd < len(shown)) && width > 20 {
 					drawText(screen, width-6, refTextStartY+refSectionHeight-1, tcell.StyleDefault, "↓")
 				}
 			}
 		} else if len(refLines) > 0 {
 			// Draw all reference text if it fits
-			for i, line := range refLines {
+			for i := range refLines {
 				if i < refSectionHeight { // Bounds check
-					drawText(screen, hPadding, refTextStartY+i, tcell.StyleDefault, line)
+					drawRefLine(refTextStartY+i, i)
 				}
 			}
 		}
@@ -445,7 +1011,7 @@ func renderScreen(screen tcell.Screen, state *TestState, width int) {
 	
 	// Draw input area label
 	if inputLabelY < screenHeight-1 {
-		drawText(screen, hPadding, inputLabelY, tcell.StyleDefault, "Your typing:")
+		drawText(screen, hPadding, inputLabelY, tcell.StyleDefault, state.inputLabel())
 	}
 	
 	// Draw user input if we have space
@@ -466,14 +1032,14 @@ func renderScreen(screen tcell.Screen, state *TestState, width int) {
 			// Safety check for array bounds
 			if inputStartLine < inputEndLine && inputStartLine >= 0 && inputEndLine <= len(inputLines) {
 				// Draw visible input lines
-				for i, line := range inputLines[inputStartLine:inputEndLine] {
+				for i := range inputLines[inputStartLine:inputEndLine] {
 					if inputStartY+i < screenHeight-1 { // Bounds check
-						drawText(screen, hPadding, inputStartY+i, tcell.StyleDefault, line)
+						drawInputLine(inputStartY+i, inputStartLine+i)
 					}
 				}
 				
 				// Add scroll indicators if needed (if we have room)
-				if inputStartLine > 0 && width > 20 {
+				if (inputStartLine > 0 || inputStart > 0) && width > 20 {
 					drawText(screen, width-6, inputStartY, tcell.StyleDefault, "↑")
 				}
 				if inputEndLine < len(inputLines) && width > 20 && inputStartY+inputSectionHeight-1 < screenHeight-1 {
@@ -487,12 +1053,8 @@ func renderScreen(screen tcell.Screen, state *TestState, width int) {
 				cursorX := hPadding + cursorPos
 				
 				if cursorX < width && cursorY < screenHeight-1 {
-					// Draw blinking cursor at end of input
-					if time.Now().UnixNano()/4e7%10 >= 5 {
-						screen.SetContent(cursorX, cursorY, ' ', nil, tcell.StyleDefault.Reverse(true))
-					} else {
-						screen.SetContent(cursorX, cursorY, '_', nil, tcell.StyleDefault)
-					}
+					// Mark the insertion point at end of input
+					drawCursor(screen, cursorX, cursorY, state.clock.Now(), opts)
 				}
 			}
 		} else {
@@ -501,21 +1063,24 @@ func renderScreen(screen tcell.Screen, state *TestState, width int) {
 			cursorY := inputStartY
 			
 			if cursorX < width && cursorY < screenHeight-1 {
-				if time.Now().UnixNano()/4e7%10 >= 5 {
-					screen.SetContent(cursorX, cursorY, ' ', nil, tcell.StyleDefault.Reverse(true))
-				} else {
-					screen.SetContent(cursorX, cursorY, '_', nil, tcell.StyleDefault)
-				}
+				drawCursor(screen, cursorX, cursorY, state.clock.Now(), opts)
 			}
 		}
 	}
 	
+	drawProgress(screen, state, width, screenHeight, hPadding)
+	screen.Show()
+}
+
+// drawProgress draws the test screen's progress bar and help line
+func drawProgress(screen tcell.Screen, state *TestState, width, screenHeight, hPadding int) {
 	// Draw progress bar at bottom
 	progressBarY := screenHeight - 2
 	if progressBarY > 0 {
 		progress := 0
-		if len(state.referenceText) > 0 {
-			progress = len(state.userInput) * 100 / len(state.referenceText)
+		if len(state.reference) > 0 {
+			// Extra characters typed past the end don't fill it further
+			progress = min(100, state.typed() * 100 / len(state.reference))
 		}
 		
 		// Adaptive progress bar width
@@ -526,13 +1091,8 @@ func renderScreen(screen tcell.Screen, state *TestState, width int) {
 			drawCenteredText(screen, width/2, progressBarY, tcell.StyleDefault, progressText)
 		} else {
 			// Draw progress bar
-			filledWidth := progressBarWidth * progress / 100
-			
-			progressBar := fmt.Sprintf("[%s%s] %d%%", 
-				strings.Repeat("=", filledWidth), 
-				strings.Repeat(" ", progressBarWidth-filledWidth),
-				progress)
-			drawText(screen, hPadding, progressBarY, tcell.StyleDefault, progressBar)
+			bar := fmt.Sprintf("%s %d%%", progressBar(progressBarWidth, float64(progress)/100), progress)
+			drawText(screen, hPadding, progressBarY, tcell.StyleDefault, bar)
 		}
 		
 		// Draw help text at very bottom
@@ -540,8 +1100,6 @@ func renderScreen(screen tcell.Screen, state *TestState, width int) {
 			drawText(screen, hPadding, screenHeight-1, tcell.StyleDefault, "ESC to quit")
 		}
 	}
-
-	screen.Show()
 }
 
 // renderMinimalScreen is a simplified UI for very small terminal windows
@@ -563,11 +1121,7 @@ func renderMinimalScreen(screen tcell.Screen, state *TestState, width, height in
 	
 	// Show minimal stats if we have room
 	if height > 4 && state.testStarted {
-		elapsed := time.Since(state.startTime).Seconds()
-		wpm := float64(len(state.userInput)/5) / (elapsed / 60.0)
-		if wpm < 0 || elapsed < 1 {
-			wpm = 0
-		}
+		wpm := state.wpm(state.elapsed())
 		
 		statsText := fmt.Sprintf("WPM:%.1f", wpm)
 		if width > len(statsText)+2 {
@@ -606,7 +1160,7 @@ func wrapText(text string, width int) []string {
 		currentWidth := 0
 		
 		for _, word := range words {
-			wordWidth := runewidth.StringWidth(word)
+			wordWidth := displayWidth(word)
 			
 			// If word is too wide for its own line, split it
 			if wordWidth > width {
@@ -616,25 +1170,25 @@ func wrapText(text string, width int) []string {
 					currentWidth = 0
 				}
 				
-				// Split the word manually
-				runes := []rune(word)
-				lineRunes := []rune{}
+				// Split the word manually, between grapheme clusters so an
+				// accented letter or an emoji is never broken across lines
+				linePart := ""
 				lineWidth := 0
 				
-				for _, r := range runes {
-					charWidth := runewidth.RuneWidth(r)
-					if lineWidth+charWidth > width {
-						lines = append(lines, string(lineRunes))
-						lineRunes = []rune{r}
+				for _, cluster := range splitGraphemes(word) {
+					charWidth := displayWidth(cluster)
+					if lineWidth+charWidth > width && linePart != "" {
+						lines = append(lines, linePart)
+						linePart = cluster
 						lineWidth = charWidth
 					} else {
-						lineRunes = append(lineRunes, r)
+						linePart += cluster
 						lineWidth += charWidth
 					}
 				}
 				
-				if len(lineRunes) > 0 {
-					currentLine = string(lineRunes)
+				if linePart != "" {
+					currentLine = linePart
 					currentWidth = lineWidth
 				}
 				continue
@@ -668,87 +1222,240 @@ func wrapText(text string, width int) []string {
 	return lines
 }
 
-func handlePostTest(screen tcell.Screen, state TestState, originalState *TestState) bool {
-	if !state.testComplete {
-		return true // Test was interrupted, continue with a new test
-	}
+func handlePostTest(screen tcell.Screen, state TestState, pbAchieved bool, results *resultStore, lib *library, signingKey ed25519.PrivateKey, playerName string, reducedMotion bool) rune {
 
 	screen.Clear()
 	width, height := screen.Size()
 	
 	// Calculate test metrics
-	duration := state.endTime.Sub(state.startTime).Minutes()
-	wpm := float64(len(state.referenceText)/5) / duration
-	accuracy := 100.0
-	if len(state.userInput) > 0 {
-		accuracy = 100.0 * (1.0 - float64(state.errors)/float64(len(state.userInput)))
-	}
-	if accuracy < 0 {
-		accuracy = 0
-	}
+	wpm := state.wpm(state.elapsed())
+	accuracy := calculateAccuracy(state.errors, state.typed())
 	
 	// Display results with more spacing
-	drawCenteredText(screen, width/2, height/2-8, tcell.StyleDefault, "TEST COMPLETE")
+	title := "TEST COMPLETE"
+	if state.failed {
+		title = fmt.Sprintf("FAILED AT CHARACTER %d OF %d", state.failedAt+1, len(state.reference))
+	} else if state.timeLimit > 0 && state.userInput != state.referenceText {
+		title = "TIME'S UP"
+	}
+	drawCenteredText(screen, width/2, height/2-8, tcell.StyleDefault, title)
+	if pbAchieved {
+		drawCenteredText(screen, width/2, height/2-7, tcell.StyleDefault, "NEW PERSONAL BEST")
+	} else if accuracy == 100 {
+		drawCenteredText(screen, width/2, height/2-7, tcell.StyleDefault, "PERFECT ACCURACY")
+	}
 	
 	// Show source
 	drawCenteredText(screen, width/2, height/2-6, tcell.StyleDefault, fmt.Sprintf("Source: %s", state.testFile))
+	if state.attribution != "" {
+		drawCenteredText(screen, width/2, height/2-5, tcell.StyleDefault, state.attribution)
+	}
+	if state.targetWPM > 0 {
+		drawCenteredText(screen, width/2, height/2, tcell.StyleDefault, targetText(wpm, state.targetWPM))
+	}
+	if state.scorer != nil {
+		drawCenteredText(screen, width/2, height/2-4, tcell.StyleDefault, fmt.Sprintf("Score: %.1f (%s)", state.score(wpm, accuracy), state.scorer.source))
+	}
 	
 	// Draw results with more spacing
-	drawCenteredText(screen, width/2, height/2-3, tcell.StyleDefault, fmt.Sprintf("WPM: %.1f", wpm))
+	drawCenteredText(screen, width/2, height/2-3, tcell.StyleDefault, fmt.Sprintf("WPM: %.1f (net %.1f)", wpm, calculateNetWPM(wpm, state.uncorrected, state.elapsed())))
+	if best, average, runs := results.fileStats(state.testFile, hashText(state.referenceText), averageWindow); runs > 1 {
+		drawCenteredText(screen, width/2, height/2-2, tcell.StyleDefault, fmt.Sprintf("Personal best: %.1f WPM | Average of last %d runs: %.1f WPM", best, runs, average))
+	}
 	drawCenteredText(screen, width/2, height/2-1, tcell.StyleDefault, fmt.Sprintf("Accuracy: %.1f%%", accuracy))
-	drawCenteredText(screen, width/2, height/2+1, tcell.StyleDefault, fmt.Sprintf("Time: %.1fs", state.endTime.Sub(state.startTime).Seconds()))
-	drawCenteredText(screen, width/2, height/2+3, tcell.StyleDefault, fmt.Sprintf("Characters: %d (Errors: %d)", len(state.userInput), state.errors))
+	timeText := fmt.Sprintf("Time: %.1fs (estimated %.1fs, %s)", state.elapsed().Seconds(), state.estimate().Seconds(), fasterOrSlower(1-state.elapsed().Seconds()/state.estimate().Seconds()))
+	if state.estimate() == 0 {
+		timeText = fmt.Sprintf("Time: %.1fs", state.elapsed().Seconds())
+	}
+	if state.timeLimit > 0 {
+		timeText = fmt.Sprintf("Time: %.1fs of %.0fs", state.elapsed().Seconds(), state.timeLimit.Seconds())
+	}
+	drawCenteredText(screen, width/2, height/2+1, tcell.StyleDefault, timeText)
+	if faster, runs := results.estimateBias(averageWindow); runs > 1 {
+		drawCenteredText(screen, width/2, height/2+2, tcell.StyleDefault, fmt.Sprintf("Last %d tests: %s than estimated on average", runs, fasterOrSlower(faster)))
+	}
+	drawCenteredText(screen, width/2, height/2+3, tcell.StyleDefault, fmt.Sprintf("Characters: %d (Errors: %d, %d corrected, %d uncorrected)", state.typed(), state.errors, state.errors-state.uncorrected, state.uncorrected))
+	drawCenteredText(screen, width/2, height/2+4, tcell.StyleDefault, economyText(state.economy()))
+	if session := results.sessionFlow(state.endTime); session > 0 {
+		drawCenteredText(screen, width/2, height/2+6, styleFlow, flowText(state.flow.total, session))
+	}
+	if len(state.opponents) > 0 {
+		place, of := state.placing()
+		drawCenteredText(screen, width/2, height/2+5, tcell.StyleDefault, fmt.Sprintf("Race: %s of %d", ordinal(place), of))
+	}
 	
 	// Draw options with more spacing
-	drawCenteredText(screen, width/2, height/2+6, tcell.StyleDefault, "R: Retry  N: New Test  Q: Quit")
+	options := "R: Retry  N: New Test  V: Review  S: Save Recording  C: Certificate  F: Star  X: Never Again  Q: Quit"
+	drawCenteredText(screen, width/2, height/2+7, tcell.StyleDefault, options)
+
+	// Graph speed through the test below the options, a sample a
+	// second, if there's room; a blind test shows what was typed instead
+	chartWidth, chartHeight := min(60, width-4), 3
+	if state.blind {
+		drawBlindReveal(screen, &state, height/2+10, width, height)
+	} else if samples := state.wpmPerSecond(); len(samples) > 1 && height/2+11+chartHeight <= height {
+		drawCenteredText(screen, width/2, height/2+10, tcell.StyleDefault, fmt.Sprintf("WPM each second (consistency: ±%.1f WPM)", speedDeviation(samples)))
+		drawLines(screen, (width-chartWidth)/2, height/2+11, chartHeight, tcell.StyleDefault, chart.Line(samples, chartWidth, chartHeight), 0)
+	}
 	
 	screen.Show()
+
+	// Celebrate a personal best or a perfect run, unless motion is reduced
+	var party *animation
+	if (pbAchieved || accuracy == 100) && !reducedMotion {
+		party = startAnimation(screen, celebrationFrames, celebrationInterval, confetti(rand.New(rand.NewSource(state.endTime.UnixNano())), width, height))
+	}
+	defer party.end()
 	
+	star := "Star this text"
+	if lib.isStarred(state.testFile) {
+		star = "Unstar this text"
+	}
+	commands := []command{
+		{title: "Retry this test", key: 'r'},
+		{title: "New test", key: 'n'},
+		{title: "Review errors", key: 'v'},
+		{title: "Save recording", key: 's'},
+		{title: "Save certificate", key: 'c'},
+		{title: star, key: 'f'},
+		{title: "Never show this text again", key: 'x'},
+		{title: "Quit", key: 'q'},
+	}
+
 	// Wait for user choice
 	for {
 		ev := screen.PollEvent()
 		switch ev := ev.(type) {
+		case *tcell.EventInterrupt:
+			party.step()
 		case *tcell.EventKey:
+			party.end() // before anything else is drawn
 			switch ev.Key() {
-			case tcell.KeyRune:
-				switch unicode := ev.Rune(); unicode {
+			case tcell.KeyRune, tcell.KeyCtrlP:
+				switch unicode := paletteKey(screen, ev, commands); unicode {
 				case 'R', 'r':
 					// Retry the same test
-					originalState.userInput = ""
-					originalState.errors = 0
-					originalState.testStarted = false
-					originalState.testComplete = false
-					return true
+					return 'r'
 				case 'N', 'n':
 					// New test
-					return true
+					return 'n'
+				case 'V', 'v':
+					return 'v'
+				case 'S', 's':
+					path, err := saveRun(&state, signingKey, time.Now())
+					message := "Saved " + path
+					if err != nil {
+						logger.Error("saving recording failed", "err", err)
+						message = fmt.Sprintf("Error saving recording: %v", err)
+					}
+					drawCenteredText(screen, width/2, height/2+8, tcell.StyleDefault, message)
+					screen.Show()
+				case 'F', 'f':
+					if !strings.HasSuffix(strings.ToLower(state.testFile), ".txt") {
+						break // only texts from the tests directory can be starred
+					}
+					starred := !lib.isStarred(state.testFile)
+					lib.setStarred(state.testFile, starred)
+					message := "Unstarred " + state.testFile
+					if starred {
+						message = "Starred " + state.testFile
+					}
+					if err := lib.save(); err != nil {
+						logger.Error("saving library failed", "err", err)
+						message = fmt.Sprintf("Error saving library: %v", err)
+					}
+					drawCenteredText(screen, width/2, height/2+8, tcell.StyleDefault, message)
+					screen.Show()
+				case 'X', 'x':
+					if !strings.HasSuffix(strings.ToLower(state.testFile), ".txt") {
+						break // only texts from the tests directory can be archived
+					}
+					lib.setArchived(state.testFile, true, time.Now())
+					message := "Archived " + state.testFile + "; it won't be picked again"
+					if err := lib.save(); err != nil {
+						logger.Error("saving library failed", "err", err)
+						message = fmt.Sprintf("Error saving library: %v", err)
+					}
+					drawCenteredText(screen, width/2, height/2+8, tcell.StyleDefault, message)
+					screen.Show()
+				case 'C', 'c':
+					path, err := saveRunCertificate(&state, playerName, signingKey, time.Now())
+					message := "Certificate saved to " + path
+					if err != nil {
+						logger.Error("saving certificate failed", "err", err)
+						message = fmt.Sprintf("Error saving certificate: %v", err)
+					}
+					drawCenteredText(screen, width/2, height/2+8, tcell.StyleDefault, message)
+					screen.Show()
 				case 'Q', 'q':
 					// Quit
-					return false
+					return 'q'
 				}
 			case tcell.KeyEscape:
-				return false
+				return 'q'
 			}
 		}
 	}
 }
 
-func drawError(screen tcell.Screen, message string) {
-	screen.Clear()
-	width, height := screen.Size()
-	
-	// Display error message with more spacing
-	drawCenteredText(screen, width/2, height/2-4, tcell.StyleDefault, "ERROR")
-	drawCenteredText(screen, width/2, height/2, tcell.StyleDefault, message)
-	drawCenteredText(screen, width/2, height/2+4, tcell.StyleDefault, "Press any key to retry, ESC to quit")
-	
-	screen.Show()
+// targetText compares wpm with the player's target
+func targetText(wpm, target float64) string {
+	if wpm >= target {
+		return fmt.Sprintf("Target %.0f WPM: reached", target)
+	}
+	return fmt.Sprintf("Target %.0f WPM: %.1f WPM short", target, target-wpm)
+}
+
+// fasterOrSlower describes a fraction such as estimateBias returns, e.g.
+// "6% faster"
+func fasterOrSlower(faster float64) string {
+	if faster < 0 {
+		return fmt.Sprintf("%.0f%% slower", -faster*100)
+	}
+	return fmt.Sprintf("%.0f%% faster", faster*100)
+}
+
+// startTicker posts an interrupt event to screen every interval so the
+// event loop redraws even without input. Call the returned function to
+// stop it.
+func startTicker(screen tcell.Screen, interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				// A full event queue just means a redraw is already pending
+				_ = screen.PostEvent(tcell.NewEventInterrupt(nil))
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// cursorBlinkOn reports whether the blinking cursor is in its visible
+// (reversed) phase at time t; the cursor spends 200ms in each phase
+func cursorBlinkOn(t time.Time) bool {
+	return t.UnixNano()/4e7%10 >= 5
 }
 
 // Helper function to draw text at a specific position
 func drawText(screen tcell.Screen, x, y int, style tcell.Style, text string) {
-	for i, r := range text {
-		screen.SetContent(x+i, y, r, nil, style)
+	// Each cell holds one grapheme cluster: its first rune plus any that
+	// follow it (combining marks, skin tones, ZWJ-joined emoji). The cell
+	// advances x by the cluster's display width, 2 for CJK and most emoji.
+	state := -1
+	for text != "" {
+		var cluster string
+		cluster, text, _, state = uniseg.FirstGraphemeClusterInString(text, state)
+		runes := []rune(cluster)
+		screen.SetContent(x, y, runes[0], runes[1:], style)
+		x += max(1, runewidth.StringWidth(cluster))
 	}
 }
 
@@ -773,4 +1480,32 @@ func waitForKey(screen tcell.Screen) bool {
 			screen.Sync()
 		}
 	}
-}
\ No newline at end of file
+}
+
+// waitForStart waits on the welcome screen like waitForKey, also
+// returning the character pressed so it can choose what to play: D for a
+// drill, or a number for a recent test. Ctrl+P opens the command palette
+// over commands, returning the chosen command's key.
+func waitForStart(screen tcell.Screen, commands []command) (key rune, ok bool) {
+	for {
+		switch ev := screen.PollEvent().(type) {
+		case *tcell.EventKey:
+			if ev.Key() == tcell.KeyEscape {
+				return 0, false
+			}
+			if ev.Key() == tcell.KeyCtrlP {
+				if c, chosen := runPalette(screen, commands, paletteOptions{}); chosen {
+					return c.key, true
+				}
+				continue
+			}
+			return bindings.key(ev.Rune()), true
+		case *tcell.EventInterrupt:
+			if _, ok := ev.Data().(controlWake); ok {
+				return controlKey, true
+			}
+		case *tcell.EventResize:
+			screen.Sync()
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Session metrics are numbers about the player rather than their typing,
+// such as resting heart rate, caffeine or hours slept, taken when keysmash
+// starts and stored with every result of the session, so they can be set
+// against speed and accuracy later. They're asked for at the start, or
+// read from the output of a hook command, or both.
+
+// metricsHookTimeout is how long the hook command gets to print its
+// metrics before it's killed
+const metricsHookTimeout = 5 * time.Second
+
+// metricNamePattern is what a metric's name may look like, so names
+// read well as JSON keys and column headings
+var metricNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// checkMetricNames checks session_metrics: valid names, none twice
+func checkMetricNames(names []string) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !metricNamePattern.MatchString(name) {
+			return fmt.Errorf("%q is not a metric name (want lowercase letters, digits and _)", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("%q is listed twice", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// parseMetricValue reads a metric's value. NaN and infinities parse as
+// floats but aren't numbers anyone measured, and JSON can't store them,
+// so they're refused.
+func parseMetricValue(s string) (float64, error) {
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("%q is not a finite number", s)
+	}
+	return value, nil
+}
+
+// parseMetrics reads a hook's output: one "name value" or "name=value"
+// per line, skipping blank lines and # comments. Lines that aren't a
+// metric are reported in skipped rather than failing the rest.
+func parseMetrics(output string) (metrics map[string]float64, skipped []string) {
+	metrics = make(map[string]float64)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(strings.Replace(line, "=", " ", 1))
+		if len(fields) != 2 || !metricNamePattern.MatchString(fields[0]) {
+			skipped = append(skipped, line)
+			continue
+		}
+		value, err := parseMetricValue(fields[1])
+		if err != nil {
+			skipped = append(skipped, line)
+			continue
+		}
+		metrics[fields[0]] = value
+	}
+	return metrics, skipped
+}
+
+// runMetricsHook runs command with the shell and parses what it prints
+func runMetricsHook(command string) (map[string]float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsHookTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "sh", "-c", command).Output()
+	if err != nil {
+		return nil, fmt.Errorf("metrics hook %q: %w", command, err)
+	}
+	metrics, skipped := parseMetrics(string(out))
+	for _, line := range skipped {
+		logger.Warn("metrics hook printed a line that isn't a metric", "line", line)
+	}
+	return metrics, nil
+}
+
+// askMetrics asks for each of names in turn, skipping any already in
+// metrics, and adds the answers. A blank answer skips one; Escape skips
+// the rest.
+func askMetrics(screen tcell.Screen, names []string, metrics map[string]float64) {
+	for _, name := range names {
+		if _, ok := metrics[name]; ok {
+			continue
+		}
+		var field inputField
+		problem := ""
+		for answered := false; !answered; {
+			screen.Clear()
+			drawBox(screen, []string{
+				"Before you start: " + strings.ReplaceAll(name, "_", " ") + "?",
+				"",
+				"> " + field.String(),
+				problem,
+				"Enter: next (blank skips)  Esc: skip the rest",
+			})
+			screen.Show()
+
+			ev, isKey := screen.PollEvent().(*tcell.EventKey)
+			if !isKey {
+				continue
+			}
+			switch ev.Key() {
+			case tcell.KeyEscape:
+				return
+			case tcell.KeyEnter:
+				if field.String() == "" {
+					answered = true
+					break
+				}
+				value, err := parseMetricValue(strings.TrimSpace(field.String()))
+				if err != nil {
+					problem = "That isn't a number"
+					break
+				}
+				metrics[name] = value
+				answered = true
+			default:
+				field.handle(ev)
+			}
+		}
+	}
+}
+
+// sessionMetrics gathers the session's metrics as cfg asks: from the
+// hook, then the prompts for whatever it didn't give. It returns nil if
+// none are configured or none were given.
+func sessionMetrics(screen tcell.Screen, cfg Config) map[string]float64 {
+	metrics := make(map[string]float64)
+	if cfg.MetricsHook != "" {
+		hooked, err := runMetricsHook(cfg.MetricsHook)
+		if err != nil {
+			logger.Error("metrics hook failed", "err", err)
+		}
+		for name, value := range hooked {
+			metrics[name] = value
+		}
+	}
+	askMetrics(screen, cfg.SessionMetrics, metrics)
+	if len(metrics) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	logger.Info("session metrics", "names", names)
+	return metrics
+}
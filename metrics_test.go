@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseMetrics(t *testing.T) {
+	metrics, skipped := parseMetrics("# from my watch\nresting_hr 58\n\nsleep_hours=7.5\ncaffeine_mg = 80\nmood great\nNot A Name 1\nstress NaN\nsteps=+Inf\n")
+	if want := map[string]float64{"resting_hr": 58, "sleep_hours": 7.5, "caffeine_mg": 80}; !reflect.DeepEqual(metrics, want) {
+		t.Errorf("metrics = %v, want %v", metrics, want)
+	}
+	if want := []string{"mood great", "Not A Name 1", "stress NaN", "steps=+Inf"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped = %q, want %q", skipped, want)
+	}
+
+	for _, bad := range [][]string{{"Resting HR"}, {"hr", "hr"}, {""}} {
+		if err := checkMetricNames(bad); err == nil {
+			t.Errorf("checkMetricNames(%q) accepted it", bad)
+		}
+	}
+}
+
+func TestRunMetricsHook(t *testing.T) {
+	metrics, err := runMetricsHook("echo resting_hr 61")
+	if err != nil || metrics["resting_hr"] != 61 {
+		t.Errorf("got %v, %v", metrics, err)
+	}
+	if _, err := runMetricsHook("exit 3"); err == nil {
+		t.Error("a failing hook gave no error")
+	}
+}
+
+func TestAskMetrics(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(80, 24)
+
+	// resting_hr came from the hook; caffeine is mistyped, then fixed;
+	// sleep is skipped and Escape leaves mood unasked
+	metrics := map[string]float64{"resting_hr": 58}
+	screen.InjectKeyBytes([]byte("8o"))
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyBackspace2, 0, tcell.ModNone)
+	screen.InjectKeyBytes([]byte("0"))
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	askMetrics(screen, []string{"resting_hr", "caffeine_mg", "sleep_hours", "mood"}, metrics)
+
+	if want := map[string]float64{"resting_hr": 58, "caffeine_mg": 80}; !reflect.DeepEqual(metrics, want) {
+		t.Errorf("metrics = %v, want %v", metrics, want)
+	}
+
+	// NaN parses as a float but isn't a number, so it's asked again
+	metrics = map[string]float64{}
+	screen.InjectKeyBytes([]byte("nan"))
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	askMetrics(screen, []string{"stress"}, metrics)
+	if len(metrics) != 0 {
+		t.Errorf("metrics = %v, want NaN refused", metrics)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// highEchoLatency is the calibrated median echo latency above which the
+// terminal is treated as remote even if no SSH or mosh session is found
+const highEchoLatency = 15 * time.Millisecond
+
+// reducedMotionTick is how often the test screen refreshes on its own in
+// reduced-motion mode, against 100ms normally
+const reducedMotionTick = time.Second
+
+// renderOptions adjust how renderScreen draws a frame
+type renderOptions struct {
+	// reducedMotion draws a steady cursor and shows stats from stats
+	// instead of live, so a keystroke changes as few cells as possible
+	reducedMotion bool
+
+	// lowPower goes with reducedMotion and slows the refresh further, so
+	// the process wakes up as little as possible on battery
+	lowPower bool
+
+	// maxFPS caps how many frames a second the test screen draws, however
+	// fast events arrive; 0 is no cap
+	maxFPS int
+
+	// stats, if set, are the numbers shown above the text; nil shows them
+	// live
+	stats *frameStats
+
+	// split draws the text and the typing side by side on wide screens;
+	// see drawSplit
+	split bool
+
+	// largeText shows the characters around the typing position spaced
+	// out above the text; see drawLargeText
+	largeText bool
+
+	// lineNumbers numbers the lines of the text and shows the line and
+	// column being typed; see lineNumbers
+	lineNumbers bool
+
+	// speedGauge shows WPM on a gauge instead of in the stats line,
+	// when there's room for it
+	speedGauge bool
+
+	// inputMethod shows the terminal's cursor at the insertion point
+	// instead of drawing one, for typing through an IME; see drawCursor
+	inputMethod bool
+
+	// reference, if set, keeps the wrapped reference text between frames,
+	// since it only changes when the width or the window of it shown does
+	reference *wrapCache
+}
+
+// wrapCache remembers the last wrapText result
+type wrapCache struct {
+	text    string
+	width   int
+	lines   []string
+	offsets []int // where each line starts in text, once asked for
+}
+
+// wrap returns wrapText(text, width), reusing the previous result if
+// neither has changed. A nil cache always wraps.
+func (c *wrapCache) wrap(text string, width int) []string {
+	if c == nil {
+		return wrapText(text, width)
+	}
+	if c.lines == nil || c.width != width || c.text != text {
+		c.text, c.width, c.lines = text, width, wrapText(text, width)
+		c.offsets = nil
+	}
+	return c.lines
+}
+
+// lineOffsets returns lineOffsets(text, lines) for the lines last returned
+// by wrap(text, ...), reusing the previous result if they haven't changed.
+// A nil cache always finds them.
+func (c *wrapCache) lineOffsets(text string, lines []string) []int {
+	if c == nil {
+		return lineOffsets(text, lines)
+	}
+	if c.offsets == nil {
+		c.offsets = lineOffsets(text, lines)
+	}
+	return c.offsets
+}
+
+// frameStats are a sample of the live numbers shown above the text
+type frameStats struct {
+	elapsed time.Duration
+	wpm     float64
+	errors  int
+}
+
+func sampleStats(state *TestState) *frameStats {
+	elapsed := state.elapsed()
+	return &frameStats{elapsed: elapsed, wpm: state.wpm(elapsed), errors: state.errors}
+}
+
+// remoteSession names the remote connection keysmash is running over:
+// "ssh", "mosh", or "" for none found
+func remoteSession() string {
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" {
+		return "ssh"
+	}
+	// mosh-server doesn't pass SSH's variables on to the shell, so look
+	// for it among our ancestors instead
+	if hasAncestor("mosh-server") {
+		return "mosh"
+	}
+	return ""
+}
+
+// hasAncestor reports whether a process named name is an ancestor of this
+// one. It reads /proc, so it's always false where there is none.
+func hasAncestor(name string) bool {
+	pid := os.Getppid()
+	for depth := 0; pid > 1 && depth < 32; depth++ {
+		stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err != nil {
+			return false
+		}
+		comm, ppid, ok := parseProcStat(string(stat))
+		if !ok {
+			return false
+		}
+		if comm == name {
+			return true
+		}
+		pid = ppid
+	}
+	return false
+}
+
+// parseProcStat extracts the command name and parent pid from the
+// contents of /proc/PID/stat: "PID (COMM) STATE PPID ...". The name is
+// parenthesised and may itself contain spaces and parentheses.
+func parseProcStat(stat string) (comm string, ppid int, ok bool) {
+	open, close := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+	if open < 0 || close < open {
+		return "", 0, false
+	}
+	fields := strings.Fields(stat[close+1:])
+	if len(fields) < 2 {
+		return "", 0, false
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, false
+	}
+	return stat[open+1 : close], ppid, true
+}
+
+// reducedMotionReason says why reduced-motion mode should be on, or ""
+// if it shouldn't. forced is the --reduced-motion flag.
+func reducedMotionReason(forced bool, remote string, latency *latencyProfiles) string {
+	switch {
+	case forced:
+		return "--reduced-motion"
+	case remote != "":
+		return remote + " session"
+	}
+	if profile, ok := latency.current(); ok && profile.Median >= highEchoLatency {
+		return fmt.Sprintf("calibrated echo latency %v", profile.Median.Round(time.Millisecond))
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	comm, ppid, ok := parseProcStat("4242 (mosh-server) S 4100 4242 4242 0 -1 4194560")
+	if !ok || comm != "mosh-server" || ppid != 4100 {
+		t.Errorf("got %q, %d, %v", comm, ppid, ok)
+	}
+	// Command names can contain spaces and parentheses
+	comm, ppid, ok = parseProcStat("7 (tmux: server (1)) S 1 7 7 0")
+	if !ok || comm != "tmux: server (1)" || ppid != 1 {
+		t.Errorf("got %q, %d, %v", comm, ppid, ok)
+	}
+	if _, _, ok := parseProcStat("garbage"); ok {
+		t.Error("parsed garbage")
+	}
+}
+
+func TestReducedMotionReason(t *testing.T) {
+	t.Setenv("TERM", "xterm")
+	t.Setenv("SSH_CONNECTION", "")
+	t.Setenv("SSH_TTY", "")
+	slow := &latencyProfiles{Profiles: map[string]latencyProfile{
+		terminalKey(): {Median: 40 * time.Millisecond},
+	}}
+	fast := &latencyProfiles{Profiles: map[string]latencyProfile{
+		terminalKey(): {Median: time.Millisecond},
+	}}
+
+	cases := []struct {
+		forced  bool
+		remote  string
+		latency *latencyProfiles
+		want    string
+	}{
+		{false, "", nil, ""},
+		{false, "", fast, ""},
+		{true, "", nil, "--reduced-motion"},
+		{false, "mosh", nil, "mosh session"},
+		{false, "", slow, "latency"},
+	}
+	for _, c := range cases {
+		got := reducedMotionReason(c.forced, c.remote, c.latency)
+		if (c.want == "") != (got == "") || !strings.Contains(got, c.want) {
+			t.Errorf("reducedMotionReason(%v, %q) = %q, want %q", c.forced, c.remote, got, c.want)
+		}
+	}
+}
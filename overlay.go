@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// The stream overlay serves the live test over HTTP for an OBS browser
+// source: /state.json is the test on screen, as the control API reports
+// it, with the race's racers, and / is a page that polls it and shows
+// WPM, accuracy and progress on a transparent background. Like the
+// spectator feed it only reads Engine.Snapshot.
+
+// overlayPoll is how often the overlay page asks for the state, in
+// milliseconds
+const overlayPoll = 250
+
+// overlayState is what /state.json serves
+type overlayState struct {
+	controlState
+	Racers []frameRacer `json:"racers,omitempty"`
+}
+
+// newOverlayState describes a snapshot of the test for the overlay
+func newOverlayState(state TestState, hostName string) overlayState {
+	return overlayState{controlState: *newControlState(state), Racers: newRaceFrame(state, hostName).Racers}
+}
+
+// serveOverlay serves the overlay on addr until the listener is closed
+func serveOverlay(addr string, engine *Engine) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("serving the overlay: %w", err)
+	}
+	hostName := os.Getenv("USER")
+	if hostName == "" {
+		hostName = "Host"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/state.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		state, ok := engine.Snapshot()
+		if !ok {
+			io.WriteString(w, "{}\n")
+			return
+		}
+		if err := json.NewEncoder(w).Encode(newOverlayState(state, hostName)); err != nil {
+			logger.Debug("writing overlay state failed", "err", err)
+		}
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, overlayPage, overlayPoll)
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, net.ErrClosed) {
+			logger.Error("overlay server failed", "err", err)
+		}
+	}()
+	return listener, nil
+}
+
+// overlayPage is the overlay itself, polling /state.json every %d
+// milliseconds. It's sized and styled for a browser source over a
+// stream: large, bold and transparent behind.
+const overlayPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>keysmash overlay</title>
+<style>
+  body { background: transparent; color: #fff; font: bold 28px sans-serif; text-shadow: 0 0 4px #000; margin: 8px; }
+  .stats span { margin-right: 1em; }
+  .bar { width: 400px; height: 10px; background: rgba(0,0,0,.4); margin: 4px 0; }
+  .bar div { height: 100%%; background: #4c4; }
+  .racer { font-size: 18px; }
+</style>
+</head>
+<body>
+<div class="stats"><span id="wpm">0 WPM</span><span id="accuracy">100%%</span><span id="file"></span></div>
+<div class="bar"><div id="progress" style="width: 0"></div></div>
+<div id="racers"></div>
+<script>
+async function poll() {
+  try {
+    const s = await (await fetch("/state.json")).json();
+    if (s.file !== undefined) {
+      document.getElementById("wpm").textContent = Math.round(s.wpm) + " WPM";
+      document.getElementById("accuracy").textContent = s.accuracy.toFixed(1) + "%%";
+      document.getElementById("file").textContent = s.file;
+      document.getElementById("progress").style.width = (s.length ? 100 * s.typed / s.length : 0) + "%%";
+    }
+    const racers = document.getElementById("racers");
+    racers.replaceChildren(...(s.racers || []).map(r => {
+      const div = document.createElement("div");
+      div.className = "racer";
+      div.textContent = r.name + ": " + Math.round(100 * r.progress) + "%% at " + Math.round(r.wpm) + " WPM";
+      return div;
+    }));
+  } catch (e) {}
+}
+setInterval(poll, %[1]d);
+poll();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOverlayServesState(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "stream.txt"), []byte("on air"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("USER", "host")
+
+	engine := newEngine(systemClock{}, fixedRand(0), dir)
+	listener, err := serveOverlay("127.0.0.1:0", engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	base := "http://" + listener.Addr().String()
+
+	get := func(path string) string {
+		t.Helper()
+		resp, err := http.Get(base + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s = %s", path, resp.Status)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	if body := get("/state.json"); strings.TrimSpace(body) != "{}" {
+		t.Errorf("state before any test = %q, want {}", body)
+	}
+
+	state, err := engine.selectRandomTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.typeRune('o')
+	state.typeRune('x')
+
+	var got overlayState
+	if err := json.Unmarshal([]byte(get("/state.json")), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.File != "stream.txt" || !got.Started || got.Typed != 2 || got.Length != 6 || got.Errors != 1 {
+		t.Errorf("state = %+v, want 2 of 6 typed on stream.txt with 1 error", got.controlState)
+	}
+	if len(got.Racers) != 1 || got.Racers[0].Name != "host" {
+		t.Errorf("racers = %+v, want just host", got.Racers)
+	}
+
+	page := get("/")
+	if !strings.Contains(page, `fetch("/state.json")`) || !strings.Contains(page, "setInterval(poll, 250)") {
+		t.Errorf("overlay page doesn't poll the state:\n%s", page)
+	}
+}
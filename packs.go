@@ -0,0 +1,412 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// packFilePrefix marks challenge pack tests in TestState.testFile
+const packFilePrefix = "pack-"
+
+// maxPackSize bounds a fetched pack, which is only text and rules
+const maxPackSize = 1 << 20
+
+// challengePack is a themed bundle of texts with goals and badges, such as
+// "Punctuation November", read from a .json file in the challenges
+// directory
+type challengePack struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Starts      string      `json:"starts,omitempty"` // YYYY-MM-DD; a pack without dates is always open
+	Ends        string      `json:"ends,omitempty"`
+	Challenges  []challenge `json:"challenges"`
+	Badges      []badge     `json:"badges"`
+
+	file string
+}
+
+// challenge is one text in a pack and what a run of it must achieve
+type challenge struct {
+	Title       string  `json:"title"`
+	Text        string  `json:"text"`
+	MinWPM      float64 `json:"min_wpm,omitempty"`
+	MinAccuracy float64 `json:"min_accuracy,omitempty"`
+	MaxErrors   *int    `json:"max_errors,omitempty"`
+}
+
+// badge is awarded once enough of a pack's challenges are met
+type badge struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Requires    int    `json:"requires"` // challenges met; 0 means all of them
+}
+
+// met reports whether a completed run satisfies the challenge
+func (c challenge) met(ev Event) bool {
+	return ev.WPM >= c.MinWPM && ev.Accuracy >= c.MinAccuracy && (c.MaxErrors == nil || ev.Errors <= *c.MaxErrors)
+}
+
+// goal describes the challenge's constraints for the welcome screen
+func (c challenge) goal() string {
+	var parts []string
+	if c.MinWPM > 0 {
+		parts = append(parts, fmt.Sprintf("%.0f WPM", c.MinWPM))
+	}
+	if c.MinAccuracy > 0 {
+		parts = append(parts, fmt.Sprintf("%.0f%% accuracy", c.MinAccuracy))
+	}
+	if c.MaxErrors != nil {
+		parts = append(parts, fmt.Sprintf("at most %d errors", *c.MaxErrors))
+	}
+	if len(parts) == 0 {
+		return "complete it"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (p challengePack) validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name is required")
+	}
+	if len(p.Challenges) == 0 {
+		return errors.New("at least one challenge is required")
+	}
+	titles := make(map[string]bool)
+	for i, c := range p.Challenges {
+		if strings.TrimSpace(c.Title) == "" || strings.TrimSpace(c.Text) == "" {
+			return fmt.Errorf("challenge %d needs a title and text", i+1)
+		}
+		if titles[c.Title] {
+			return fmt.Errorf("challenge title %q is used twice", c.Title)
+		}
+		titles[c.Title] = true
+		if c.MinWPM < 0 || c.MinAccuracy < 0 || c.MinAccuracy > 100 || (c.MaxErrors != nil && *c.MaxErrors < 0) {
+			return fmt.Errorf("challenge %q has an impossible goal", c.Title)
+		}
+	}
+	for _, b := range p.Badges {
+		if strings.TrimSpace(b.Name) == "" || b.Requires < 0 || b.Requires > len(p.Challenges) {
+			return fmt.Errorf("badge %q needs a name and between 0 and %d challenges", b.Name, len(p.Challenges))
+		}
+	}
+	for _, date := range []string{p.Starts, p.Ends} {
+		if _, err := time.Parse(dateLayout, date); date != "" && err != nil {
+			return fmt.Errorf("bad date %q (want YYYY-MM-DD)", date)
+		}
+	}
+	return nil
+}
+
+// open reports whether the pack's season includes today, describing when
+// it runs if not
+func (p challengePack) open(today time.Time) (bool, string) {
+	date := today.Format(dateLayout)
+	switch {
+	case p.Starts != "" && date < p.Starts:
+		return false, fmt.Sprintf("%s opens on %s", p.Name, p.Starts)
+	case p.Ends != "" && date > p.Ends:
+		return false, fmt.Sprintf("%s closed on %s", p.Name, p.Ends)
+	}
+	return true, ""
+}
+
+// parsePack decodes and validates a pack, rejecting unknown fields like
+// config.toml does
+func parsePack(r io.Reader) (challengePack, error) {
+	var pack challengePack
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&pack); err != nil {
+		return pack, err
+	}
+	return pack, pack.validate()
+}
+
+func challengesDir() (string, error) {
+	return dataFile("challenges")
+}
+
+// loadPacks reads every .json pack in dir, by name
+func loadPacks(dir string) ([]challengePack, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var packs []challengePack
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		pack, err := parsePack(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("challenge pack %s: %w", path, err)
+		}
+		pack.file = path
+		packs = append(packs, pack)
+	}
+	sort.Slice(packs, func(i, j int) bool { return packs[i].Name < packs[j].Name })
+	return packs, nil
+}
+
+// packProgress is which challenges and badges the player has won in each
+// pack, keyed by pack name
+type packProgress struct {
+	Packs map[string]packRecord `json:"packs"`
+	path  string
+}
+
+type packRecord struct {
+	Met    map[string]time.Time `json:"met"`    // by challenge title
+	Badges map[string]time.Time `json:"badges"` // by badge name
+}
+
+func loadPackProgress() (*packProgress, error) {
+	path, err := dataFile("packs.json")
+	if err != nil {
+		return nil, err
+	}
+	progress := &packProgress{Packs: make(map[string]packRecord), path: path}
+	if err := readJSONFile(path, progress); err != nil {
+		return nil, err
+	}
+	if progress.Packs == nil {
+		progress.Packs = make(map[string]packRecord)
+	}
+	return progress, nil
+}
+
+func (p *packProgress) save() error {
+	if p.path == "" {
+		return nil
+	}
+	return saveDataFile(p.path, p)
+}
+
+// record notes a completed run of a challenge, and returns any badges it
+// earned
+func (p *packProgress) record(pack challengePack, c challenge, ev Event) []badge {
+	record := p.Packs[pack.Name]
+	if record.Met == nil {
+		record.Met = make(map[string]time.Time)
+		record.Badges = make(map[string]time.Time)
+	}
+	defer func() { p.Packs[pack.Name] = record }()
+	if !c.met(ev) {
+		return nil
+	}
+	if _, done := record.Met[c.Title]; !done {
+		record.Met[c.Title] = ev.Time
+	}
+
+	var earned []badge
+	for _, b := range pack.Badges {
+		requires := b.Requires
+		if requires == 0 {
+			requires = len(pack.Challenges)
+		}
+		if _, has := record.Badges[b.Name]; !has && len(record.Met) >= requires {
+			record.Badges[b.Name] = ev.Time
+			earned = append(earned, b)
+		}
+	}
+	return earned
+}
+
+// packSession is pack mode's chosen pack and the player's progress
+type packSession struct {
+	pack     challengePack
+	progress *packProgress
+}
+
+// next returns the first challenge not yet met; once all are, the pack
+// starts over for practice
+func (s *packSession) next() challenge {
+	record := s.progress.Packs[s.pack.Name]
+	for _, c := range s.pack.Challenges {
+		if _, done := record.Met[c.Title]; !done {
+			return c
+		}
+	}
+	return s.pack.Challenges[len(record.Met)%len(s.pack.Challenges)]
+}
+
+// packFile is the testFile for a challenge
+func packFile(pack challengePack, c challenge) string {
+	return packFilePrefix + pack.Name + ": " + c.Title
+}
+
+// packTest returns the next challenge of the pack, if it's in season
+func (e *Engine) packTest() (TestState, error) {
+	if open, why := e.pack.pack.open(e.clock.Now()); !open {
+		return TestState{}, errors.New(why)
+	}
+	c := e.pack.next()
+	logger.Info("selected challenge", "pack", e.pack.pack.Name, "challenge", c.Title)
+	text, err := e.prepareText(c.Text, packFile(e.pack.pack, c))
+	if err != nil {
+		return TestState{}, err
+	}
+	return e.newTest(text, packFile(e.pack.pack, c)), nil
+}
+
+// recordPackResults updates pack progress whenever a challenge completes
+func recordPackResults(bus *EventBus, s *packSession) {
+	bus.Subscribe(func(ev Event) {
+		for _, c := range s.pack.Challenges {
+			if ev.TestFile != packFile(s.pack, c) {
+				continue
+			}
+			for _, b := range s.progress.record(s.pack, c, ev) {
+				logger.Info("badge earned", "pack", s.pack.Name, "badge", b.Name)
+				bus.Publish(Event{Kind: EventBadgeEarned, Time: ev.Time, TestFile: ev.TestFile, Badge: b.Name})
+			}
+			if err := s.progress.save(); err != nil {
+				logger.Error("saving pack progress failed", "err", err)
+			}
+		}
+	}, EventTestCompleted)
+}
+
+// loadPackSession finds the named pack in the challenges directory
+func loadPackSession(name string) (*packSession, error) {
+	dir, err := challengesDir()
+	if err != nil {
+		return nil, err
+	}
+	packs, err := loadPacks(dir)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := loadPackProgress()
+	if err != nil {
+		return nil, err
+	}
+	for _, pack := range packs {
+		if strings.EqualFold(pack.Name, name) {
+			return &packSession{pack: pack, progress: progress}, nil
+		}
+	}
+	return nil, fmt.Errorf("no challenge pack named %q in %s (see keysmash pack list)", name, dir)
+}
+
+// showPackWelcomeScreen replaces the welcome screen in pack mode with the
+// pack's progress, next challenge and badges
+func showPackWelcomeScreen(screen tcell.Screen, s *packSession, today time.Time) {
+	screen.Clear()
+	width, height := screen.Size()
+	record := s.progress.Packs[s.pack.Name]
+
+	drawCenteredText(screen, width/2, height/2-8, tcell.StyleDefault, "KEYSMASH")
+	drawCenteredText(screen, width/2, height/2-6, tcell.StyleDefault, strings.ToUpper(s.pack.Name))
+	drawCenteredText(screen, width/2, height/2-5, tcell.StyleDefault, s.pack.Description)
+	drawCenteredText(screen, width/2, height/2-3, tcell.StyleDefault, fmt.Sprintf("%d of %d challenges met", len(record.Met), len(s.pack.Challenges)))
+	if open, why := s.pack.open(today); !open {
+		drawCenteredText(screen, width/2, height/2-1, tcell.StyleDefault, why)
+	} else {
+		c := s.next()
+		drawCenteredText(screen, width/2, height/2-1, tcell.StyleDefault, fmt.Sprintf("Next: %s (%s)", c.Title, c.goal()))
+	}
+
+	var badges []string
+	for _, b := range s.pack.Badges {
+		if _, has := record.Badges[b.Name]; has {
+			badges = append(badges, "["+b.Name+"]")
+		}
+	}
+	if len(badges) > 0 {
+		drawCenteredText(screen, width/2, height/2+1, tcell.StyleDefault, "Badges: "+strings.Join(badges, " "))
+	}
+	drawCenteredText(screen, width/2, height/2+3, tcell.StyleDefault, "Press any key to start, Ctrl+P for commands, ESC to quit")
+
+	screen.Show()
+}
+
+// fetchPack downloads a pack from url, checks it and saves it to dir
+// under its own name, returning the path
+func fetchPack(client *http.Client, url, dir string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPackSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxPackSize {
+		return "", fmt.Errorf("%s is larger than %d bytes", url, maxPackSize)
+	}
+	pack, err := parsePack(strings.NewReader(string(data)))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", url, err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fileSafe(strings.ToLower(pack.Name))+".json")
+	return path, os.WriteFile(path, data, 0o644)
+}
+
+// runPack lists packs or fetches one for the pack subcommand. It returns
+// the process exit code.
+func runPack(w io.Writer, args []string) int {
+	dir, err := challengesDir()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	switch {
+	case len(args) == 2 && args[0] == "fetch":
+		client := &http.Client{Timeout: 30 * time.Second}
+		path, err := fetchPack(client, args[1], dir)
+		if err != nil {
+			fmt.Fprintf(w, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(w, "Saved %s\n", path)
+		return 0
+	case len(args) == 0 || len(args) == 1 && args[0] == "list":
+	default:
+		fmt.Fprintln(w, "Usage: keysmash pack [list | fetch URL]")
+		return 2
+	}
+
+	packs, err := loadPacks(dir)
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	progress, err := loadPackProgress()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	if len(packs) == 0 {
+		fmt.Fprintf(w, "No challenge packs in %s\n", dir)
+	}
+	for _, pack := range packs {
+		record := progress.Packs[pack.Name]
+		status := ""
+		if open, why := pack.open(time.Now()); !open {
+			status = " (" + why + ")"
+		}
+		fmt.Fprintf(w, "%s: %d/%d challenges, %d/%d badges%s\n", pack.Name, len(record.Met), len(pack.Challenges), len(record.Badges), len(pack.Badges), status)
+	}
+	return 0
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const punctuationPack = `{
+	"name": "Punctuation November",
+	"description": "A month of commas",
+	"starts": "2026-11-01",
+	"ends": "2026-11-30",
+	"challenges": [
+		{"title": "Commas", "text": "a, b, c", "min_wpm": 30},
+		{"title": "Quotes", "text": "\"a\" 'b'", "min_accuracy": 95, "max_errors": 0}
+	],
+	"badges": [
+		{"name": "First Comma", "requires": 1},
+		{"name": "Punctuator"}
+	]
+}`
+
+func TestParsePack(t *testing.T) {
+	pack, err := parsePack(strings.NewReader(punctuationPack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pack.Challenges) != 2 || *pack.Challenges[1].MaxErrors != 0 {
+		t.Errorf("pack = %+v", pack)
+	}
+	if got := pack.Challenges[1].goal(); got != "95% accuracy, at most 0 errors" {
+		t.Errorf("goal = %q", got)
+	}
+
+	for _, bad := range []struct{ body, want string }{
+		{`{"name": "x", "challenges": [{"title": "a", "text": "a"}], "prize": 1}`, "unknown field"},
+		{`{"name": "x", "challenges": []}`, "at least one challenge"},
+		{`{"name": "x", "challenges": [{"title": "a", "text": "a"}, {"title": "a", "text": "b"}]}`, "used twice"},
+		{`{"name": "x", "challenges": [{"title": "a", "text": "a"}], "badges": [{"name": "b", "requires": 2}]}`, "badge"},
+		{`{"name": "x", "starts": "Nov 1", "challenges": [{"title": "a", "text": "a"}]}`, "bad date"},
+	} {
+		if _, err := parsePack(strings.NewReader(bad.body)); err == nil || !strings.Contains(err.Error(), bad.want) {
+			t.Errorf("%s: err = %v, want %q", bad.body, err, bad.want)
+		}
+	}
+}
+
+func TestPackProgress(t *testing.T) {
+	pack, err := parsePack(strings.NewReader(punctuationPack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}
+	engine := newEngine(clock, fixedRand(0), t.TempDir())
+	engine.mode = modePack
+	engine.pack = &packSession{pack: pack, progress: &packProgress{Packs: make(map[string]packRecord)}}
+	recordPackResults(engine.events, engine.pack)
+	var earned []string
+	engine.events.Subscribe(func(ev Event) { earned = append(earned, ev.Badge) }, EventBadgeEarned)
+
+	// Out of season
+	if _, err := engine.nextTest(); err == nil || !strings.Contains(err.Error(), "opens on 2026-11-01") {
+		t.Errorf("before the season: err = %v", err)
+	}
+	clock.now = time.Date(2026, 11, 3, 9, 0, 0, 0, time.UTC)
+
+	// complete runs the next challenge with the given results
+	complete := func(wpm, accuracy float64, errors int) string {
+		t.Helper()
+		state, err := engine.nextTest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		engine.events.Publish(Event{Kind: EventTestCompleted, Time: clock.now, TestFile: state.testFile, WPM: wpm, Accuracy: accuracy, Errors: errors})
+		return state.referenceText
+	}
+
+	record := func() packRecord { return engine.pack.progress.Packs[pack.Name] }
+	complete(20, 100, 0) // too slow
+	if len(record().Met) != 0 {
+		t.Fatal("challenge met below its WPM goal")
+	}
+	complete(35, 90, 2)
+	if len(record().Met) != 1 || len(record().Badges) != 1 {
+		t.Fatalf("after one challenge: %+v", record())
+	}
+	if got := complete(50, 99, 1); got != `"a" 'b'` || len(record().Met) != 1 {
+		t.Errorf("an error counted against max_errors 0: %+v", record())
+	}
+	complete(50, 100, 0)
+	if _, has := record().Badges["Punctuator"]; !has {
+		t.Errorf("no badge for meeting every challenge: %+v", record())
+	}
+	if len(earned) != 2 || earned[1] != "Punctuator" {
+		t.Errorf("badge events = %q, want one per badge", earned)
+	}
+}
+
+func TestFetchPack(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/november.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(punctuationPack))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	path, err := fetchPack(server.Client(), server.URL+"/november.json", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(path, "punctuation_november.json") {
+		t.Errorf("saved to %s", path)
+	}
+	packs, err := loadPacks(dir)
+	if err != nil || len(packs) != 1 || packs[0].Name != "Punctuation November" {
+		t.Errorf("loaded %+v, %v", packs, err)
+	}
+
+	if _, err := fetchPack(server.Client(), server.URL+"/missing.json", dir); err == nil {
+		t.Error("fetched a missing pack")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("%d files after a failed fetch, want 1", len(entries))
+	}
+}
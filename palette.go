@@ -0,0 +1,423 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+)
+
+// command is an action offered by the Ctrl+P command palette. Choosing it
+// does the same as pressing key on the screen the palette was opened from.
+type command struct {
+	title string
+	key   rune
+	file  string // the text a test picker entry opens
+}
+
+// shortcut describes c's key for the palette's listing
+func (c command) shortcut() string {
+	switch {
+	case c.key == ' ':
+		return "Space"
+	case c.key > ' ':
+		return string(unicode.ToUpper(bindings.boundTo(c.key)))
+	}
+	return ""
+}
+
+// fuzzyMatch matches query against title as a case-insensitive
+// subsequence, so "sr" finds "Save Recording", returning the rune indexes
+// of title that matched. Higher scores are better matches: runs of
+// consecutive letters and letters that start a word count extra, and gaps
+// count against.
+func fuzzyMatch(query, title string) (score int, positions []int, ok bool) {
+	q := []rune(strings.ToLower(strings.Join(strings.Fields(query), "")))
+	t := []rune(strings.ToLower(title))
+	next := 0
+	last := -1
+	for i, r := range t {
+		if next == len(q) {
+			break
+		}
+		if r != q[next] {
+			continue
+		}
+		score++
+		if i == 0 || !unicode.IsLetter(t[i-1]) && !unicode.IsDigit(t[i-1]) {
+			score += 2
+		}
+		if last >= 0 {
+			if i == last+1 {
+				score += 3
+			} else {
+				score -= min(3, i-last-1)
+			}
+		}
+		last = i
+		next++
+		positions = append(positions, i)
+	}
+	return score, positions, next == len(q)
+}
+
+// paletteMatch is a command matching the palette's query
+type paletteMatch struct {
+	command
+	score     int
+	positions []int // rune indexes of title to highlight
+}
+
+// filterCommands returns the commands matching query, best first; ties
+// keep their original order
+func filterCommands(commands []command, query string) []paletteMatch {
+	var matches []paletteMatch
+	for _, c := range commands {
+		if score, positions, ok := fuzzyMatch(query, c.title); ok {
+			matches = append(matches, paletteMatch{c, score, positions})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	return matches
+}
+
+// paletteRows is how many matches the palette shows at once
+const paletteRows = 8
+
+// paletteOptions extend the palette for longer lists, such as the test
+// picker's
+type paletteOptions struct {
+	// preview, if set, gives the text to show for the selected command in a
+	// pane below the matches
+	preview func(command) string
+
+	// sorts, if set, are the orders Tab cycles the matches through,
+	// starting with the first
+	sorts []paletteSort
+}
+
+// paletteSort is an order for the palette's matches
+type paletteSort struct {
+	name   string
+	less   func(a, b command) bool // nil keeps the best matches first
+	detail func(command) string    // shown beside each command, if set
+}
+
+// runPalette draws the command palette over whatever is on screen and
+// lets the player search commands by typing. It returns the chosen
+// command, or false if they closed it with Escape or Ctrl+P. The cells it
+// covered are put back, so the screen underneath needn't redraw.
+//
+// With a preview, the palette grows to fill most of the screen and shows
+// the selected command's text below the matches, scrolled with Page Up and
+// Page Down. With sorts, Tab changes the order of the matches.
+func runPalette(screen tcell.Screen, commands []command, opts paletteOptions) (command, bool) {
+	preview := opts.preview
+	width, height := screen.Size()
+	boxWidth := min(width-2, 60)
+	boxHeight := min(height-2, paletteRows+4)
+	left, top := (width-boxWidth)/2, max(1, height/6)
+	if preview != nil {
+		boxWidth = min(width-2, 80)
+		boxHeight = max(1, height-2)
+		left, top = (width-boxWidth)/2, 1
+	}
+	restore := saveCells(screen, left, top, boxWidth, boxHeight)
+	defer restore()
+
+	var query inputField
+	selected := 0
+	scroll := 0 // preview lines scrolled past
+	sortBy := 0
+	for {
+		matches := filterCommands(commands, query.String())
+		var order paletteSort
+		if len(opts.sorts) > 0 {
+			order = opts.sorts[sortBy]
+		}
+		if order.less != nil {
+			sort.SliceStable(matches, func(i, j int) bool { return order.less(matches[i].command, matches[j].command) })
+		}
+		selected = max(0, min(selected, len(matches)-1))
+		listRows := boxHeight - 4
+		if preview != nil {
+			listRows = min(listRows, paletteRows)
+		}
+		drawPalette(screen, left, top, boxWidth, listRows, &query, matches, selected, order)
+		if preview != nil {
+			// The preview takes the rows under the list, down to the box's
+			// bottom edge
+			text := ""
+			if len(matches) > 0 {
+				text = preview(matches[selected].command)
+			}
+			previewTop := top + listRows + 4
+			scroll = drawPreview(screen, left, previewTop, boxWidth, top+boxHeight-1-previewTop, text, scroll)
+		}
+		screen.Show()
+
+		switch ev := screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			switch ev.Key() {
+			case tcell.KeyEscape, tcell.KeyCtrlP:
+				return command{}, false
+			case tcell.KeyEnter:
+				if len(matches) > 0 {
+					return matches[selected].command, true
+				}
+			case tcell.KeyUp:
+				selected--
+				scroll = 0
+			case tcell.KeyDown:
+				selected++
+				scroll = 0
+			case tcell.KeyPgUp:
+				scroll -= previewPage
+			case tcell.KeyPgDn:
+				scroll += previewPage
+			case tcell.KeyTab:
+				if len(opts.sorts) > 0 {
+					sortBy = (sortBy + 1) % len(opts.sorts)
+					selected, scroll = 0, 0
+				}
+			default:
+				if query.handle(ev) {
+					selected, scroll = 0, 0
+				}
+			}
+		}
+	}
+}
+
+// drawPalette draws the palette box, sized for rows of matches: the query
+// on top, then a window of matches that scrolls to keep the selected one
+// in view, with the characters matching the query highlighted. A named
+// order is shown beside the query, and its details beside each match.
+// Anything drawn below the box, such as a preview, goes after its bottom
+// edge.
+func drawPalette(screen tcell.Screen, left, top, boxWidth, rows int, query *inputField, matches []paletteMatch, selected int, order paletteSort) {
+	clearBox(screen, left, top, boxWidth, rows+4)
+	for x := left; x < left+boxWidth; x++ {
+		screen.SetContent(x, top, tcell.RuneHLine, nil, tcell.StyleDefault)
+		screen.SetContent(x, top+rows+3, tcell.RuneHLine, nil, tcell.StyleDefault)
+	}
+	inner := boxWidth - 4
+	if order.name != "" {
+		label := "Tab: sort by " + order.name
+		if width := runewidth.StringWidth(label); width < inner/2 {
+			drawText(screen, left+2+inner-width, top+1, tcell.StyleDefault.Dim(true), label)
+			inner -= width + 1
+		}
+	}
+	query.draw(screen, left+2, top+1, inner, "> ")
+
+	inner = boxWidth - 4
+	if len(matches) == 0 {
+		drawText(screen, left+2, top+3, tcell.StyleDefault, "No matching commands")
+		return
+	}
+	first := max(0, selected-rows+1)
+	for i := first; i < len(matches) && i-first < rows; i++ {
+		style := tcell.StyleDefault
+		if i == selected {
+			style = style.Reverse(true)
+		}
+		key := matches[i].shortcut()
+		if key == "" && order.detail != nil {
+			key = order.detail(matches[i].command)
+		}
+		title := runewidth.Truncate(matches[i].title, inner-runewidth.StringWidth(key)-1, "...")
+		line := title + strings.Repeat(" ", inner-runewidth.StringWidth(title)-runewidth.StringWidth(key)) + key
+		drawHighlighted(screen, left+2, top+3+i-first, style, line, matches[i].positions)
+	}
+}
+
+// drawHighlighted draws text like drawText, underlining and emboldening
+// the grapheme clusters containing the runes at positions
+func drawHighlighted(screen tcell.Screen, x, y int, style tcell.Style, text string, positions []int) {
+	highlight := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		highlight[p] = true
+	}
+	index, state := 0, -1
+	for text != "" {
+		var cluster string
+		cluster, text, _, state = uniseg.FirstGraphemeClusterInString(text, state)
+		runes := []rune(cluster)
+		clusterStyle := style
+		for i := range runes {
+			if highlight[index+i] {
+				clusterStyle = style.Bold(true).Underline(true)
+			}
+		}
+		screen.SetContent(x, y, runes[0], runes[1:], clusterStyle)
+		x += max(1, runewidth.StringWidth(cluster))
+		index += len(runes)
+	}
+}
+
+// previewPage is how many lines Page Up and Page Down scroll the preview
+const previewPage = 5
+
+// drawPreview shows text, wrapped, in rows lines below top, starting scroll
+// lines in. It returns scroll clamped to the text, so scrolling past either
+// end stops there. When the text doesn't fit, the edge under it shows which
+// lines are in view.
+func drawPreview(screen tcell.Screen, left, top, boxWidth, rows int, text string, scroll int) int {
+	if rows < 1 {
+		return 0 // no room on a small screen
+	}
+	clearBox(screen, left, top, boxWidth, rows+1)
+	for x := left; x < left+boxWidth; x++ {
+		screen.SetContent(x, top+rows, tcell.RuneHLine, nil, tcell.StyleDefault)
+	}
+	lines := wrapText(text, max(1, boxWidth-4))
+	scroll = drawLines(screen, left+2, top, rows, tcell.StyleDefault.Dim(true), lines, scroll)
+	if len(lines) > rows {
+		last := min(scroll+rows, len(lines))
+		position := fmt.Sprintf(" lines %d-%d of %d, PgUp/PgDn to scroll ", scroll+1, last, len(lines))
+		if runewidth.StringWidth(position) > boxWidth-4 {
+			position = fmt.Sprintf(" %d-%d/%d ", scroll+1, last, len(lines))
+		}
+		drawText(screen, left+max(2, boxWidth-2-runewidth.StringWidth(position)), top+rows, tcell.StyleDefault, position)
+	}
+	return scroll
+}
+
+// paletteKey returns the character ev stands for on a screen offering
+// commands: its rune, or for Ctrl+P the key of the command chosen from the
+// palette, or 0 if none was
+func paletteKey(screen tcell.Screen, ev *tcell.EventKey, commands []command) rune {
+	if ev.Key() != tcell.KeyCtrlP {
+		return bindings.key(ev.Rune())
+	}
+	if c, ok := runPalette(screen, commands, paletteOptions{}); ok {
+		return c.key
+	}
+	return 0
+}
+
+// pickableTests names the texts the test picker offers: those random
+// selection could choose, ignoring --favorites, alphabetically
+func (e *Engine) pickableTests() ([]string, error) {
+	files, err := listTexts(e.texts())
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, file := range files {
+		if e.includeArchived || !e.library.isArchived(file.Name()) {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// pickerTitle is how the test picker lists a text, and what its search
+// matches: the file name, then any title and tags from the library
+func pickerTitle(name string, entry libraryEntry) string {
+	title := name
+	if entry.Title != "" {
+		title += "  " + entry.Title
+	}
+	for _, tag := range entry.Tags {
+		title += "  #" + tag
+	}
+	return title
+}
+
+// textDifficulty rates how hard a text is to type, from 0 to 1: the share
+// of its characters that aren't lowercase letters or spaces, since capitals,
+// digits and punctuation take Shift or a reach off the home rows
+func textDifficulty(text string) float64 {
+	total, hard := 0, 0
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if !unicode.IsLower(r) {
+			hard++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(hard) / float64(total)
+}
+
+// pickerSorts are the test picker's orders, using the texts and the
+// player's results on each
+func pickerSorts(texts map[string]string, stats map[string]textStats) []paletteSort {
+	length := func(c command) int { return utf8.RuneCountInString(texts[c.file]) }
+	difficulty := func(c command) float64 { return textDifficulty(texts[c.file]) }
+	return []paletteSort{
+		{name: "best match"},
+		{name: "name", less: func(a, b command) bool { return a.file < b.file }},
+		{
+			name:   "length",
+			less:   func(a, b command) bool { return length(a) < length(b) },
+			detail: func(c command) string { return fmt.Sprintf("%d chars", length(c)) },
+		},
+		{
+			name:   "difficulty",
+			less:   func(a, b command) bool { return difficulty(a) < difficulty(b) },
+			detail: func(c command) string { return fmt.Sprintf("%.0f%% hard", difficulty(c)*100) },
+		},
+		{
+			name: "last played",
+			less: func(a, b command) bool { return stats[a.file].Last.After(stats[b.file].Last) },
+			detail: func(c command) string {
+				if stats[c.file].Attempts == 0 {
+					return "never"
+				}
+				return stats[c.file].Last.Format(dateLayout)
+			},
+		},
+		{
+			name:   "best WPM",
+			less:   func(a, b command) bool { return stats[a.file].BestWPM > stats[b.file].BestWPM },
+			detail: func(c command) string { return fmt.Sprintf("%.0f WPM", stats[c.file].BestWPM) },
+		},
+		{
+			name:   "attempts",
+			less:   func(a, b command) bool { return stats[a.file].Attempts > stats[b.file].Attempts },
+			detail: func(c command) string { return fmt.Sprintf("%d played", stats[c.file].Attempts) },
+		},
+	}
+}
+
+// pickTest searches the tests directory with the palette, previewing the
+// selected text and sorting with the player's results, and returns the
+// chosen text's file name
+func pickTest(screen tcell.Screen, e *Engine, results *resultStore) (string, bool, error) {
+	names, err := e.pickableTests()
+	if err != nil {
+		return "", false, err
+	}
+	commands := make([]command, len(names))
+	texts := make(map[string]string, len(names))
+	for i, name := range names {
+		commands[i] = command{title: pickerTitle(name, e.library.entry(name)), file: name}
+		content, err := fs.ReadFile(e.texts(), name)
+		texts[name] = strings.TrimSpace(string(content))
+		if err != nil {
+			texts[name] = "Can't read " + name + ": " + err.Error()
+		}
+	}
+
+	c, ok := runPalette(screen, commands, paletteOptions{
+		preview: func(c command) string { return texts[c.file] },
+		sorts:   pickerSorts(texts, results.textStats(e.index)),
+	})
+	return c.file, ok, nil
+}
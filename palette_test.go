@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestFilterCommands(t *testing.T) {
+	commands := []command{
+		{title: "Start a new test", key: ' '},
+		{title: "Save recording", key: 's'},
+		{title: "Save certificate", key: 'c'},
+		{title: "Toggle reduced motion", key: 'm'},
+	}
+	for _, tt := range []struct {
+		query string
+		want  []rune
+	}{
+		{"", []rune{' ', 's', 'c', 'm'}},
+		{"srec", []rune{'s'}},
+		{"cert", []rune{'c'}},
+		{"SAVE  C", []rune{'c', 's'}},
+		{"motion", []rune{'m'}},
+		{"xyz", nil},
+	} {
+		var got []rune
+		for _, c := range filterCommands(commands, tt.query) {
+			got = append(got, c.key)
+		}
+		if string(got) != string(tt.want) {
+			t.Errorf("filterCommands(%q) = %q, want %q", tt.query, string(got), string(tt.want))
+		}
+	}
+}
+
+func TestRunPalette(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(80, 24)
+	drawText(screen, 35, 5, tcell.StyleDefault, "underneath") // inside the palette box
+	screen.Show()
+
+	// "tes" matches both, New test better
+	commands := []command{{title: "Retry this test", key: 'r'}, {title: "New test", key: 'n'}}
+	screen.InjectKeyBytes([]byte("tes"))
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	c, ok := runPalette(screen, commands, paletteOptions{})
+	if !ok || c.key != 'r' {
+		t.Errorf("chose %+v, %v; want the second match, Retry this test", c, ok)
+	}
+	if got := cellText(screen, 35, 5, 10); got != "underneath" {
+		t.Errorf("screen under the palette = %q after closing it", got)
+	}
+
+	screen.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	if _, ok := runPalette(screen, commands, paletteOptions{}); ok {
+		t.Error("Escape chose a command")
+	}
+}
+
+// cellText reads n cells of screen starting at x, y
+func cellText(screen tcell.Screen, x, y, n int) string {
+	var s []rune
+	for i := 0; i < n; i++ {
+		r, _, _, _ := screen.GetContent(x+i, y)
+		s = append(s, r)
+	}
+	return string(s)
+}
+
+func TestPickTest(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"gettysburg.txt": "Four score and seven years ago",
+		"pangrams.txt":   "The quick brown fox",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lib, err := loadLibraryFile(filepath.Join(t.TempDir(), "library.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib.set("gettysburg.txt", libraryEntry{Title: "The Gettysburg Address", Tags: []string{"speech"}})
+	engine := newEngine(&fakeClock{}, fixedRand(0), dir)
+	engine.library = lib
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(80, 24)
+
+	// Tags are searched as well as names
+	screen.InjectKeyBytes([]byte("#speech"))
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	if name, ok, err := pickTest(screen, engine, &resultStore{}); err != nil || !ok || name != "gettysburg.txt" {
+		t.Errorf("pickTest = %q, %v, %v; want gettysburg.txt", name, ok, err)
+	}
+}
+
+func TestDrawHighlightedAndPreview(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(40, 10)
+
+	_, positions, _ := fuzzyMatch("nt", "New test")
+	drawHighlighted(screen, 0, 0, tcell.StyleDefault, "New test", positions)
+	for x, want := range []bool{true, false, false, false, true, false, false, false} {
+		_, _, style, _ := screen.GetContent(x, 0)
+		if _, _, attrs := style.Decompose(); (attrs&tcell.AttrBold != 0) != want {
+			t.Errorf("cell %d highlighted = %v, want %v", x, !want, want)
+		}
+	}
+
+	text := "Four score and seven years ago our fathers"
+	if scroll := drawPreview(screen, 0, 2, 20, 2, text, 0); scroll != 0 {
+		t.Errorf("scroll = %d at the top", scroll)
+	}
+	if got := cellText(screen, 2, 2, 14); got != "Four score and" {
+		t.Errorf("preview first line = %q", got)
+	}
+	if got := cellText(screen, 0, 4, 3); got != string([]rune{tcell.RuneHLine, tcell.RuneHLine, tcell.RuneHLine}) {
+		t.Errorf("no edge under a two-line preview: %q", got)
+	}
+
+	// The text wraps to three lines, so scrolling stops one line in
+	if scroll := drawPreview(screen, 0, 2, 20, 2, text, 10); scroll != 1 {
+		t.Errorf("scrolling past the end: scroll = %d, want 1", scroll)
+	}
+	if got := cellText(screen, 2, 3, 11); got != "our fathers" {
+		t.Errorf("last line in view = %q", got)
+	}
+}
+
+func TestPickerSorts(t *testing.T) {
+	texts := map[string]string{"a.txt": "Hello, World!", "b.txt": "plain words only here", "c.txt": "hi"}
+	stats := map[string]textStats{"b.txt": {Attempts: 3, BestWPM: 70}, "c.txt": {Attempts: 1, BestWPM: 90}}
+	commands := []command{{title: "a.txt", file: "a.txt"}, {title: "b.txt", file: "b.txt"}, {title: "c.txt", file: "c.txt"}}
+
+	want := map[string]string{
+		"length":     "cab",
+		"difficulty": "bca",
+		"best WPM":   "cba",
+		"attempts":   "bca",
+	}
+	for _, order := range pickerSorts(texts, stats) {
+		if want[order.name] == "" {
+			continue
+		}
+		sorted := append([]command(nil), commands...)
+		sort.SliceStable(sorted, func(i, j int) bool { return order.less(sorted[i], sorted[j]) })
+		var got string
+		for _, c := range sorted {
+			got += c.file[:1]
+		}
+		if got != want[order.name] {
+			t.Errorf("by %s: %s, want %s", order.name, got, want[order.name])
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// configDir returns the directory keysmash reads its configuration from,
+// following the XDG base directory spec ($XDG_CONFIG_HOME/keysmash, which
+// defaults to ~/.config/keysmash). It returns "" if no home directory can
+// be determined.
+func configDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "keysmash")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "keysmash")
+}
+
+// configPath returns the full path of the config file, or "" if the config
+// directory cannot be determined.
+func configPath() string {
+	dir := configDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "config.toml")
+}
+
+// dataDir returns the directory keysmash keeps persistent state in
+// ($XDG_DATA_HOME/keysmash, which defaults to ~/.local/share/keysmash).
+// It returns "" if no home directory can be determined.
+func dataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "keysmash")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".local", "share", "keysmash")
+}
+
+// testsDirEnv names the environment variable that can point keysmash at a
+// tests directory
+const testsDirEnv = "KEYSMASH_TESTS"
+
+// defaultTestsDir is where keysmash init puts texts, in the data
+// directory, or "" if there is none
+func defaultTestsDir() string {
+	dir := dataDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "tests")
+}
+
+// findTestsDir locates the tests directory: the one $KEYSMASH_TESTS
+// names, the one in the data directory, one next to the executable (or
+// a level up, for GOPATH/bin), or ./tests, in that order. It returns ""
+// if there is none.
+func findTestsDir() string {
+	candidates := []string{os.Getenv(testsDirEnv), defaultTestsDir()}
+	if execPath, err := os.Executable(); err == nil {
+		execDir := filepath.Dir(execPath)
+		candidates = append(candidates, filepath.Join(execDir, "tests"), filepath.Join(filepath.Dir(execDir), "tests"))
+	}
+	candidates = append(candidates, "tests")
+	for _, dir := range candidates {
+		if info, err := os.Stat(dir); dir != "" && err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return ""
+}
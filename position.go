@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Line numbers help find your place in long texts and code: a gutter
+// left of the text numbering each of its lines, with lines wrapping
+// onto more rows numbered only on the first, and where the typing is,
+// by line and column, in the stats area.
+
+// styleLineNumber is how the gutter's numbers are drawn
+var styleLineNumber = tcell.StyleDefault.Dim(true)
+
+// lineNumberWidth is how many cells the gutter takes for a text of
+// lines lines: the widest number and a space
+func lineNumberWidth(lines int) int {
+	return len(strconv.Itoa(lines)) + 1
+}
+
+// lineNumbers returns the number in text of the line each wrapped line
+// starts, or 0 where it carries on the one before. window is the part of
+// text that was wrapped, starting at byte offset start, and offsets are
+// where each wrapped line starts in window. Wrapping starts a new line at
+// every newline, blank lines included, so each newline passed numbers the
+// next wrapped line.
+func lineNumbers(text string, start int, window string, offsets []int) []int {
+	numbers := make([]int, len(offsets))
+	first := strings.Count(text[:start], "\n") + 1
+	passed, newlines, counted := 0, 0, 0
+	for i, off := range offsets {
+		// A wrapped line's offset is before the whitespace dropped ahead
+		// of it, newlines included
+		content := off + len(window[off:]) - len(strings.TrimLeftFunc(window[off:], unicode.IsSpace))
+		newlines += strings.Count(window[counted:max(counted, content)], "\n")
+		counted = max(counted, content)
+		switch {
+		case i == 0:
+			if start == 0 || text[start-1] == '\n' {
+				numbers[i] = first
+			}
+		case newlines > passed:
+			passed++
+			numbers[i] = first + passed
+		}
+	}
+	return numbers
+}
+
+// position returns the line and column of the next character to type,
+// counting from 1, and how many lines the text has. Columns count
+// characters, so a wide one is still one column.
+func (s *TestState) position() (line, col, lines int) {
+	next := min(s.typed(), len(s.reference))
+	off := len(s.referenceText)
+	if next < len(s.reference) {
+		off = s.refStarts[next]
+	}
+	line = strings.Count(s.referenceText[:off], "\n") + 1
+	lineStart := strings.LastIndexByte(s.referenceText[:off], '\n') + 1
+	col = next - clusterIndex(s.refStarts, lineStart) + 1
+	lines = strings.Count(s.referenceText, "\n") + 1
+	return line, col, lines
+}
+
+// positionText is the position indicator for the stats area
+func positionText(s *TestState) string {
+	line, col, lines := s.position()
+	return fmt.Sprintf("Line %d/%d, col %d", line, lines, col)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestPosition(t *testing.T) {
+	state := newTestState("func a() {\n\treturn 1\n}", "a.go", &fakeClock{})
+	tests := []struct {
+		typed           string
+		line, col, last int
+	}{
+		{"", 1, 1, 3},
+		{"func", 1, 5, 3},
+		{"func a() {\n", 2, 1, 3},
+		{"func a() {\n\tret", 2, 5, 3},
+		{"func a() {\n\treturn 1\n}", 3, 2, 3},
+	}
+	for _, tt := range tests {
+		state := state
+		state.reset()
+		for _, r := range tt.typed {
+			state.typeRune(r)
+		}
+		if line, col, lines := state.position(); line != tt.line || col != tt.col || lines != tt.last {
+			t.Errorf("after %q: line %d col %d of %d, want line %d col %d of %d", tt.typed, line, col, lines, tt.line, tt.col, tt.last)
+		}
+	}
+}
+
+func TestLineNumbers(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(60, 30)
+
+	// The second line wraps; only the row it starts on is numbered
+	text := "one\n" + strings.Repeat("two ", 20) + "\nthree"
+	state := newTestState(text, "test.txt", &fakeClock{})
+	for _, r := range "one\ntw" {
+		state.typeRune(r)
+	}
+	renderScreen(screen, &state, 60, renderOptions{lineNumbers: true})
+
+	gutter := lineNumberWidth(3)
+	var numbers, rows []string
+	for y := 0; y < 30; y++ {
+		if strings.HasPrefix(cellText(screen, 4+gutter, y, 3), "thr") || strings.HasPrefix(cellText(screen, 4+gutter, y, 3), "one") || strings.HasPrefix(cellText(screen, 4+gutter, y, 3), "two") {
+			numbers = append(numbers, strings.TrimSpace(cellText(screen, 4, y, gutter)))
+			rows = append(rows, cellText(screen, 4+gutter, y, 3))
+		}
+	}
+	if want := []string{"1", "2", "", "3"}; strings.Join(numbers, ",") != strings.Join(want, ",") {
+		t.Errorf("numbered %q as %q, want %q", rows, numbers, want)
+	}
+
+	found := false
+	for y := 0; y < 10; y++ {
+		if strings.Contains(cellText(screen, 0, y, 60), "Line 2/3, col 3") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("no position indicator")
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lowPowerTick is how often the test screen refreshes on its own in
+// low-power mode
+const lowPowerTick = 5 * time.Second
+
+// battery is the state of the machine's battery
+type battery struct {
+	percent     int
+	discharging bool
+}
+
+// readBattery reports the battery's state, if there is a battery and this
+// system can say. It reads sysfs on Linux and asks pmset on macOS.
+func readBattery() (battery, bool) {
+	switch runtime.GOOS {
+	case "linux":
+		return readSysfsBattery("/sys/class/power_supply")
+	case "darwin":
+		out, err := exec.Command("pmset", "-g", "batt").Output()
+		if err != nil {
+			return battery{}, false
+		}
+		return parsePmset(string(out))
+	}
+	return battery{}, false
+}
+
+// readSysfsBattery reads the first battery under a Linux power_supply
+// directory
+func readSysfsBattery(dir string) (battery, bool) {
+	supplies, err := os.ReadDir(dir)
+	if err != nil {
+		return battery{}, false
+	}
+	read := func(supply, name string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, supply, name))
+		return strings.TrimSpace(string(data))
+	}
+	for _, supply := range supplies {
+		if read(supply.Name(), "type") != "Battery" {
+			continue
+		}
+		percent, err := strconv.Atoi(read(supply.Name(), "capacity"))
+		if err != nil {
+			continue
+		}
+		return battery{percent: percent, discharging: read(supply.Name(), "status") == "Discharging"}, true
+	}
+	return battery{}, false
+}
+
+// pmsetBattery matches a battery line of `pmset -g batt`, such as
+// " -InternalBattery-0 (id=1234)	85%; discharging; 4:10 remaining"
+var pmsetBattery = regexp.MustCompile(`(\d+)%; ([a-zA-Z ]+);`)
+
+func parsePmset(out string) (battery, bool) {
+	match := pmsetBattery.FindStringSubmatch(out)
+	if match == nil {
+		return battery{}, false
+	}
+	percent, _ := strconv.Atoi(match[1])
+	return battery{percent: percent, discharging: match[2] == "discharging"}, true
+}
+
+// lowPowerReason says why low-power mode should be on, or "" if it
+// shouldn't. forced is the --low-power flag; below is the low_power_battery
+// setting, the charge at or under which running on battery turns it on.
+func lowPowerReason(forced bool, below int, b battery, found bool) string {
+	switch {
+	case forced:
+		return "--low-power"
+	case below > 0 && found && b.discharging && b.percent <= below:
+		return fmt.Sprintf("on battery at %d%%", b.percent)
+	}
+	return ""
+}
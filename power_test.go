@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadSysfsBattery(t *testing.T) {
+	dir := t.TempDir()
+	write := func(supply, name, value string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(dir, supply), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, supply, name), []byte(value+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("AC", "type", "Mains")
+	if _, ok := readSysfsBattery(dir); ok {
+		t.Error("found a battery with only mains power")
+	}
+
+	write("BAT0", "type", "Battery")
+	write("BAT0", "capacity", "17")
+	write("BAT0", "status", "Discharging")
+	if b, ok := readSysfsBattery(dir); !ok || b != (battery{percent: 17, discharging: true}) {
+		t.Errorf("readSysfsBattery = %+v, %v", b, ok)
+	}
+}
+
+func TestParsePmset(t *testing.T) {
+	out := "Now drawing from 'Battery Power'\n -InternalBattery-0 (id=4653155)\t85%; discharging; 4:10 remaining present: true\n"
+	if b, ok := parsePmset(out); !ok || b != (battery{percent: 85, discharging: true}) {
+		t.Errorf("parsePmset = %+v, %v", b, ok)
+	}
+	out = "Now drawing from 'AC Power'\n -InternalBattery-0 (id=4653155)\t100%; charged; 0:00 remaining present: true\n"
+	if b, ok := parsePmset(out); !ok || b.discharging {
+		t.Errorf("parsePmset on AC = %+v, %v", b, ok)
+	}
+	if _, ok := parsePmset("Now drawing from 'AC Power'\n"); ok {
+		t.Error("found a battery on a desktop")
+	}
+}
+
+func TestLowPowerReason(t *testing.T) {
+	low := battery{percent: 15, discharging: true}
+	for _, tt := range []struct {
+		forced bool
+		below  int
+		b      battery
+		found  bool
+		want   string
+	}{
+		{true, 0, battery{}, false, "--low-power"},
+		{false, 20, low, true, "on battery at 15%"},
+		{false, 10, low, true, ""},                                     // above the threshold
+		{false, 20, battery{percent: 15}, true, ""},                    // charging
+		{false, 0, low, true, ""},                                      // turned off
+		{false, 20, battery{percent: 0, discharging: true}, false, ""}, // no battery found
+	} {
+		if got := lowPowerReason(tt.forced, tt.below, tt.b, tt.found); got != tt.want {
+			t.Errorf("lowPowerReason(%v, %d, %+v, %v) = %q, want %q", tt.forced, tt.below, tt.b, tt.found, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestServePprof(t *testing.T) {
+	for _, addr := range []string{"0.0.0.0:0", "example.com:6060", "6060"} {
+		if listener, err := servePprof(addr); err == nil {
+			listener.Close()
+			t.Errorf("servePprof(%q) served profiles", addr)
+		}
+	}
+
+	listener, err := servePprof("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	resp, err := http.Get("http://" + listener.Addr().String() + "/debug/pprof/goroutine?debug=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("goroutine profile: %s", resp.Status)
+	}
+}
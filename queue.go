@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// The practice queue holds texts the player did badly on: a run slower
+// than their rolling average puts its text in the queue, and a run at or
+// above it takes it out again. Daily challenges are queued too; they're
+// replayed under practiceFilePrefix so practising an old day doesn't
+// count as completing it.
+
+// practiceFilePrefix marks a queued daily challenge played again
+const practiceFilePrefix = "practice-"
+
+// queueMinRuns is how many results there must be before a run's speed is
+// judged against the average
+const queueMinRuns = 5
+
+// queueMax is the most texts the queue holds; the oldest go first
+const queueMax = 20
+
+// queuedText is a text in the practice queue
+type queuedText struct {
+	TestFile string    `json:"test_file"`
+	WPM      float64   `json:"wpm"`     // the run that queued it, or its latest since
+	Average  float64   `json:"average"` // the rolling average it fell short of
+	Added    time.Time `json:"added"`
+}
+
+// practiceQueue is the "needs work" queue, next up first
+type practiceQueue struct {
+	Texts []queuedText `json:"texts"`
+	path  string
+}
+
+func loadPracticeQueue() (*practiceQueue, error) {
+	path, err := dataFile("queue.json")
+	if err != nil {
+		return nil, err
+	}
+	queue := &practiceQueue{path: path}
+	if err := readJSONFile(path, queue); err != nil {
+		return nil, err
+	}
+	return queue, nil
+}
+
+func (q *practiceQueue) save() error {
+	if q.path == "" {
+		return nil
+	}
+	return saveDataFile(q.path, q)
+}
+
+// queueable reports whether a test can be played again from the queue:
+// texts from the tests directory and daily challenges
+func queueable(testFile string) bool {
+	return strings.HasSuffix(strings.ToLower(testFile), ".txt") || strings.HasPrefix(testFile, dailyFilePrefix)
+}
+
+// find returns the position of testFile in the queue, or -1
+func (q *practiceQueue) find(testFile string) int {
+	for i, t := range q.Texts {
+		if t.TestFile == testFile {
+			return i
+		}
+	}
+	return -1
+}
+
+// remove takes testFile out of the queue, if it's there
+func (q *practiceQueue) remove(testFile string) {
+	if i := q.find(testFile); i >= 0 {
+		q.Texts = append(q.Texts[:i], q.Texts[i+1:]...)
+	}
+}
+
+// update judges a completed run against average: a slower one queues its
+// text, at the back, and one as fast or faster takes it out. It reports
+// whether the queue changed.
+func (q *practiceQueue) update(testFile string, wpm, average float64, now time.Time) bool {
+	if wpm >= average {
+		if q.find(testFile) < 0 {
+			return false
+		}
+		q.remove(testFile)
+		logger.Info("worked through queued text", "file", testFile, "wpm", wpm, "average", average)
+		return true
+	}
+	added := now
+	if i := q.find(testFile); i >= 0 {
+		added = q.Texts[i].Added
+		q.remove(testFile)
+	}
+	q.Texts = append(q.Texts, queuedText{TestFile: testFile, WPM: wpm, Average: average, Added: added})
+	q.Texts = q.Texts[max(0, len(q.Texts)-queueMax):]
+	logger.Info("queued text for practice", "file", testFile, "wpm", wpm, "average", average)
+	return true
+}
+
+// averageBefore is the average speed of the last n counted results
+// completed before t, and how many there were
+func (s *resultStore) averageBefore(t time.Time, n int) (average float64, runs int) {
+	counted := s.counted()
+	for i := len(counted) - 1; i >= 0 && runs < n; i-- {
+		if !counted[i].Completed.Before(t) {
+			continue
+		}
+		average += counted[i].WPM
+		runs++
+	}
+	if runs == 0 {
+		return 0, 0
+	}
+	return average / float64(runs), runs
+}
+
+// trackPracticeQueue updates the queue as tests are completed, judging
+// each against the average of the averageWindow results before it
+func trackPracticeQueue(bus *EventBus, queue *practiceQueue, results *resultStore) {
+	bus.Subscribe(func(ev Event) {
+		file := strings.TrimPrefix(ev.TestFile, practiceFilePrefix)
+		if !queueable(file) {
+			return
+		}
+		average, runs := results.averageBefore(ev.Time.Add(-ev.Duration), averageWindow)
+		if runs < queueMinRuns || !queue.update(file, ev.WPM, average, ev.Time) {
+			return
+		}
+		if err := queue.save(); err != nil {
+			logger.Error("saving practice queue failed", "err", err)
+		}
+	}, EventTestCompleted)
+}
+
+// queuedTest loads a text from the queue to play again
+func (e *Engine) queuedTest(testFile string) (TestState, error) {
+	date, daily := strings.CutPrefix(testFile, dailyFilePrefix)
+	if !daily {
+		return e.loadTest(testFile)
+	}
+	day, err := time.ParseInLocation(dateLayout, date, e.clock.Now().Location())
+	if err != nil {
+		return TestState{}, fmt.Errorf("queued daily challenge %q: %w", testFile, err)
+	}
+	logger.Info("selected queued daily challenge", "date", date)
+	return e.newTest(dailyChallengeText(day), practiceFilePrefix+testFile), nil
+}
+
+// drawQueueHint offers the practice queue on the welcome screen
+func drawQueueHint(screen tcell.Screen, queue *practiceQueue) {
+	if len(queue.Texts) == 0 {
+		return
+	}
+	next := queue.Texts[0]
+	width, height := screen.Size()
+	drawCenteredText(screen, width/2, height/2+1, tcell.StyleDefault, fmt.Sprintf("B: %d text(s) need work, next %s (%.0f WPM against your %.0f)", len(queue.Texts), next.TestFile, next.WPM, next.Average))
+	screen.Show()
+}
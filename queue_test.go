@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPracticeQueue(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	results := &resultStore{}
+	for i := 0; i < queueMinRuns; i++ {
+		results.Results = append(results.Results, result{TestFile: "old.txt", WPM: 60, Completed: start.Add(time.Duration(i) * time.Minute)})
+	}
+	bus := newEventBus()
+	queue := &practiceQueue{}
+	trackPracticeQueue(bus, queue, results)
+	complete := func(file string, wpm float64, at time.Duration) {
+		bus.Publish(Event{Kind: EventTestCompleted, TestFile: file, WPM: wpm, Time: start.Add(at), Duration: 30 * time.Second})
+	}
+
+	complete("daily-2024-03-01", 40, time.Hour)
+	complete("a.txt", 50, 2*time.Hour)
+	complete("drill", 10, 3*time.Hour) // can't be played again
+	complete("b.txt", 65, 4*time.Hour)
+	if len(queue.Texts) != 2 || queue.Texts[0].TestFile != "daily-2024-03-01" || queue.Texts[0].Average != 60 || queue.Texts[1].TestFile != "a.txt" {
+		t.Fatalf("queue = %+v", queue.Texts)
+	}
+
+	// Falling short again sends a text to the back; beating the average
+	// takes it out
+	complete("practice-daily-2024-03-01", 55, 5*time.Hour)
+	if queue.Texts[0].TestFile != "a.txt" || queue.Texts[1].WPM != 55 || !queue.Texts[1].Added.Equal(start.Add(time.Hour)) {
+		t.Errorf("after a second slow run: %+v", queue.Texts)
+	}
+	complete("a.txt", 61, 6*time.Hour)
+	if len(queue.Texts) != 1 || queue.Texts[0].TestFile != "daily-2024-03-01" {
+		t.Errorf("after a fast run: %+v", queue.Texts)
+	}
+}
+
+func TestQueuedDailyChallenge(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 3, 9, 9, 0, 0, 0, time.UTC)}
+	engine := newEngine(clock, fixedRand(0), t.TempDir())
+	state, err := engine.queuedTest("daily-2024-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Played under another name, so it isn't recorded as that day's
+	// challenge
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	if state.testFile != "practice-daily-2024-03-01" || state.referenceText != dailyChallengeText(day) {
+		t.Errorf("queued daily = %q: %q", state.testFile, state.referenceText)
+	}
+	if _, err := engine.queuedTest("daily-someday"); err == nil {
+		t.Error("a bad date loaded")
+	}
+}
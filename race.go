@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// defaultAverageWPM stands in for "your average self" before any test has
+// been completed; it's roughly the average adult typing speed
+const defaultAverageWPM = 40.0
+
+// burstPeriod is how long one fast-then-slow cycle of a bursty bot lasts
+const burstPeriod = 8 * time.Second
+
+// burstAmplitude is how far a bursty bot's speed swings around its average
+// (0.6 = between 40% and 160% of it)
+const burstAmplitude = 0.6
+
+// botProfile is a parsed --bots entry
+type botProfile struct {
+	kind string // "steady", "bursty" or "self"
+	wpm  float64
+}
+
+// parseBots parses a comma-separated bot list such as "steady,bursty:110,self".
+// steady defaults to 60 WPM and bursty to 90; self always races at the
+// player's average, so it takes no speed.
+func parseBots(spec string) ([]botProfile, error) {
+	var profiles []botProfile
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kind, speed, hasSpeed := strings.Cut(entry, ":")
+		profile := botProfile{kind: kind}
+		switch kind {
+		case "steady":
+			profile.wpm = 60
+		case "bursty":
+			profile.wpm = 90
+		case "self":
+			if hasSpeed {
+				return nil, fmt.Errorf("bot %q: self always races at your average speed", entry)
+			}
+		default:
+			return nil, fmt.Errorf("unknown bot %q (want steady, bursty or self)", kind)
+		}
+
+		if hasSpeed {
+			wpm, err := strconv.ParseFloat(speed, 64)
+			if err != nil || wpm <= 0 {
+				return nil, fmt.Errorf("bot %q: invalid WPM %q", entry, speed)
+			}
+			profile.wpm = wpm
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// opponent is a bot racing in the current test. Its progress is a pure
+// function of elapsed time, so it needs no goroutine of its own and replays
+// identically under a fake clock.
+type opponent struct {
+	name   string
+	wpm    float64
+	bursty bool
+	phase  float64 // radians; staggers bursty bots so they don't move in lockstep
+
+	// headStart shifts the bot's clock: positive values let it start
+	// ahead, negative values hold it back; see applyHandicap
+	headStart time.Duration
+}
+
+// newOpponents instantiates profiles for one test. averageWPM is what the
+// "self" bot races at, or defaultAverageWPM if it isn't positive, since a
+// bot that never types never finishes.
+func newOpponents(profiles []botProfile, averageWPM float64, rng Rand) []opponent {
+	opponents := make([]opponent, 0, len(profiles))
+	for _, p := range profiles {
+		o := opponent{wpm: p.wpm}
+		switch p.kind {
+		case "steady":
+			o.name = fmt.Sprintf("Steady %.0f", p.wpm)
+		case "bursty":
+			o.name = fmt.Sprintf("Bursty %.0f", p.wpm)
+			o.bursty = true
+			o.phase = 2 * math.Pi * float64(rng.Intn(1000)) / 1000
+		case "self":
+			o.name = "Average you"
+			o.wpm = averageWPM
+			if o.wpm <= 0 {
+				o.wpm = defaultAverageWPM
+			}
+		}
+		opponents = append(opponents, o)
+	}
+	return opponents
+}
+
+// applyHandicap gives whichever of the player and each bot is expected to
+// be slower a head start, sized so both would finish a text of length
+// chars at the same moment if everyone typed at their usual speed. The
+// player's usual speed is averageWPM. With no speed to size it from,
+// there's no head start.
+func applyHandicap(opponents []opponent, averageWPM float64, chars int) {
+	if averageWPM <= 0 {
+		return
+	}
+	player := expectedDuration(chars, averageWPM)
+	for i := range opponents {
+		o := &opponents[i]
+		if o.wpm <= 0 {
+			continue
+		}
+		o.headStart = expectedDuration(chars, o.wpm) - player
+		switch {
+		case o.headStart >= time.Second:
+			o.name += fmt.Sprintf(" (+%.0fs)", o.headStart.Seconds())
+		case o.headStart <= -time.Second:
+			o.name += fmt.Sprintf(" (-%.0fs)", -o.headStart.Seconds())
+		}
+	}
+}
+
+// charsTyped returns how many characters the bot has typed after elapsed.
+// A bursty bot's speed follows a sine wave around its average, which
+// integrates to the closed form below.
+func (o opponent) charsTyped(elapsed time.Duration) float64 {
+	charsPerSecond := o.wpm * 5 / 60
+	t := (elapsed + o.headStart).Seconds()
+	if t <= 0 {
+		return 0
+	}
+	if !o.bursty {
+		return charsPerSecond * t
+	}
+
+	omega := 2 * math.Pi / burstPeriod.Seconds()
+	wobble := burstAmplitude / omega * (math.Cos(o.phase) - math.Cos(omega*t+o.phase))
+	return charsPerSecond * (t + wobble)
+}
+
+// neverFinishes is the finish time of a bot that doesn't type
+const neverFinishes = time.Duration(math.MaxInt64)
+
+// finishTime returns when the bot completes a text of length chars,
+// found by bisection since the bursty curve has no closed-form inverse.
+// A bot with no speed returns neverFinishes.
+func (o opponent) finishTime(chars int) time.Duration {
+	if o.wpm <= 0 {
+		return neverFinishes
+	}
+	target := float64(chars)
+	hi := time.Second
+	for o.charsTyped(hi) < target {
+		hi *= 2
+	}
+	lo := time.Duration(0)
+	for hi-lo > time.Millisecond {
+		mid := (lo + hi) / 2
+		if o.charsTyped(mid) < target {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi
+}
+
+// racer is one row of the race panel. The player and opponents render the
+// same way, so any future source of racers only has to produce these.
+type racer struct {
+	name     string
+	progress float64 // 0..1
+	wpm      float64
+	you      bool
+}
+
+// racers returns the player and all opponents at the test's current
+// elapsed time
+func (s *TestState) racers() []racer {
+	elapsed := s.elapsed()
+	total := len(s.reference)
+
+	rows := []racer{{
+		name:     "You",
+		progress: math.Min(1, float64(s.typed())/float64(max(1, total))),
+		wpm:      s.wpm(elapsed),
+		you:      true,
+	}}
+	for _, o := range s.opponents {
+		typed := math.Min(float64(total), o.charsTyped(elapsed))
+		row := racer{name: o.name, progress: typed / float64(max(1, total))}
+		// Measure speed over the bot's own running time so a head start
+		// doesn't read as extra speed
+		if active := elapsed + o.headStart; active > 0 {
+			row.wpm = typed / 5 / active.Minutes()
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// placing returns the player's finishing position (1-based) and the
+// number of racers, given that the player finished after elapsed
+func (s *TestState) placing() (place, of int) {
+	place = 1
+	for _, o := range s.opponents {
+		if o.finishTime(len(s.reference)) < s.elapsed() {
+			place++
+		}
+	}
+	return place, len(s.opponents) + 1
+}
+
+// drawRacePanel draws one progress bar per racer, fastest first
+func drawRacePanel(screen tcell.Screen, x, y, width int, racers []racer) {
+	sorted := append([]racer(nil), racers...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].progress > sorted[j].progress })
+
+	nameWidth := 0
+	for _, r := range sorted {
+		nameWidth = max(nameWidth, runewidth.StringWidth(r.name))
+	}
+
+	// name, space, [bar], space, WPM column
+	barWidth := width - nameWidth - len(" [] 100% 000 WPM")
+	if barWidth < 5 {
+		return
+	}
+
+	for i, r := range sorted {
+		line := fmt.Sprintf("%s %s %3d%% %3.0f WPM",
+			runewidth.FillRight(r.name, nameWidth),
+			progressBar(barWidth, r.progress),
+			int(r.progress*100),
+			r.wpm)
+
+		style := tcell.StyleDefault
+		if r.you {
+			style = style.Bold(true)
+		}
+		drawText(screen, x, y+i, style, line)
+	}
+}
+
+// ordinal formats 1 as "1st", 2 as "2nd" and so on
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
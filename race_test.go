@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestParseBots(t *testing.T) {
+	profiles, err := parseBots("steady, bursty:120,self")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []botProfile{{"steady", 60}, {"bursty", 120}, {"self", 0}}
+	if len(profiles) != len(want) {
+		t.Fatalf("got %d profiles, want %d", len(profiles), len(want))
+	}
+	for i := range want {
+		if profiles[i] != want[i] {
+			t.Errorf("profile %d = %+v, want %+v", i, profiles[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"speedy", "steady:fast", "steady:-5", "self:80"} {
+		if _, err := parseBots(bad); err == nil {
+			t.Errorf("parseBots(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestBurstyBotAveragesTargetSpeed(t *testing.T) {
+	for _, phase := range []float64{0, 1, 2.5} {
+		bot := opponent{wpm: 90, bursty: true, phase: phase}
+
+		// Over whole burst periods the wobble cancels out exactly
+		elapsed := 5 * burstPeriod
+		want := 90.0 * 5 / 60 * elapsed.Seconds()
+		if got := bot.charsTyped(elapsed); math.Abs(got-want) > 1e-6 {
+			t.Errorf("phase %v: typed %.3f chars in %v, want %.3f", phase, got, elapsed, want)
+		}
+
+		// Progress never goes backwards
+		prev := 0.0
+		for d := time.Duration(0); d < 2*burstPeriod; d += 50 * time.Millisecond {
+			chars := bot.charsTyped(d)
+			if chars < prev {
+				t.Fatalf("phase %v: progress went backwards at %v", phase, d)
+			}
+			prev = chars
+		}
+	}
+}
+
+func TestPlacing(t *testing.T) {
+	clock := &fakeClock{}
+	// 100 chars: the 60 WPM bot needs 20s, the 30 WPM bot 40s
+	state := newTestState(benchText(100), "race.txt", clock)
+	state.opponents = []opponent{{name: "fast", wpm: 60}, {name: "slow", wpm: 30}}
+
+	state.typeRune('T')
+	clock.advance(30 * time.Second)
+	setInput(&state, state.referenceText[:len(state.referenceText)-1])
+	state.typeRune(rune(state.referenceText[len(state.referenceText)-1]))
+
+	if place, of := state.placing(); place != 2 || of != 3 {
+		t.Errorf("placing = %d of %d, want 2 of 3", place, of)
+	}
+}
+
+func TestStoppedBotNeverFinishes(t *testing.T) {
+	clock := &fakeClock{}
+	state := newTestState(benchText(100), "race.txt", clock)
+	// With no average to race, "self" falls back to the default
+	state.opponents = newOpponents([]botProfile{{"self", 0}}, 0, rand.New(rand.NewSource(1)))
+	if got := state.opponents[0].wpm; got != defaultAverageWPM {
+		t.Errorf("self bot races at %v WPM with no average, want %v", got, defaultAverageWPM)
+	}
+	state.opponents = append(state.opponents, opponent{name: "stopped"})
+	if got := state.opponents[1].finishTime(100); got != neverFinishes {
+		t.Errorf("stopped bot finishes at %v, want never", got)
+	}
+
+	state.typeRune('T')
+	clock.advance(time.Hour)
+	setInput(&state, state.referenceText[:len(state.referenceText)-1])
+	state.typeRune(rune(state.referenceText[len(state.referenceText)-1]))
+	if place, of := state.placing(); place != 2 || of != 3 {
+		t.Errorf("placing = %d of %d, want 2 of 3", place, of)
+	}
+}
+
+func TestHandicapEqualizesExpectedFinish(t *testing.T) {
+	const chars = 500
+	opponents := []opponent{{name: "fast", wpm: 100}, {name: "slow", wpm: 25, bursty: true, phase: 1}}
+	applyHandicap(opponents, 50, chars)
+
+	// At 50 WPM the player needs 120s for 500 chars
+	for _, o := range opponents {
+		finish := o.finishTime(chars)
+		if diff := finish - 120*time.Second; diff < -2*time.Second || diff > 2*time.Second {
+			t.Errorf("%s finishes at %v, want about 2m0s", o.name, finish)
+		}
+	}
+	if opponents[0].name != "fast (-60s)" || opponents[1].name != "slow (+120s)" {
+		t.Errorf("names = %q, %q; want the handicap shown", opponents[0].name, opponents[1].name)
+	}
+	if got := opponents[0].charsTyped(30 * time.Second); got != 0 {
+		t.Errorf("held-back bot typed %v chars before its start, want 0", got)
+	}
+}
+
+func TestHandicapWithoutSpeed(t *testing.T) {
+	opponents := []opponent{{name: "fast", wpm: 100}, {name: "stopped"}}
+	applyHandicap(opponents, 0, 500)
+	if opponents[0].headStart != 0 || opponents[0].name != "fast" {
+		t.Errorf("with no average, fast bot got %v as %q, want no head start", opponents[0].headStart, opponents[0].name)
+	}
+	applyHandicap(opponents, 50, 500)
+	if opponents[1].headStart != 0 || opponents[1].name != "stopped" {
+		t.Errorf("stopped bot got %v as %q, want no head start", opponents[1].headStart, opponents[1].name)
+	}
+	if got := expectedDuration(500, 0); got != 0 {
+		t.Errorf("expectedDuration at 0 WPM = %v, want 0", got)
+	}
+}
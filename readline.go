@@ -0,0 +1,337 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// keysmash readline drills the line editing keys of bash, zsh and
+// anything else built on readline's emacs bindings. Each drill shows a
+// command line as if recalled from history, cursor at the end, and the
+// line it should become, with the keys that get there. The player edits
+// it and presses Enter; the drill is done if the line is right, and the
+// edit keys pressed are scored against the suggested ones, so reaching
+// for the arrows or holding backspace costs even when the line comes out
+// right.
+
+// readlineKeyNames are the editing keys in readline's notation, for
+// drills, with how they're shown
+var readlineKeyNames = map[string]string{
+	"C-a": "Ctrl+A", "C-e": "Ctrl+E", "C-b": "Ctrl+B", "C-f": "Ctrl+F",
+	"M-b": "Alt+B", "M-f": "Alt+F",
+	"C-w": "Ctrl+W", "C-u": "Ctrl+U", "C-k": "Ctrl+K", "M-d": "Alt+D",
+	"C-d": "Ctrl+D", "C-y": "Ctrl+Y", "BS": "Backspace",
+	"Left": "Left", "Right": "Right", "Home": "Home", "End": "End", "Del": "Delete",
+}
+
+// readlineDrill is one drill: Start is to be made Target. Solution is a
+// way to do it, editing keys (keys of readlineKeyNames) and text typed in
+// between.
+type readlineDrill struct {
+	Title    string
+	Start    string
+	Target   string
+	Solution []string
+}
+
+// readlineDrills are the drills, easiest first
+var readlineDrills = []readlineDrill{
+	{"Start over", "ls -la /tmp", "pwd", []string{"C-u", "pwd"}},
+	{"Add sudo", "apt install ripgrep", "sudo apt install ripgrep", []string{"C-a", "sudo "}},
+	{"Change the branch", "git push origin main", "git push origin dev", []string{"C-w", "dev"}},
+	{"Drop the last argument", "rm -rf build dist", "rm -rf build", []string{"C-w", "BS"}},
+	{"Replace the command", "cat notes.txt", "less notes.txt", []string{"C-a", "M-d", "less"}},
+	{"Fix the command's typo", "gti status", "git status", []string{"C-a", "M-d", "git"}},
+	{"Add a flag", "grep TODO main.go", "grep -n TODO main.go", []string{"C-a", "M-f", " -n"}},
+	{"Cut off the pipe", "ps aux | grep ssh", "ps aux", []string{"C-a", "M-f", "M-f", "C-k"}},
+	{"Fix a letter in the middle", "docker biuld .", "docker build .", []string{"M-b", "C-f", "C-d", "C-f", "i"}},
+	{"Move the last word to the front", "main.go vim", "vim main.go", []string{"C-w", "BS", "C-a", "C-y", " "}},
+}
+
+// editKeys is the editing keys of a solution, without the text
+func (d readlineDrill) editKeys() []string {
+	var keys []string
+	for _, step := range d.Solution {
+		if _, ok := readlineKeyNames[step]; ok {
+			keys = append(keys, step)
+		}
+	}
+	return keys
+}
+
+// hint shows the solution, keys by name and text quoted
+func (d readlineDrill) hint() string {
+	steps := make([]string, len(d.Solution))
+	for i, step := range d.Solution {
+		if name, ok := readlineKeyNames[step]; ok {
+			steps[i] = name
+		} else {
+			steps[i] = fmt.Sprintf("%q", step)
+		}
+	}
+	return strings.Join(steps, ", ")
+}
+
+// lineEditor is a command line being edited the way readline does
+type lineEditor struct {
+	line   []rune
+	cursor int
+	killed []rune // the last text killed, for C-y
+}
+
+func newLineEditor(line string) *lineEditor {
+	e := &lineEditor{line: []rune(line)}
+	e.cursor = len(e.line)
+	return e
+}
+
+func (e *lineEditor) String() string {
+	return string(e.line)
+}
+
+// insert types text at the cursor
+func (e *lineEditor) insert(text string) {
+	r := []rune(text)
+	e.line = append(e.line[:e.cursor], append(r, e.line[e.cursor:]...)...)
+	e.cursor += len(r)
+}
+
+// kill cuts the line from i to j, keeping it for C-y, and leaves the
+// cursor at i
+func (e *lineEditor) kill(i, j int) {
+	e.killed = append([]rune(nil), e.line[i:j]...)
+	e.line = append(e.line[:i], e.line[j:]...)
+	e.cursor = i
+}
+
+// wordEnd is where M-f from i lands: past any non-word characters, then
+// past the word
+func (e *lineEditor) wordEnd(i int) int {
+	for i < len(e.line) && !isWordRune(e.line[i]) {
+		i++
+	}
+	for i < len(e.line) && isWordRune(e.line[i]) {
+		i++
+	}
+	return i
+}
+
+// wordStart is where M-b from i lands: back over any non-word characters,
+// then to the start of the word
+func (e *lineEditor) wordStart(i int) int {
+	for i > 0 && !isWordRune(e.line[i-1]) {
+		i--
+	}
+	for i > 0 && isWordRune(e.line[i-1]) {
+		i--
+	}
+	return i
+}
+
+// isWordRune reports whether r is part of a word for M-f, M-b and M-d
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// apply carries out an editing key, in readline's notation; see
+// readlineKeyNames
+func (e *lineEditor) apply(key string) {
+	switch key {
+	case "C-a", "Home":
+		e.cursor = 0
+	case "C-e", "End":
+		e.cursor = len(e.line)
+	case "C-b", "Left":
+		e.cursor = max(0, e.cursor-1)
+	case "C-f", "Right":
+		e.cursor = min(len(e.line), e.cursor+1)
+	case "M-b":
+		e.cursor = e.wordStart(e.cursor)
+	case "M-f":
+		e.cursor = e.wordEnd(e.cursor)
+	case "C-w":
+		// Back to whitespace, unlike M-b, so it takes paths and flags whole
+		i := e.cursor
+		for i > 0 && unicode.IsSpace(e.line[i-1]) {
+			i--
+		}
+		for i > 0 && !unicode.IsSpace(e.line[i-1]) {
+			i--
+		}
+		e.kill(i, e.cursor)
+	case "C-u":
+		e.kill(0, e.cursor)
+	case "C-k":
+		e.kill(e.cursor, len(e.line))
+	case "M-d":
+		e.kill(e.cursor, e.wordEnd(e.cursor))
+	case "C-d", "Del":
+		if e.cursor < len(e.line) {
+			e.line = append(e.line[:e.cursor], e.line[e.cursor+1:]...)
+		}
+	case "BS":
+		if e.cursor > 0 {
+			e.line = append(e.line[:e.cursor-1], e.line[e.cursor:]...)
+			e.cursor--
+		}
+	case "C-y":
+		e.insert(string(e.killed))
+	}
+}
+
+// readlineKey names the editing key ev is, if it's one
+func readlineKey(ev *tcell.EventKey) (string, bool) {
+	if ev.Key() == tcell.KeyRune {
+		if ev.Modifiers()&tcell.ModAlt == 0 {
+			return "", false
+		}
+		key := "M-" + string(unicode.ToLower(ev.Rune()))
+		_, ok := readlineKeyNames[key]
+		return key, ok
+	}
+	key, ok := map[tcell.Key]string{
+		tcell.KeyCtrlA: "C-a", tcell.KeyCtrlE: "C-e", tcell.KeyCtrlB: "C-b", tcell.KeyCtrlF: "C-f",
+		tcell.KeyCtrlW: "C-w", tcell.KeyCtrlU: "C-u", tcell.KeyCtrlK: "C-k", tcell.KeyCtrlD: "C-d",
+		tcell.KeyCtrlY: "C-y", tcell.KeyBackspace: "BS", tcell.KeyBackspace2: "BS",
+		tcell.KeyLeft: "Left", tcell.KeyRight: "Right", tcell.KeyHome: "Home", tcell.KeyEnd: "End",
+		tcell.KeyDelete: "Del",
+	}[ev.Key()]
+	return key, ok
+}
+
+// editScore scores the editing keys pressed against those of a solution,
+// 0 to 100: the longest run of them in the same order, as a share of
+// whichever is longer. Extra keys and missing ones both cost.
+func editScore(pressed, want []string) float64 {
+	if len(pressed) == 0 && len(want) == 0 {
+		return 100
+	}
+	// row[j] is the longest common subsequence of pressed so far and
+	// want[:j]
+	row := make([]int, len(want)+1)
+	for _, p := range pressed {
+		diagonal := 0
+		for j, w := range want {
+			next := row[j+1]
+			if p == w {
+				row[j+1] = diagonal + 1
+			} else {
+				row[j+1] = max(row[j+1], row[j])
+			}
+			diagonal = next
+		}
+	}
+	return 100 * float64(row[len(want)]) / float64(max(len(pressed), len(want)))
+}
+
+// readlineRun is how a drill went
+type readlineRun struct {
+	drill   readlineDrill
+	done    bool // the line was right when Enter was pressed
+	pressed []string
+	score   float64
+	time    time.Duration
+}
+
+// playReadlineDrill plays one drill, drill n of total. ok is false if the
+// player pressed Escape.
+func playReadlineDrill(screen tcell.Screen, d readlineDrill, n, total int) (run readlineRun, ok bool) {
+	editor := newLineEditor(d.Start)
+	run.drill = d
+	var start time.Time
+	for {
+		screen.Clear()
+		width, height := screen.Size()
+		drawCenteredText(screen, width/2, height/2-5, tcell.StyleDefault, "READLINE DRILLS")
+		drawCenteredText(screen, width/2, height/2-4, tcell.StyleDefault, fmt.Sprintf("Drill %d of %d: %s", n, total, d.Title))
+		drawCenteredText(screen, width/2, height/2-2, styleCorrect, "Make it: "+d.Target)
+		prompt := "$ " + editor.String()
+		x := max(0, (width-len([]rune("$ "+d.Target)))/2)
+		drawText(screen, x, height/2, tcell.StyleDefault, prompt)
+		screen.ShowCursor(x+2+editor.cursor, height/2)
+		drawCenteredText(screen, width/2, height/2+2, tcell.StyleDefault, "Keys: "+d.hint())
+		drawCenteredText(screen, width/2, height/2+4, tcell.StyleDefault, "Enter: run the line | ESC to stop")
+		screen.Show()
+
+		ev, isKey := screen.PollEvent().(*tcell.EventKey)
+		if !isKey {
+			continue
+		}
+		if start.IsZero() {
+			start = ev.When()
+		}
+		switch key, edit := readlineKey(ev); {
+		case ev.Key() == tcell.KeyEscape:
+			screen.HideCursor()
+			return run, false
+		case ev.Key() == tcell.KeyEnter:
+			screen.HideCursor()
+			run.done = editor.String() == d.Target
+			run.score = editScore(run.pressed, d.editKeys())
+			run.time = ev.When().Sub(start)
+			return run, true
+		case edit:
+			editor.apply(key)
+			run.pressed = append(run.pressed, key)
+		case ev.Key() == tcell.KeyRune && ev.Modifiers()&tcell.ModAlt == 0:
+			editor.insert(string(ev.Rune()))
+		}
+	}
+}
+
+// readlineSummary sums up a session of drills, a line each and a total
+func readlineSummary(runs []readlineRun) []string {
+	var lines []string
+	done, score := 0, 0.0
+	for _, r := range runs {
+		mark := "missed"
+		if r.done {
+			mark = "done"
+			done++
+		}
+		score += r.score
+		lines = append(lines, fmt.Sprintf("%-32s %-6s keys %3.0f%%  %5.1fs", r.drill.Title, mark, r.score, r.time.Seconds()))
+	}
+	if len(runs) > 0 {
+		lines = append(lines, fmt.Sprintf("%d of %d lines right, edit keys %.0f%% on average", done, len(runs), score/float64(len(runs))))
+	}
+	return lines
+}
+
+// runReadline plays the readline drills and prints how they went to w. It
+// returns the process exit code.
+func runReadline(w io.Writer) int {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		fmt.Fprintf(w, "Error creating screen: %v\n", err)
+		return 1
+	}
+	if err := screen.Init(); err != nil {
+		fmt.Fprintf(w, "Error initializing screen: %v\n", err)
+		return 1
+	}
+	var runs []readlineRun
+	for i, d := range readlineDrills {
+		run, ok := playReadlineDrill(screen, d, i+1, len(readlineDrills))
+		if !ok {
+			break
+		}
+		runs = append(runs, run)
+	}
+	screen.Fini()
+
+	if len(runs) == 0 {
+		fmt.Fprintln(w, "Readline drills stopped")
+		return 1
+	}
+	logger.Info("readline drills played", "drills", len(runs))
+	for _, line := range readlineSummary(runs) {
+		fmt.Fprintln(w, line)
+	}
+	return 0
+}
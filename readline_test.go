@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// solve plays a drill's solution on a line editor
+func solve(d readlineDrill) string {
+	editor := newLineEditor(d.Start)
+	for _, step := range d.Solution {
+		if _, ok := readlineKeyNames[step]; ok {
+			editor.apply(step)
+		} else {
+			editor.insert(step)
+		}
+	}
+	return editor.String()
+}
+
+func TestReadlineDrillsSolve(t *testing.T) {
+	for _, d := range readlineDrills {
+		if got := solve(d); got != d.Target {
+			t.Errorf("%s: solution gives %q, want %q", d.Title, got, d.Target)
+		}
+		if len(d.editKeys()) == 0 {
+			t.Errorf("%s: no editing keys to drill", d.Title)
+		}
+	}
+}
+
+func TestLineEditorWords(t *testing.T) {
+	editor := newLineEditor("cp ~/a.txt /tmp")
+	editor.apply("C-w")
+	if editor.String() != "cp ~/a.txt " {
+		t.Errorf("C-w left %q, want the path killed whole", editor.String())
+	}
+	editor.apply("M-b")
+	if editor.cursor != 7 {
+		t.Errorf("M-b went to %d, want 7, the start of txt", editor.cursor)
+	}
+	editor.apply("M-d")
+	editor.insert("md")
+	if editor.String() != "cp ~/a.md " {
+		t.Errorf("line = %q", editor.String())
+	}
+}
+
+func TestEditScore(t *testing.T) {
+	for _, tt := range []struct {
+		pressed, want []string
+		score         float64
+	}{
+		{nil, nil, 100},
+		{[]string{"C-a", "M-d"}, []string{"C-a", "M-d"}, 100},
+		{[]string{"Home", "M-d"}, []string{"C-a", "M-d"}, 50},
+		{[]string{"C-a", "BS", "BS", "M-d"}, []string{"C-a", "M-d"}, 50},
+		{nil, []string{"C-w"}, 0},
+	} {
+		if got := editScore(tt.pressed, tt.want); got != tt.score {
+			t.Errorf("editScore(%v, %v) = %v, want %v", tt.pressed, tt.want, got, tt.score)
+		}
+	}
+}
+
+func TestPlayReadlineDrill(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+
+	d := readlineDrill{"Change the branch", "git push origin main", "git push origin dev", []string{"C-w", "dev"}}
+	go func() {
+		// Backspace four times rather than C-w
+		for i := 0; i < 4; i++ {
+			screen.PostEventWait(tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone))
+		}
+		for _, r := range "dev" {
+			screen.PostEventWait(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+		}
+		screen.PostEventWait(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	}()
+
+	run, ok := playReadlineDrill(screen, d, 1, 1)
+	if !ok || !run.done {
+		t.Fatalf("ok %v, done %v; want the drill done", ok, run.done)
+	}
+	if len(run.pressed) != 4 || run.score != 0 {
+		t.Errorf("pressed %v scored %v, want four backspaces scoring 0", run.pressed, run.score)
+	}
+}
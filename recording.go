@@ -0,0 +1,295 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// recordingVersion is the .ksm format version written by this build
+const recordingVersion = 1
+
+// backspaceKey stands for a backspace in a keystroke log
+const backspaceKey = '\b'
+
+// keystroke is one input a test received: a typed rune or backspaceKey
+type keystroke struct {
+	at time.Time
+	r  rune
+}
+
+// recording is a .ksm run file: enough to replay a completed test and
+// show its results, and nothing about the player beyond their typing. It
+// holds no timestamps, names or environment, and the text itself is only
+// implied by the keystrokes, checked against TextHash.
+type recording struct {
+	Version     int           `json:"version"`
+	TextHash    string        `json:"text_hash"`
+	Mode        string        `json:"mode"`
+	TestFile    string        `json:"test_file"`
+	WPM         float64       `json:"wpm"`
+	Accuracy    float64       `json:"accuracy"`
+	Consistency float64       `json:"consistency"`
+	Score       float64       `json:"score"`
+	Errors      int           `json:"errors"`
+	Duration    time.Duration `json:"duration"`
+
+	// Keys is every rune typed in order, with \b for each backspace, and
+	// Gaps the milliseconds before each of them (the first is 0)
+	Keys string  `json:"keys"`
+	Gaps []int64 `json:"gaps_ms"`
+
+	// PublicKey and Signature are set if the player signs recordings;
+	// see signing.go
+	PublicKey ed25519.PublicKey `json:"public_key,omitempty"`
+	Signature []byte            `json:"signature,omitempty"`
+}
+
+// newRecording captures a completed test
+func newRecording(state *TestState) recording {
+	wpm := state.wpm(state.elapsed())
+	accuracy := calculateAccuracy(state.errors, state.typed())
+	rec := recording{
+		Version:     recordingVersion,
+		TextHash:    hashText(state.referenceText),
+		Mode:        state.mode,
+		TestFile:    state.testFile,
+		WPM:         wpm,
+		Accuracy:    accuracy,
+		Consistency: state.consistency(),
+		Score:       state.score(wpm, accuracy),
+		Errors:      state.errors,
+		Duration:    state.elapsed(),
+		Gaps:        make([]int64, len(state.keys)),
+	}
+
+	var keys strings.Builder
+	for i, key := range state.keys {
+		keys.WriteRune(key.r)
+		if i > 0 {
+			rec.Gaps[i] = key.at.Sub(state.keys[i-1].at).Milliseconds()
+		}
+	}
+	rec.Keys = keys.String()
+	return rec
+}
+
+// reference replays the keystrokes to recover the text that was typed,
+// which a completed run's final input always is, and checks it against
+// TextHash. Like TestState.backspace, a backspace removes a whole
+// grapheme cluster.
+func (rec recording) reference() (string, error) {
+	var clusters []string
+	for _, r := range rec.Keys {
+		switch {
+		case r == backspaceKey:
+			if len(clusters) > 0 {
+				clusters = clusters[:len(clusters)-1]
+			}
+		case len(clusters) > 0 && extendsCluster(clusters[len(clusters)-1], r):
+			clusters[len(clusters)-1] += string(r)
+		default:
+			clusters = append(clusters, string(r))
+		}
+	}
+	text := strings.Join(clusters, "")
+	if hashText(text) != rec.TextHash {
+		return "", errors.New("keystrokes don't reproduce the recorded text; the file is damaged or was edited")
+	}
+	return text, nil
+}
+
+// saveRecording writes rec to the recordings directory, named after when
+// it was saved and the text, and returns its path
+func saveRecording(rec recording, now time.Time) (string, error) {
+	dir, err := dataFile("recordings")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	name := strings.TrimSuffix(filepath.Base(rec.TestFile), filepath.Ext(rec.TestFile))
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.ksm", now.Format("20060102-150405"), name))
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// saveRun records a completed test, signed with key unless it's nil, and
+// saves it with saveRecording
+func saveRun(state *TestState, key ed25519.PrivateKey, now time.Time) (string, error) {
+	if state.userInput != state.referenceText {
+		// A timed test that ran out; the keystrokes can't reproduce the text
+		return "", errors.New("only runs that type the whole text can be recorded")
+	}
+	if state.wordByWord {
+		// Skipped letters aren't keystrokes, so a replay couldn't follow
+		return "", errors.New("word-by-word runs can't be recorded")
+	}
+	rec := newRecording(state)
+	if key != nil {
+		if err := rec.sign(key); err != nil {
+			return "", err
+		}
+	}
+	return saveRecording(rec, now)
+}
+
+// loadRecording reads a .ksm file
+func loadRecording(path string) (recording, error) {
+	var rec recording
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rec, err
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return rec, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if rec.Version > recordingVersion {
+		return rec, fmt.Errorf("%s was made by a newer keysmash (format %d); please upgrade", path, rec.Version)
+	}
+	if len(rec.Gaps) != len([]rune(rec.Keys)) {
+		return rec, fmt.Errorf("%s: %d keys but %d gaps", path, len([]rune(rec.Keys)), len(rec.Gaps))
+	}
+	if _, err := rec.verify(); err != nil {
+		return rec, fmt.Errorf("%s: %w", path, err)
+	}
+	return rec, nil
+}
+
+// scaledClock runs speed times faster than the wall clock from start, so
+// a sped-up replay's timer and WPM read as they did in the original run
+type scaledClock struct {
+	start time.Time
+	speed float64
+}
+
+func (c scaledClock) Now() time.Time {
+	return c.start.Add(time.Duration(float64(time.Since(c.start)) * c.speed))
+}
+
+// replayKey and replayDone are the interrupts a replay's player posts to
+// the event loop
+type replayKey rune
+type replayDone struct{}
+
+// runReplay plays a .ksm file back on screen, keystroke by keystroke at
+// its original pace (or --speed times it), then shows its results. It
+// returns the process exit code.
+func runReplay(stderr io.Writer, args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	speed := flags.Float64("speed", 1, "playback speed multiplier")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || *speed <= 0 {
+		fmt.Fprintln(stderr, "Usage: keysmash replay [--speed N] FILE.ksm")
+		return 2
+	}
+
+	rec, err := loadRecording(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	text, err := rec.reference()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error creating screen: %v\n", err)
+		return 1
+	}
+	if err := screen.Init(); err != nil {
+		fmt.Fprintf(stderr, "Error initializing screen: %v\n", err)
+		return 1
+	}
+	defer screen.Fini()
+
+	clock := scaledClock{start: time.Now(), speed: *speed}
+	state := newTestState(text, rec.TestFile, clock)
+
+	// The player posts each key when it's due; waiting to post rather
+	// than dropping keeps the replay complete if the loop falls behind
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		var offset time.Duration
+		for i, r := range []rune(rec.Keys) {
+			offset += time.Duration(rec.Gaps[i]) * time.Millisecond
+			select {
+			case <-time.After(time.Until(clock.start.Add(time.Duration(float64(offset) / *speed)))):
+			case <-stop:
+				return
+			}
+			screen.PostEventWait(tcell.NewEventInterrupt(replayKey(r)))
+		}
+		screen.PostEventWait(tcell.NewEventInterrupt(replayDone{}))
+	}()
+	stopTicker := startTicker(screen, 100*time.Millisecond)
+	defer stopTicker()
+
+	footer := fmt.Sprintf("Replay of %s at %gx - ESC to stop", rec.TestFile, *speed)
+	for {
+		width, height := screen.Size()
+		renderScreen(screen, &state, width, renderOptions{})
+		drawText(screen, min(4, width/10), height-1, tcell.StyleDefault, footer)
+		screen.Show()
+
+		switch ev := screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			if ev.Key() == tcell.KeyEscape {
+				return 0
+			}
+		case *tcell.EventInterrupt:
+			switch data := ev.Data().(type) {
+			case replayKey:
+				if rune(data) == backspaceKey {
+					state.backspace()
+				} else {
+					state.typeRune(rune(data))
+				}
+			case replayDone:
+				showRecordingResults(screen, rec)
+				waitForKey(screen)
+				return 0
+			}
+		}
+	}
+}
+
+// showRecordingResults shows the results stored in a recording, which are
+// what the player actually got, rather than ones recomputed from a replay
+// whose timing is only as exact as the scheduler
+func showRecordingResults(screen tcell.Screen, rec recording) {
+	screen.Clear()
+	width, height := screen.Size()
+
+	drawCenteredText(screen, width/2, height/2-6, tcell.StyleDefault, "REPLAY COMPLETE")
+	drawCenteredText(screen, width/2, height/2-4, tcell.StyleDefault, fmt.Sprintf("Source: %s (%s)", rec.TestFile, rec.Mode))
+	drawCenteredText(screen, width/2, height/2-2, tcell.StyleDefault, fmt.Sprintf("WPM: %.1f", rec.WPM))
+	drawCenteredText(screen, width/2, height/2, tcell.StyleDefault, fmt.Sprintf("Accuracy: %.1f%%  Consistency: %.0f", rec.Accuracy, rec.Consistency))
+	drawCenteredText(screen, width/2, height/2+2, tcell.StyleDefault, fmt.Sprintf("Time: %.1fs (Errors: %d)", rec.Duration.Seconds(), rec.Errors))
+	drawCenteredText(screen, width/2, height/2+3, tcell.StyleDefault, rec.signer())
+	drawCenteredText(screen, width/2, height/2+5, tcell.StyleDefault, "Press any key to exit")
+	screen.Show()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordingRoundTrip(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	text := "hi \U0001F44D\U0001F3FD!"
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)}
+	state := newTestState(text, "thumbs.txt", clock)
+	state.mode = modeRandom
+	for _, r := range "hj" {
+		state.typeRune(r)
+		clock.advance(150 * time.Millisecond)
+	}
+	state.backspace()
+	for _, r := range "i \U0001F44D\U0001F3FD!" {
+		clock.advance(200 * time.Millisecond)
+		state.typeRune(r)
+	}
+	if !state.testComplete {
+		t.Fatal("test not complete")
+	}
+
+	path, err := saveRecording(newRecording(&state), clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(path, "20240501-090001-thumbs.ksm") {
+		t.Errorf("saved to %s, want a name from the time and text", path)
+	}
+	rec, err := loadRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Keys != "hj\bi \U0001F44D\U0001F3FD!" {
+		t.Errorf("keys = %q", rec.Keys)
+	}
+	wantGaps := []int64{0, 150, 150, 200, 200, 200, 200, 200}
+	if len(rec.Gaps) != len(wantGaps) {
+		t.Fatalf("gaps = %v, want %v", rec.Gaps, wantGaps)
+	}
+	for i := range wantGaps {
+		if rec.Gaps[i] != wantGaps[i] {
+			t.Fatalf("gaps = %v, want %v", rec.Gaps, wantGaps)
+		}
+	}
+	if rec.Errors != 1 || rec.Duration != state.elapsed() || rec.Mode != modeRandom {
+		t.Errorf("results = %d errors in %v (%s), want 1 in %v (random)", rec.Errors, rec.Duration, rec.Mode, state.elapsed())
+	}
+
+	got, err := rec.reference()
+	if err != nil || got != text {
+		t.Errorf("reference = %q, %v; want %q", got, err, text)
+	}
+
+	// Tampering with the keys no longer reproduces the text
+	rec.Keys = strings.Replace(rec.Keys, "!", "?", 1)
+	if _, err := rec.reference(); err == nil {
+		t.Error("edited recording still verified")
+	}
+}
+
+func TestLoadRecordingRejectsNewerVersion(t *testing.T) {
+	path := t.TempDir() + "/future.ksm"
+	if err := os.WriteFile(path, []byte(`{"version": 99, "keys": "", "gaps_ms": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadRecording(path); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("got %v, want an error asking to upgrade", err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// keyRemap rewrites typed characters before they're scored, to correct
+// for odd layouts and firmware (a keyboard that swaps \ and #, say). A
+// character mapped to 0 is dropped, for a key that fires on its own.
+type keyRemap map[rune]rune
+
+// parseRemap builds a keyRemap from the config's [remap] table, where
+// each key and value is a single character and an empty value drops the
+// key
+func parseRemap(table map[string]string) (keyRemap, error) {
+	from := make([]string, 0, len(table))
+	for key := range table {
+		from = append(from, key)
+	}
+	sort.Strings(from) // report the same error every time
+
+	remap := make(keyRemap, len(table))
+	for _, key := range from {
+		value := table[key]
+		if utf8.RuneCountInString(key) != 1 {
+			return nil, fmt.Errorf("remap %q: keys must be a single character", key)
+		}
+		if utf8.RuneCountInString(value) > 1 {
+			return nil, fmt.Errorf("remap %q = %q: must map to a single character, or \"\" to ignore the key", key, value)
+		}
+		to, _ := utf8.DecodeRuneInString(value)
+		if value == "" {
+			to = 0
+		}
+		r, _ := utf8.DecodeRuneInString(key)
+		remap[r] = to
+	}
+	return remap, nil
+}
+
+// apply returns the character to score for a typed r, and false if the
+// key is ignored
+func (m keyRemap) apply(r rune) (rune, bool) {
+	to, ok := m[r]
+	if !ok {
+		return r, true
+	}
+	if to != r {
+		logger.Debug("remapped key", "from", string(r), "to", string(to))
+	}
+	return to, to != 0
+}
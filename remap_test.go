@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseRemap(t *testing.T) {
+	remap, err := parseRemap(map[string]string{`\`: "#", "#": `\`, "§": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		typed rune
+		want  rune
+		ok    bool
+	}{
+		{'\\', '#', true},
+		{'#', '\\', true},
+		{'§', 0, false},
+		{'a', 'a', true},
+	}
+	for _, tt := range tests {
+		if got, ok := remap.apply(tt.typed); got != tt.want || ok != tt.ok {
+			t.Errorf("apply(%q) = %q, %v; want %q, %v", tt.typed, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	for _, bad := range []map[string]string{
+		{"ab": "c"},
+		{"": "c"},
+		{"a": "bc"},
+	} {
+		if _, err := parseRemap(bad); err == nil {
+			t.Errorf("parseRemap(%q) accepted an invalid table", bad)
+		}
+	}
+
+	var none keyRemap
+	if got, ok := none.apply('x'); got != 'x' || !ok {
+		t.Errorf("empty remap changed x to %q, %v", got, ok)
+	}
+}
+
+func TestLoadConfigRemap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	content := "[remap]\n\"\\\\\" = \"#\"\n\"#\" = \"\\\\\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Remap[`\`] != "#" || cfg.Remap["#"] != `\` {
+		t.Errorf("remap = %q, want \\ and # swapped", cfg.Remap)
+	}
+
+	if err := os.WriteFile(path, []byte("[remap]\nab = \"c\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(path); err == nil {
+		t.Error("loadConfig accepted a multi-character remap key")
+	}
+}
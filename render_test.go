@@ -0,0 +1,175 @@
+package main
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/gdamore/tcell/v2/terminfo"
+)
+
+// countingTty is a tcell.Tty that discards output but counts it, for
+// measuring what each frame costs on the wire
+type countingTty struct {
+	mu      sync.Mutex
+	written int
+	closed  chan struct{}
+	once    sync.Once
+	width   int
+	height  int
+}
+
+func newCountingTty(width, height int) *countingTty {
+	return &countingTty{closed: make(chan struct{}), width: width, height: height}
+}
+
+func (t *countingTty) Start() error        { return nil }
+func (t *countingTty) Stop() error         { return nil }
+func (t *countingTty) Drain() error        { return t.Close() }
+func (t *countingTty) NotifyResize(func()) {}
+func (t *countingTty) Close() error        { t.once.Do(func() { close(t.closed) }); return nil }
+func (t *countingTty) WindowSize() (tcell.WindowSize, error) {
+	return tcell.WindowSize{Width: t.width, Height: t.height}, nil
+}
+
+func (t *countingTty) Read(p []byte) (int, error) {
+	<-t.closed
+	return 0, io.EOF
+}
+
+func (t *countingTty) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	t.written += len(p)
+	t.mu.Unlock()
+	return len(p), nil
+}
+
+// take returns the bytes written since the last call
+func (t *countingTty) take() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := t.written
+	t.written = 0
+	return n
+}
+
+func newCountingScreen(tb testing.TB, width, height int) (tcell.Screen, *countingTty) {
+	ti, err := terminfo.LookupTerminfo("xterm-256color")
+	if err != nil {
+		tb.Skip("no xterm-256color terminfo:", err)
+	}
+	tty := newCountingTty(width, height)
+	screen, err := tcell.NewTerminfoScreenFromTtyTerminfo(tty, ti)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := screen.Init(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(screen.Fini)
+	return screen, tty
+}
+
+// TestKeystrokeBandwidth checks that a keystroke mid-test only sends the
+// cells it changed, not a repaint of the whole screen
+func TestKeystrokeBandwidth(t *testing.T) {
+	screen, tty := newCountingScreen(t, 100, 40)
+	clock := &fakeClock{}
+	text := benchText(2000)
+	state := newTestState(text, "bench.txt", clock)
+	opts := renderOptions{reducedMotion: true}
+	for _, r := range text[:1000] {
+		state.typeRune(r)
+	}
+	opts.stats = sampleStats(&state)
+
+	renderScreen(screen, &state, 100, opts)
+	full := tty.take()
+
+	clock.advance(200 * time.Millisecond)
+	state.typeRune(rune(text[1000]))
+	renderScreen(screen, &state, 100, opts)
+	keystroke := tty.take()
+
+	t.Logf("first frame %d bytes, keystroke %d bytes", full, keystroke)
+	if keystroke*10 > full {
+		t.Errorf("keystroke sent %d bytes, more than a tenth of a full frame (%d)", keystroke, full)
+	}
+}
+
+func TestDrawTextWidths(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(20, 1)
+
+	// "日" is two cells wide; "é" is e plus a combining acute accent; the
+	// thumbs up with a skin tone is one two-cell emoji
+	drawText(screen, 0, 0, tcell.StyleDefault, "日e\u0301x\U0001F44D\U0001F3FDy")
+
+	tests := []struct {
+		x         int
+		main      rune
+		combining []rune
+	}{
+		{0, '日', nil},
+		{2, 'e', []rune{'\u0301'}},
+		{3, 'x', nil},
+		{4, '\U0001F44D', []rune{'\U0001F3FD'}},
+		{6, 'y', nil},
+	}
+	for _, tt := range tests {
+		main, combining, _, _ := screen.GetContent(tt.x, 0)
+		if main != tt.main || string(combining) != string(tt.combining) {
+			t.Errorf("cell %d = %q%q, want %q%q", tt.x, main, combining, tt.main, tt.combining)
+		}
+	}
+}
+
+func TestInputMethodCursor(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(80, 24)
+
+	state := newTestState("ab", "test.txt", &fakeClock{})
+	state.typeRune('a')
+
+	renderScreen(screen, &state, 80, renderOptions{})
+	if _, _, visible := screen.GetCursor(); visible {
+		t.Error("terminal cursor shown without an input method")
+	}
+
+	renderScreen(screen, &state, 80, renderOptions{inputMethod: true})
+	x, y, visible := screen.GetCursor()
+	if !visible {
+		t.Fatal("terminal cursor hidden with an input method")
+	}
+	if main, _, style, _ := screen.GetContent(x, y); main != ' ' || style != tcell.StyleDefault {
+		t.Errorf("cursor cell = %q, want it left blank for the pre-edit text", main)
+	}
+	if main, _, _, _ := screen.GetContent(x-1, y); main != 'a' {
+		t.Errorf("cursor is not after the typed text: cell before it is %q", main)
+	}
+}
+
+func TestNeedsIME(t *testing.T) {
+	for text, want := range map[string]bool{
+		"hello":              false,
+		"café \U0001F680":    false,
+		"日本語":                true, // kanji
+		"ひらがな":               true, // hiragana
+		"한글":                 true, // hangul
+		"mixed 中文 and latin": true,
+	} {
+		if got := needsIME(text); got != want {
+			t.Errorf("needsIME(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
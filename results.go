@@ -0,0 +1,286 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// result is one completed test as kept in the results store
+type result struct {
+	TestFile    string         `json:"test_file"`
+	TextHash    string         `json:"text_hash,omitempty"`
+	Started     time.Time      `json:"started"`
+	Completed   time.Time      `json:"completed"`
+	WPM         float64        `json:"wpm"`
+	Accuracy    float64        `json:"accuracy"`
+	Consistency float64        `json:"consistency"`
+	Score       float64        `json:"score"`
+	Errors      int            `json:"errors"`
+	Uncorrected int            `json:"uncorrected,omitempty"` // errors left in, as a timed test can
+	NetWPM      float64        `json:"net_wpm,omitempty"`
+	Duration    time.Duration  `json:"duration"`
+	Estimate    time.Duration  `json:"estimate,omitempty"`
+	Flow        time.Duration  `json:"flow,omitempty"`       // time typing in flow; see flowTracker
+	Keystrokes  int            `json:"keystrokes,omitempty"` // see typingEconomy
+	Backspaces  int            `json:"backspaces,omitempty"`
+	Efficiency  float64        `json:"efficiency,omitempty"`
+	Peeks       int            `json:"peeks,omitempty"`   // times a blind test was peeked at
+	Comfort     int            `json:"comfort,omitempty"` // end-of-session check-in, 1 (fine) to comfortScale
+	Environment runEnvironment `json:"environment"`
+
+	// Metrics are the session's metrics, such as resting heart rate, by
+	// name; see sessionMetrics
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+
+	// CorrectionOf is set on a correction drill, a paragraph of a run
+	// typed again from the review screen, to when that run completed
+	CorrectionOf time.Time `json:"correction_of,omitempty"`
+
+	// Set from the history screen. Excluded runs stay in the history but
+	// don't count towards averages, estimates, warm-up or bests.
+	Tags     []string `json:"tags,omitempty"`
+	Excluded bool     `json:"excluded,omitempty"`
+
+	// Failed is set on a sudden death run ended by an error, made at
+	// cluster FailedAt. It stays in the history but doesn't count.
+	Failed   bool `json:"failed,omitempty"`
+	FailedAt int  `json:"failed_at,omitempty"`
+}
+
+// hashText identifies a reference text by content, independent of the
+// file it was loaded from
+func hashText(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:16])
+}
+
+// resultStore is the history of every completed test, oldest first
+type resultStore struct {
+	Results []result `json:"results"`
+	path    string
+
+	// The player's own store keeps an audit log of its changes
+	auditPath    string
+	pendingAudit []auditEntry
+
+	// If set, the store is the daemon's, saved through it; see daemon.go
+	daemon *daemonClient
+
+	// session is the keys of the runs added since the store was loaded,
+	// the tests of this session
+	session []string
+}
+
+func loadResults() (*resultStore, error) {
+	path, err := dataFile("results.json")
+	if err != nil {
+		return nil, err
+	}
+	store, err := loadResultStore(path)
+	if err != nil {
+		return nil, err
+	}
+	if store.auditPath, err = auditPath(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func loadResultStore(path string) (*resultStore, error) {
+	store := &resultStore{path: path}
+	if err := readJSONFile(path, store); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *resultStore) add(r result) {
+	s.Results = append(s.Results, r)
+	s.session = append(s.session, r.key())
+	s.audit("add", "", "", []result{r})
+}
+
+// save writes the store, then appends the changes made since the last save
+// to its audit log. A daemon's client sends the changes to the daemon
+// instead, which audits them there; see saveToDaemon.
+func (s *resultStore) save() error {
+	if s.daemon != nil {
+		return s.saveToDaemon()
+	}
+	if err := saveDataFile(s.path, s); err != nil {
+		return err
+	}
+	return s.flushAudit()
+}
+
+// counted returns the results that count towards stats, leaving out those
+// excluded from the history screen and failed sudden death runs
+func (s *resultStore) counted() []result {
+	counted := make([]result, 0, len(s.Results))
+	for _, r := range s.Results {
+		if !r.Excluded && !r.Failed {
+			counted = append(counted, r)
+		}
+	}
+	return counted
+}
+
+// latest returns the most recent result, if there is one
+func (s *resultStore) latest() (result, bool) {
+	if len(s.Results) == 0 {
+		return result{}, false
+	}
+	return s.Results[len(s.Results)-1], true
+}
+
+// recentWPM returns the speeds of the last n results, oldest first
+func (s *resultStore) recentWPM(n int) []float64 {
+	counted := s.counted()
+	recent := counted[max(0, len(counted)-n):]
+	wpm := make([]float64, len(recent))
+	for i, r := range recent {
+		wpm[i] = r.WPM
+	}
+	return wpm
+}
+
+// bestWPM is the fastest result, or 0 if there are none
+func (s *resultStore) bestWPM() float64 {
+	best := 0.0
+	for _, r := range s.counted() {
+		best = max(best, r.WPM)
+	}
+	return best
+}
+
+// estimateBias compares the last n runs that had a time estimate with that
+// estimate. It returns how much faster than estimated they were on average,
+// as a fraction (0.05 = 5% faster; negative = slower), and how many runs
+// that covers. Steadily beating the estimate means the average is rising.
+func (s *resultStore) estimateBias(n int) (faster float64, runs int) {
+	counted := s.counted()
+	for i := len(counted) - 1; i >= 0 && runs < n; i-- {
+		r := counted[i]
+		if r.Estimate <= 0 {
+			continue
+		}
+		faster += 1 - r.Duration.Seconds()/r.Estimate.Seconds()
+		runs++
+	}
+	if runs == 0 {
+		return 0, 0
+	}
+	return faster / float64(runs), runs
+}
+
+// recordResults saves every completed test to the store, along with the
+// environment it was played in and the session's metrics
+func recordResults(bus *EventBus, store *resultStore, environment func() runEnvironment, metrics map[string]float64) {
+	bus.Subscribe(func(ev Event) {
+		r := eventResult(ev)
+		r.Environment = environment()
+		r.Metrics = metrics
+		store.add(r)
+		if err := store.save(); err != nil {
+			logger.Error("saving results failed", "err", err)
+		}
+	}, EventTestCompleted, EventTestFailed)
+}
+
+// eventResult is the result an EventTestCompleted or EventTestFailed
+// reports, without the run's environment or session metrics
+func eventResult(ev Event) result {
+	r := result{
+		TestFile:    ev.TestFile,
+		TextHash:    ev.TextHash,
+		Started:     ev.Time.Add(-ev.Duration),
+		Completed:   ev.Time,
+		WPM:         ev.WPM,
+		Accuracy:    ev.Accuracy,
+		Consistency: ev.Consistency,
+		Score:       ev.Score,
+		Errors:      ev.Errors,
+		Uncorrected: ev.Uncorrected,
+		NetWPM:      ev.NetWPM,
+		Duration:    ev.Duration,
+		Estimate:    ev.Estimate,
+		Flow:        ev.Flow,
+		Keystrokes:  ev.Keystrokes,
+		Backspaces:  ev.Backspaces,
+		Efficiency:  ev.Efficiency,
+		Peeks:       ev.Peeks,
+
+		CorrectionOf: ev.CorrectionOf,
+	}
+	if ev.Kind == EventTestFailed {
+		r.Failed, r.FailedAt = true, ev.Position
+	}
+	return r
+}
+
+// recentTests returns the last n distinct texts completed from the tests
+// directory, most recent first, with the speed of their latest run.
+// Generated tests such as daily challenges, drills and lessons can't be
+// replayed by name, so they're left out.
+func (s *resultStore) recentTests(n int) []result {
+	seen := make(map[string]bool)
+	var recent []result
+	for i := len(s.Results) - 1; i >= 0 && len(recent) < n; i-- {
+		r := s.Results[i]
+		if seen[r.TestFile] || !strings.HasSuffix(strings.ToLower(r.TestFile), ".txt") {
+			continue
+		}
+		seen[r.TestFile] = true
+		recent = append(recent, r)
+	}
+	return recent
+}
+
+// fileStats returns the best speed on one text, named file with the
+// given hash, and the average speed of its last n runs, with how many
+// runs that average covers, counting only results that count towards
+// stats. Runs of the same text under another name count too.
+func (s *resultStore) fileStats(file, hash string, n int) (best, average float64, runs int) {
+	counted := s.counted()
+	for i := len(counted) - 1; i >= 0; i-- {
+		r := counted[i]
+		if !sameText(r, file, hash) {
+			continue
+		}
+		best = max(best, r.WPM)
+		if runs < n {
+			average += r.WPM
+			runs++
+		}
+	}
+	if runs > 0 {
+		average /= float64(runs)
+	}
+	return best, average, runs
+}
+
+// textStats summarises the player's results on one text
+type textStats struct {
+	Attempts int
+	BestWPM  float64
+	Last     time.Time
+}
+
+// textStats summarises the counted results for each text, by its current
+// file name in ix
+func (s *resultStore) textStats(ix textIndex) map[string]textStats {
+	stats := make(map[string]textStats)
+	for _, r := range s.counted() {
+		name := ix.name(r)
+		st := stats[name]
+		st.Attempts++
+		st.BestWPM = max(st.BestWPM, r.WPM)
+		if r.Completed.After(st.Last) {
+			st.Last = r.Completed
+		}
+		stats[name] = st
+	}
+	return stats
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestRecordResultsCapturesEnvironment(t *testing.T) {
+	t.Setenv("TERM", "xterm-256color")
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(100, 30)
+
+	path := filepath.Join(t.TempDir(), "results.json")
+	store, err := loadResultStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := newEventBus()
+	settings := runSettings{Bots: "steady", ScoreFormula: "wpm"}
+	recordResults(bus, store, func() runEnvironment {
+		return captureEnvironment(screen, modeDaily, settings, nil)
+	}, map[string]float64{"resting_hr": 58})
+
+	completed := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	bus.Publish(Event{Kind: EventTestCompleted, Time: completed, TestFile: "a.txt", WPM: 72, Score: 72, Duration: time.Minute})
+
+	reloaded, err := loadResultStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, ok := reloaded.latest()
+	if !ok || len(reloaded.Results) != 1 {
+		t.Fatalf("store has %d results after one completed test", len(reloaded.Results))
+	}
+	if last.TestFile != "a.txt" || last.WPM != 72 || !last.Completed.Equal(completed) || last.Duration != time.Minute {
+		t.Errorf("result = %+v", last)
+	}
+
+	env := last.Environment
+	if env.TerminalWidth != 100 || env.TerminalHeight != 30 {
+		t.Errorf("terminal size = %dx%d, want 100x30", env.TerminalWidth, env.TerminalHeight)
+	}
+	if env.Term != "xterm-256color" || env.Mode != modeDaily || env.Settings != settings || env.Version == "" {
+		t.Errorf("environment = %+v", env)
+	}
+	if last.Metrics["resting_hr"] != 58 {
+		t.Errorf("metrics = %v", last.Metrics)
+	}
+}
+
+func TestEstimateBias(t *testing.T) {
+	store := &resultStore{}
+	if _, runs := store.estimateBias(10); runs != 0 {
+		t.Errorf("empty store covers %d runs", runs)
+	}
+
+	store.add(result{Duration: time.Minute}) // no estimate, skipped
+	store.add(result{Duration: 90 * time.Second, Estimate: time.Minute})
+	store.add(result{Duration: 45 * time.Second, Estimate: time.Minute})
+	store.add(result{Duration: 54 * time.Second, Estimate: time.Minute})
+
+	// Only the last two: 25% and 10% faster
+	faster, runs := store.estimateBias(2)
+	if runs != 2 || math.Abs(faster-0.175) > 1e-9 {
+		t.Errorf("estimateBias(2) = %v over %d runs, want 0.175 over 2", faster, runs)
+	}
+	// All three with estimates: -50%, +25%, +10%
+	faster, runs = store.estimateBias(10)
+	if runs != 3 || math.Abs(faster-(-0.15/3)) > 1e-9 {
+		t.Errorf("estimateBias(10) = %v over %d runs, want %v over 3", faster, runs, -0.15/3)
+	}
+}
+
+func TestAverageWPMWindow(t *testing.T) {
+	engine := newEngine(&fakeClock{}, fixedRand(0), t.TempDir())
+	if got := engine.averageWPM(); got != defaultAverageWPM {
+		t.Errorf("average with no history = %v, want %v", got, defaultAverageWPM)
+	}
+
+	store := &resultStore{}
+	for wpm := 1; wpm <= 15; wpm++ {
+		store.add(result{WPM: float64(wpm)})
+	}
+	engine.recentWPM = store.recentWPM(averageWindow)
+	engine.events.Publish(Event{Kind: EventTestCompleted, WPM: 16})
+
+	// The last ten are 7..16
+	if got := engine.averageWPM(); got != 11.5 {
+		t.Errorf("average = %v, want 11.5", got)
+	}
+}
+
+func TestRecordComfort(t *testing.T) {
+	earlier, err := loadResultStore(filepath.Join(t.TempDir(), "results.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	earlier.add(result{TestFile: "a.txt", Started: start, WPM: 40}) // an earlier session
+	earlier.add(result{TestFile: "b.txt", Started: start.Add(time.Minute), WPM: 45})
+	if err := earlier.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := loadResultStore(earlier.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.add(result{TestFile: "a.txt", Started: start.Add(time.Hour), WPM: 50})
+	// A daemon's store also has runs from other terminals
+	store.Results = append(store.Results, result{TestFile: "c.txt", Started: start.Add(time.Hour + time.Minute), WPM: 55})
+	store.add(result{TestFile: "b.txt", Started: start.Add(time.Hour + 2*time.Minute), WPM: 60})
+	// Deleting an earlier run shifts the session's runs down
+	store.Results = store.Results[1:]
+
+	if err := store.recordComfort(3); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := loadResultStore(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int{0, 3, 0, 3} {
+		if got := reloaded.Results[i].Comfort; got != want {
+			t.Errorf("result %d comfort = %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestRecentTests(t *testing.T) {
+	store := &resultStore{}
+	for i, file := range []string{"a.txt", "b.txt", "daily-2026-10-15", "a.txt", "drill", "c.txt", "lesson-Home row"} {
+		store.add(result{TestFile: file, WPM: float64(i)})
+	}
+	recent := store.recentTests(2)
+	if len(recent) != 2 || recent[0].TestFile != "c.txt" || recent[1].TestFile != "a.txt" || recent[1].WPM != 3 {
+		t.Errorf("recent = %+v, want c.txt then a.txt's latest run", recent)
+	}
+	if got := len(store.recentTests(10)); got != 3 {
+		t.Errorf("%d recent tests, want 3 from the tests directory", got)
+	}
+}
+
+func TestTextStats(t *testing.T) {
+	day := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	store := &resultStore{Results: []result{
+		{TestFile: "a.txt", Completed: day.Add(time.Hour), WPM: 70},
+		{TestFile: "a.txt", Completed: day, WPM: 75},
+		{TestFile: "b.txt", Completed: day, WPM: 50},
+	}}
+	stats := store.textStats(textIndex{})
+	if a := stats["a.txt"]; a.Attempts != 2 || a.BestWPM != 75 || !a.Last.Equal(day.Add(time.Hour)) {
+		t.Errorf("a.txt stats = %+v", a)
+	}
+	if _, ok := stats["c.txt"]; ok {
+		t.Error("stats for a text never played")
+	}
+}
+
+func TestFileStats(t *testing.T) {
+	store := &resultStore{Results: []result{
+		{TestFile: "a.txt", WPM: 90},
+		{TestFile: "a.txt", WPM: 40},
+		{TestFile: "b.txt", WPM: 100},
+		{TestFile: "a.txt", WPM: 60},
+		{TestFile: "a.txt", WPM: 200, Excluded: true},
+	}}
+	best, average, runs := store.fileStats("a.txt", "", 2)
+	if best != 90 || average != 50 || runs != 2 {
+		t.Errorf("fileStats = %v, %v, %d; want best 90 and 50 over the last 2", best, average, runs)
+	}
+	if _, _, runs := store.fileStats("c.txt", "", 10); runs != 0 {
+		t.Errorf("%d runs of a text never played", runs)
+	}
+}
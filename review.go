@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// The review screen, opened from the results, shows the whole text with
+// every place the player went wrong marked, even those put right before
+// the end, and jumps from one to the next with N and P so a long text
+// needn't be scrolled through a line at a time. Enter types the paragraph
+// of the error jumped to again, as a correction drill: a short test of
+// its own, recorded against the run it corrects. Below the text it says
+// what was typed at the error jumped to and what was expected there.
+
+// correctionFilePrefix starts the TestState.testFile of a correction
+// drill, followed by the text's name and which paragraph of it it is
+const correctionFilePrefix = "correction-"
+
+// currentMistakeStyle marks the mistake the review screen jumped to, in
+// the theme's style for mistakes
+func currentMistakeStyle() tcell.Style {
+	return styleMissed.Underline(true).Bold(true)
+}
+
+// mistake is an error made in a test
+type mistake struct {
+	pos   int    // the cluster of the reference it was made at, or past its end
+	typed string // the cluster typed there, as it was when scored wrong
+}
+
+// reviewMistakes returns the positions in the reference the player made
+// errors at, in order, each once. Characters typed past the end of the
+// text count against its last character.
+func reviewMistakes(state *TestState) []int {
+	seen := make(map[int]bool, len(state.mistakes))
+	var positions []int
+	for _, m := range state.mistakes {
+		i := min(m.pos, len(state.reference)-1)
+		if i >= 0 && !seen[i] {
+			seen[i] = true
+			positions = append(positions, i)
+		}
+	}
+	sort.Ints(positions)
+	return positions
+}
+
+// mistakeText describes the errors made at position pos, one of
+// reviewMistakes: what was typed there, each different thing once, and
+// what was expected, with the word it's in
+func mistakeText(state *TestState, pos int) string {
+	var typed, extra []string
+	seen := make(map[string]bool)
+	for _, m := range state.mistakes {
+		if min(m.pos, len(state.reference)-1) != pos || seen[m.typed] {
+			continue
+		}
+		seen[m.typed] = true
+		if m.pos >= len(state.reference) {
+			extra = append(extra, quoteCluster(m.typed))
+		} else {
+			typed = append(typed, quoteCluster(m.typed))
+		}
+	}
+	var parts []string
+	if len(typed) > 0 {
+		want := state.reference[pos]
+		part := fmt.Sprintf("typed %s where %s was expected", strings.Join(typed, " then "), quoteCluster(want))
+		if word := wordAt(state.referenceText, state.refStarts[pos]); !isBlank(want) && word != want {
+			part += fmt.Sprintf(" in %q", word)
+		}
+		parts = append(parts, part)
+	}
+	if len(extra) > 0 {
+		parts = append(parts, fmt.Sprintf("typed %s past the end", strings.Join(extra, " then ")))
+	}
+	text := strings.Join(parts, "; ")
+	if text == "" {
+		return ""
+	}
+	return strings.ToUpper(text[:1]) + text[1:]
+}
+
+// quoteCluster shows a typed or expected cluster in mistakeText, naming
+// whitespace rather than quoting it
+func quoteCluster(cluster string) string {
+	if isBlank(cluster) {
+		return keyName(cluster)
+	}
+	return fmt.Sprintf("%q", cluster)
+}
+
+// wordAt returns the word of text around byte offset off, up to the
+// whitespace either side
+func wordAt(text string, off int) string {
+	start := strings.LastIndexAny(text[:off], " \t\n") + 1
+	end := strings.IndexAny(text[off:], " \t\n")
+	if end < 0 {
+		return text[start:]
+	}
+	return text[start : off+end]
+}
+
+// paragraphAt returns the paragraph of text holding byte offset off, as
+// its byte range and its number counting from 1. Paragraphs are split by
+// blank lines; an offset in the blank lines goes with the paragraph
+// before.
+func paragraphAt(text string, off int) (start, end, n int) {
+	n = 1
+	inBlank := false
+	lineStart := 0
+	for lineStart <= len(text) {
+		lineEnd := strings.IndexByte(text[lineStart:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(text)
+		} else {
+			lineEnd += lineStart
+		}
+		blank := strings.TrimSpace(text[lineStart:lineEnd]) == ""
+		if !blank && inBlank {
+			if lineStart > off {
+				break // off was in the paragraph before
+			}
+			start, n = lineStart, n+1
+		}
+		if !blank {
+			end = lineEnd
+		}
+		inBlank = blank
+		lineStart = lineEnd + 1
+	}
+	return start, end, n
+}
+
+// correctionDrill returns a test of the nth paragraph of parent, start
+// to end in its text, to type again as a correction drill. It's untimed
+// and unraced, whatever parent was.
+func (e *Engine) correctionDrill(parent *TestState, start, end, n int) TestState {
+	state := e.newTest(parent.referenceText[start:end], fmt.Sprintf("%s%s#%d", correctionFilePrefix, parent.testFile, n))
+	state.correctionOf = parent.endTime
+	state.timeLimit = 0
+	state.opponents = nil
+	state.publishSnapshot()
+	return state
+}
+
+// reviewView shows a finished test's mistakes; see showReview
+type reviewView struct {
+	app     *app
+	state   *TestState
+	current int // the mistake jumped to, kept for coming back from a drill
+}
+
+func (v *reviewView) run(screen tcell.Screen) navigation {
+	current, retype := showReview(screen, v.state, v.current)
+	v.current = current
+	if !retype {
+		return goBack
+	}
+	start, end, n := paragraphAt(v.state.referenceText, v.state.refStarts[reviewMistakes(v.state)[current]])
+	drill := v.app.engine.correctionDrill(v.state, start, end, n)
+	logger.Info("selected correction drill", "file", drill.testFile)
+	return open(&testView{app: v.app, state: &drill})
+}
+
+// showReview runs the review screen from the mistake current, the index
+// of one in reviewMistakes or -1 for none, until the player leaves it or
+// presses Enter to type the paragraph of the one jumped to again. It
+// returns the mistake it ended on and whether Enter was pressed.
+func showReview(screen tcell.Screen, state *TestState, current int) (int, bool) {
+	mistakes := reviewMistakes(state)
+	wrong := make(map[int]bool, len(mistakes))
+	for _, i := range mistakes {
+		wrong[i] = true
+	}
+	top := 0 // first line shown
+	jumped := current >= 0
+	for {
+		screen.Clear()
+		width, height := screen.Size()
+		lines := wrapText(state.referenceText, max(20, width-8))
+		offsets := lineOffsets(state.referenceText, lines)
+		rows := max(1, height-6)
+
+		counter := fmt.Sprintf("%d errors; press N to go to the first", len(mistakes))
+		currentLine := -1
+		switch {
+		case len(mistakes) == 0:
+			counter = "No errors"
+		case len(mistakes) == 1 && current < 0:
+			counter = "1 error; press N to go to it"
+		case current >= 0:
+			counter = fmt.Sprintf("Error %d of %d; Enter types its paragraph again", current+1, len(mistakes))
+			currentLine = sort.SearchInts(offsets, state.refStarts[mistakes[current]]+1) - 1
+		}
+		if jumped && (currentLine < top || currentLine >= top+rows) {
+			top = currentLine - rows/2 // into view, towards the middle
+		}
+		jumped = false
+		top = max(0, min(top, len(lines)-rows))
+
+		drawCenteredText(screen, width/2, 1, tcell.StyleDefault, "REVIEW: "+state.testFile)
+		drawCenteredText(screen, width/2, 2, tcell.StyleDefault, counter)
+		for i := top; i < len(lines) && i < top+rows; i++ {
+			y := 4 + i - top
+			drawWrappedLine(screen, 4, y, lines[i], state.referenceText, offsets[i], func(offset int) tcell.Style {
+				c := clusterIndex(state.refStarts, offset)
+				switch {
+				case current >= 0 && c == mistakes[current]:
+					return currentMistakeStyle()
+				case wrong[c]:
+					return styleMissed
+				case c >= state.typed():
+					return styleUntyped
+				}
+				return tcell.StyleDefault
+			})
+			if i == currentLine {
+				screen.SetContent(2, y, '›', nil, currentMistakeStyle())
+			}
+		}
+		if top > 0 {
+			drawText(screen, width-3, 4, tcell.StyleDefault, "↑")
+		}
+		if top+rows < len(lines) {
+			drawText(screen, width-3, 3+rows, tcell.StyleDefault, "↓")
+		}
+		if current >= 0 {
+			drawCenteredText(screen, width/2, height-2, styleMissed, mistakeText(state, mistakes[current]))
+		}
+		drawCenteredText(screen, width/2, height-1, tcell.StyleDefault, "N/P: Next/previous error  Enter: Retype paragraph  ↑/↓/PgUp/PgDn: Scroll  Q: Back")
+		screen.Show()
+
+		switch ev := screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			switch ev.Key() {
+			case tcell.KeyEscape:
+				return current, false
+			case tcell.KeyEnter:
+				if current >= 0 {
+					return current, true
+				}
+			case tcell.KeyUp:
+				top--
+			case tcell.KeyDown:
+				top++
+			case tcell.KeyPgUp:
+				top -= rows - 1
+			case tcell.KeyPgDn:
+				top += rows - 1
+			case tcell.KeyRune:
+				// N and P go round from the last mistake to the first
+				switch ev.Rune() {
+				case 'n', 'N':
+					if len(mistakes) > 0 {
+						current, jumped = (current+1)%len(mistakes), true
+					}
+				case 'p', 'P':
+					if len(mistakes) > 0 {
+						current, jumped = (max(current, 0)+len(mistakes)-1)%len(mistakes), true
+					}
+				case 'q', 'Q':
+					return current, false
+				}
+			}
+		}
+	}
+}
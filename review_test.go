@@ -0,0 +1,162 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// typeWithMistakes types text, first getting wrong each position in wrong
+// and correcting it
+func typeWithMistakes(state *TestState, text string, wrong ...int) {
+	for i, r := range text {
+		for _, w := range wrong {
+			if w == i {
+				state.typeRune('#')
+				state.backspace()
+			}
+		}
+		state.typeRune(r)
+	}
+}
+
+func TestReviewMistakes(t *testing.T) {
+	state := newTestState("the quick brown fox", "test.txt", &fakeClock{})
+	typeWithMistakes(&state, state.referenceText, 10, 4, 10)
+	if !state.testComplete {
+		t.Fatal("test didn't complete")
+	}
+	if got, want := reviewMistakes(&state), []int{4, 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("reviewMistakes = %v, want %v", got, want)
+	}
+
+	if got, want := mistakeText(&state, 4), `Typed "#" where "q" was expected in "quick"`; got != want {
+		t.Errorf("mistakeText = %q, want %q", got, want)
+	}
+
+	state.reset()
+	if got := reviewMistakes(&state); len(got) != 0 {
+		t.Errorf("after reset: %v", got)
+	}
+}
+
+func TestMistakeText(t *testing.T) {
+	state := newTestState("to be", "test.txt", &fakeClock{})
+	for _, r := range "tp" {
+		state.typeRune(r)
+	}
+	state.backspace()
+	for _, r := range "ox" {
+		state.typeRune(r)
+	}
+	state.backspace()
+	for _, r := range " bed" {
+		state.typeRune(r)
+	}
+
+	for _, tt := range []struct {
+		pos  int
+		want string
+	}{
+		{1, `Typed "p" where "o" was expected in "to"`},
+		{2, `Typed "x" where space was expected`},
+		{4, `Typed "d" past the end`},
+	} {
+		if got := mistakeText(&state, tt.pos); got != tt.want {
+			t.Errorf("mistakeText(%d) = %q, want %q", tt.pos, got, tt.want)
+		}
+	}
+}
+
+func TestShowReview(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(60, 12)
+
+	// Far more text than fits, with the last mistake well down it
+	lines := make([]string, 40)
+	for i := range lines {
+		lines[i] = "line of text"
+	}
+	text := strings.Join(lines, "\n")
+	state := newTestState(text, "test.txt", &fakeClock{})
+	last := strings.LastIndex(text, "line")
+	typeWithMistakes(&state, text, 2, last)
+
+	// Forward three times goes round to the first, and back from there
+	// to the last, scrolled into view
+	screen.InjectKeyBytes([]byte("nnnpq"))
+	if current, retype := showReview(screen, &state, -1); current != 1 || retype {
+		t.Errorf("showReview = %d, %v; want 1, false", current, retype)
+	}
+
+	if got := strings.TrimSpace(cellText(screen, 0, 2, 60)); !strings.HasPrefix(got, "Error 2 of 2;") {
+		t.Errorf("counter = %q", got)
+	}
+	if got, want := strings.TrimSpace(cellText(screen, 0, 10, 60)), `Typed "#" where "l" was expected in "line"`; got != want {
+		t.Errorf("mistake line = %q, want %q", got, want)
+	}
+	marked := -1
+	for y := 4; y < 12; y++ {
+		if cellText(screen, 2, y, 1) == "›" {
+			marked = y
+		}
+	}
+	if marked < 0 {
+		t.Fatal("the current error's line isn't marked")
+	}
+	if _, _, style, _ := screen.GetContent(4, marked); style != currentMistakeStyle() {
+		t.Errorf("the current error is styled %v", style)
+	}
+}
+
+func TestParagraphAt(t *testing.T) {
+	text := "one\ntwo\n\n  \nthree\n\nfour"
+	tests := []struct {
+		off  int
+		want string
+		n    int
+	}{
+		{0, "one\ntwo", 1},
+		{5, "one\ntwo", 1},
+		{8, "one\ntwo", 1}, // the blank lines after it
+		{14, "three", 2},
+		{len(text) - 1, "four", 3},
+	}
+	for _, tt := range tests {
+		start, end, n := paragraphAt(text, tt.off)
+		if text[start:end] != tt.want || n != tt.n {
+			t.Errorf("paragraphAt(%d) = %q, %d; want %q, %d", tt.off, text[start:end], n, tt.want, tt.n)
+		}
+	}
+}
+
+func TestCorrectionDrill(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)}
+	engine := newEngine(clock, fixedRand(0), t.TempDir())
+	engine.timeLimit = 30 * time.Second
+	parent := engine.newTest("first part\n\nsecond part", "a.txt")
+	typeWithMistakes(&parent, parent.referenceText, 15)
+
+	var completed []Event
+	engine.events.Subscribe(func(ev Event) { completed = append(completed, ev) }, EventTestCompleted)
+
+	start, end, n := paragraphAt(parent.referenceText, parent.refStarts[reviewMistakes(&parent)[0]])
+	drill := engine.correctionDrill(&parent, start, end, n)
+	if drill.referenceText != "second part" || drill.testFile != "correction-a.txt#2" || drill.timeLimit != 0 {
+		t.Fatalf("drill of %q from %s, limit %v", drill.referenceText, drill.testFile, drill.timeLimit)
+	}
+	clock.advance(time.Minute)
+	for _, r := range drill.referenceText {
+		drill.typeRune(r)
+	}
+	if len(completed) != 1 || parent.endTime.IsZero() || !completed[0].CorrectionOf.Equal(parent.endTime) {
+		t.Errorf("completed %+v, want it attached to the run ending %v", completed, parent.endTime)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import "github.com/gdamore/tcell/v2"
+
+// view is one screen of the app: the welcome screen, the picker, a test,
+// its results and so on. run takes over the screen, handling events in its
+// own loop until the player leaves, and says where to go next.
+type view interface {
+	run(screen tcell.Screen) navigation
+}
+
+// navigation is where the router goes when a view returns. The zero value
+// stays on the same view and runs it again, to redraw after a change.
+type navigation struct {
+	open    view // a view to open on top of this one
+	replace bool // with open, close this view first
+	back    bool // close this view, returning to the one under it
+	quit    bool // close every view
+}
+
+// stay, goBack and quit are the navigations that open nothing
+var (
+	stay   = navigation{}
+	goBack = navigation{back: true}
+	quit   = navigation{quit: true}
+)
+
+// open goes to v, leaving the current view under it to come back to
+func open(v view) navigation {
+	return navigation{open: v}
+}
+
+// replaceWith goes to v in place of the current view, so leaving v goes
+// back to the view under both, as a test's results replace the test
+func replaceWith(v view) navigation {
+	return navigation{open: v, replace: true}
+}
+
+// runViews runs a stack of views starting from root until the last is
+// closed
+func runViews(screen tcell.Screen, root view) {
+	stack := []view{root}
+	for len(stack) > 0 {
+		nav := stack[len(stack)-1].run(screen)
+		switch {
+		case nav.quit:
+			stack = nil
+		case nav.back:
+			stack = stack[:len(stack)-1]
+		case nav.open != nil:
+			if nav.replace {
+				stack = stack[:len(stack)-1]
+			}
+			stack = append(stack, nav.open)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// scriptedView returns its navigations in turn, logging each run
+type scriptedView struct {
+	name string
+	navs []navigation
+	log  *[]string
+}
+
+func (v *scriptedView) run(tcell.Screen) navigation {
+	*v.log = append(*v.log, v.name)
+	nav := v.navs[0]
+	v.navs = v.navs[1:]
+	return nav
+}
+
+func TestRunViews(t *testing.T) {
+	var log []string
+	results := &scriptedView{name: "results", navs: []navigation{goBack}, log: &log}
+	test := &scriptedView{name: "test", navs: []navigation{replaceWith(results)}, log: &log}
+	help := &scriptedView{name: "help", navs: []navigation{goBack}, log: &log}
+	welcome := &scriptedView{name: "welcome", navs: []navigation{stay, open(help), open(test), quit}, log: &log}
+
+	runViews(nil, welcome)
+	want := []string{"welcome", "welcome", "help", "welcome", "test", "results", "welcome"}
+	if len(log) != len(want) {
+		t.Fatalf("ran %v, want %v", log, want)
+	}
+	for i := range want {
+		if log[i] != want[i] {
+			t.Fatalf("ran %v, want %v", log, want)
+		}
+	}
+}
+
+func TestResultsRetry(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(120, 30)
+
+	lib, err := loadLibraryFile(filepath.Join(t.TempDir(), "library.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := newEngine(&fakeClock{}, fixedRand(0), t.TempDir())
+	engine.library = lib
+	a := &app{engine: engine, results: &resultStore{}}
+
+	state := newTestState("hi", "hi.txt", &fakeClock{})
+	state.typeRune('h')
+	state.typeRune('i')
+	screen.InjectKeyBytes([]byte("r"))
+	nav := (&resultsView{app: a, state: &state}).run(screen)
+
+	// Retry plays the same text again from the start
+	test, ok := nav.open.(*testView)
+	if !ok || !nav.replace {
+		t.Fatalf("retry went to %+v, want the test in place of its results", nav)
+	}
+	if test.state != &state || test.state.typed() != 0 || test.state.testComplete {
+		t.Errorf("retry test = %q, %d typed, complete %v; want hi.txt afresh", test.state.testFile, test.state.typed(), test.state.testComplete)
+	}
+}
+
+func TestWelcomeStartsOnFirst(t *testing.T) {
+	a := &app{engine: newEngine(&fakeClock{}, fixedRand(0), t.TempDir())}
+	loaded := false
+	welcome := &welcomeView{app: a, first: func() (TestState, error) {
+		loaded = true
+		return TestState{}, nil
+	}}
+
+	// The test opens over the welcome screen, so it comes back there
+	nav := welcome.run(nil)
+	loading, ok := nav.open.(*loadingView)
+	if !ok || nav.replace {
+		t.Fatalf("first run navigated %+v, want to open the test", nav)
+	}
+	loading.load()
+	if !loaded || welcome.first != nil {
+		t.Errorf("loaded = %v, first still set = %v; want the test loaded once", loaded, welcome.first != nil)
+	}
+}
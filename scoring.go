@@ -0,0 +1,477 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// typeRune appends a typed character to the input, starting the timer on
+// the first keystroke. Input is scored a grapheme cluster at a time, so a
+// rune that joins the previous cluster (a combining accent, a skin tone, a
+// ZWJ-joined emoji) rescores that cluster instead of starting a new one,
+// and a cluster that is still a prefix of the reference's, like the first
+// code point of a multi-part emoji, isn't scored until it's finished or
+// abandoned. A cluster that doesn't match the reference at the same
+// position (or runs past its end) counts as one error while typing; the
+// final count is aligned (see slipCost). It reports whether the test is
+// over: whether the input now matches the reference exactly, which
+// completes it, or the key was a sudden death test's first error.
+//
+// Events are published only after the new snapshot, so subscribers that
+// read Engine.Snapshot see the state the event describes.
+func (s *TestState) typeRune(r rune) (over bool) {
+	var events []Event
+	errors := s.errors
+	defer func() {
+		if s.suddenDeath && s.errors > errors && !s.failed {
+			events = s.fail(s.clock.Now(), events)
+			over = true
+		}
+		s.publishSnapshot()
+		for _, ev := range events {
+			s.events.Publish(ev)
+		}
+	}()
+
+	now := s.clock.Now()
+	if s.afterBackspace() {
+		// Typing again after cutting a wrong stretch back: what's left of
+		// it was counted when the backspacing started
+		s.slipCredit = s.slipCost()
+	}
+	if s.refusesKey(r) {
+		// Stop on error: the cursor stays put until the right key
+		pos := s.typed()
+		if extendsCluster(s.lastTyped(), r) {
+			pos--
+		}
+		var expected string
+		if pos < len(s.reference) {
+			expected = s.reference[pos]
+		}
+		s.errors++
+		s.refusedErrors++
+		s.mistakes = append(s.mistakes, mistake{pos: pos, typed: string(r)})
+		events = append(events, Event{Kind: EventKeystrokeScored, Time: now, TestFile: s.testFile, Rune: r, Expected: expected, Position: pos})
+		return false
+	}
+	if s.submitsWord(r) {
+		// Space submits the word, however much of it was typed
+		if !s.skipRestOfWord() {
+			return false // none of it typed yet, so the space is ignored
+		}
+		if s.typed() == len(s.reference) {
+			s.keys = append(s.keys, keystroke{at: now, r: r})
+			events = append(events, s.complete(now))
+			return true
+		}
+	}
+	if s.overrunsWord(r) {
+		// Refused, so it can't run on into the next word
+		pos := s.typed()
+		s.errors++
+		s.wordErrors++
+		s.mistakes = append(s.mistakes, mistake{pos: pos, typed: string(r)})
+		events = append(events, Event{Kind: EventKeystrokeScored, Time: now, TestFile: s.testFile, Rune: r, Expected: s.reference[pos], Position: pos})
+		return false
+	}
+	s.keys = append(s.keys, keystroke{at: now, r: r})
+	scored := func(correct bool) {
+		last, _ := utf8.DecodeLastRuneInString(s.lastTyped())
+		pos := s.typed() - 1
+		if !correct {
+			s.mistakes = append(s.mistakes, mistake{pos: pos, typed: s.lastTyped()})
+		}
+		var expected string
+		if pos < len(s.reference) {
+			expected = s.reference[pos]
+		}
+		events = append(events, Event{
+			Kind:     EventKeystrokeScored,
+			Time:     now,
+			TestFile: s.testFile,
+			Rune:     last,
+			Expected: expected,
+			Position: pos,
+			Correct:  correct,
+		})
+	}
+
+	if !extendsCluster(s.lastTyped(), r) {
+		if s.lastUnit == unitPending && s.typed() > 0 {
+			// The previous cluster was abandoned part-way
+			s.errors++
+			s.lastUnit = unitWrong
+			scored(false)
+		}
+		if !s.testStarted {
+			s.testStarted = true
+			s.startTime = now
+			events = append(events, Event{Kind: EventTestStarted, Time: now, TestFile: s.testFile})
+		} else if gap := now.Sub(s.lastKeyTime); !s.steno || gap >= stenoBurstGap {
+			// A steno stroke arrives as a burst of keystrokes; only the
+			// gaps between strokes say anything about rhythm
+			s.recordInterval(gap)
+			if !s.steno {
+				s.flow.add(gap, s.averageWPM)
+			}
+		}
+		s.lastKeyTime = now
+		s.unitStarts = append(s.unitStarts, len(s.userInput))
+	}
+	s.userInput += string(r)
+
+	var want string
+	if i := s.typed() - 1; i < len(s.reference) {
+		want = s.reference[i]
+	}
+	switch typed := s.lastTyped(); {
+	case typed == want:
+		s.lastUnit = unitCorrect
+		scored(true)
+	case strings.HasPrefix(want, typed):
+		s.lastUnit = unitPending
+	case s.lastUnit == unitWrong && len(typed) > len(string(r)):
+		// Already counted when the cluster first went wrong
+	default:
+		// Mismatches and extra characters are errors
+		s.errors++
+		s.lastUnit = unitWrong
+		scored(false)
+	}
+
+	// Check if test is complete. Word by word, the last word need only be
+	// right, whatever was left wrong before it; on a typewriter or blind,
+	// the text need only be typed.
+	if len(s.userInput) == len(s.referenceText) && s.userInput == s.referenceText || s.wordByWord && s.typed() == len(s.reference) && s.lastWordRight() || s.typewriterDone() || s.blindDone() {
+		events = append(events, s.complete(now))
+		return true
+	}
+	return false
+}
+
+// complete ends the test at end and returns its EventTestCompleted, with
+// the errors counted by alignment
+func (s *TestState) complete(end time.Time) Event {
+	s.testComplete = true
+	s.endTime = end
+	if !s.afterBackspace() {
+		s.countSlip()
+	}
+	if s.noBackspace {
+		// Counted raw as typed, and none could be corrected; see
+		// typewriter.go
+		s.uncorrected = s.errors
+	} else {
+		s.errors = s.slipErrors + s.wordErrors + s.refusedErrors
+		s.uncorrected = s.slipCost() + len(s.skipped)
+	}
+	wpm := s.wpm(s.elapsed())
+	accuracy := calculateAccuracy(s.errors, s.typed())
+	economy := s.economy()
+	return Event{
+		Kind:        EventTestCompleted,
+		Time:        end,
+		TestFile:    s.testFile,
+		TextHash:    hashText(s.referenceText),
+		WPM:         wpm,
+		Accuracy:    accuracy,
+		Consistency: s.consistency(),
+		Score:       s.score(wpm, accuracy),
+		Errors:      s.errors,
+		Uncorrected: s.uncorrected,
+		NetWPM:      calculateNetWPM(wpm, s.uncorrected, s.elapsed()),
+		Duration:    s.elapsed(),
+		Estimate:    s.estimate(),
+		Flow:        s.flow.total,
+		Keystrokes:  economy.Keystrokes,
+		Backspaces:  economy.Backspaces,
+		Efficiency:  economy.efficiency(),
+		Peeks:       s.peeks,
+
+		CorrectionOf: s.correctionOf,
+	}
+}
+
+// timeLeft is how long a timed test has to run, counting down from
+// timeLimit once it starts
+func (s *TestState) timeLeft() time.Duration {
+	return max(0, s.timeLimit-s.elapsed())
+}
+
+// expire ends a timed test whose time is up, scoring whatever was typed
+// by then, and reports whether it did. The test ends exactly at its
+// limit however late this is called, so the input loop need only call
+// it on every event, before handling any keystroke.
+func (s *TestState) expire() bool {
+	if s.timeLimit <= 0 || !s.testStarted || s.testComplete || s.timeLeft() > 0 {
+		return false
+	}
+	ev := s.complete(s.startTime.Add(s.timeLimit))
+	s.publishSnapshot()
+	s.events.Publish(ev)
+	return true
+}
+
+// typed is how many characters (grapheme clusters) have been typed
+func (s *TestState) typed() int {
+	return len(s.unitStarts)
+}
+
+// lastTyped returns the last typed grapheme cluster, or "" before any
+func (s *TestState) lastTyped() string {
+	if len(s.unitStarts) == 0 {
+		return ""
+	}
+	return s.userInput[s.unitStarts[len(s.unitStarts)-1]:]
+}
+
+// scoreLast compares the last typed cluster with the reference
+func (s *TestState) scoreLast() unitState {
+	i := s.typed() - 1
+	if i >= 0 && i < len(s.reference) && s.lastTyped() != s.reference[i] {
+		return unitWrong
+	}
+	return unitCorrect
+}
+
+// backspace removes the last typed character, a whole grapheme cluster.
+// Errors already counted are kept, so correcting a mistake doesn't erase
+// it from the stats. In typewriter mode it does nothing.
+func (s *TestState) backspace() {
+	if s.noBackspace {
+		return
+	}
+	now := s.clock.Now()
+	if !s.afterBackspace() {
+		s.countSlip()
+	}
+	s.keys = append(s.keys, keystroke{at: now, r: backspaceKey})
+	n := len(s.unitStarts)
+	if n == 0 {
+		s.publishSnapshot()
+		return
+	}
+	ev := Event{
+		Kind:     EventBackspace,
+		Time:     now,
+		TestFile: s.testFile,
+		Rune:     backspaceKey,
+		Position: n - 1,
+		Correct:  s.firstWrong() < n,
+		Deleted:  s.lastTyped(),
+	}
+	if n-1 < len(s.reference) {
+		ev.Expected = s.reference[n-1]
+	}
+	s.userInput = s.userInput[:s.unitStarts[n-1]]
+	s.unitStarts = s.unitStarts[:n-1]
+	if len(s.skipped) > 0 && s.skipped[len(s.skipped)-1] == n-1 {
+		s.skipped = s.skipped[:len(s.skipped)-1]
+	}
+	s.lastUnit = s.scoreLast()
+	s.publishSnapshot()
+	s.events.Publish(ev)
+}
+
+// firstWrong returns the position of the first typed character that
+// doesn't match the reference, or typed() if they all do
+func (s *TestState) firstWrong() int {
+	for i, start := range s.unitStarts {
+		end := len(s.userInput)
+		if i+1 < len(s.unitStarts) {
+			end = s.unitStarts[i+1]
+		}
+		if i >= len(s.reference) || s.userInput[start:end] != s.reference[i] {
+			return i
+		}
+	}
+	return s.typed()
+}
+
+// reset clears all progress so the same text can be typed again
+func (s *TestState) reset() {
+	s.userInput = ""
+	s.unitStarts = nil
+	s.lastUnit = unitCorrect
+	s.keys = nil
+	s.errors = 0
+	s.slipErrors, s.slipCredit, s.uncorrected = 0, 0, 0
+	s.skipped, s.wordErrors = nil, 0
+	s.refusedErrors = 0
+	s.failed, s.failedAt = false, 0
+	s.peeking, s.peeks = false, 0
+	s.mistakes = nil
+	s.startTime = time.Time{}
+	s.endTime = time.Time{}
+	s.testStarted = false
+	s.testComplete = false
+	s.lastKeyTime = time.Time{}
+	s.intervalCount, s.intervalMean, s.intervalM2 = 0, 0, 0
+	s.flow = flowTracker{}
+	s.publishSnapshot()
+}
+
+// recordInterval folds the gap between two keystrokes into the running
+// mean and variance
+func (s *TestState) recordInterval(gap time.Duration) {
+	s.intervalCount++
+	x := gap.Seconds()
+	delta := x - s.intervalMean
+	s.intervalMean += delta / float64(s.intervalCount)
+	s.intervalM2 += delta * (x - s.intervalMean)
+}
+
+// consistency rates how even the typing rhythm was, from 100 (metronomic)
+// towards 0. It maps the coefficient of variation of the keystroke
+// intervals through tanh so the score degrades smoothly and stays bounded.
+func (s *TestState) consistency() float64 {
+	if s.intervalCount < 2 || s.intervalMean <= 0 {
+		return 100
+	}
+	stddev := math.Sqrt(s.intervalM2 / float64(s.intervalCount-1))
+	return 100 * (1 - math.Tanh(stddev/s.intervalMean))
+}
+
+// score rates a completed run with the configured formula, or by WPM
+// alone if there is none
+func (s *TestState) score(wpm, accuracy float64) float64 {
+	if s.scorer == nil {
+		return wpm
+	}
+	return s.scorer.evaluate(map[string]float64{
+		"wpm":         wpm,
+		"accuracy":    accuracy,
+		"consistency": s.consistency(),
+		"length":      float64(len(s.reference)),
+	})
+}
+
+// stenoBurstGap is the longest gap between keystrokes that still belong to
+// one steno stroke. Stroke output arrives a few milliseconds apart at
+// most, while 30ms between real keypresses would be 400 WPM.
+const stenoBurstGap = 30 * time.Millisecond
+
+// wpm is the speed of the input so far over elapsed. It counts standard
+// five-character words, except in steno mode where each stroke commits a
+// whole word at once and speed is measured in words actually written, as
+// stenographers measure it.
+func (s *TestState) wpm(elapsed time.Duration) float64 {
+	if s.steno {
+		return calculateWordsPerMinute(len(strings.Fields(s.userInput)), elapsed)
+	}
+	return calculateWPM(s.typed(), elapsed)
+}
+
+// wpmSamples is the speed of the test over time, as the WPM of each of up
+// to n equal slices of it, for graphing. Every keystroke counts, mistakes
+// included, and slices are at least a second long so a single burst
+// doesn't spike the graph.
+func (s *TestState) wpmSamples(n int) []float64 {
+	elapsed := s.elapsed()
+	n = min(n, int(elapsed/time.Second))
+	if n <= 0 {
+		return nil
+	}
+	slice := elapsed / time.Duration(n)
+	counts := make([]int, n)
+	for _, k := range s.keys {
+		if k.r == backspaceKey {
+			continue
+		}
+		i := int(k.at.Sub(s.startTime) / slice)
+		counts[max(0, min(n-1, i))]++
+	}
+	samples := make([]float64, n)
+	for i, count := range counts {
+		samples[i] = float64(count) / 5 / slice.Minutes()
+	}
+	return samples
+}
+
+// wpmPerSecond is the speed through the test sampled once a second, as
+// wpmSamples for as many slices as the test lasted whole seconds, so each
+// is a second or a little over and together they cover all of it
+func (s *TestState) wpmPerSecond() []float64 {
+	return s.wpmSamples(int(s.elapsed() / time.Second))
+}
+
+// speedDeviation is the standard deviation of samples, such as
+// wpmPerSecond's: how far the speed typically strayed from its average,
+// in WPM, with 0 for perfectly even
+func speedDeviation(samples []float64) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	var mean float64
+	for _, v := range samples {
+		mean += v
+	}
+	mean /= float64(len(samples))
+	var squares float64
+	for _, v := range samples {
+		squares += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(squares / float64(len(samples)))
+}
+
+// calculateWordsPerMinute is calculateWPM for a count of actual words
+func calculateWordsPerMinute(words int, elapsed time.Duration) float64 {
+	if elapsed < time.Second {
+		return 0
+	}
+	return float64(words) / elapsed.Minutes()
+}
+
+// calculateWPM converts a character count into words per minute using the
+// standard five-characters-per-word convention. Durations under a second
+// report 0 rather than a meaningless spike.
+func calculateWPM(chars int, elapsed time.Duration) float64 {
+	if elapsed < time.Second {
+		return 0
+	}
+	return float64(chars/5) / elapsed.Minutes()
+}
+
+// calculateNetWPM takes a word a minute off wpm for each error left
+// uncorrected over elapsed, the usual net speed, never below 0
+func calculateNetWPM(wpm float64, uncorrected int, elapsed time.Duration) float64 {
+	if elapsed < time.Second {
+		return 0
+	}
+	return max(0, wpm-float64(uncorrected)/elapsed.Minutes())
+}
+
+// expectedDuration is how long typing chars characters takes at wpm, or 0
+// if wpm isn't positive
+func expectedDuration(chars int, wpm float64) time.Duration {
+	if wpm <= 0 {
+		return 0
+	}
+	return time.Duration(float64(chars) / (wpm * 5 / 60) * float64(time.Second))
+}
+
+// estimate is how long the test should take at the player's average
+// speed. A timed test takes its time limit, so it has none, and there's
+// none without an average to go on.
+func (s *TestState) estimate() time.Duration {
+	if s.timeLimit > 0 || s.averageWPM <= 0 {
+		return 0
+	}
+	return expectedDuration(len(s.reference), s.averageWPM)
+}
+
+// calculateAccuracy returns the percentage of typed characters that were
+// not errors, clamped to [0, 100].
+func calculateAccuracy(errors, typed int) float64 {
+	if typed == 0 {
+		return 100.0
+	}
+	accuracy := 100.0 * (1.0 - float64(errors)/float64(typed))
+	if accuracy < 0 {
+		return 0
+	}
+	return accuracy
+}
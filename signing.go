@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// signingKeyFile holds the player's recording signing key. It's created
+// on first use and never leaves the machine; only the public half is
+// written into recordings.
+type signingKeyFile struct {
+	PrivateKey ed25519.PrivateKey `json:"private_key"`
+}
+
+// loadSigningKey returns the player's signing key, generating and saving
+// one the first time. It's saved even in a read-only session, so every
+// recording carries the same fingerprint.
+func loadSigningKey() (ed25519.PrivateKey, error) {
+	path, err := dataFile("signing.key")
+	if err != nil {
+		return nil, err
+	}
+	var file signingKeyFile
+	if err := readJSONFile(path, &file); err != nil {
+		return nil, err
+	}
+	if len(file.PrivateKey) == ed25519.PrivateKeySize {
+		return file.PrivateKey, nil
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	// writeJSONFile's temporary file is created 0600, so the key is
+	// readable only by the player
+	if err := writeJSONFile(path, signingKeyFile{PrivateKey: key}); err != nil {
+		return nil, fmt.Errorf("saving signing key: %w", err)
+	}
+	logger.Info("generated recording signing key", "path", path, "fingerprint", keyFingerprint(key.Public().(ed25519.PublicKey)))
+	return key, nil
+}
+
+// keyFingerprint is a short, readable identity for a public key, for
+// leaderboards to tell players apart
+func keyFingerprint(public ed25519.PublicKey) string {
+	sum := sha256.Sum256(public)
+	hex := hex.EncodeToString(sum[:8])
+	return hex[:4] + "-" + hex[4:8] + "-" + hex[8:12] + "-" + hex[12:]
+}
+
+// signedBytes is what a recording's signature covers: the recording as
+// JSON without its signature. Fields added to recording later must be
+// omitempty, so files signed before they existed still verify.
+func (rec recording) signedBytes() ([]byte, error) {
+	rec.Signature = nil
+	return json.Marshal(rec)
+}
+
+// sign adds key's public half and signature to the recording
+func (rec *recording) sign(key ed25519.PrivateKey) error {
+	rec.PublicKey = key.Public().(ed25519.PublicKey)
+	data, err := rec.signedBytes()
+	if err != nil {
+		return err
+	}
+	rec.Signature = ed25519.Sign(key, data)
+	return nil
+}
+
+// verify checks a signed recording's signature. It reports false for an
+// unsigned recording, which isn't an error: signing is optional.
+func (rec recording) verify() (signed bool, err error) {
+	if rec.PublicKey == nil && rec.Signature == nil {
+		return false, nil
+	}
+	if len(rec.PublicKey) != ed25519.PublicKeySize {
+		return true, errors.New("malformed public key")
+	}
+	data, err := rec.signedBytes()
+	if err != nil {
+		return true, err
+	}
+	if !ed25519.Verify(rec.PublicKey, data, rec.Signature) {
+		return true, errors.New("signature doesn't match; the file was changed after it was signed")
+	}
+	return true, nil
+}
+
+// signer describes who signed a recording, for display
+func (rec recording) signer() string {
+	if rec.PublicKey == nil {
+		return "Unsigned"
+	}
+	return "Signed by " + keyFingerprint(rec.PublicKey)
+}
+
+// runVerify checks recordings' and exam reports' integrity and signatures
+// for the verify subcommand, printing one line per file. It returns the
+// process exit code: 1 if any file fails.
+func runVerify(w io.Writer, paths []string) int {
+	status := 0
+	for _, path := range paths {
+		if strings.HasSuffix(path, examExt) {
+			report, err := loadExamReport(path)
+			if err == nil {
+				err = report.verify()
+			}
+			if err != nil {
+				fmt.Fprintf(w, "%s: FAILED: %v\n", path, err)
+				status = 1
+				continue
+			}
+			fmt.Fprintf(w, "%s: OK, %s %s %s, %.1f WPM at %.1f%% (signed by %s)\n", path, report.Candidate, report.Outcome, report.TestFile, report.WPM, report.Accuracy, keyFingerprint(report.PublicKey))
+			continue
+		}
+		rec, err := loadRecording(path)
+		if err == nil {
+			_, err = rec.reference()
+		}
+		if err != nil {
+			fmt.Fprintf(w, "%s: FAILED: %v\n", path, err)
+			status = 1
+			continue
+		}
+		fmt.Fprintf(w, "%s: OK, %s, %.1f WPM (%s)\n", path, rec.TestFile, rec.WPM, rec.signer())
+	}
+	return status
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSigningKeyPersists(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	first, err := loadSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := loadSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.Equal(second) {
+		t.Error("second load generated a new key")
+	}
+
+	path, _ := dataFile("signing.key")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		t.Errorf("signing key permissions = %v, want readable by the owner only", perm)
+	}
+}
+
+func TestSignAndVerifyRecording(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := recording{Version: recordingVersion, TextHash: hashText("hi"), TestFile: "hi.txt", WPM: 80, Keys: "hi", Gaps: []int64{0, 100}}
+
+	if signed, err := rec.verify(); signed || err != nil {
+		t.Errorf("unsigned recording: verify = %v, %v; want false, nil", signed, err)
+	}
+
+	if err := rec.sign(key); err != nil {
+		t.Fatal(err)
+	}
+	if signed, err := rec.verify(); !signed || err != nil {
+		t.Errorf("signed recording: verify = %v, %v; want true, nil", signed, err)
+	}
+	if want := "Signed by " + keyFingerprint(key.Public().(ed25519.PublicKey)); rec.signer() != want {
+		t.Errorf("signer = %q, want %q", rec.signer(), want)
+	}
+
+	// Round-trip through a file, then claim a faster run
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.ksm")
+	data, _ := json.Marshal(rec)
+	if err := os.WriteFile(good, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec.WPM = 180
+	bad := filepath.Join(dir, "bad.ksm")
+	data, _ = json.Marshal(rec)
+	if err := os.WriteFile(bad, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadRecording(bad); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Errorf("tampered recording: got %v, want a signature error", err)
+	}
+
+	var out bytes.Buffer
+	if code := runVerify(&out, []string{good, bad}); code != 1 {
+		t.Errorf("verify exit code = %d, want 1 with a tampered file", code)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "OK") || !strings.Contains(lines[0], "Signed by") || !strings.Contains(lines[1], "FAILED") {
+		t.Errorf("verify output:\n%s", out.String())
+	}
+}
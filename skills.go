@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Skill decay is judged by comparing the last skillRecentDays of typing
+// against the best day in the skillPeakDays before that
+const (
+	skillRecentDays = 7
+	skillPeakDays   = 30
+)
+
+// skillMinSamples is how many keystrokes a day (or the recent window)
+// needs before its speed for a key is trusted
+const skillMinSamples = 10
+
+// skillRegression is how much slower than its peak a key must be to be
+// flagged
+const skillRegression = 0.15
+
+// skillMaxGap drops keystroke gaps longer than this, which are pauses
+// rather than the time a key takes
+const skillMaxGap = 2 * time.Second
+
+// drillFile names drill tests in TestState.testFile
+const drillFile = "drill"
+
+// skillSample sums the time taken to type one key or bigram
+type skillSample struct {
+	Count   int     `json:"n"`
+	TotalMs float64 `json:"ms"`
+}
+
+func (s skillSample) mean() float64 { return s.TotalMs / float64(s.Count) }
+
+func (s *skillSample) add(other skillSample) {
+	s.Count += other.Count
+	s.TotalMs += other.TotalMs
+}
+
+// skillLog keeps per-day timings for every key and bigram typed
+// correctly, so their speed can be followed over months
+type skillLog struct {
+	Days map[string]map[string]skillSample `json:"days"` // YYYY-MM-DD, then key or bigram
+	path string
+}
+
+func loadSkillLog() (*skillLog, error) {
+	path, err := dataFile("skills.json")
+	if err != nil {
+		return nil, err
+	}
+	log := &skillLog{Days: make(map[string]map[string]skillSample), path: path}
+	if err := readJSONFile(path, log); err != nil {
+		return nil, err
+	}
+	if log.Days == nil {
+		log.Days = make(map[string]map[string]skillSample)
+	}
+	return log, nil
+}
+
+func (l *skillLog) save() error {
+	if l.path == "" {
+		return nil
+	}
+	return saveDataFile(l.path, l)
+}
+
+// record folds one test's samples into the day they were typed
+func (l *skillLog) record(date string, samples map[string]skillSample) {
+	day := l.Days[date]
+	if day == nil {
+		day = make(map[string]skillSample)
+		l.Days[date] = day
+	}
+	for key, sample := range samples {
+		total := day[key]
+		total.add(sample)
+		day[key] = total
+	}
+}
+
+// trackSkills times every correct keystroke that directly follows another
+// correct one, for the key and for the bigram the two make. A test's
+// timings are only kept if it's completed.
+func trackSkills(bus *EventBus, log *skillLog) {
+	var pending map[string]skillSample
+	var prev Event
+	bus.Subscribe(func(ev Event) {
+		switch ev.Kind {
+		case EventTestStarted:
+			pending = make(map[string]skillSample)
+			prev = Event{}
+
+		case EventKeystrokeScored:
+			gap := ev.Time.Sub(prev.Time)
+			if ev.Correct && prev.Correct && ev.Position == prev.Position+1 && gap <= skillMaxGap && pending != nil {
+				sample := skillSample{Count: 1, TotalMs: float64(gap.Microseconds()) / 1000}
+				key := pending[string(ev.Rune)]
+				key.add(sample)
+				pending[string(ev.Rune)] = key
+				if !unicode.IsSpace(prev.Rune) && !unicode.IsSpace(ev.Rune) {
+					bigram := string(prev.Rune) + string(ev.Rune)
+					pair := pending[bigram]
+					pair.add(sample)
+					pending[bigram] = pair
+				}
+			}
+			prev = ev
+
+		case EventTestCompleted, EventTestFailed:
+			log.record(ev.Time.Format(dateLayout), pending)
+			pending = nil
+			if err := log.save(); err != nil {
+				logger.Error("saving skill log failed", "err", err)
+			}
+		}
+	}, EventTestStarted, EventKeystrokeScored, EventTestCompleted, EventTestFailed)
+}
+
+// skillDecay is a key or bigram that has slowed down since its peak
+type skillDecay struct {
+	Key    string
+	Slower float64 // fraction slower than peak, e.g. 0.2
+}
+
+// decayed returns the keys and bigrams whose speed over the last
+// skillRecentDays has fallen skillRegression or more behind their best
+// day in the skillPeakDays before, worst first
+func (l *skillLog) decayed(now time.Time) []skillDecay {
+	recent := make(map[string]skillSample)
+	peak := make(map[string]float64) // fastest daily mean
+	for date, day := range l.Days {
+		t, err := time.ParseInLocation(dateLayout, date, now.Location())
+		if err != nil {
+			continue
+		}
+		age := int(now.Sub(t).Hours() / 24)
+		switch {
+		case age < 0:
+		case age < skillRecentDays:
+			for key, sample := range day {
+				total := recent[key]
+				total.add(sample)
+				recent[key] = total
+			}
+		case age < skillRecentDays+skillPeakDays:
+			for key, sample := range day {
+				if sample.Count < skillMinSamples {
+					continue
+				}
+				if best, ok := peak[key]; !ok || sample.mean() < best {
+					peak[key] = sample.mean()
+				}
+			}
+		}
+	}
+
+	var decays []skillDecay
+	for key, sample := range recent {
+		best, ok := peak[key]
+		if !ok || sample.Count < skillMinSamples {
+			continue
+		}
+		if slower := sample.mean()/best - 1; slower >= skillRegression {
+			decays = append(decays, skillDecay{Key: key, Slower: slower})
+		}
+	}
+	sort.Slice(decays, func(i, j int) bool {
+		if decays[i].Slower != decays[j].Slower {
+			return decays[i].Slower > decays[j].Slower
+		}
+		return decays[i].Key < decays[j].Key
+	})
+	return decays
+}
+
+// drillTargetCount is how many decayed keys a drill works on at once
+const drillTargetCount = 3
+
+// drillWords is the length of a drill in words
+const drillWords = 30
+
+// drillText builds a drill on targets from common words containing them,
+// taking each target in turn. A target no common word contains, such as
+// a punctuation key, is practised tacked onto the end of a word.
+func drillText(targets []string, rng Rand) string {
+	words := make([]string, drillWords)
+	for i := range words {
+		words[i] = drillWord(targets[i%len(targets)], rng)
+	}
+	return strings.Join(words, " ")
+}
+
+// drillWord picks a common word containing target, or failing that one
+// with target tacked onto the end
+func drillWord(target string, rng Rand) string {
+	var candidates []string
+	for _, word := range commonWords {
+		if strings.Contains(word, target) {
+			candidates = append(candidates, word)
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[rng.Intn(len(candidates))]
+	}
+	return commonWords[rng.Intn(dailyVocabulary)] + target
+}
+
+// drill returns a test practising the given keys and bigrams
+func (e *Engine) drill(targets []string) TestState {
+	logger.Info("selected drill", "targets", targets)
+	return e.newTest(drillText(targets, e.rng), drillFile)
+}
+
+// skillReminder describes the worst decayed key for the welcome screen,
+// and returns the targets a drill would practise; nil if nothing has
+// decayed
+func skillReminder(decays []skillDecay) (line string, targets []string) {
+	if len(decays) == 0 {
+		return "", nil
+	}
+	for _, d := range decays[:min(drillTargetCount, len(decays))] {
+		targets = append(targets, d.Key)
+	}
+	return fmt.Sprintf("Your %q is %.0f%% slower than at its peak. Press D to drill %s", decays[0].Key, decays[0].Slower*100, strings.Join(targets, " ")), targets
+}
+
+// drawSkillReminder adds a skill reminder line to the welcome screen
+func drawSkillReminder(screen tcell.Screen, line string) {
+	if line == "" {
+		return
+	}
+	width, height := screen.Size()
+	drawCenteredText(screen, width/2, height/2+5, tcell.StyleDefault, line)
+	screen.Show()
+}
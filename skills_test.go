@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrackSkills(t *testing.T) {
+	bus := newEventBus()
+	log := &skillLog{Days: make(map[string]map[string]skillSample)}
+	trackSkills(bus, log)
+
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	// typeKeys types text with a gap before each key, wrong where marked
+	typeKeys := func(text string, gap time.Duration, wrong int) {
+		bus.Publish(Event{Kind: EventTestStarted, Time: now})
+		for i, r := range text {
+			now = now.Add(gap)
+			bus.Publish(Event{Kind: EventKeystrokeScored, Time: now, Rune: r, Position: i, Correct: i != wrong})
+		}
+	}
+
+	typeKeys("the cat", 100*time.Millisecond, 5)
+	bus.Publish(Event{Kind: EventTestCompleted, Time: now})
+
+	day := log.Days["2026-10-16"]
+	// The first key has nothing before it, and the wrong "a" isn't timed
+	// nor is the "t" after it
+	want := map[string]skillSample{
+		"h": {1, 100}, "e": {1, 100}, " ": {1, 100}, "c": {1, 100},
+		"th": {1, 100}, "he": {1, 100},
+	}
+	if len(day) != len(want) {
+		t.Errorf("samples = %v, want %v", day, want)
+	}
+	for key, sample := range want {
+		if day[key] != sample {
+			t.Errorf("%q = %v, want %v", key, day[key], sample)
+		}
+	}
+
+	// An abandoned test isn't recorded
+	typeKeys("the", 100*time.Millisecond, -1)
+	typeKeys("the", 100*time.Millisecond, -1)
+	bus.Publish(Event{Kind: EventTestCompleted, Time: now})
+	if got := log.Days["2026-10-16"]["h"]; got.Count != 2 {
+		t.Errorf("h recorded %d times after an abandoned test, want 2", got.Count)
+	}
+}
+
+func TestSkillDecayed(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	daysAgo := func(n int) string { return now.AddDate(0, 0, -n).Format(dateLayout) }
+	log := &skillLog{Days: map[string]map[string]skillSample{
+		// Peaks
+		daysAgo(20): {"q": {20, 2000}, "e": {20, 2000}, "th": {20, 2000}},
+		daysAgo(15): {"q": {20, 3000}, "x": {5, 100}},
+		// Too old to count as a peak
+		daysAgo(60): {"e": {20, 1000}},
+		// Recent: q is 50% slower, th 20%, e 10%; x's peak day was too small
+		daysAgo(1): {"q": {10, 1500}, "e": {20, 2200}, "th": {10, 1200}, "x": {20, 4000}},
+	}}
+
+	decays := log.decayed(now)
+	if len(decays) != 2 || decays[0].Key != "q" || decays[1].Key != "th" {
+		t.Fatalf("decayed = %v, want q then th", decays)
+	}
+	if diff := decays[0].Slower - 0.5; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("q slower = %v, want 0.5", decays[0].Slower)
+	}
+
+	line, targets := skillReminder(decays)
+	if !strings.Contains(line, `"q" is 50% slower`) {
+		t.Errorf("reminder = %q", line)
+	}
+	if len(targets) != 2 {
+		t.Errorf("targets = %v, want q and th", targets)
+	}
+	if line, targets := skillReminder(nil); line != "" || targets != nil {
+		t.Errorf("reminder with nothing decayed = %q, %v", line, targets)
+	}
+}
+
+func TestDrillText(t *testing.T) {
+	text := drillText([]string{"th", ";"}, fixedRand(3))
+	words := strings.Fields(text)
+	if len(words) != drillWords {
+		t.Fatalf("drill has %d words, want %d", len(words), drillWords)
+	}
+	for i, word := range words {
+		target := []string{"th", ";"}[i%2]
+		if !strings.Contains(word, target) {
+			t.Errorf("word %d %q doesn't practise %q", i, word, target)
+		}
+	}
+}
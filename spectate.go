@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// spectatorInterval is how often race frames are sent to spectators
+const spectatorInterval = 250 * time.Millisecond
+
+// raceFrame is the wire format sent to spectators, one JSON object per line
+type raceFrame struct {
+	File     string        `json:"file"`
+	Elapsed  time.Duration `json:"elapsed"`
+	Started  bool          `json:"started"`
+	Complete bool          `json:"complete"`
+	Racers   []frameRacer  `json:"racers"`
+}
+
+type frameRacer struct {
+	Name     string  `json:"name"`
+	Progress float64 `json:"progress"`
+	WPM      float64 `json:"wpm"`
+}
+
+// newRaceFrame describes a snapshot of the host's test. The host's own row
+// is labelled with their user name, since "You" means something else to a
+// spectator.
+func newRaceFrame(state TestState, hostName string) raceFrame {
+	frame := raceFrame{
+		File:     state.testFile,
+		Elapsed:  state.elapsed(),
+		Started:  state.testStarted,
+		Complete: state.testComplete,
+	}
+	for _, r := range state.racers() {
+		name := r.name
+		if r.you {
+			name = hostName
+		}
+		frame.Racers = append(frame.Racers, frameRacer{Name: name, Progress: r.progress, WPM: r.wpm})
+	}
+	return frame
+}
+
+// serveSpectators accepts spectator connections on addr and streams the
+// engine's live race to each of them until the listener is closed. It only
+// reads Engine.Snapshot, so it never blocks the input loop.
+func serveSpectators(addr string, engine *Engine) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listening for spectators: %w", err)
+	}
+
+	hostName := os.Getenv("USER")
+	if hostName == "" {
+		hostName = "Host"
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					logger.Error("accepting spectator failed", "err", err)
+				}
+				return
+			}
+			logger.Info("spectator joined", "remote", conn.RemoteAddr())
+			go streamRace(conn, engine, hostName)
+		}
+	}()
+	return listener, nil
+}
+
+func streamRace(conn net.Conn, engine *Engine, hostName string) {
+	defer conn.Close()
+	encoder := json.NewEncoder(conn)
+	ticker := time.NewTicker(spectatorInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		state, ok := engine.Snapshot()
+		if !ok {
+			continue
+		}
+		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if err := encoder.Encode(newRaceFrame(state, hostName)); err != nil {
+			logger.Info("spectator left", "remote", conn.RemoteAddr(), "err", err)
+			return
+		}
+	}
+}
+
+// runSpectator connects to a host started with --spectators and shows its
+// race until the user presses Escape or the host goes away. It returns the
+// process exit code.
+func runSpectator(addr string) int {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer conn.Close()
+
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating screen: %v\n", err)
+		return 1
+	}
+	if err := screen.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing screen: %v\n", err)
+		return 1
+	}
+	defer screen.Fini()
+
+	// Frames arrive on a separate goroutine and are handed to the event
+	// loop as interrupts; a nil payload means the host disconnected
+	go func() {
+		decoder := json.NewDecoder(conn)
+		for {
+			var frame raceFrame
+			if err := decoder.Decode(&frame); err != nil {
+				screen.PostEvent(tcell.NewEventInterrupt(nil))
+				return
+			}
+			screen.PostEvent(tcell.NewEventInterrupt(frame))
+		}
+	}()
+
+	var frame *raceFrame
+	disconnected := false
+	for {
+		renderSpectator(screen, addr, frame, disconnected)
+
+		switch ev := screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			if ev.Key() == tcell.KeyEscape {
+				return 0
+			}
+		case *tcell.EventInterrupt:
+			if f, ok := ev.Data().(raceFrame); ok {
+				frame = &f
+			} else {
+				disconnected = true
+			}
+		}
+	}
+}
+
+func renderSpectator(screen tcell.Screen, addr string, frame *raceFrame, disconnected bool) {
+	screen.Clear()
+	width, height := screen.Size()
+
+	drawCenteredText(screen, width/2, 1, tcell.StyleDefault, "KEYSMASH - SPECTATING "+addr)
+
+	switch {
+	case disconnected:
+		drawCenteredText(screen, width/2, height/2, tcell.StyleDefault, "Host disconnected")
+	case frame == nil:
+		drawCenteredText(screen, width/2, height/2, tcell.StyleDefault, "Waiting for the host to pick a test...")
+	default:
+		drawCenteredText(screen, width/2, 3, tcell.StyleDefault, fmt.Sprintf("Source: %s", frame.File))
+
+		status := fmt.Sprintf("Time: %.1fs", frame.Elapsed.Seconds())
+		if !frame.Started {
+			status = "Waiting for the host to start typing"
+		} else if frame.Complete {
+			status = fmt.Sprintf("Finished in %.1fs", frame.Elapsed.Seconds())
+		}
+		drawCenteredText(screen, width/2, 5, tcell.StyleDefault, status)
+
+		racers := make([]racer, len(frame.Racers))
+		for i, r := range frame.Racers {
+			racers[i] = racer{name: r.Name, progress: r.Progress, wpm: r.WPM}
+		}
+		hPadding := min(4, width/10)
+		drawText(screen, 0, 7, tcell.StyleDefault, strings.Repeat("-", width))
+		drawRacePanel(screen, hPadding, 9, width-2*hPadding, racers)
+	}
+
+	drawText(screen, min(4, width/10), height-1, tcell.StyleDefault, "ESC to quit")
+	screen.Show()
+}
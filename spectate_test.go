@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSpectatorReceivesRace(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "race.txt"), []byte("ready set go"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("USER", "host")
+
+	engine := newEngine(systemClock{}, fixedRand(0), dir)
+	engine.bots = []botProfile{{kind: "steady", wpm: 60}}
+	state, err := engine.selectRandomTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.typeRune('r')
+
+	listener, err := serveSpectators("127.0.0.1:0", engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var frame raceFrame
+	if err := json.NewDecoder(conn).Decode(&frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame.File != "race.txt" || !frame.Started {
+		t.Errorf("frame = %+v, want a started race on race.txt", frame)
+	}
+	if len(frame.Racers) != 2 || frame.Racers[0].Name != "host" || frame.Racers[1].Name != "Steady 60" {
+		t.Errorf("racers = %+v, want host and Steady 60", frame.Racers)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// The split layout puts the text in a column on the left and the typing
+// in one on the right, each typed line beside the line of text it copies,
+// instead of the text above the typing. It needs a wide screen to leave
+// the columns a readable width, and isn't used for transliterations,
+// where what's typed isn't the text shown.
+
+// splitMinWidth is the narrowest text area the split layout is used for
+const splitMinWidth = 100
+
+// splitGap is the room between the columns, with a rule down the middle
+const splitGap = 3
+
+// useSplit reports whether the test screen is drawn split at width
+func useSplit(state *TestState, width int, opts renderOptions) bool {
+	return opts.split && width >= splitMinWidth && state.display == "" && !state.hidden()
+}
+
+// splitColumnWidth is how wide each column is when the content is width
+func splitColumnWidth(width int) int {
+	return (width - splitGap) / 2
+}
+
+// drawSplit draws the split layout in the height rows from y, with the
+// left column at x. lines are wrapped to splitColumnWidth from text, which
+// starts at byte offset textStart in the reference, and offsets are where
+// each starts in text. Typing is lined up with the text a character at a
+// time, so each typed line has as many characters as the line beside it,
+// and the lines shown scroll to keep the one being typed in view.
+func drawSplit(screen tcell.Screen, state *TestState, x, y, width, height int, text string, textStart int, lines []string, offsets []int, opts renderOptions) {
+	column := splitColumnWidth(width)
+	right := x + column + splitGap
+	drawText(screen, x, y, tcell.StyleDefault, "Text to type:")
+	drawText(screen, right, y, tcell.StyleDefault, "Your typing:")
+	for row := y; row < y+height; row++ {
+		screen.SetContent(x+column+splitGap/2, row, '│', nil, tcell.StyleDefault)
+	}
+	y, height = y+2, height-2
+	if len(lines) == 0 || height <= 0 {
+		drawCursor(screen, right, y, state.clock.Now(), opts)
+		return
+	}
+
+	// The characters of the text each line starts at, and the line being
+	// typed: the one holding the next character, or the last. Whitespace
+	// wrapping dropped between lines is typed at the end of the line
+	// before.
+	starts := make([]int, len(lines)+1)
+	for i, off := range offsets {
+		off += len(text[off:]) - len(strings.TrimLeftFunc(text[off:], unicode.IsSpace))
+		starts[i] = clusterIndex(state.refStarts, textStart+off)
+	}
+	starts[len(lines)] = clusterIndex(state.refStarts, textStart+len(text))
+	current := len(lines) - 1
+	for current > 0 && starts[current] > state.typed() {
+		current--
+	}
+
+	first := max(0, min(current-height/2, len(lines)-height))
+	for i := first; i < len(lines) && i < first+height; i++ {
+		row := y + i - first
+		drawWrappedLine(screen, x, row, lines[i], text, offsets[i], func(offset int) tcell.Style {
+			return state.referenceStyle(clusterIndex(state.refStarts, textStart+offset))
+		})
+
+		// The typing for this line, and on the last anything typed past
+		// the end of the text
+		end := starts[i+1]
+		if i == len(lines)-1 {
+			end = state.typed()
+		}
+		cx := right
+		for j := starts[i]; j < min(end, state.typed()); j++ {
+			cluster := state.typedCluster(j)
+			w := max(1, runewidth.StringWidth(cluster))
+			if cx+w > right+column {
+				break
+			}
+			if isSpace(cluster) {
+				cluster = " " // tabs and newlines too
+			}
+			runes := []rune(cluster)
+			screen.SetContent(cx, row, runes[0], runes[1:], state.inputStyle(j))
+			cx += w
+		}
+		if i == current && cx < right+column {
+			drawCursor(screen, cx, row, state.clock.Now(), opts)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestSplitLayout(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(120, 30)
+
+	text := benchText(900)
+	state := newTestState(text, "test.txt", &fakeClock{})
+	for _, r := range text[:300] {
+		state.typeRune(r)
+	}
+	state.typeRune('#')
+	renderScreen(screen, &state, 120, renderOptions{split: true})
+
+	// Each typed line sits beside the line of text it copies
+	column := splitColumnWidth(120 - 8)
+	right := 4 + column + splitGap
+	typedRows := 0
+	for y := 0; y < 30; y++ {
+		if cellText(screen, 4+column+1, y, 1) != "│" {
+			continue // not in the columns
+		}
+		typed := strings.TrimRight(cellText(screen, right, y, column), " _")
+		if typed == "" || strings.HasPrefix(typed, "Your typing") {
+			continue
+		}
+		typedRows++
+		shown := strings.TrimRight(cellText(screen, 4, y, column), " ")
+		if strings.HasSuffix(typed, "#") {
+			if !strings.HasPrefix(shown, typed[:len(typed)-1]) {
+				t.Errorf("row %d: typing %q isn't beside its text %q", y, typed, shown)
+			}
+			if _, _, style, _ := screen.GetContent(right+len(typed)-1, y); style != styleIncorrect {
+				t.Errorf("the mistake is styled %v", style)
+			}
+			continue
+		}
+		if typed != shown {
+			t.Errorf("row %d: typing %q isn't beside its text %q", y, typed, shown)
+		}
+	}
+	if typedRows < 5 {
+		t.Errorf("only %d rows of typing shown", typedRows)
+	}
+
+	// Too narrow for two columns, it's stacked as usual
+	screen.SetSize(90, 30)
+	renderScreen(screen, &state, 90, renderOptions{split: true})
+	for y := 0; y < 30; y++ {
+		if row := cellText(screen, 0, y, 90); strings.Contains(row, "Text to type") && strings.Contains(row, "Your typing") {
+			t.Fatalf("split at width 90: %q", row)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// dataFile returns the path of name inside the data directory, creating
+// the directory if needed
+func dataFile(name string) (string, error) {
+	dir := dataDir()
+	if dir == "" {
+		return "", errors.New("cannot locate data directory: no home directory")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("creating data directory: %w", err)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// readJSONFile decodes path into v. A missing file is not an error and
+// leaves v untouched, so callers can pre-fill defaults.
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		logger.Debug("storage read: file absent", "path", path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	logger.Debug("storage read", "path", path, "bytes", len(data))
+	return nil
+}
+
+// saveDataFile saves one of the data stores, such as results.json, with
+// writeJSONFile. While another keysmash has the data (see readOnly) it
+// writes nothing, so this session's changes stay in memory.
+func saveDataFile(path string, v interface{}) error {
+	if readOnly {
+		logger.Debug("storage write skipped: read-only", "path", path)
+		return nil
+	}
+	return writeJSONFile(path, v)
+}
+
+// writeJSONFile encodes v to path atomically: it writes a temporary file
+// next to it and renames it into place, so a crash mid-write can never
+// leave a truncated file behind.
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	logger.Debug("storage write", "path", path, "bytes", len(data))
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import "strings"
+
+// In stop-on-error mode the cursor won't move past a mistake: a key that
+// doesn't match the text is refused and counted as an error, and the
+// player has to type the right one (or backspace over a cluster begun
+// wrong) to go on. What's typed is always right, so there's nothing to
+// correct and nothing left uncorrected; the errors are the keys refused.
+
+// refusesKey reports whether stop-on-error mode refuses r: whether the
+// cluster it would leave at the cursor isn't the reference's, or the start
+// of it
+func (s *TestState) refusesKey(r rune) bool {
+	if !s.stopOnError {
+		return false
+	}
+	pos, typed := s.typed(), string(r)
+	if extendsCluster(s.lastTyped(), r) {
+		pos, typed = pos-1, s.lastTyped()+typed
+	} else if s.lastUnit == unitPending && pos > 0 {
+		return true // it would leave the cluster before it part-way
+	}
+	return pos >= len(s.reference) || !strings.HasPrefix(s.reference[pos], typed)
+}
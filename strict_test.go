@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestStopOnError(t *testing.T) {
+	state := newTestState("the fox", "test.txt", &fakeClock{})
+	state.stopOnError = true
+
+	typeKeys(&state, "thw")
+	if state.userInput != "th" || state.errors != 1 {
+		t.Fatalf("input %q with %d errors after a wrong key, want %q and 1", state.userInput, state.errors, "th")
+	}
+	if len(state.mistakes) != 1 || state.mistakes[0] != (mistake{pos: 2, typed: "w"}) {
+		t.Errorf("mistakes = %v, want w at 2", state.mistakes)
+	}
+	typeKeys(&state, "e  fox")
+	if !state.testComplete {
+		t.Fatalf("typing on with the right keys didn't complete the test: %q", state.userInput)
+	}
+	// The w and the second space; nothing was left to correct
+	if state.errors != 2 || state.uncorrected != 0 {
+		t.Errorf("%d errors, %d uncorrected; want 2 and 0", state.errors, state.uncorrected)
+	}
+}
+
+func TestStopOnErrorWaitsOnCluster(t *testing.T) {
+	state := newTestState("e\u0301x", "test.txt", &fakeClock{})
+	state.stopOnError = true
+
+	// e begins the accented cluster, but x would leave it part-way
+	typeKeys(&state, "ex")
+	if state.userInput != "e" || state.errors != 1 {
+		t.Fatalf("input %q with %d errors, want %q and 1", state.userInput, state.errors, "e")
+	}
+	typeKeys(&state, "\u0301x")
+	if !state.testComplete || state.errors != 1 {
+		t.Errorf("complete %v with %d errors, want complete with 1", state.testComplete, state.errors)
+	}
+}
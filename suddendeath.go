@@ -0,0 +1,38 @@
+package main
+
+import "time"
+
+// In sudden death the first error ends the test. The run is published as
+// EventTestFailed rather than EventTestCompleted, so it's saved, marked
+// failed with where it went wrong, but doesn't count towards bests,
+// averages, lessons, packs or the daily challenge: its speed is over
+// however little was typed before the slip.
+
+// firstError is the cluster the first error was made at: the first
+// mistake, or the first cluster skipped word by word, whichever came first
+func (s *TestState) firstError() int {
+	pos := len(s.reference)
+	if len(s.mistakes) > 0 {
+		pos = s.mistakes[0].pos
+	}
+	if len(s.skipped) > 0 {
+		pos = min(pos, s.skipped[0])
+	}
+	return pos
+}
+
+// fail ends a sudden death test on its first error and returns events with
+// its EventTestFailed. If the error came with the last key, the test's
+// EventTestCompleted among events is turned into that instead.
+func (s *TestState) fail(now time.Time, events []Event) []Event {
+	s.failed, s.failedAt = true, s.firstError()
+	for i := range events {
+		if events[i].Kind == EventTestCompleted {
+			events[i].Kind, events[i].Position = EventTestFailed, s.failedAt
+			return events
+		}
+	}
+	ev := s.complete(now)
+	ev.Kind, ev.Position = EventTestFailed, s.failedAt
+	return append(events, ev)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSuddenDeath(t *testing.T) {
+	state := newTestState("the fox", "test.txt", &fakeClock{})
+	state.suddenDeath = true
+	state.events = newEventBus()
+	var ended []Event
+	state.events.Subscribe(func(ev Event) { ended = append(ended, ev) }, EventTestCompleted, EventTestFailed)
+	store := &resultStore{}
+	recordResults(state.events, store, func() runEnvironment { return runEnvironment{} }, nil)
+
+	for _, r := range "th" {
+		if state.typeRune(r) {
+			t.Fatalf("%q ended the test", r)
+		}
+	}
+	if !state.typeRune('x') {
+		t.Fatal("the first error didn't end the test")
+	}
+	if !state.testComplete || !state.failed || state.failedAt != 2 {
+		t.Errorf("complete %v, failed %v at %d; want failed at 2", state.testComplete, state.failed, state.failedAt)
+	}
+	if len(ended) != 1 || ended[0].Kind != EventTestFailed || ended[0].Position != 2 {
+		t.Fatalf("events = %+v, want one EventTestFailed at 2", ended)
+	}
+
+	// Saved, but not counted
+	if len(store.Results) != 1 || !store.Results[0].Failed || store.Results[0].FailedAt != 2 {
+		t.Errorf("results = %+v, want a run failed at 2", store.Results)
+	}
+	if counted := store.counted(); len(counted) != 0 {
+		t.Errorf("counted %d failed runs", len(counted))
+	}
+
+	state.reset()
+	if state.failed {
+		t.Error("reset left the test failed")
+	}
+}
+
+func TestSuddenDeathOnLastKey(t *testing.T) {
+	// Submitting the last word early both ends the text and makes the error
+	clock := &fakeClock{}
+	state := newWordByWordState("the fox")
+	state.clock = clock
+	state.suddenDeath = true
+	state.events = newEventBus()
+	var ended []Event
+	state.events.Subscribe(func(ev Event) { ended = append(ended, ev) }, EventTestCompleted, EventTestFailed)
+
+	for _, r := range "the fo " {
+		clock.advance(100 * time.Millisecond)
+		state.typeRune(r)
+	}
+	if len(ended) != 1 || ended[0].Kind != EventTestFailed || ended[0].Position != 6 {
+		t.Errorf("events = %+v, want just an EventTestFailed at 6", ended)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// key identifies a run by content: the text typed and the moment typing
+// started. Two copies of the same run, however they reached this store,
+// share a key; two runs can't, since one person can't start two tests at
+// the same instant. Profiles will need adding to the key once they exist.
+func (r result) key() string {
+	text := r.TextHash
+	if text == "" {
+		text = "file:" + r.TestFile
+	}
+	started := r.Started
+	if started.IsZero() {
+		// Results saved before start times were recorded
+		started = r.Completed.Add(-r.Duration)
+	}
+	sum := sha256.Sum256([]byte(text + "\x00" + started.UTC().Format(time.RFC3339Nano)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// mergeResult combines two copies of the same run, preferring a and taking
+// anything a lacks from b, so a copy made by an older version doesn't
+// erase details a newer one recorded
+func mergeResult(a, b result) result {
+	if a.TextHash == "" {
+		a.TextHash = b.TextHash
+	}
+	if a.Started.IsZero() {
+		a.Started = b.Started
+	}
+	if a.Consistency == 0 {
+		a.Consistency = b.Consistency
+	}
+	if a.NetWPM == 0 {
+		a.Uncorrected, a.NetWPM = b.Uncorrected, b.NetWPM
+	}
+	if a.Estimate == 0 {
+		a.Estimate = b.Estimate
+	}
+	if a.Flow == 0 {
+		a.Flow = b.Flow
+	}
+	if a.Keystrokes == 0 {
+		a.Keystrokes, a.Backspaces, a.Efficiency = b.Keystrokes, b.Backspaces, b.Efficiency
+	}
+	if a.Peeks == 0 {
+		a.Peeks = b.Peeks
+	}
+	if a.Comfort == 0 {
+		a.Comfort = b.Comfort
+	}
+	if a.Environment == (runEnvironment{}) {
+		a.Environment = b.Environment
+	}
+	if a.Metrics == nil {
+		a.Metrics = b.Metrics
+	}
+	if a.CorrectionOf.IsZero() {
+		a.CorrectionOf = b.CorrectionOf
+	}
+	if a.Tags == nil {
+		a.Tags = b.Tags
+	}
+	a.Excluded = a.Excluded || b.Excluded
+	return a
+}
+
+// merge folds incoming results into the store, skipping runs it already
+// holds. It returns how many results were new and how many were
+// duplicates. Merging the same results twice changes nothing.
+func (s *resultStore) merge(incoming []result) (added, duplicates int) {
+	runs, duplicates := s.mergeRuns(incoming)
+	return len(runs), duplicates
+}
+
+// mergeRuns is merge, returning the runs that were new
+func (s *resultStore) mergeRuns(incoming []result) (added []result, duplicates int) {
+	index := make(map[string]int, len(s.Results))
+	for i, r := range s.Results {
+		index[r.key()] = i
+	}
+	for _, r := range incoming {
+		key := r.key()
+		if i, seen := index[key]; seen {
+			s.Results[i] = mergeResult(s.Results[i], r)
+			duplicates++
+			continue
+		}
+		index[key] = len(s.Results)
+		s.Results = append(s.Results, r)
+		added = append(added, r)
+	}
+	sort.SliceStable(s.Results, func(i, j int) bool {
+		return s.Results[i].Completed.Before(s.Results[j].Completed)
+	})
+	return added, duplicates
+}
+
+// keyLogBeside is where the keystroke log that goes with the results
+// file at path is kept: keystrokes.jsonl beside a results.json, as in a
+// data directory, or the file's name with .keystrokes.jsonl for its
+// extension
+func keyLogBeside(path string) string {
+	if filepath.Base(path) == "results.json" {
+		return filepath.Join(filepath.Dir(path), "keystrokes.jsonl")
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".keystrokes.jsonl"
+}
+
+// key identifies a keystroke: when it was typed, in which text and where.
+// Copies of the same keystroke share it.
+func (e keyLogEntry) key() string {
+	return e.Time.UTC().Format(time.RFC3339Nano) + "\x00" + e.TestFile + "\x00" + strconv.Itoa(e.Position)
+}
+
+// mergeKeyLog appends to the keystroke log at path the entries of
+// incoming it doesn't already hold, oldest first, and returns how many
+// that was. Merging the same entries twice changes nothing.
+func mergeKeyLog(path string, incoming []keyLogEntry) (int, error) {
+	entries, err := readKeyLog(path)
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		seen[e.key()] = true
+	}
+	log := &keyLog{path: path, tally: newKeyTally()}
+	for _, e := range incoming {
+		if !seen[e.key()] {
+			seen[e.key()] = true
+			log.pending = append(log.pending, e)
+		}
+	}
+	sort.SliceStable(log.pending, func(i, j int) bool {
+		return log.pending[i].Time.Before(log.pending[j].Time)
+	})
+	return len(log.pending), log.flush()
+}
+
+// runImport merges results files (such as results.json from another
+// machine) into the local store, with the keystroke logs beside them, and
+// returns the process exit code
+func runImport(w io.Writer, paths []string) int {
+	local, err := loadResults()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	localKeys, err := keyLogPath()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	for _, path := range paths {
+		other, err := loadResultStore(path)
+		if err != nil {
+			fmt.Fprintf(w, "Error: %v\n", err)
+			return 1
+		}
+		added, duplicates := local.mergeRuns(other.Results)
+		local.audit("import", path, "", added)
+		fmt.Fprintf(w, "%s: %d new, %d already present\n", path, len(added), duplicates)
+		logger.Info("imported results", "path", path, "added", len(added), "duplicates", duplicates)
+
+		keys, err := readKeyLog(keyLogBeside(path))
+		if err != nil {
+			fmt.Fprintf(w, "Error: %v\n", err)
+			return 1
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		merged, err := mergeKeyLog(localKeys, keys)
+		if err != nil {
+			fmt.Fprintf(w, "Error saving keystrokes: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(w, "%s: %d new keystrokes\n", keyLogBeside(path), merged)
+		logger.Info("imported keystrokes", "path", keyLogBeside(path), "added", merged)
+	}
+	if err := local.save(); err != nil {
+		fmt.Fprintf(w, "Error saving results: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runSync merges the local store and the results file at path in both
+// directions, so a file in a shared folder can keep several machines'
+// histories in step. The keystroke logs are merged the same way, the
+// shared one beside the results file. It returns the process exit code.
+func runSync(w io.Writer, path string) int {
+	local, err := loadResults()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	remote, err := loadResultStore(path)
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+
+	pulled, _ := local.mergeRuns(remote.Results)
+	local.audit("sync", path, "", pulled)
+	pushed, _ := remote.merge(local.Results)
+	for _, store := range []*resultStore{local, remote} {
+		if err := store.save(); err != nil {
+			fmt.Fprintf(w, "Error saving %s: %v\n", store.path, err)
+			return 1
+		}
+	}
+	fmt.Fprintf(w, "Synced with %s: %d pulled, %d pushed\n", path, len(pulled), pushed)
+	logger.Info("synced results", "path", path, "pulled", len(pulled), "pushed", pushed)
+
+	localKeys, err := keyLogPath()
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return 1
+	}
+	remoteKeys := keyLogBeside(path)
+	var logs [2][]keyLogEntry
+	for i, keys := range []string{localKeys, remoteKeys} {
+		if logs[i], err = readKeyLog(keys); err != nil {
+			fmt.Fprintf(w, "Error: %v\n", err)
+			return 1
+		}
+	}
+	keysPulled, err := mergeKeyLog(localKeys, logs[1])
+	if err != nil {
+		fmt.Fprintf(w, "Error saving %s: %v\n", localKeys, err)
+		return 1
+	}
+	keysPushed, err := mergeKeyLog(remoteKeys, logs[0])
+	if err != nil {
+		fmt.Fprintf(w, "Error saving %s: %v\n", remoteKeys, err)
+		return 1
+	}
+	if keysPulled+keysPushed > 0 {
+		fmt.Fprintf(w, "Keystrokes synced with %s: %d pulled, %d pushed\n", remoteKeys, keysPulled, keysPushed)
+		logger.Info("synced keystrokes", "path", remoteKeys, "pulled", keysPulled, "pushed", keysPushed)
+	}
+	return 0
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResultStoreMerge(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	run := func(file, text string, offset time.Duration) result {
+		return result{
+			TestFile:  file,
+			TextHash:  hashText(text),
+			Started:   start.Add(offset),
+			Completed: start.Add(offset + time.Minute),
+			Duration:  time.Minute,
+		}
+	}
+
+	a := run("a.txt", "alpha", 0)
+	b := run("b.txt", "beta", time.Hour)
+	store := &resultStore{Results: []result{b}}
+
+	// The same run under a renamed file is still the same run; an older
+	// copy without an environment picks it up from the newer one
+	renamed := b
+	renamed.TestFile = "renamed.txt"
+	renamed.Environment = runEnvironment{Version: "v1.0.0"}
+
+	added, duplicates := store.merge([]result{a, renamed})
+	if added != 1 || duplicates != 1 {
+		t.Fatalf("merge = %d added, %d duplicates; want 1, 1", added, duplicates)
+	}
+	if len(store.Results) != 2 || store.Results[0].key() != a.key() {
+		t.Fatalf("results not ordered oldest first: %+v", store.Results)
+	}
+	if got := store.Results[1]; got.TestFile != "b.txt" || got.Environment.Version != "v1.0.0" {
+		t.Errorf("merged duplicate = %+v, want b.txt with the incoming environment", got)
+	}
+
+	// Importing again changes nothing
+	if added, _ := store.merge([]result{a, b, renamed}); added != 0 || len(store.Results) != 2 {
+		t.Errorf("repeated merge added %d results, store has %d", added, len(store.Results))
+	}
+
+	// Details an older copy lacks come from the newer one, and what the
+	// store's copy has is kept
+	detailed := a
+	detailed.Uncorrected, detailed.NetWPM = 2, 38
+	detailed.Keystrokes, detailed.Backspaces, detailed.Efficiency = 320, 12, 93.75
+	detailed.Peeks = 1
+	detailed.Comfort = 4
+	detailed.Metrics = map[string]float64{"heart_rate": 62}
+	store.Results[0].Comfort = 2
+	store.merge([]result{detailed})
+	got := store.Results[0]
+	if got.Uncorrected != 2 || got.NetWPM != 38 || got.Keystrokes != 320 || got.Backspaces != 12 || got.Efficiency != 93.75 {
+		t.Errorf("merged errors and economy = %+v, want the incoming copy's", got)
+	}
+	if got.Peeks != 1 || got.Metrics["heart_rate"] != 62 || got.Comfort != 2 {
+		t.Errorf("merged peeks %d, metrics %v, comfort %d; want 1, the incoming metrics and the store's 2", got.Peeks, got.Metrics, got.Comfort)
+	}
+
+	// Results saved without a start time key the same as ones with it
+	legacy := a
+	legacy.Started = time.Time{}
+	if legacy.key() != a.key() {
+		t.Error("legacy result without start time has a different key")
+	}
+}
+
+func TestRunSync(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	shared := filepath.Join(t.TempDir(), "shared.json")
+
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	local, err := loadResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	local.add(result{TextHash: hashText("local"), Started: start, Completed: start.Add(time.Minute)})
+	if err := local.save(); err != nil {
+		t.Fatal(err)
+	}
+	remote := &resultStore{path: shared}
+	remote.add(result{TextHash: hashText("remote"), Started: start.Add(time.Hour), Completed: start.Add(time.Hour + time.Minute)})
+	if err := remote.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each side has its own keystrokes and one they share
+	localKeys, err := keyLogPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remoteKeys := keyLogBeside(shared)
+	if remoteKeys != filepath.Join(filepath.Dir(shared), "shared.keystrokes.jsonl") {
+		t.Errorf("shared keystroke log at %s", remoteKeys)
+	}
+	both := keyLogEntry{Time: start.Add(time.Second), TestFile: "a.txt", Position: 0, Typed: "a", Expected: "a", Correct: true}
+	for path, entries := range map[string][]keyLogEntry{
+		localKeys:  {both, {Time: start.Add(2 * time.Second), TestFile: "a.txt", Position: 1, Typed: "b", Expected: "b", Correct: true}},
+		remoteKeys: {both, {Time: start.Add(time.Hour), TestFile: "b.txt", Position: 0, Typed: "x", Expected: "y"}},
+	} {
+		if _, err := mergeKeyLog(path, entries); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Syncing twice must not double anything
+	for i := 0; i < 2; i++ {
+		if code := runSync(io.Discard, shared); code != 0 {
+			t.Fatalf("runSync exit code %d", code)
+		}
+	}
+	for _, load := range []func() (*resultStore, error){loadResults, func() (*resultStore, error) { return loadResultStore(shared) }} {
+		store, err := load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(store.Results) != 2 {
+			t.Errorf("%s has %d results after sync, want 2", store.path, len(store.Results))
+		}
+	}
+	for _, path := range []string{localKeys, remoteKeys} {
+		entries, err := readKeyLog(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 3 {
+			t.Errorf("%s has %d keystrokes after sync, want 3", path, len(entries))
+		}
+	}
+}
+
+func TestKeyLogBeside(t *testing.T) {
+	for path, want := range map[string]string{
+		"/data/keysmash/results.json": "/data/keysmash/keystrokes.jsonl",
+		"/sync/keysmash.json":         "/sync/keysmash.keystrokes.jsonl",
+		"/sync/shared":                "/sync/shared.keystrokes.jsonl",
+	} {
+		if got := keyLogBeside(path); got != filepath.FromSlash(want) {
+			t.Errorf("keyLogBeside(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
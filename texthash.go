@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sort"
+)
+
+// Results are tied to their text by a hash of its content (result.TextHash)
+// as well as by file name, so renaming or moving a file doesn't cut it
+// off from its history. Per-text stats match results by hash where they
+// have one, and show them under the text's current name.
+
+// textIndex knows the content hash of each text in the tests directory
+type textIndex struct {
+	hashes map[string]string // by file name
+	names  map[string]string // file name by hash
+}
+
+// indexTexts hashes every text in the tests directory as loadTest would
+// prepare it, so the hashes match those of new results. Where two files
+// hold the same text the first by name is its name; files that can't be
+// read are left out.
+func (e *Engine) indexTexts() (textIndex, error) {
+	files, err := listTexts(e.texts())
+	if err != nil {
+		return textIndex{}, err
+	}
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = file.Name()
+	}
+	sort.Strings(names)
+
+	ix := textIndex{hashes: make(map[string]string, len(names)), names: make(map[string]string, len(names))}
+	for _, name := range names {
+		reference, _, err := e.readText(name)
+		if err != nil {
+			logger.Warn("can't index text", "file", name, "err", err)
+			continue
+		}
+		hash := hashText(reference)
+		ix.hashes[name] = hash
+		if _, ok := ix.names[hash]; !ok {
+			ix.names[hash] = name
+		}
+	}
+	return ix, nil
+}
+
+// name is the current file name of the text r was typed on: the file
+// holding its text now, or the name it was typed under if none does
+func (ix textIndex) name(r result) string {
+	if name, ok := ix.names[r.TextHash]; ok && r.TextHash != "" {
+		return name
+	}
+	return r.TestFile
+}
+
+// sameText reports whether r was typed on the text named file with the
+// given hash: by hash if both are known, otherwise by name
+func sameText(r result, file, hash string) bool {
+	if hash != "" && r.TextHash != "" {
+		return r.TextHash == hash
+	}
+	return r.TestFile == file
+}
+
+// backfillHashes sets the hash of results from before hashes were
+// recorded, for texts that are still in the tests directory under the
+// same name, and returns how many it set
+func (s *resultStore) backfillHashes(ix textIndex) int {
+	var filled []result
+	for i, r := range s.Results {
+		if r.TextHash != "" {
+			continue
+		}
+		if hash, ok := ix.hashes[r.TestFile]; ok {
+			s.Results[i].TextHash = hash
+			filled = append(filled, s.Results[i])
+		}
+	}
+	s.audit("hash", "", "", filled)
+	return len(filled)
+}
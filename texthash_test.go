@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTextHashesFollowRenames(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"fox.txt": "The quick brown fox.\n", "copy.txt": "The quick brown fox.", "dog.txt": "Lazy dog."} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	engine := newEngine(&fakeClock{}, fixedRand(0), dir)
+	ix, err := engine.indexTexts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fox := hashText("The quick brown fox.")
+	if ix.hashes["fox.txt"] != fox || ix.names[fox] != "copy.txt" {
+		t.Fatalf("index = %+v", ix)
+	}
+
+	// An old result for a file since renamed, one from before hashes
+	// were kept, and one for a file that's gone
+	day := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	store := &resultStore{Results: []result{
+		{TestFile: "old-fox.txt", TextHash: fox, WPM: 80, Completed: day},
+		{TestFile: "fox.txt", WPM: 60, Completed: day.Add(time.Hour)},
+		{TestFile: "gone.txt", WPM: 50, Completed: day},
+	}}
+	if filled := store.backfillHashes(ix); filled != 1 || store.Results[1].TextHash != fox || store.Results[2].TextHash != "" {
+		t.Errorf("backfilled %d: %+v", filled, store.Results)
+	}
+
+	if best, _, runs := store.fileStats("fox.txt", fox, averageWindow); best != 80 || runs != 2 {
+		t.Errorf("fileStats = best %v over %d runs, want 80 over 2", best, runs)
+	}
+	stats := store.textStats(ix)
+	if s := stats["copy.txt"]; s.Attempts != 2 || s.BestWPM != 80 {
+		t.Errorf("stats under the current name = %+v", s)
+	}
+	if s := stats["gone.txt"]; s.Attempts != 1 {
+		t.Errorf("stats for a file that's gone = %+v", s)
+	}
+}
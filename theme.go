@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// theme is a set of styles for the feedback while typing; see feedback.go
+type theme struct {
+	correct, incorrect, missed, untyped tcell.Style
+}
+
+// themes are the themes config.toml's theme can name
+var themes = map[string]theme{
+	"default": {
+		correct:   tcell.StyleDefault.Foreground(tcell.ColorGreen),
+		incorrect: tcell.StyleDefault.Foreground(tcell.ColorRed),
+		missed:    tcell.StyleDefault.Foreground(tcell.ColorRed).Reverse(true),
+		untyped:   tcell.StyleDefault.Dim(true),
+	},
+	// Bold, saturated colours, with mistakes on a red background
+	"high-contrast": {
+		correct:   tcell.StyleDefault.Foreground(tcell.ColorLime).Bold(true),
+		incorrect: tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorRed).Bold(true),
+		missed:    tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorRed).Bold(true),
+		untyped:   tcell.StyleDefault,
+	},
+	// No colour at all, for monochrome terminals and colour blindness
+	"mono": {
+		correct:   tcell.StyleDefault,
+		incorrect: tcell.StyleDefault.Underline(true).Bold(true),
+		missed:    tcell.StyleDefault.Reverse(true),
+		untyped:   tcell.StyleDefault.Dim(true),
+	},
+}
+
+// themeNames lists themes for messages
+func themeNames() string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// applyTheme switches the feedback styles to the named theme
+func applyTheme(name string) error {
+	t, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q (want %s)", name, themeNames())
+	}
+	styleCorrect, styleIncorrect, styleMissed, styleUntyped = t.correct, t.incorrect, t.missed, t.untyped
+	return nil
+}
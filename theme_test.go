@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+func TestApplyTheme(t *testing.T) {
+	defer applyTheme("default")
+	if err := applyTheme("mono"); err != nil {
+		t.Fatal(err)
+	}
+	if styleCorrect != themes["mono"].correct || styleMissed != themes["mono"].missed {
+		t.Error("mono theme not applied")
+	}
+	if err := applyTheme("neon"); err == nil {
+		t.Error("applyTheme accepted an unknown theme")
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// toasts is a queue of short notices, such as a new personal best, shown
+// one at a time for a while each in the top right corner of the screen.
+// They take no input and don't stop whatever screen is up, so they can
+// arrive mid-test. Notices may be pushed from any goroutine.
+type toasts struct {
+	clock    Clock
+	duration time.Duration // how long each is shown; 0 shows none
+
+	mu      sync.Mutex
+	queue   []string
+	showing bool      // whether the first in queue has been shown
+	since   time.Time // and if so, since when
+}
+
+func newToasts(clock Clock, duration time.Duration) *toasts {
+	return &toasts{clock: clock, duration: duration}
+}
+
+// push queues a notice
+func (t *toasts) push(text string) {
+	if t.duration <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.queue = append(t.queue, text)
+}
+
+// current returns the notice to show now, dropping any that have been
+// shown for long enough, and reports whether it's being shown for the
+// first time, so its time starts now
+func (t *toasts) current() (text string, started, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.clock.Now()
+	for len(t.queue) > 0 && t.showing && now.Sub(t.since) >= t.duration {
+		t.queue = t.queue[1:]
+		t.showing = false
+	}
+	if len(t.queue) == 0 {
+		return "", false, false
+	}
+	if !t.showing {
+		t.showing, t.since = true, now
+		started = true
+	}
+	return t.queue[0], started, true
+}
+
+// toastWake is posted to the screen when a toast needs drawing or taking
+// down; toastScreen handles it and the screens underneath never see it
+type toastWake struct{}
+
+// toastScreen shows toasts over whatever else is drawn. Every Show draws
+// the current toast over the frame as an overlay, first taking off the
+// last one, and toasts come and go through events handled inside
+// PollEvent, so screens need no changes to show them. Drawing all happens
+// on the goroutine polling for events.
+type toastScreen struct {
+	tcell.Screen
+	toasts *toasts
+	layer  overlay
+}
+
+// toastStyle is how toasts are drawn
+var toastStyle = tcell.StyleDefault.Reverse(true)
+
+func newToastScreen(screen tcell.Screen, t *toasts) *toastScreen {
+	return &toastScreen{Screen: screen, toasts: t}
+}
+
+// notify queues a toast and wakes the screen to show it
+func (s *toastScreen) notify(text string) {
+	s.toasts.push(text)
+	s.wake()
+}
+
+func (s *toastScreen) wake() {
+	_ = s.PostEvent(tcell.NewEventInterrupt(toastWake{}))
+}
+
+func (s *toastScreen) Show() {
+	s.drawToast()
+	s.Screen.Show()
+}
+
+// PollEvent handles toast wake-ups itself, passing every other event on
+func (s *toastScreen) PollEvent() tcell.Event {
+	for {
+		ev := s.Screen.PollEvent()
+		if ev, ok := ev.(*tcell.EventInterrupt); ok {
+			if _, ok := ev.Data().(toastWake); ok {
+				s.Show()
+				continue
+			}
+		}
+		return ev
+	}
+}
+
+// drawToast puts back what the last toast covered, unless it's since been
+// drawn over, then draws the current toast if there is one
+func (s *toastScreen) drawToast() {
+	s.layer.clear(s.Screen)
+
+	text, started, ok := s.toasts.current()
+	if !ok {
+		return
+	}
+	if started {
+		time.AfterFunc(s.toasts.duration, s.wake)
+	}
+	width, _ := s.Size()
+	text = runewidth.Truncate(" "+text+" ", max(0, width-2), "…")
+	x := width - 1 - runewidth.StringWidth(text)
+	for _, r := range text {
+		s.layer.set(s.Screen, x, 0, r, toastStyle)
+		x += max(1, runewidth.RuneWidth(r))
+	}
+}
+
+// announceEvents shows a toast for the events worth interrupting for
+func announceEvents(bus *EventBus, screen *toastScreen) {
+	bus.Subscribe(func(ev Event) {
+		switch ev.Kind {
+		case EventPBAchieved:
+			screen.notify(fmt.Sprintf("New personal best: %.0f WPM", ev.WPM))
+		case EventBadgeEarned:
+			screen.notify("Badge earned: " + ev.Badge)
+		}
+	}, EventPBAchieved, EventBadgeEarned)
+}
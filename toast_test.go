@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestToastQueue(t *testing.T) {
+	clock := &fakeClock{}
+	q := newToasts(clock, 2*time.Second)
+	q.push("first")
+	q.push("second")
+
+	for _, tt := range []struct {
+		advance time.Duration
+		text    string
+		started bool
+	}{
+		{0, "first", true},
+		{time.Second, "first", false},
+		{time.Second, "second", true},
+		{2 * time.Second, "", false},
+	} {
+		clock.advance(tt.advance)
+		text, started, _ := q.current()
+		if text != tt.text || started != tt.started {
+			t.Errorf("at %v: current = %q, %v; want %q, %v", clock.now.Sub(time.Time{}), text, started, tt.text, tt.started)
+		}
+	}
+
+	off := newToasts(clock, 0)
+	off.push("ignored")
+	if _, _, ok := off.current(); ok {
+		t.Error("toasts shown with a duration of 0")
+	}
+}
+
+func TestToastScreen(t *testing.T) {
+	sim := tcell.NewSimulationScreen("UTF-8")
+	if err := sim.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer sim.Fini()
+	sim.SetSize(30, 5)
+
+	clock := &fakeClock{}
+	screen := newToastScreen(sim, newToasts(clock, time.Second))
+	drawText(screen, 0, 0, tcell.StyleDefault, "KEYSMASH - TYPING TEST")
+	screen.notify("New PB")
+	// The wake-up redraws with the toast, and isn't passed on
+	sim.InjectKey(tcell.KeyRune, 'a', tcell.ModNone)
+	if ev, ok := screen.PollEvent().(*tcell.EventKey); !ok || ev.Rune() != 'a' {
+		t.Fatalf("PollEvent = %v, want the key", ev)
+	}
+	if got := cellText(screen, 0, 0, 30); got != "KEYSMASH - TYPING TES New PB  " {
+		t.Errorf("with a toast, top row = %q", got)
+	}
+
+	// Redrawing part of the frame keeps the toast over it
+	drawText(screen, 0, 0, tcell.StyleDefault, "KEYSMASH")
+	screen.Show()
+	if got := cellText(screen, 0, 0, 30); got != "KEYSMASH - TYPING TES New PB  " {
+		t.Errorf("after a redraw, top row = %q", got)
+	}
+
+	// Once its time is up, what it covered comes back
+	clock.advance(time.Second)
+	screen.Show()
+	if got := cellText(screen, 0, 0, 30); got != "KEYSMASH - TYPING TEST        " {
+		t.Errorf("after the toast, top row = %q", got)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// transliteration romanizes a text into the keystrokes that type it, or
+// fails if the text has characters the table doesn't cover
+type transliteration func(text string) (string, error)
+
+// transliterations are the bundled tables, selected with --transliterate
+var transliterations = map[string]transliteration{
+	"kana":    romanizeKana,
+	"russian": romanizeRussian,
+}
+
+// transliterationNames lists the bundled tables for messages
+func transliterationNames() string {
+	names := make([]string, 0, len(transliterations))
+	for name := range transliterations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// kanaRomaji spells each hiragana the way it's typed into a romaji IME.
+// Katakana is looked up through its hiragana twin.
+var kanaRomaji = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "wi", 'ゑ': "we", 'を': "wo",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'だ': "da", 'ぢ': "di", 'づ': "du", 'で': "de", 'ど': "do",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ゔ': "vu",
+	'ぁ': "xa", 'ぃ': "xi", 'ぅ': "xu", 'ぇ': "xe", 'ぉ': "xo",
+	'ゃ': "xya", 'ゅ': "xyu", 'ょ': "xyo", 'ゎ': "xwa",
+	'ー': "-", '。': ".", '、': ",", '「': "[", '」': "]",
+	'！': "!", '？': "?", '・': "/", '　': " ",
+}
+
+// smallKana are the small kana that combine with the one before them
+// (きゃ is kya, ファ is fa), mapped to the vowel they contribute
+var smallKana = map[rune]string{
+	'ゃ': "a", 'ゅ': "u", 'ょ': "o",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o",
+}
+
+// kanaOnsets are the consonants a small vowel can follow, keyed by the
+// romaji of the kana before it (フィ is fi, ティ is ti)
+var kanaOnsets = map[string]string{
+	"fu": "f", "te": "t", "de": "d", "u": "w", "vu": "v", "tsu": "ts",
+	"shi": "sh", "chi": "ch", "ji": "j",
+}
+
+// toHiragana maps katakana onto hiragana, which the table is keyed by
+func toHiragana(r rune) rune {
+	if r >= 'ァ' && r <= 'ヶ' {
+		return r - ('ァ' - 'ぁ')
+	}
+	return r
+}
+
+// romanizeKana spells hiragana and katakana as romaji IME keystrokes:
+// shi, chi, tsu and fu; きゃ as kya and しゃ as sha; っ as a doubled
+// consonant; ん as n, or nn before a vowel, y or n and at the end, where
+// an IME needs it; ー as -. Kanji can't be romanized without a dictionary,
+// so texts containing it are refused.
+func romanizeKana(text string) (string, error) {
+	runes := []rune(text)
+
+	// First spell each kana, combining yōon and small-vowel pairs; っ and
+	// ん are left as markers since they depend on what follows
+	var units []string
+	for i := 0; i < len(runes); i++ {
+		r := toHiragana(runes[i])
+		if unicode.Is(unicode.Han, r) {
+			return "", fmt.Errorf("kanji %q can't be romanized; use texts written in kana", r)
+		}
+		if r == 'っ' || r == 'ん' {
+			units = append(units, string(r))
+			continue
+		}
+		romaji, ok := kanaRomaji[r]
+		if !ok {
+			units = append(units, string(runes[i]))
+			continue
+		}
+		if i+1 < len(runes) {
+			if vowel, small := smallKana[toHiragana(runes[i+1])]; small {
+				if onset, ok := combinedOnset(romaji, toHiragana(runes[i+1])); ok {
+					romaji = onset + vowel
+					i++
+				}
+			}
+		}
+		units = append(units, romaji)
+	}
+
+	var out strings.Builder
+	for i, unit := range units {
+		next := ""
+		if i+1 < len(units) {
+			next = units[i+1]
+		}
+		switch unit {
+		case "っ":
+			switch {
+			case strings.HasPrefix(next, "ch"):
+				out.WriteString("t")
+			case next != "" && strings.IndexByte("bcdfghjkmprstvwz", next[0]) >= 0:
+				out.WriteByte(next[0])
+			default:
+				out.WriteString("xtsu")
+			}
+		case "ん":
+			if next == "" || next == "ん" || strings.IndexByte("aiueoyn", next[0]) >= 0 {
+				out.WriteString("nn")
+			} else {
+				out.WriteString("n")
+			}
+		default:
+			out.WriteString(unit)
+		}
+	}
+	return out.String(), nil
+}
+
+// combinedOnset returns the consonant that romaji contributes when the
+// small kana follows it: the i-row before ゃゅょ (ki → ky, shi → sh), or
+// the onsets in kanaOnsets before a small vowel
+func combinedOnset(romaji string, small rune) (string, bool) {
+	switch small {
+	case 'ゃ', 'ゅ', 'ょ':
+		base, isIRow := strings.CutSuffix(romaji, "i")
+		if !isIRow || base == "" || base == "x" {
+			return "", false
+		}
+		if base == "sh" || base == "ch" || base == "j" {
+			return base, true
+		}
+		return base + "y", true
+	}
+	onset, ok := kanaOnsets[romaji]
+	return onset, ok
+}
+
+// russianLatin is a simple, typeable romanization of Russian: one that
+// only uses letters on a US keyboard, with ь as an apostrophe
+var russianLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "'", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// romanizeRussian spells Cyrillic with russianLatin, keeping capitals on
+// the first letter (Щ is Shch). Anything else passes through unchanged.
+func romanizeRussian(text string) (string, error) {
+	var out strings.Builder
+	for _, r := range text {
+		latin, ok := russianLatin[unicode.ToLower(r)]
+		switch {
+		case !ok:
+			out.WriteRune(r)
+		case unicode.IsUpper(r) && latin != "":
+			out.WriteString(strings.ToUpper(latin[:1]) + latin[1:])
+		default:
+			out.WriteString(latin)
+		}
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRomanizeKana(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"ひらがな", "hiragana"},
+		{"カタカナ", "katakana"},
+		{"しんぶん", "shinbunn"},
+		{"きょうと", "kyouto"},
+		{"しゃしん", "shashinn"},
+		{"ちょっと", "chotto"},
+		{"まっちゃ", "matcha"},
+		{"こんにちは。", "konnnichiha."},
+		{"ほんや", "honnya"},
+		{"コーヒー", "ko-hi-"},
+		{"ファイル", "fairu"},
+		{"パーティー", "pa-ti-"},
+		{"ぁ", "xa"},
+		{"あっ", "axtsu"},
+		{"abc 123", "abc 123"},
+	}
+	for _, tt := range tests {
+		got, err := romanizeKana(tt.in)
+		if err != nil {
+			t.Errorf("romanizeKana(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("romanizeKana(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := romanizeKana("日本"); err == nil {
+		t.Error("romanizeKana accepted kanji")
+	}
+}
+
+func TestRomanizeRussian(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Привет, мир!", "Privet, mir!"},
+		{"Щука и ёж", "Shchuka i yozh"},
+		{"объявление", "obyavlenie"},
+		{"Хорошо", "Khorosho"},
+		{"соль", "sol'"},
+	}
+	for _, tt := range tests {
+		if got, _ := romanizeRussian(tt.in); got != tt.want {
+			t.Errorf("romanizeRussian(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSelectRandomTestTransliterates(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "neko.txt"), []byte("ねこ\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := newEngine(&fakeClock{}, fixedRand(0), dir)
+	engine.transliterate = romanizeKana
+	state, err := engine.selectRandomTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.referenceText != "neko" || state.shownText() != "ねこ" {
+		t.Errorf("reference %q shown as %q, want neko shown as ねこ", state.referenceText, state.shownText())
+	}
+	for _, r := range "neko" {
+		state.typeRune(r)
+	}
+	if !state.testComplete || state.errors != 0 {
+		t.Errorf("typing the romaji: complete=%v errors=%d, want complete with no errors", state.testComplete, state.errors)
+	}
+}
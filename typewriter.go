@@ -0,0 +1,27 @@
+package main
+
+import "github.com/gdamore/tcell/v2"
+
+// In typewriter mode backspace does nothing, so every keystroke is final.
+// The test ends once the whole text has been typed, right or not, and
+// errors are counted from the raw stream, each wrong keystroke where it
+// landed, rather than aligned: with no way back, a slip that puts the
+// rest of a word out of step costs every character it put out.
+
+// typewriterDone reports whether a typewriter test has been typed to the
+// end of the text
+func (s *TestState) typewriterDone() bool {
+	return s.noBackspace && s.typed() == len(s.reference) && s.lastUnit != unitPending
+}
+
+// drawTypewriterNotice tells the player on the welcome screen that
+// typewriter mode is on, so it isn't a surprise when backspace does
+// nothing
+func drawTypewriterNotice(screen tcell.Screen, on bool) {
+	if !on {
+		return
+	}
+	width, _ := screen.Size()
+	drawCenteredText(screen, width/2, 2, tcell.StyleDefault, "Typewriter mode: backspace is off (T turns it back on)")
+	screen.Show()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestTypewriter(t *testing.T) {
+	state := newTestState("the brown fox", "test.txt", &fakeClock{})
+	state.noBackspace = true
+
+	// Leaving out the r puts the rest of the text one place out, and
+	// backspace can't take anything back
+	typeKeys(&state, "the bown<<")
+	if state.userInput != "the bown" {
+		t.Fatalf("input %q after backspaces, want them ignored", state.userInput)
+	}
+	typeKeys(&state, " fox")
+	if state.testComplete {
+		t.Fatal("complete before the end of the text")
+	}
+	if !state.typeRune('x') {
+		t.Fatal("typing to the end of the text didn't complete it")
+	}
+	// Everything from the o to the first x landed a place early; aligned,
+	// it would count as the one omission
+	if state.errors != 7 || state.uncorrected != 7 {
+		t.Errorf("%d errors, %d uncorrected; want 7 and 7", state.errors, state.uncorrected)
+	}
+}
+
+func TestTypewriterNotice(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(80, 24)
+
+	drawTypewriterNotice(screen, false)
+	if got := cellText(screen, 0, 2, 80); strings.TrimSpace(got) != "" {
+		t.Errorf("notice drawn with typewriter mode off: %q", got)
+	}
+	drawTypewriterNotice(screen, true)
+	if got := cellText(screen, 0, 2, 80); !strings.Contains(got, "backspace is off") {
+		t.Errorf("row 2 = %q, want the notice", got)
+	}
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// app is the state the interactive views share for the whole session
+type app struct {
+	engine     *Engine
+	results    *resultStore
+	daily      *dailyHistory
+	skills     *skillLog
+	keyLog     *keyLog
+	queue      *practiceQueue
+	breaks     *breakTracker
+	render     renderOptions
+	settings   *runSettings // recorded with each result, so toggles update it
+	remap      keyRemap
+	signingKey ed25519.PrivateKey
+	playerName string
+	logPath    string
+	pbAchieved bool           // whether the last run set a personal best
+	dataLock   *dataLock      // not held if another keysmash has the data; see readOnly
+	daemon     *daemonClient  // the daemon this session is a client of, if any
+	control    *controlServer // serving the control API, if it's on
+}
+
+// start loads a test with load and opens it, or the error screen if it
+// can't be loaded, from which Retry calls load again
+func (a *app) start(load func() (TestState, error)) navigation {
+	state, err := load()
+	if err != nil {
+		logger.Error("loading test failed", "err", err)
+		return replaceWith(&failureView{app: a, failure: failure{
+			doing:   "loading a test",
+			err:     err,
+			logPath: a.logPath,
+			retry:   true,
+			canPick: a.engine.mode == modeRandom,
+		}, retry: &loadingView{app: a, load: load}})
+	}
+	return replaceWith(&testView{app: a, state: &state})
+}
+
+// welcomeView is the welcome screen of the engine's mode, where a session
+// starts and every test returns to
+type welcomeView struct {
+	app *app
+
+	// first, if set, loads a test to start on instead of showing the
+	// screen, as for --file; the test still comes back here
+	first func() (TestState, error)
+}
+
+func (v *welcomeView) run(screen tcell.Screen) navigation {
+	a, engine := v.app, v.app.engine
+	if load := v.first; load != nil {
+		v.first = nil
+		return open(&loadingView{app: a, load: load})
+	}
+	if req := a.control.takePending(); req != nil {
+		// Asked for while the player was elsewhere
+		return open(&loadingView{app: a, load: func() (TestState, error) { return a.control.startTest(req) }})
+	}
+	switch {
+	case a.daily != nil:
+		showDailyWelcomeScreen(screen, engine.clock.Now(), a.daily)
+	case engine.course != nil:
+		showLearnWelcomeScreen(screen, engine.course)
+	case engine.pack != nil:
+		showPackWelcomeScreen(screen, engine.pack, engine.clock.Now())
+	default:
+		showWelcomeScreen(screen)
+	}
+	drawWarmupHint(screen, a.results, engine.clock.Now())
+	drawReadOnlyNotice(screen, a.dataLock, a.daemon)
+	drawTypewriterNotice(screen, engine.noBackspace)
+	var drillTargets []string
+	var weak []weakness
+	var recent []result
+	commands := []command{{title: "Start a new test", key: ' '}}
+	if engine.mode == modeRandom {
+		var reminder string
+		reminder, drillTargets = skillReminder(a.skills.decayed(engine.clock.Now()))
+		drawSkillReminder(screen, reminder)
+		weak = a.keyLog.tally.analytics().weaknesses()
+		drawWeaknessHint(screen, weak)
+		drawQueueHint(screen, a.queue)
+		recent = a.results.recentTests(recentShown)
+		drawRecentTests(screen, recent)
+		if len(engine.library.archivedNames()) > 0 {
+			width, height := screen.Size()
+			drawCenteredText(screen, width/2, height/2+4, tcell.StyleDefault, "A: manage archived texts")
+			screen.Show()
+			commands = append(commands, command{title: "Manage archived texts", key: 'a'})
+		}
+		commands = append(commands, command{title: "Pick a test by name", key: 'p'})
+		if drillTargets != nil {
+			commands = append(commands, command{title: "Drill weak keys", key: 'd'})
+		}
+		if weak != nil {
+			commands = append(commands, command{title: "Practise weaknesses", key: 'w'})
+		}
+		if len(a.queue.Texts) > 0 {
+			commands = append(commands, command{title: "Work through the practice queue", key: 'b'})
+		}
+		for i, r := range recent {
+			commands = append(commands, command{title: "Play again: " + r.TestFile, key: '1' + rune(i)})
+		}
+	}
+	typewriter := "Turn typewriter mode (no backspace) on"
+	if engine.noBackspace {
+		typewriter = "Turn typewriter mode (no backspace) off"
+	}
+	commands = append(commands,
+		command{title: "Show history", key: 'h'},
+		command{title: "Key analytics", key: 'k'},
+		command{title: "Accuracy by text length", key: 'l'},
+		command{title: "Zen mode: type freely", key: 'z'},
+		command{title: "Toggle reduced motion", key: 'm'},
+		command{title: typewriter, key: 't'},
+		command{title: "Help", key: '?'},
+	)
+	if a.breaks.due() {
+		a.breaks.remind(engine.clock.Now(), showBreakReminder(screen, a.breaks))
+		return stay
+	}
+
+	var key rune
+	var ok bool
+	if engine.class != nil {
+		ok = chooseStudent(screen, engine.class)
+	} else {
+		key, ok = waitForStart(screen, commands)
+	}
+	if !ok {
+		// User pressed Escape, exit the program
+		return quit
+	}
+
+	switch {
+	case key == controlKey:
+		return stay // the test asked for is started above
+	case (key == 'a' || key == 'A') && engine.mode == modeRandom:
+		return open(&archiveView{app: a})
+	case key == 'h' || key == 'H':
+		return open(&historyView{app: a})
+	case key == 'k' || key == 'K':
+		return open(&keyAnalyticsView{app: a})
+	case key == 'l' || key == 'L':
+		return open(&accuracyCurveView{app: a})
+	case key == 'z' || key == 'Z':
+		return open(&zenView{app: a})
+	case key == '?':
+		return open(&helpView{commands: commands})
+	case key == 'm' || key == 'M':
+		a.render.reducedMotion = !a.render.reducedMotion
+		a.render.lowPower = a.render.lowPower && a.render.reducedMotion // low power needs reduced motion
+		a.settings.ReducedMotion, a.settings.LowPower = a.render.reducedMotion, a.render.lowPower
+		logger.Info("reduced motion toggled", "on", a.render.reducedMotion)
+		return stay
+	case key == 't' || key == 'T':
+		engine.noBackspace = !engine.noBackspace
+		a.settings.NoBackspace = engine.noBackspace
+		logger.Info("typewriter mode toggled", "on", engine.noBackspace)
+		return stay
+	case (key == 'p' || key == 'P') && engine.mode == modeRandom:
+		return open(&pickerView{app: a})
+	case (key == 'd' || key == 'D') && drillTargets != nil:
+		return open(&loadingView{app: a, load: func() (TestState, error) { return engine.drill(drillTargets), nil }})
+	case (key == 'w' || key == 'W') && weak != nil:
+		return open(&loadingView{app: a, load: func() (TestState, error) { return engine.practiseWeaknesses(weak), nil }})
+	case (key == 'b' || key == 'B') && engine.mode == modeRandom && len(a.queue.Texts) > 0:
+		return open(&loadingView{app: a, load: a.nextQueued})
+	case key >= '1' && int(key-'1') < len(recent):
+		name := recent[key-'1'].TestFile
+		return open(&loadingView{app: a, load: func() (TestState, error) { return engine.loadTest(name) }})
+	}
+	return open(&loadingView{app: a, load: engine.nextTest})
+}
+
+// nextQueued loads the text at the front of the practice queue. One that
+// can't be loaded, say because it's been deleted, is dropped from the
+// queue so the next attempt moves on.
+func (a *app) nextQueued() (TestState, error) {
+	if len(a.queue.Texts) == 0 {
+		return a.engine.nextTest()
+	}
+	file := a.queue.Texts[0].TestFile
+	state, err := a.engine.queuedTest(file)
+	if err != nil {
+		a.queue.remove(file)
+		if err := a.queue.save(); err != nil {
+			logger.Error("saving practice queue failed", "err", err)
+		}
+	}
+	return state, err
+}
+
+// loadingView loads a test and hands over to it, or to the error screen
+type loadingView struct {
+	app  *app
+	load func() (TestState, error)
+}
+
+func (v *loadingView) run(tcell.Screen) navigation {
+	return v.app.start(v.load)
+}
+
+// pickerView picks a test by name; see pickTest
+type pickerView struct {
+	app *app
+}
+
+func (v *pickerView) run(screen tcell.Screen) navigation {
+	a := v.app
+	name, chosen, err := pickTest(screen, a.engine, a.results)
+	if err != nil {
+		logger.Error("listing tests failed", "err", err)
+		return replaceWith(&failureView{app: a, failure: failure{
+			doing:   "listing tests",
+			err:     err,
+			logPath: a.logPath,
+			retry:   true,
+		}, retry: v})
+	}
+	if !chosen {
+		return goBack
+	}
+	return a.start(func() (TestState, error) { return a.engine.loadTest(name) })
+}
+
+// testView runs a typing test, then shows its results in its place; a
+// test abandoned with Escape goes straight back
+type testView struct {
+	app   *app
+	state *TestState
+}
+
+func (v *testView) run(screen tcell.Screen) navigation {
+	v.app.pbAchieved = false
+	runTypingTest(screen, v.state, v.app.render, v.app.remap)
+	if !v.state.testComplete {
+		return goBack
+	}
+	return replaceWith(&resultsView{app: v.app, state: v.state})
+}
+
+// resultsView shows a completed test's results; see handlePostTest
+type resultsView struct {
+	app        *app
+	state      *TestState
+	celebrated bool // shown once already, so no more confetti
+}
+
+func (v *resultsView) run(screen tcell.Screen) navigation {
+	a := v.app
+	celebrated := v.celebrated
+	v.celebrated = true
+	switch handlePostTest(screen, *v.state, a.pbAchieved, a.results, a.engine.library, a.signingKey, a.playerName, a.render.reducedMotion || celebrated) {
+	case 'v':
+		return open(&reviewView{app: a, state: v.state, current: -1})
+	case 'r':
+		// Retry the same test
+		v.state.reset()
+		return replaceWith(&testView{app: a, state: v.state})
+	case 'q':
+		return quit
+	}
+	return goBack
+}
+
+// historyView browses past results; see showHistory
+type historyView struct {
+	app *app
+}
+
+func (v *historyView) run(screen tcell.Screen) navigation {
+	showHistory(screen, v.app.results)
+	return goBack
+}
+
+// keyAnalyticsView shows what the keystroke log says about each key; see
+// showKeyAnalytics
+type keyAnalyticsView struct {
+	app *app
+}
+
+func (v *keyAnalyticsView) run(screen tcell.Screen) navigation {
+	showKeyAnalytics(screen, v.app.keyLog.tally.analytics())
+	return goBack
+}
+
+// accuracyCurveView charts accuracy by text length; see
+// showAccuracyCurve
+type accuracyCurveView struct {
+	app *app
+}
+
+func (v *accuracyCurveView) run(screen tcell.Screen) navigation {
+	showAccuracyCurve(screen, accuracyByLength(v.app.results.counted()), v.app.engine.targetAccuracy)
+	return goBack
+}
+
+// zenView runs a zen session and shows how it went; see runZen
+type zenView struct {
+	app *app
+}
+
+func (v *zenView) run(screen tcell.Screen) navigation {
+	engine := v.app.engine
+	limit := zenDuration
+	if engine.timeLimit > 0 {
+		limit = engine.timeLimit
+	}
+	z, ok := runZen(screen, engine.clock, limit)
+	if !ok {
+		return goBack
+	}
+	now := engine.clock.Now()
+	saved, err := saveZenText(string(z.text), now)
+	if err != nil {
+		logger.Error("saving zen text failed", "err", err)
+		saved = "Couldn't save the text: " + err.Error()
+	} else {
+		logger.Info("zen session saved", "path", saved, "characters", len(z.text))
+		saved = "Saved to " + saved
+	}
+	showZenResults(screen, z.stats(now), saved)
+	return goBack
+}
+
+// archiveView archives and unarchives texts; see manageArchive
+type archiveView struct {
+	app *app
+}
+
+func (v *archiveView) run(screen tcell.Screen) navigation {
+	manageArchive(screen, v.app.engine.library)
+	return goBack
+}
+
+// failureView is the error screen for a view that failed, whose Retry
+// runs that view again
+type failureView struct {
+	app     *app
+	failure failure
+	retry   view
+}
+
+func (v *failureView) run(screen tcell.Screen) navigation {
+	a := v.app
+	action, ok := showFailure(screen, v.failure)
+	if !ok {
+		return quit // User pressed Escape to quit
+	}
+	switch action {
+	case 'r':
+		return replaceWith(v.retry)
+	case 'n':
+		return replaceWith(&loadingView{app: a, load: a.engine.nextTest})
+	case 'p':
+		return replaceWith(&pickerView{app: a})
+	case 'a':
+		return replaceWith(&archiveView{app: a})
+	}
+	return goBack
+}
+
+// helpView lists the welcome screen's keys until any key is pressed
+type helpView struct {
+	commands []command
+}
+
+func (v *helpView) run(screen tcell.Screen) navigation {
+	screen.Clear()
+	width, height := screen.Size()
+	top := max(1, height/2-len(v.commands)/2-2)
+	drawCenteredText(screen, width/2, top, tcell.StyleDefault, "HELP")
+	for i, c := range v.commands {
+		drawCenteredText(screen, width/2, top+2+i, tcell.StyleDefault, fmt.Sprintf("%-6s %-36s", c.shortcut(), c.title))
+	}
+	drawCenteredText(screen, width/2, top+3+len(v.commands), tcell.StyleDefault, "Ctrl+P opens any of these by name; ESC quits from the welcome screen")
+	drawCenteredText(screen, width/2, top+5+len(v.commands), tcell.StyleDefault, "Press any key to go back")
+	screen.Show()
+	waitForKey(screen)
+	return goBack
+}
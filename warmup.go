@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// sessionGap is the pause after which the next test starts a new session
+const sessionGap = 30 * time.Minute
+
+// warmupPositions is how far into a session warm-up is looked for
+const warmupPositions = 5
+
+// warmupMinSessions is how many sessions must reach a position before its
+// gain is trusted
+const warmupMinSessions = 3
+
+// warmupFactor is how much faster the player usually is at some test of a
+// session than at its first
+type warmupFactor struct {
+	position int     // 1-based test number within a session
+	gain     float64 // mean WPM over the session's first test
+	sessions int     // sessions that reached position
+}
+
+// sessionPositions returns each result's 1-based position within its
+// session. Results must be oldest first, as the store keeps them.
+func sessionPositions(results []result) []int {
+	positions := make([]int, len(results))
+	for i, r := range results {
+		positions[i] = 1
+		if i > 0 && r.Completed.Sub(results[i-1].Completed) < sessionGap {
+			positions[i] = positions[i-1] + 1
+		}
+	}
+	return positions
+}
+
+// warmup finds the test position with the largest average gain over the
+// first test of the same session. ok is false until there's enough history
+// to say, or if the player doesn't actually speed up.
+func (s *resultStore) warmup() (best warmupFactor, ok bool) {
+	counted := s.counted()
+	positions := sessionPositions(counted)
+
+	var gains [warmupPositions + 1]float64
+	var sessions [warmupPositions + 1]int
+	var first float64
+	for i, r := range counted {
+		p := positions[i]
+		if p == 1 {
+			first = r.WPM
+			continue
+		}
+		if p <= warmupPositions {
+			gains[p] += r.WPM - first
+			sessions[p]++
+		}
+	}
+
+	for p := 2; p <= warmupPositions; p++ {
+		if sessions[p] < warmupMinSessions {
+			continue
+		}
+		gain := gains[p] / float64(sessions[p])
+		if gain > 0 && (!ok || gain > best.gain) {
+			best, ok = warmupFactor{position: p, gain: gain, sessions: sessions[p]}, true
+		}
+	}
+	return best, ok
+}
+
+// nextPosition is the session position the next test will have if it's
+// finished around now
+func (s *resultStore) nextPosition(now time.Time) int {
+	if len(s.Results) == 0 {
+		return 1
+	}
+	last := s.Results[len(s.Results)-1]
+	if now.Sub(last.Completed) >= sessionGap {
+		return 1
+	}
+	return sessionPositions(s.Results)[len(s.Results)-1] + 1
+}
+
+// warmupHint is the welcome screen's warm-up advice: the player's warm-up
+// factor, and a nudge to keep warming up if this session is still short of
+// it. Either may be "".
+func (s *resultStore) warmupHint(now time.Time) (factor, advice string) {
+	best, ok := s.warmup()
+	if !ok {
+		return "", ""
+	}
+	factor = fmt.Sprintf("Your %s test of a session is usually %+.0f WPM over your first", ordinal(best.position), best.gain)
+	if next := s.nextPosition(now); next < best.position {
+		advice = fmt.Sprintf("Up next: test %d of this session. Warm up before going for a PB.", next)
+	}
+	return factor, advice
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWarmup(t *testing.T) {
+	start := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	store := &resultStore{}
+	// Three days of sessions, each test five minutes apart: the first test
+	// is slow, the 3rd is the fastest
+	for day := 0; day < 3; day++ {
+		for i, wpm := range []float64{50, 54, 58, 55} {
+			completed := start.AddDate(0, 0, day).Add(time.Duration(i) * 5 * time.Minute)
+			store.add(result{WPM: wpm + float64(day), Completed: completed})
+		}
+	}
+
+	positions := sessionPositions(store.Results)
+	if got := positions[:5]; got[0] != 1 || got[3] != 4 || got[4] != 1 {
+		t.Errorf("positions = %v, want sessions of 4", positions)
+	}
+
+	factor, ok := store.warmup()
+	if !ok || factor.position != 3 || factor.gain != 8 || factor.sessions != 3 {
+		t.Errorf("warmup = %+v, %v; want +8 WPM at test 3 over 3 sessions", factor, ok)
+	}
+
+	// An hour after the last test, the next one starts a new session
+	last := store.Results[len(store.Results)-1].Completed
+	if got := store.nextPosition(last.Add(time.Hour)); got != 1 {
+		t.Errorf("next position after a break = %d, want 1", got)
+	}
+	if _, advice := store.warmupHint(last.Add(time.Hour)); advice == "" {
+		t.Error("no warm-up advice at the start of a session")
+	}
+	if _, advice := store.warmupHint(last.Add(time.Minute)); advice != "" {
+		t.Errorf("warm-up advice %q for test 5 of a session", advice)
+	}
+}
+
+func TestWarmupNeedsHistory(t *testing.T) {
+	start := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	store := &resultStore{}
+	store.add(result{WPM: 40, Completed: start})
+	store.add(result{WPM: 60, Completed: start.Add(time.Minute)})
+	if factor, ok := store.warmup(); ok {
+		t.Errorf("warmup from one session = %+v, want none", factor)
+	}
+}
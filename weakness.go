@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Weakness practice drills the keys and bigrams the keystroke log says
+// the player misses most. Unlike the skill decay drill, which takes its
+// targets in turn, it picks each word's target at random weighted by how
+// often it's missed, so the worst come up most.
+
+// weaknessMinKeystrokes is how much of the keystroke log there must be
+// before weakness practice is offered
+const weaknessMinKeystrokes = 500
+
+// weaknessTargets is how many keys and bigrams a practice works on
+const weaknessTargets = 6
+
+// weaknessFile names weakness practice tests in TestState.testFile
+const weaknessFile = "weaknesses"
+
+// weakness is a key or bigram to practise, and how often it's missed
+type weakness struct {
+	Key      string
+	MissRate float64
+}
+
+// weaknesses returns the keys and bigrams missed most often, worst first,
+// or nil if there's too little of the log to go on. Whitespace is left
+// out, since every word break practises it anyway.
+func (a keyAnalytics) weaknesses() []weakness {
+	if a.Keystrokes < weaknessMinKeystrokes {
+		return nil
+	}
+	var found []weakness
+	keys, bigrams := a.Missed, a.MissedBigrams
+	for len(found) < weaknessTargets && (len(keys) > 0 || len(bigrams) > 0) {
+		// Merge the two lists, both sorted worst first
+		var next keyStat
+		if len(bigrams) == 0 || len(keys) > 0 && keys[0].missRate() >= bigrams[0].missRate() {
+			next, keys = keys[0], keys[1:]
+		} else {
+			next, bigrams = bigrams[0], bigrams[1:]
+		}
+		if !isBlank(next.Key) {
+			found = append(found, weakness{Key: next.Key, MissRate: next.missRate()})
+		}
+	}
+	return found
+}
+
+// weaknessText builds a practice of drillWords words, each containing a
+// target chosen at random in proportion to its miss rate
+func weaknessText(targets []weakness, rng Rand) string {
+	weights := make([]int, len(targets))
+	total := 0
+	for i, t := range targets {
+		weights[i] = max(1, int(t.MissRate*1000))
+		total += weights[i]
+	}
+	words := make([]string, drillWords)
+	for i := range words {
+		pick := rng.Intn(total)
+		n := 0
+		for pick >= weights[n] {
+			pick -= weights[n]
+			n++
+		}
+		words[i] = drillWord(targets[n].Key, rng)
+	}
+	return strings.Join(words, " ")
+}
+
+// practiseWeaknesses returns a test practising the given weaknesses
+func (e *Engine) practiseWeaknesses(targets []weakness) TestState {
+	logger.Info("selected weakness practice", "targets", len(targets))
+	return e.newTest(weaknessText(targets, e.rng), weaknessFile)
+}
+
+// drawWeaknessHint offers weakness practice on the welcome screen
+func drawWeaknessHint(screen tcell.Screen, targets []weakness) {
+	if len(targets) == 0 {
+		return
+	}
+	names := make([]string, len(targets))
+	for i, t := range targets {
+		names[i] = keyName(t.Key)
+	}
+	width, height := screen.Size()
+	drawCenteredText(screen, width/2, height/2+6, tcell.StyleDefault, fmt.Sprintf("W: practise your weakest keys (%s)", strings.Join(names, " ")))
+	screen.Show()
+}
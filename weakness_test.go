@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// logWords logs words typed over and over until there are n keystrokes,
+// getting wrong every keystroke wrong says to
+func logWords(words string, n int, wrong func(pos int, want string) bool) []keyLogEntry {
+	var entries []keyLogEntry
+	for pos := 0; len(entries) < n; pos++ {
+		want := string(words[pos%len(words)])
+		entries = append(entries, keyLogEntry{TestFile: "t", Position: pos, Typed: want, Expected: want, Correct: !wrong(pos, want)})
+	}
+	return entries
+}
+
+func TestWeaknesses(t *testing.T) {
+	// Every other q missed, one z in five, and i whenever it follows h.
+	// A key comes before a bigram missed as often, and ties go
+	// alphabetically.
+	entries := logWords("quiz this ", weaknessMinKeystrokes, func(pos int, want string) bool {
+		switch want {
+		case "q":
+			return pos%20 == 0
+		case "z":
+			return pos%50 == 3
+		case "i":
+			return pos%10 == 7
+		}
+		return false
+	})
+	got := analyzeKeys(entries).weaknesses()
+	want := []weakness{{"hi", 1}, {"i", 0.5}, {"q", 0.5}, {"z", 0.2}, {"iz", 0.2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("weaknesses = %+v, want %+v", got, want)
+	}
+
+	if got := analyzeKeys(entries[:weaknessMinKeystrokes-1]).weaknesses(); got != nil {
+		t.Errorf("with too little logged: %+v", got)
+	}
+}
+
+func TestWeaknessText(t *testing.T) {
+	text := weaknessText([]weakness{{"q", 0.9}, {"x", 0.1}}, fixedRand(0))
+	words := strings.Fields(text)
+	if len(words) != drillWords {
+		t.Fatalf("%d words: %q", len(words), text)
+	}
+	// fixedRand(0) always picks the first, and most missed, target
+	for _, word := range words {
+		if !strings.Contains(word, "q") {
+			t.Errorf("word %q doesn't practise q", word)
+		}
+	}
+}
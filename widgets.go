@@ -0,0 +1,220 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// The widgets here are the pieces screens are built from: a menu, a text
+// field, a scrolling text view, a progress bar and an overlay box. Each
+// draws into a rectangle it's given and leaves the rest of the screen
+// alone, so screens only decide where things go.
+
+// menu is a scrolling list with one item selected, such as the history,
+// archive and student lists. It holds only the selection; the items are
+// passed in each time, since screens rebuild them as they change.
+type menu struct {
+	selected int
+	centered bool // center items on x instead of starting them there
+}
+
+// handle moves the selection among count items for Up, Down, Page Up,
+// Page Down, Home and End, with rows items to a page, and reports whether
+// ev was one of them
+func (m *menu) handle(ev *tcell.EventKey, count, rows int) bool {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		m.selected--
+	case tcell.KeyDown:
+		m.selected++
+	case tcell.KeyPgUp:
+		m.selected -= rows
+	case tcell.KeyPgDn:
+		m.selected += rows
+	case tcell.KeyHome:
+		m.selected = 0
+	case tcell.KeyEnd:
+		m.selected = count - 1
+	default:
+		return false
+	}
+	m.clamp(count)
+	return true
+}
+
+// clamp keeps the selection on one of count items, or 0 if there are none
+func (m *menu) clamp(count int) {
+	m.selected = max(0, min(m.selected, count-1))
+}
+
+// draw shows up to rows of items from y, scrolled so the selected item is
+// in view, and shows the selected one in reverse video
+func (m *menu) draw(screen tcell.Screen, x, y, rows int, items []string) {
+	m.clamp(len(items))
+	first := max(0, m.selected-rows+1)
+	for i := first; i < len(items) && i-first < rows; i++ {
+		style := tcell.StyleDefault
+		if i == m.selected {
+			style = style.Reverse(true)
+		}
+		if m.centered {
+			drawCenteredText(screen, x, y+i-first, style, items[i])
+		} else {
+			drawText(screen, x, y+i-first, style, items[i])
+		}
+	}
+}
+
+// inputField is a line of text being typed, like the palette's query or
+// a tag for the history screen
+type inputField struct {
+	text []rune
+}
+
+// handle applies a key to the field: a character is added and Backspace
+// takes the last one off. It reports whether the text changed.
+func (f *inputField) handle(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyRune:
+		f.text = append(f.text, ev.Rune())
+		return true
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(f.text) > 0 {
+			f.text = f.text[:len(f.text)-1]
+			return true
+		}
+	}
+	return false
+}
+
+func (f *inputField) String() string {
+	return string(f.text)
+}
+
+// draw blanks width cells from x, y and shows prompt and the text there,
+// cut off at width, with the terminal's cursor after it
+func (f *inputField) draw(screen tcell.Screen, x, y, width int, prompt string) {
+	clearBox(screen, x, y, width, 1)
+	line := runewidth.Truncate(prompt+string(f.text), width, "")
+	drawText(screen, x, y, tcell.StyleDefault, line)
+	screen.ShowCursor(x+min(width, runewidth.StringWidth(line)), y)
+}
+
+// drawLines is a text view: it shows rows of lines from y, starting
+// scroll lines in, and returns scroll clamped so the view never scrolls
+// past either end
+func drawLines(screen tcell.Screen, x, y, rows int, style tcell.Style, lines []string, scroll int) int {
+	scroll = max(0, min(scroll, len(lines)-rows))
+	for i := 0; i < rows && scroll+i < len(lines); i++ {
+		drawText(screen, x, y+i, style, lines[scroll+i])
+	}
+	return scroll
+}
+
+// progressBar draws fraction (0 to 1) of a bar width cells inside
+// brackets, as "[====      ]"
+func progressBar(width int, fraction float64) string {
+	filled := int(max(0, min(1, fraction)) * float64(width))
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "]"
+}
+
+// drawBox draws lines centered on the screen inside a border, clearing the
+// cells underneath so it reads as an overlay
+func drawBox(screen tcell.Screen, lines []string) {
+	width, height := screen.Size()
+	inner := 0
+	for _, line := range lines {
+		inner = max(inner, runewidth.StringWidth(line))
+	}
+	x := (width - inner - 4) / 2
+	y := (height - len(lines) - 2) / 2
+
+	border := "+" + strings.Repeat("-", inner+2) + "+"
+	drawText(screen, x, y, tcell.StyleDefault, border)
+	for i, line := range lines {
+		drawText(screen, x, y+1+i, tcell.StyleDefault, "| "+runewidth.FillRight(line, inner)+" |")
+	}
+	drawText(screen, x, y+1+len(lines), tcell.StyleDefault, border)
+}
+
+// clearBox blanks a rectangle of the screen
+func clearBox(screen tcell.Screen, left, top, w, h int) {
+	for y := top; y < top+h; y++ {
+		for x := left; x < left+w; x++ {
+			screen.SetContent(x, y, ' ', nil, tcell.StyleDefault)
+		}
+	}
+}
+
+// overlay is a layer of cells drawn over a screen that can be taken off
+// again, for things that come and go over a screen still in use, like
+// toasts and animations. Taking it off puts back what each cell covered,
+// unless the screen has drawn over it since.
+type overlay struct {
+	cells []overlayCell
+}
+
+// overlayCell is a cell the overlay covers: what it held, and the
+// character the overlay put there, or 0 for the second cell of a wide one
+type overlayCell struct {
+	x, y  int
+	main  rune
+	comb  []rune
+	style tcell.Style
+	shown rune
+	as    tcell.Style
+}
+
+// set draws r in style at x, y, remembering what it covers
+func (o *overlay) set(screen tcell.Screen, x, y int, r rune, style tcell.Style) {
+	for i := 0; i < max(1, runewidth.RuneWidth(r)); i++ {
+		main, comb, under, _ := screen.GetContent(x+i, y)
+		c := overlayCell{x: x + i, y: y, main: main, comb: comb, style: under, shown: r, as: style}
+		if i > 0 {
+			c.shown = 0
+		}
+		o.cells = append(o.cells, c)
+	}
+	screen.SetContent(x, y, r, nil, style)
+}
+
+// clear takes the overlay off, putting back the cells it still covers
+func (o *overlay) clear(screen tcell.Screen) {
+	restore := false
+	for _, c := range o.cells {
+		if c.shown != 0 {
+			main, _, style, _ := screen.GetContent(c.x, c.y)
+			restore = main == c.shown && style == c.as
+		}
+		if restore {
+			screen.SetContent(c.x, c.y, c.main, c.comb, c.style)
+		}
+	}
+	o.cells = o.cells[:0]
+}
+
+// saveCells remembers a rectangle of the screen and returns a function
+// that puts it back, for overlays that leave the screen as they found it
+func saveCells(screen tcell.Screen, left, top, w, h int) func() {
+	type cell struct {
+		main  rune
+		comb  []rune
+		style tcell.Style
+	}
+	cells := make([]cell, 0, w*h)
+	for y := top; y < top+h; y++ {
+		for x := left; x < left+w; x++ {
+			main, comb, style, _ := screen.GetContent(x, y)
+			cells = append(cells, cell{main, comb, style})
+		}
+	}
+	return func() {
+		for i, c := range cells {
+			screen.SetContent(left+i%w, top+i/w, c.main, c.comb, c.style)
+		}
+		screen.HideCursor()
+		screen.Show()
+	}
+}
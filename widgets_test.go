@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestMenu(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(20, 5)
+
+	var m menu
+	key := func(k tcell.Key) *tcell.EventKey { return tcell.NewEventKey(k, 0, tcell.ModNone) }
+	for _, tt := range []struct {
+		key  tcell.Key
+		want int
+	}{
+		{tcell.KeyUp, 0}, // stops at the top
+		{tcell.KeyDown, 1},
+		{tcell.KeyPgDn, 4},
+		{tcell.KeyEnd, 5},
+		{tcell.KeyDown, 5}, // and at the bottom
+		{tcell.KeyHome, 0},
+	} {
+		if !m.handle(key(tt.key), 6, 3) {
+			t.Fatalf("handle(%v) didn't take the key", tt.key)
+		}
+		if m.selected != tt.want {
+			t.Errorf("after %v selected = %d, want %d", tt.key, m.selected, tt.want)
+		}
+	}
+	if m.handle(key(tcell.KeyEnter), 6, 3) {
+		t.Error("handle took Enter")
+	}
+
+	// Scrolled so the selection is the last of three rows
+	m.selected = 4
+	m.draw(screen, 0, 0, 3, []string{"a", "b", "c", "d", "e", "f"})
+	for y, want := range "cde" {
+		if got := cellText(screen, 0, y, 1); got != string(want) {
+			t.Errorf("row %d = %q, want %q", y, got, string(want))
+		}
+	}
+	if _, _, style, _ := screen.GetContent(0, 2); style != tcell.StyleDefault.Reverse(true) {
+		t.Error("selected item isn't highlighted")
+	}
+}
+
+func TestInputField(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(20, 2)
+
+	var f inputField
+	for _, r := range "tagz" {
+		f.handle(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+	f.handle(tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone))
+	if got := f.String(); got != "tag" {
+		t.Errorf("text = %q, want %q", got, "tag")
+	}
+	if f.handle(tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone)) {
+		t.Error("Escape changed the text")
+	}
+
+	drawText(screen, 0, 0, tcell.StyleDefault, strings.Repeat("x", 20))
+	f.draw(screen, 2, 0, 6, "> ")
+	if got := cellText(screen, 0, 0, 10); got != "xx> tag xx" {
+		t.Errorf("field drawn as %q", got)
+	}
+}
+
+func TestDrawLines(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(10, 5)
+
+	lines := []string{"one", "two", "three", "four"}
+	if got := drawLines(screen, 0, 0, 2, tcell.StyleDefault, lines, 10); got != 2 {
+		t.Errorf("scroll past the end = %d, want 2", got)
+	}
+	if got := cellText(screen, 0, 1, 4); got != "four" {
+		t.Errorf("last row = %q, want %q", got, "four")
+	}
+	if got := drawLines(screen, 0, 0, 8, tcell.StyleDefault, lines, 3); got != 0 {
+		t.Errorf("scroll with room to spare = %d, want 0", got)
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	for _, tt := range []struct {
+		fraction float64
+		want     string
+	}{
+		{0, "[    ]"},
+		{0.5, "[==  ]"},
+		{1, "[====]"},
+		{1.5, "[====]"},
+		{-1, "[    ]"},
+	} {
+		if got := progressBar(4, tt.fraction); got != tt.want {
+			t.Errorf("progressBar(4, %v) = %q, want %q", tt.fraction, got, tt.want)
+		}
+	}
+}
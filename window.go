@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// wrapChunk is the size, in bytes, of the chunks a long text is split
+// into for display. The test screen only ever shows a few lines either
+// side of the typing position, so it wraps the chunk being typed and its
+// neighbours rather than the whole text, and a book costs no more per
+// frame than a page of it.
+const wrapChunk = 8 << 10
+
+// textWindow returns the part of text to wrap for display around the byte
+// offset pos: the chunk holding pos and the one either side of it. The
+// window only moves when pos crosses into another chunk, so the lines
+// shown stay put while typing within one. Its edges are moved to the
+// nearest line or word break inside it, so wrapping the window breaks
+// lines where wrapping the whole text would, or near enough.
+func textWindow(text string, pos int) (start, end int) {
+	chunk := pos / wrapChunk
+	start = max(0, (chunk-1)*wrapChunk)
+	end = min(len(text), (chunk+2)*wrapChunk)
+	if start > 0 {
+		start = breakAfter(text, start, min(pos, end))
+	}
+	if end < len(text) {
+		end = breakBefore(text, max(pos, start), end)
+	}
+	return start, end
+}
+
+// breakAfter returns the first position in text[from:to] just after a
+// newline, or failing that a space, or failing that the next rune
+func breakAfter(text string, from, to int) int {
+	if i := strings.IndexByte(text[from:to], '\n'); i >= 0 {
+		return from + i + 1
+	}
+	if i := strings.IndexByte(text[from:to], ' '); i >= 0 {
+		return from + i + 1
+	}
+	for from < to && !utf8.RuneStart(text[from]) {
+		from++
+	}
+	return from
+}
+
+// breakBefore returns the last position in text[from:to] just before a
+// newline, or failing that a space, or failing that the last rune start
+func breakBefore(text string, from, to int) int {
+	if i := strings.LastIndexByte(text[from:to], '\n'); i >= 0 {
+		return from + i
+	}
+	if i := strings.LastIndexByte(text[from:to], ' '); i >= 0 {
+		return from + i
+	}
+	for to > from && !utf8.RuneStart(text[to]) {
+		to--
+	}
+	return to
+}
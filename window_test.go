@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestTextWindow(t *testing.T) {
+	short := benchText(1000)
+	if start, end := textWindow(short, 500); start != 0 || end != len(short) {
+		t.Errorf("short text window = %d:%d, want all of it", start, end)
+	}
+
+	text := benchText(1 << 20)
+	for _, pos := range []int{0, 5 * wrapChunk, 5*wrapChunk + 100, len(text) - 1} {
+		start, end := textWindow(text, pos)
+		if start > pos || end < pos || end-start > 3*wrapChunk {
+			t.Errorf("window around %d = %d:%d", pos, start, end)
+		}
+		if start > 0 && text[start-1] != '\n' {
+			t.Errorf("window around %d starts mid-line: %q", pos, text[start-1:start+10])
+		}
+		if end < len(text) && text[end] != '\n' {
+			t.Errorf("window around %d ends mid-line: %q", pos, text[end-10:end+1])
+		}
+	}
+
+	// The window holds still while typing within a chunk
+	a, _ := textWindow(text, 5*wrapChunk)
+	b, _ := textWindow(text, 6*wrapChunk-1)
+	if a != b {
+		t.Errorf("window moved within a chunk: %d then %d", a, b)
+	}
+
+	// With no breaks at all it still cuts between runes
+	runes := strings.Repeat("é", 1<<16)
+	start, end := textWindow(runes, len(runes)/2+1)
+	if !strings.HasPrefix(runes[start:end], "é") || !strings.HasSuffix(runes[start:end], "é") {
+		t.Errorf("window %d:%d splits a rune", start, end)
+	}
+}
+
+func FuzzTextWindow(f *testing.F) {
+	f.Add(benchText(wrapChunk*5/2), 2*wrapChunk)
+	f.Add(strings.Repeat("日本", wrapChunk/2), wrapChunk+1)
+	f.Fuzz(func(t *testing.T, text string, pos int) {
+		if len(text) == 0 {
+			return
+		}
+		pos = fuzzRange(pos, 0, len(text))
+		start, end := textWindow(text, pos)
+		if start < 0 || start > pos || end < pos || end > len(text) || end-start > 3*wrapChunk {
+			t.Fatalf("window around %d of %d bytes = %d:%d", pos, len(text), start, end)
+		}
+		_ = text[start:end]
+	})
+}
+
+func TestRenderLongText(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(100, 40)
+
+	// Halfway through a book, the text being typed is on screen
+	text := strings.Repeat(benchSentence, 1<<12) + "The end."
+	clock := &fakeClock{}
+	state := newTestState(text, "book.txt", clock)
+	setInput(&state, text[:len(text)-8])
+	clock.advance(time.Hour)
+	renderScreen(screen, &state, 100, renderOptions{reference: &wrapCache{}})
+
+	var shown strings.Builder
+	for y := 0; y < 40; y++ {
+		shown.WriteString(cellText(screen, 0, y, 100))
+	}
+	if !strings.Contains(shown.String(), "The end.") {
+		t.Error("the end of the text isn't shown as it's typed")
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// In word-by-word mode, as on monkeytype, space submits the word being
+// typed whether or not it's right, and typing goes on at the next one.
+// The rest of a word submitted early is skipped: it's filled in from the
+// reference so positions still line up, but marked, styled and counted
+// as errors. Letters typed where a word should end are refused and
+// counted too, so a slip can't run on into the next word. Words already
+// submitted are coloured whole, by whether they were right.
+
+// wordSpan is a word of the reference, the clusters start to end
+type wordSpan struct {
+	start, end int
+}
+
+// splitWords finds the words of reference, the runs of clusters between
+// whitespace
+func splitWords(reference []string) []wordSpan {
+	var words []wordSpan
+	start := -1
+	for i, c := range reference {
+		switch {
+		case isBlank(c) && start >= 0:
+			words = append(words, wordSpan{start, i})
+			start = -1
+		case !isBlank(c) && start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, wordSpan{start, len(reference)})
+	}
+	return words
+}
+
+// wordAround returns the word holding cluster i, or the one ending there;
+// ok is false if there's neither
+func (s *TestState) wordAround(i int) (w wordSpan, ok bool) {
+	n := sort.Search(len(s.words), func(n int) bool { return s.words[n].end >= i })
+	if n == len(s.words) || s.words[n].start > i {
+		return wordSpan{}, false
+	}
+	return s.words[n], true
+}
+
+// submitsWord reports whether typing r submits the word being typed: a
+// space in word-by-word mode anywhere but where the text has whitespace
+func (s *TestState) submitsWord(r rune) bool {
+	if !s.wordByWord || r != ' ' {
+		return false
+	}
+	pos := s.typed()
+	return pos >= len(s.reference) || !isBlank(s.reference[pos])
+}
+
+// overrunsWord reports whether typing r in word-by-word mode would run
+// past the end of the word into the whitespace after it
+func (s *TestState) overrunsWord(r rune) bool {
+	pos := s.typed()
+	return s.wordByWord && pos > 0 && pos < len(s.reference) && isBlank(s.reference[pos]) &&
+		!isBlank(string(r)) && !extendsCluster(s.lastTyped(), r)
+}
+
+// skipRestOfWord fills in the rest of the word being typed from the
+// reference, marking it skipped, and reports whether there was a word
+// begun to skip to the end of
+func (s *TestState) skipRestOfWord() bool {
+	pos := s.typed()
+	w, ok := s.wordAround(pos)
+	if !ok || pos == w.start {
+		return false
+	}
+	for i := pos; i < w.end; i++ {
+		s.unitStarts = append(s.unitStarts, len(s.userInput))
+		s.userInput += s.reference[i]
+		s.skipped = append(s.skipped, i)
+		s.errors++
+		s.wordErrors++
+	}
+	s.lastUnit = unitCorrect
+	return true
+}
+
+// lastWordRight reports whether the text's last word is typed right
+func (s *TestState) lastWordRight() bool {
+	if len(s.words) == 0 {
+		return false
+	}
+	w := s.words[len(s.words)-1]
+	for i := w.start; i < w.end; i++ {
+		if !s.typedCorrectly(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSkipped reports whether cluster i was skipped by submitting its word
+// early
+func (s *TestState) isSkipped(i int) bool {
+	n := sort.SearchInts(s.skipped, i)
+	return n < len(s.skipped) && s.skipped[n] == i
+}
+
+// wordDone reports whether the word holding cluster i has been submitted
+// (typing has gone on past it), and if so whether it was right
+func (s *TestState) wordDone(i int) (done, right bool) {
+	w, ok := s.wordAround(i)
+	if !ok || s.typed() <= w.end && !s.testComplete {
+		return false, false
+	}
+	for j := w.start; j < w.end; j++ {
+		if !s.typedCorrectly(j) {
+			return true, false
+		}
+	}
+	return true, true
+}
+
+// wordStyle styles the reference's cluster i by its word in word-by-word
+// mode, once the word's been submitted, and reports whether it did
+func (s *TestState) wordStyle(i int) (tcell.Style, bool) {
+	if !s.wordByWord || i >= s.typed() || isBlank(s.reference[i]) {
+		return tcell.StyleDefault, false
+	}
+	done, right := s.wordDone(i)
+	switch {
+	case !done:
+		return tcell.StyleDefault, false
+	case right:
+		return styleCorrect, true
+	}
+	return styleMissed, true
+}
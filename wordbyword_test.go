@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// newWordByWordState is a test of text typed word by word
+func newWordByWordState(text string) TestState {
+	state := newTestState(text, "test.txt", &fakeClock{})
+	state.wordByWord, state.words = true, splitWords(state.reference)
+	return state
+}
+
+func TestSplitWords(t *testing.T) {
+	got := splitWords(splitGraphemes(" the  quick\nfox"))
+	want := []wordSpan{{1, 4}, {6, 11}, {12, 15}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitWords = %v, want %v", got, want)
+	}
+}
+
+func TestWordByWord(t *testing.T) {
+	state := newWordByWordState("the quick fox")
+	state.typeRune(' ') // nothing of the first word typed yet
+	if state.typed() != 0 || state.errors != 0 {
+		t.Fatalf("a space before the word: typed %d, %d errors", state.typed(), state.errors)
+	}
+
+	// Submit "th", then get quick wrong and run on past its end
+	typeKeys(&state, "th quack")
+	if state.userInput != "the quack" || !reflect.DeepEqual(state.skipped, []int{2}) {
+		t.Fatalf("input %q, skipped %v", state.userInput, state.skipped)
+	}
+	state.typeRune('x')
+	if state.userInput != "the quack" {
+		t.Fatalf("a letter past the end of the word was let in: %q", state.userInput)
+	}
+	typeKeys(&state, " fox")
+	if !state.testComplete {
+		t.Fatal("typing the last word right didn't complete the test")
+	}
+
+	// The skipped e, the refused x and quack's a
+	if state.errors != 3 || state.uncorrected != 2 {
+		t.Errorf("%d errors, %d uncorrected; want 3 and 2", state.errors, state.uncorrected)
+	}
+	for i, want := range map[int]tcell.Style{0: styleMissed, 4: styleMissed, 10: styleCorrect} {
+		if got := state.referenceStyle(i); got != want {
+			t.Errorf("reference %d styled %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestWordByWordBackspaceUnskips(t *testing.T) {
+	state := newWordByWordState("the fox")
+	typeKeys(&state, "th <<e")
+	if len(state.skipped) != 0 || state.userInput != "the" {
+		t.Fatalf("after backspacing over a skip: input %q, skipped %v", state.userInput, state.skipped)
+	}
+	// Submitting the last word early ends the test
+	typeKeys(&state, " f ")
+	if !state.testComplete || state.uncorrected != 2 {
+		t.Errorf("complete %v with %d uncorrected, want the 2 skipped", state.testComplete, state.uncorrected)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// commonWords are the most frequent English words, most common first.
+// Generators that must be reproducible (such as the daily challenge) index
+// into this list, so entries must only ever be appended; reordering or
+// removing words changes every generated text.
+var commonWords = []string{
+	"the", "be", "of", "and", "a", "to", "in", "he", "have", "it",
+	"that", "for", "they", "with", "as", "not", "on", "she", "at", "by",
+	"this", "we", "you", "do", "but", "from", "or", "which", "one", "would",
+	"all", "will", "there", "say", "who", "make", "when", "can", "more", "if",
+	"no", "man", "out", "other", "so", "what", "time", "up", "go", "about",
+	"than", "into", "could", "state", "only", "new", "year", "some", "take", "come",
+	"these", "know", "see", "use", "get", "like", "then", "first", "any", "work",
+	"now", "may", "such", "give", "over", "think", "most", "even", "find", "day",
+	"also", "after", "way", "many", "must", "look", "before", "great", "back", "through",
+	"long", "where", "much", "should", "well", "people", "down", "own", "just", "because",
+	"good", "each", "those", "feel", "seem", "how", "high", "too", "place", "little",
+	"world", "very", "still", "nation", "hand", "old", "life", "tell", "write", "become",
+	"here", "show", "house", "both", "between", "need", "mean", "call", "develop", "under",
+	"last", "right", "move", "thing", "general", "school", "never", "same", "another", "begin",
+	"while", "number", "part", "turn", "real", "leave", "might", "want", "point", "form",
+	"off", "child", "few", "small", "since", "against", "ask", "late", "home", "interest",
+	"large", "person", "end", "open", "public", "follow", "during", "present", "without", "again",
+	"hold", "govern", "around", "possible", "head", "consider", "word", "program", "problem", "however",
+	"lead", "system", "set", "order", "eye", "plan", "run", "keep", "face", "fact",
+	"group", "play", "stand", "increase", "early", "course", "change", "help", "line", "city",
+	"put", "close", "case", "force", "meet", "once", "water", "upon", "war", "build",
+	"hear", "light", "unite", "live", "every", "country", "bring", "center", "let", "side",
+	"try", "provide", "continue", "name", "certain", "power", "pay", "result", "question", "study",
+	"woman", "member", "until", "far", "night", "always", "service", "away", "report", "something",
+	"company", "week", "church", "toward", "start", "social", "room", "figure", "nature", "though",
+	"young", "less", "enough", "almost", "read", "include", "president", "nothing", "yet", "better",
+	"big", "boy", "cost", "business", "value", "second", "why", "clear", "expect", "family",
+	"complete", "act", "sense", "mind", "experience", "art", "next", "near", "direct", "car",
+	"law", "industry", "important", "girl", "god", "several", "matter", "usual", "rather", "per",
+	"often", "kind", "among", "white", "reason", "action", "return", "foot", "care", "simple",
+}
+
+// defaultWordCount is the length of a words mode test unless --count says
+// otherwise
+const defaultWordCount = 50
+
+// wordsVocabulary is how many of the most common words words mode draws
+// from, enough for variety while keeping to words everyone types daily
+const wordsVocabulary = 200
+
+// wordsFilePrefix names words mode tests in TestState.testFile; the rest
+// is the word count, so each length has its own bests
+const wordsFilePrefix = "words-"
+
+// randomWords returns n common words picked at random, never the same
+// word twice in a row
+func randomWords(n int, rng Rand) string {
+	words := make([]string, n)
+	last := -1
+	for i := range words {
+		var j int
+		if last < 0 {
+			j = rng.Intn(wordsVocabulary)
+		} else if j = rng.Intn(wordsVocabulary - 1); j >= last {
+			j++ // pick from all but the last word by skipping over it
+		}
+		words[i], last = commonWords[j], j
+	}
+	return strings.Join(words, " ")
+}
+
+// wordTest returns a words mode test of the engine's word count
+func (e *Engine) wordTest() TestState {
+	logger.Info("selected random words", "count", e.wordCount)
+	return e.newTest(randomWords(e.wordCount, e.rng), fmt.Sprintf("%s%d", wordsFilePrefix, e.wordCount))
+}
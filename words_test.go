@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestWordTest(t *testing.T) {
+	engine := newEngine(&fakeClock{}, fixedRand(1), "")
+	engine.mode = modeWords
+	engine.wordCount = 5
+	state, err := engine.nextTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same pick each time still never repeats a word
+	if want := "be of be of be"; state.referenceText != want {
+		t.Errorf("text = %q, want %q", state.referenceText, want)
+	}
+	if state.testFile != "words-5" {
+		t.Errorf("test file = %q, want words-5", state.testFile)
+	}
+}
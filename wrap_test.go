@@ -0,0 +1,149 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// wrapInput is a random wrapText argument pair. Text is drawn from an
+// alphabet deliberately heavy in the cases the table tests miss: double-width
+// CJK, emoji (including ZWJ sequences), combining marks, tabs, blank lines,
+// and words longer than the wrap width.
+type wrapInput struct {
+	Text  string
+	Width int
+}
+
+var wrapAlphabet = []string{
+	"a", "b", "z", "Q", ".", ",", "'", "é", "ß", "—",
+	"中", "文", "日本", "한",
+	"😀", "👩‍💻", "👍🏽", "🇯🇵",
+	"é", "ñ",
+	" ", " ", " ", "  ", "\t", "\n", "\n\n",
+}
+
+func (wrapInput) Generate(r *rand.Rand, size int) reflect.Value {
+	var sb strings.Builder
+	for i := r.Intn(size*4 + 1); i > 0; i-- {
+		// Occasionally emit an unbreakable run to force mid-word splits
+		if r.Intn(20) == 0 {
+			sb.WriteString(strings.Repeat(wrapAlphabet[r.Intn(14)], 10+r.Intn(30)))
+			continue
+		}
+		sb.WriteString(wrapAlphabet[r.Intn(len(wrapAlphabet))])
+	}
+
+	// A double-width glyph can never fit in a single cell, so the
+	// narrowest meaningful width is 2
+	return reflect.ValueOf(wrapInput{Text: sb.String(), Width: 2 + r.Intn(40)})
+}
+
+func stripSpace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func checkWrapProperty(t *testing.T, name string, property func(in wrapInput) bool) {
+	t.Helper()
+	if err := quick.Check(property, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Errorf("%s: %v", name, err)
+	}
+}
+
+func TestWrapTextLinesFitWidth(t *testing.T) {
+	checkWrapProperty(t, "line wider than width", func(in wrapInput) bool {
+		for _, line := range wrapText(in.Text, in.Width) {
+			if runewidth.StringWidth(line) > in.Width {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestWrapTextPreservesContent(t *testing.T) {
+	checkWrapProperty(t, "non-whitespace content changed", func(in wrapInput) bool {
+		lines := wrapText(in.Text, in.Width)
+		return stripSpace(strings.Join(lines, "")) == stripSpace(in.Text)
+	})
+}
+
+func TestWrapTextLinesAreTrimmed(t *testing.T) {
+	checkWrapProperty(t, "line has stray whitespace", func(in wrapInput) bool {
+		for _, line := range wrapText(in.Text, in.Width) {
+			if line != strings.TrimSpace(line) || strings.Contains(line, "\n") {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestWrapTextKeepsParagraphBreaks(t *testing.T) {
+	checkWrapProperty(t, "paragraph breaks lost", func(in wrapInput) bool {
+		// Every newline starts a new line, so wrapping can only add lines
+		return len(wrapText(in.Text, in.Width)) >= strings.Count(in.Text, "\n")+1
+	})
+}
+
+// FuzzWrapText checks the wrapping properties above on whatever text the
+// fuzzer finds, including invalid UTF-8, which must at least not panic
+func FuzzWrapText(f *testing.F) {
+	for _, seed := range []string{"Hello, world", "中文日本한 words\n\nand 👩‍💻", "\t  é\n", strings.Repeat("x", 50)} {
+		f.Add(seed, 10)
+	}
+	f.Fuzz(func(t *testing.T, text string, width int) {
+		// A double-width glyph can never fit in a single cell
+		width = fuzzRange(width, 2, 80)
+		lines := wrapText(text, width)
+		if !utf8.ValidString(text) {
+			return
+		}
+		for _, line := range lines {
+			if runewidth.StringWidth(line) > width {
+				t.Errorf("line %q wider than %d", line, width)
+			}
+		}
+		if stripSpace(strings.Join(lines, "")) != stripSpace(text) {
+			t.Errorf("wrapping %q at %d changed its content: %q", text, width, lines)
+		}
+		if len(lines) < strings.Count(text, "\n")+1 {
+			t.Errorf("wrapping %q lost paragraph breaks: %q", text, lines)
+		}
+	})
+}
+
+// fuzzRange maps an arbitrary fuzzed int onto lo..hi
+func fuzzRange(n, lo, hi int) int {
+	span := hi - lo + 1
+	return lo + (n%span+span)%span
+}
+
+func TestWrapTextKeepsClustersWhole(t *testing.T) {
+	const coder = "\U0001F469\u200d\U0001F4BB" // two cells, three code points
+	tests := []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{strings.Repeat("e\u0301", 6), 4, []string{strings.Repeat("e\u0301", 4), strings.Repeat("e\u0301", 2)}},
+		{strings.Repeat(coder, 3), 4, []string{coder + coder, coder}},
+		{"a" + coder, 2, []string{"a", coder}},
+	}
+	for _, tt := range tests {
+		if got := wrapText(tt.text, tt.width); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wrapText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Zen mode is free typing with no text to copy: whatever is typed is kept
+// for a fixed time, a minute unless --time says otherwise, then scored
+// for speed and rhythm only, since there's nothing to be wrong against.
+// The text is saved as a draft in zen/ in the data directory. Press Z on
+// the welcome screen to start.
+
+// zenDuration is how long zen mode runs when no time limit is set
+const zenDuration = time.Minute
+
+// zenResultsGrace is how long the results ignore keys for, so the last
+// few keystrokes of a session cut off mid-word don't dismiss them
+const zenResultsGrace = time.Second
+
+// zenSession is a run of zen mode
+type zenSession struct {
+	limit      time.Duration
+	text       []rune
+	start, end time.Time // end is set once it's over
+	keystrokes int
+	backspaces int
+	lastKey    time.Time
+
+	// rhythm keeps only the gaps between keystrokes, for its consistency
+	rhythm TestState
+}
+
+func newZenSession(limit time.Duration) *zenSession {
+	return &zenSession{limit: limit}
+}
+
+// key records a keystroke at now, the first starting the clock
+func (z *zenSession) key(now time.Time) {
+	if z.start.IsZero() {
+		z.start = now
+	} else {
+		z.rhythm.recordInterval(now.Sub(z.lastKey))
+	}
+	z.lastKey = now
+	z.keystrokes++
+}
+
+// typeRune adds r to the text
+func (z *zenSession) typeRune(r rune, now time.Time) {
+	z.key(now)
+	z.text = append(z.text, r)
+}
+
+// backspace takes the last character off the text
+func (z *zenSession) backspace(now time.Time) {
+	if len(z.text) == 0 {
+		return
+	}
+	z.key(now)
+	z.backspaces++
+	z.text = z.text[:len(z.text)-1]
+}
+
+// elapsed is how long the session has run by now, up to its limit
+func (z *zenSession) elapsed(now time.Time) time.Duration {
+	switch {
+	case z.start.IsZero():
+		return 0
+	case !z.end.IsZero():
+		now = z.end
+	}
+	return min(now.Sub(z.start), z.limit)
+}
+
+// expire ends the session if its time is up, and reports whether it did
+func (z *zenSession) expire(now time.Time) bool {
+	if z.start.IsZero() || !z.end.IsZero() || now.Sub(z.start) < z.limit {
+		return false
+	}
+	z.end = z.start.Add(z.limit)
+	return true
+}
+
+// stats sums up the session as of now
+func (z *zenSession) stats(now time.Time) []string {
+	elapsed := z.elapsed(now)
+	return []string{
+		fmt.Sprintf("WPM: %.1f", calculateWPM(len(z.text), elapsed)),
+		fmt.Sprintf("Characters: %d in %d words (%d keystrokes, %d backspaces)", len(z.text), len(strings.Fields(string(z.text))), z.keystrokes, z.backspaces),
+		fmt.Sprintf("Rhythm: consistency %.0f%%, a key every %.0f ms on average", z.rhythm.consistency(), z.rhythm.intervalMean*1000),
+		fmt.Sprintf("Time: %.1fs", elapsed.Seconds()),
+	}
+}
+
+// runZen plays a zen session until its time is up or Escape is pressed.
+// ok is false if it was left before anything was typed.
+func runZen(screen tcell.Screen, clock Clock, limit time.Duration) (z *zenSession, ok bool) {
+	z = newZenSession(limit)
+	stopTicker := startTicker(screen, 250*time.Millisecond)
+	defer stopTicker()
+	defer screen.HideCursor()
+	for {
+		now := clock.Now()
+		if z.expire(now) {
+			return z, true
+		}
+		screen.Clear()
+		width, height := screen.Size()
+		drawCenteredText(screen, width/2, 1, tcell.StyleDefault, "ZEN")
+		status := fmt.Sprintf("Type anything. %.0fs | %d characters | ESC to finish", (z.limit - z.elapsed(now)).Seconds(), len(z.text))
+		drawCenteredText(screen, width/2, 2, tcell.StyleDefault, status)
+		lines := wrapText(string(z.text), max(10, width-4))
+		if len(lines) == 0 {
+			lines = []string{""}
+		}
+		rows := max(1, height-6)
+		lines = lines[max(0, len(lines)-rows):]
+		for i, line := range lines {
+			drawText(screen, 2, 4+i, tcell.StyleDefault, line)
+		}
+		screen.ShowCursor(2+len([]rune(lines[len(lines)-1])), 4+len(lines)-1)
+		screen.Show()
+
+		ev, isKey := screen.PollEvent().(*tcell.EventKey)
+		if !isKey {
+			continue
+		}
+		now = clock.Now()
+		if z.expire(now) {
+			return z, true
+		}
+		switch ev.Key() {
+		case tcell.KeyEscape:
+			if z.start.IsZero() {
+				return z, false
+			}
+			z.end = now
+			return z, true
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			z.backspace(now)
+		case tcell.KeyEnter:
+			z.typeRune('\n', now)
+		case tcell.KeyRune:
+			z.typeRune(ev.Rune(), now)
+		}
+	}
+}
+
+// saveZenText keeps what was typed in a zen session as a draft, returning
+// its path
+func saveZenText(text string, now time.Time) (string, error) {
+	dir, err := dataFile("zen")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, now.Format("20060102-150405")+".txt")
+	return path, os.WriteFile(path, []byte(text), 0o644)
+}
+
+// showZenResults shows a finished session's stats and where its text was
+// saved until a key is pressed
+func showZenResults(screen tcell.Screen, stats []string, saved string) {
+	shown := time.Now()
+	for {
+		screen.Clear()
+		width, height := screen.Size()
+		drawCenteredText(screen, width/2, height/2-4, tcell.StyleDefault, "ZEN COMPLETE")
+		for i, line := range stats {
+			drawCenteredText(screen, width/2, height/2-2+i, tcell.StyleDefault, line)
+		}
+		drawCenteredText(screen, width/2, height/2+3, tcell.StyleDefault, saved)
+		drawCenteredText(screen, width/2, height/2+5, tcell.StyleDefault, "Press any key to go back")
+		screen.Show()
+
+		switch ev := screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			if ev.When().Sub(shown) >= zenResultsGrace {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestZenSession(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}
+	z := newZenSession(30 * time.Second)
+	for _, r := range "hello worlx" {
+		z.typeRune(r, clock.Now())
+		clock.advance(200 * time.Millisecond)
+	}
+	z.backspace(clock.Now())
+	clock.advance(200 * time.Millisecond)
+	z.typeRune('d', clock.Now())
+
+	if string(z.text) != "hello world" || z.keystrokes != 13 || z.backspaces != 1 {
+		t.Fatalf("text %q, %d keystrokes, %d backspaces", string(z.text), z.keystrokes, z.backspaces)
+	}
+	if z.expire(clock.Now()) {
+		t.Fatal("expired early")
+	}
+	clock.advance(30 * time.Second)
+	if !z.expire(clock.Now()) || z.elapsed(clock.Now().Add(time.Hour)) != 30*time.Second {
+		t.Errorf("elapsed %v after expiring, want the 30s limit", z.elapsed(clock.Now()))
+	}
+
+	stats := strings.Join(z.stats(clock.Now()), "\n")
+	for _, want := range []string{"Characters: 11 in 2 words (13 keystrokes, 1 backspaces)", "consistency 100%", "a key every 200 ms", "Time: 30.0s"} {
+		if !strings.Contains(stats, want) {
+			t.Errorf("stats missing %q:\n%s", want, stats)
+		}
+	}
+}
+
+func TestRunZen(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}
+
+	go func() {
+		for _, r := range "hi" {
+			screen.PostEventWait(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+		}
+		screen.PostEventWait(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+		screen.PostEventWait(tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone))
+		screen.PostEventWait(tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone))
+	}()
+	z, ok := runZen(screen, clock, time.Minute)
+	if !ok || string(z.text) != "hi\nx" {
+		t.Fatalf("ok %v, text %q", ok, string(z.text))
+	}
+
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	path, err := saveZenText(string(z.text), clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "hi\nx" {
+		t.Errorf("saved %q (%v)", data, err)
+	}
+}